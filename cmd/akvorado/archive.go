@@ -0,0 +1,111 @@
+// SPDX-FileCopyrightText: 2026 Free Mobile
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"akvorado/common/clickhousedb"
+	"akvorado/common/daemon"
+	"akvorado/common/httpserver"
+	"akvorado/common/reporter"
+	"akvorado/common/schema"
+	"akvorado/orchestrator/clickhouse"
+)
+
+type archiveRestoreOptions struct {
+	ConfigRelatedOptions
+	From  string
+	To    string
+	Table string
+}
+
+// ArchiveRestoreOptions stores the command-line option values for the
+// archive-restore command.
+var ArchiveRestoreOptions archiveRestoreOptions
+
+var archiveRestoreCmd = &cobra.Command{
+	Use:   "archive-restore",
+	Short: "Restore archived flows into a table",
+	Long: `Import flows archived by the orchestrator service into a new ClickHouse
+table for investigation. The configuration file is the one of the
+orchestrator service. The table is not expired: drop it when done.`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		from, err := time.Parse(time.RFC3339, ArchiveRestoreOptions.From)
+		if err != nil {
+			return fmt.Errorf("invalid start time: %w", err)
+		}
+		to, err := time.Parse(time.RFC3339, ArchiveRestoreOptions.To)
+		if err != nil {
+			return fmt.Errorf("invalid end time: %w", err)
+		}
+		if !from.Before(to) {
+			return errors.New("start time should be before end time")
+		}
+
+		config := OrchestratorConfiguration{}
+		ArchiveRestoreOptions.Path = args[0]
+		if _, err := ArchiveRestoreOptions.Parse(cmd.OutOrStdout(), "orchestrator", &config); err != nil {
+			return err
+		}
+		r, err := reporter.New(config.Reporting)
+		if err != nil {
+			return fmt.Errorf("unable to initialize reporter: %w", err)
+		}
+		daemonComponent, err := daemon.New(r)
+		if err != nil {
+			return fmt.Errorf("unable to initialize daemon component: %w", err)
+		}
+		httpComponent, err := httpserver.New(r, "orchestrator", config.HTTP, httpserver.Dependencies{
+			Daemon: daemonComponent,
+		})
+		if err != nil {
+			return fmt.Errorf("unable to initialize HTTP component: %w", err)
+		}
+		schemaComponent, err := schema.New(config.Schema)
+		if err != nil {
+			return fmt.Errorf("unable to initialize schema component: %w", err)
+		}
+		clickhouseDBComponent, err := clickhousedb.New(r, config.ClickHouseDB, clickhousedb.Dependencies{
+			Daemon: daemonComponent,
+		})
+		if err != nil {
+			return fmt.Errorf("unable to initialize ClickHouse component: %w", err)
+		}
+		defer clickhouseDBComponent.Close()
+		clickhouseComponent, err := clickhouse.New(r, config.ClickHouse, clickhouse.Dependencies{
+			Daemon:     daemonComponent,
+			HTTP:       httpComponent,
+			ClickHouse: clickhouseDBComponent,
+			Schema:     schemaComponent,
+		})
+		if err != nil {
+			return fmt.Errorf("unable to initialize clickhouse component: %w", err)
+		}
+
+		count, err := clickhouseComponent.RestoreArchive(cmd.Context(), from, to, ArchiveRestoreOptions.Table)
+		if err != nil {
+			return err
+		}
+		cmd.Printf("%d flows restored into %s\n", count, ArchiveRestoreOptions.Table)
+		return nil
+	},
+}
+
+func init() {
+	RootCmd.AddCommand(archiveRestoreCmd)
+	archiveRestoreCmd.Flags().StringVarP(&ArchiveRestoreOptions.From, "from", "", "",
+		"Start of the range to restore (RFC 3339)")
+	archiveRestoreCmd.Flags().StringVarP(&ArchiveRestoreOptions.To, "to", "", "",
+		"End of the range to restore (RFC 3339)")
+	archiveRestoreCmd.Flags().StringVarP(&ArchiveRestoreOptions.Table, "table", "", "flows_restored",
+		"Name of the table to create")
+	archiveRestoreCmd.MarkFlagRequired("from")
+	archiveRestoreCmd.MarkFlagRequired("to")
+}
@@ -16,6 +16,7 @@ import (
 	"akvorado/console"
 	"akvorado/console/authentication"
 	"akvorado/console/database"
+	"akvorado/outlet/geoip"
 )
 
 // ConsoleConfiguration represents the configuration file for the console command.
@@ -27,6 +28,7 @@ type ConsoleConfiguration struct {
 	Auth       authentication.Configuration
 	Database   database.Configuration
 	Schema     schema.Configuration
+	GeoIP      geoip.Configuration
 }
 
 // Reset resets the console configuration to its default value.
@@ -39,6 +41,7 @@ func (c *ConsoleConfiguration) Reset() {
 		Auth:       authentication.DefaultConfiguration(),
 		Database:   database.DefaultConfiguration(),
 		Schema:     schema.DefaultConfiguration(),
+		GeoIP:      geoip.DefaultConfiguration(),
 	}
 }
 
@@ -97,18 +100,26 @@ func consoleStart(r *reporter.Reporter, config ConsoleConfiguration, checkOnly b
 	if err != nil {
 		return fmt.Errorf("unable to initialize ClickHouse component: %w", err)
 	}
-	authenticationComponent, err := authentication.New(r, config.Auth)
-	if err != nil {
-		return fmt.Errorf("unable to initialize authentication component: %w", err)
-	}
 	databaseComponent, err := database.New(r, config.Database)
 	if err != nil {
 		return fmt.Errorf("unable to initialize database component: %w", err)
 	}
+	authenticationComponent, err := authentication.New(r, config.Auth, authentication.Dependencies{
+		Database: databaseComponent,
+	})
+	if err != nil {
+		return fmt.Errorf("unable to initialize authentication component: %w", err)
+	}
 	schemaComponent, err := schema.New(config.Schema)
 	if err != nil {
 		return fmt.Errorf("unable to initialize schema component: %w", err)
 	}
+	geoipComponent, err := geoip.New(r, config.GeoIP, geoip.Dependencies{
+		Daemon: daemonComponent,
+	})
+	if err != nil {
+		return fmt.Errorf("unable to initialize GeoIP component: %w", err)
+	}
 	consoleComponent, err := console.New(r, config.Console, console.Dependencies{
 		Daemon:       daemonComponent,
 		HTTP:         httpComponent,
@@ -116,6 +127,7 @@ func consoleStart(r *reporter.Reporter, config ConsoleConfiguration, checkOnly b
 		Auth:         authenticationComponent,
 		Database:     databaseComponent,
 		Schema:       schemaComponent,
+		GeoIP:        geoipComponent,
 	})
 	if err != nil {
 		return fmt.Errorf("unable to initialize console component: %w", err)
@@ -134,8 +146,9 @@ func consoleStart(r *reporter.Reporter, config ConsoleConfiguration, checkOnly b
 	components := []any{
 		httpComponent,
 		clickhouseComponent,
-		authenticationComponent,
 		databaseComponent,
+		authenticationComponent,
+		geoipComponent,
 		consoleComponent,
 	}
 	return StartStopComponents(r, daemonComponent, components)
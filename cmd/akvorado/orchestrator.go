@@ -7,6 +7,7 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"maps"
 	"path/filepath"
 	"reflect"
 	"slices"
@@ -117,11 +118,26 @@ components and centralizes configuration of the various other components.`,
 				}
 				config.Outlet[idx].Schema = config.Schema
 			}
+			if len(config.Outlet) > 0 {
+				// The networks dictionary is built from the networks of the
+				// first outlet. The fetcher alters the network sources, copy them.
+				config.ClickHouse.Networks = config.Outlet[0].Networks
+				config.ClickHouse.Networks.NetworkSources = maps.Clone(config.Outlet[0].Networks.NetworkSources)
+			}
 			for idx := range config.Console {
 				if !slices.Contains(metadata.Keys, fmt.Sprintf("Console[%d].ClickHouse.Servers[0]", idx)) {
 					config.Console[idx].ClickHouse = config.ClickHouseDB
 				}
 				config.Console[idx].Schema = config.Schema
+				config.Console[idx].Console.Networks = config.ClickHouse.Networks.Networks != nil ||
+					len(config.ClickHouse.Networks.NetworkSources) > 0
+				config.Console[idx].Console.Anomalies = config.ClickHouse.Anomalies.Enabled()
+				if len(config.Console[idx].GeoIP.GeoDatabase) == 0 && len(config.Outlet) > 0 {
+					// Only the geo databases are needed to locate cities.
+					// They may not be reachable from the console.
+					config.Console[idx].GeoIP.GeoDatabase = config.Outlet[0].GeoIP.GeoDatabase
+					config.Console[idx].GeoIP.Optional = true
+				}
 			}
 		}
 		// Parse and check the configuration a first time to start monitoring
@@ -5,6 +5,7 @@ package httpserver
 
 import (
 	"bytes"
+	"context"
 	"crypto/sha256"
 	"errors"
 	"fmt"
@@ -25,10 +26,36 @@ type cachedResponse struct {
 	Body    []byte
 }
 
+// cacheScopeContextKey is the key under which the cache scope is stored in the
+// request context.
+type cacheScopeContextKey struct{}
+
+// WithCacheScope returns a context where cached responses are only shared with
+// requests having the same scope. This is useful when the response depends on
+// the user.
+func WithCacheScope(ctx context.Context, scope string) context.Context {
+	return context.WithValue(ctx, cacheScopeContextKey{}, scope)
+}
+
+// CacheScope returns the cache scope stored in the provided context. It is
+// empty when no scope was set.
+func CacheScope(ctx context.Context) string {
+	scope, _ := ctx.Value(cacheScopeContextKey{}).(string)
+	return scope
+}
+
+// cacheScope returns the cache scope of the provided request.
+func cacheScope(req *http.Request) string {
+	return CacheScope(req.Context())
+}
+
 // CacheByRequestPath is a middleware that caches the response keyed
 // on the request path.
 func (c *Component) CacheByRequestPath(expire time.Duration) Middleware {
 	return c.cacheMiddleware(expire, func(req *http.Request) (string, bool) {
+		if scope := cacheScope(req); scope != "" {
+			return fmt.Sprintf("cache-path-%s\x00%s", scope, req.URL.Path), true
+		}
 		return fmt.Sprintf("cache-path-%s", req.URL.Path), true
 	})
 }
@@ -45,7 +72,7 @@ func (c *Component) CacheByRequestBody(expire time.Duration) Middleware {
 			return "", false
 		}
 		h := sha256.New()
-		fmt.Fprintf(h, "%s\x00%s\x00", req.Method, req.URL.Path)
+		fmt.Fprintf(h, "%s\x00%s\x00%s\x00", cacheScope(req), req.Method, req.URL.Path)
 		h.Write(body)
 		return fmt.Sprintf("cache-request-%s", string(h.Sum(nil))), true
 	})
@@ -116,6 +116,55 @@ func TestCacheByRequestBody(t *testing.T) {
 	}
 }
 
+func TestCacheScope(t *testing.T) {
+	r := reporter.NewMock(t)
+	h := httpserver.NewMock(t, r)
+
+	count := 0
+	scoped := h.APIRouter.Group("", func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			ctx := httpserver.WithCacheScope(req.Context(), req.Header.Get("X-Scope"))
+			next.ServeHTTP(w, req.WithContext(ctx))
+		})
+	})
+	scoped.GET("/api/v0/test",
+		func(w http.ResponseWriter, _ *http.Request) {
+			count++
+			httpserver.WriteJSON(w, http.StatusOK, helpers.M{
+				"message": "ping",
+				"count":   count,
+			})
+		},
+		h.CacheByRequestPath(time.Minute))
+	scope := func(name string) http.Header {
+		headers := make(http.Header)
+		headers.Add("X-Scope", name)
+		return headers
+	}
+
+	helpers.TestHTTPEndpoints(t, h.LocalAddr(), helpers.HTTPEndpointCases{
+		{
+			Description: "not cached",
+			URL:         "/api/v0/test",
+			JSONOutput:  helpers.M{"message": "ping", "count": 1},
+		}, {
+			Description: "other scope, not cached",
+			URL:         "/api/v0/test",
+			Header:      scope("acme"),
+			JSONOutput:  helpers.M{"message": "ping", "count": 2},
+		}, {
+			Description: "other scope, cached",
+			URL:         "/api/v0/test",
+			Header:      scope("acme"),
+			JSONOutput:  helpers.M{"message": "ping", "count": 2},
+		}, {
+			Description: "no scope, cached",
+			URL:         "/api/v0/test",
+			JSONOutput:  helpers.M{"message": "ping", "count": 1},
+		},
+	})
+}
+
 func TestRedis(t *testing.T) {
 	server := helpers.CheckExternalService(t, "Redis",
 		[]string{"redis:6379", "127.0.0.1:6379"})
@@ -81,3 +81,8 @@ func (r *Router) POST(pattern string, handler http.HandlerFunc, mw ...Middleware
 func (r *Router) DELETE(pattern string, handler http.HandlerFunc, mw ...Middleware) {
 	r.Handle(http.MethodDelete, pattern, handler, mw...)
 }
+
+// PUT registers a PUT handler.
+func (r *Router) PUT(pattern string, handler http.HandlerFunc, mw ...Middleware) {
+	r.Handle(http.MethodPut, pattern, handler, mw...)
+}
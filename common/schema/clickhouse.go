@@ -280,6 +280,30 @@ func (bf *FlowMessage) appendDebug(columnKey ColumnKey, value any) {
 	bf.OtherColumns[columnKey] = value
 }
 
+// GetUint returns the UInt64/32/16/8 or Enum8 value of the provided column
+// for the current flow. It returns 0 if the value was not set.
+func (bf *FlowMessage) GetUint(columnKey ColumnKey) uint64 {
+	columnKey = reverse(bf, columnKey)
+	col := bf.batch.columns[columnKey]
+	if col == nil || !bf.batch.columnSet.Test(uint(columnKey)) {
+		return 0
+	}
+	switch col := col.(type) {
+	case *proto.ColUInt64:
+		return (*col)[len(*col)-1]
+	case *proto.ColUInt32:
+		return uint64((*col)[len(*col)-1])
+	case *proto.ColUInt16:
+		return uint64((*col)[len(*col)-1])
+	case *proto.ColUInt8:
+		return uint64((*col)[len(*col)-1])
+	case *proto.ColEnum8:
+		return uint64((*col)[len(*col)-1])
+	default:
+		panic(fmt.Sprintf("unhandled uint type %q", col.Type()))
+	}
+}
+
 // check executes some sanity checks when in debug mode. It should be called
 // only after finalization.
 func (bf *FlowMessage) check() {
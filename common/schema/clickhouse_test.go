@@ -82,6 +82,30 @@ func TestAppendWithDisabledColumns(t *testing.T) {
 	bf.Finalize()
 }
 
+func TestGetUint(t *testing.T) {
+	c := NewMock(t)
+	bf := c.NewFlowMessage()
+
+	bf.AppendUint(ColumnDstPort, 443)
+	bf.AppendUint(ColumnProto, 6)
+	bf.AppendUint(ColumnBytes, 1500)
+	bf.Finalize()
+	bf.AppendUint(ColumnDstPort, 53)
+	bf.AppendUint(ColumnInIfBoundary, uint64(InterfaceBoundaryExternal))
+
+	got := []uint64{
+		bf.GetUint(ColumnDstPort),
+		bf.GetUint(ColumnProto),
+		bf.GetUint(ColumnBytes),
+		bf.GetUint(ColumnInIfBoundary),
+		bf.GetUint(ColumnSrcVlan), // disabled
+	}
+	expected := []uint64{53, 0, 0, uint64(InterfaceBoundaryExternal), 0}
+	if diff := helpers.Diff(got, expected); diff != "" {
+		t.Errorf("GetUint() (-got, +want):\n%s", diff)
+	}
+}
+
 func TestAppendArrayUInt32Columns(t *testing.T) {
 	c := NewMock(t)
 	bf := c.NewFlowMessage()
@@ -10,6 +10,7 @@ import (
 	"strings"
 
 	"akvorado/common/helpers"
+	"akvorado/common/remotedatasource"
 )
 
 // SkipIndexType describes a ClickHouse data-skipping index.
@@ -72,15 +73,30 @@ type Configuration struct {
 	NoIndexes []ColumnKey
 	// CustomDictionaries allows enrichment of flows with custom metadata
 	CustomDictionaries map[string]CustomDict `validate:"dive"`
+	// ComputedColumns defines additional columns populated by the outlet
+	ComputedColumns []ComputedColumn `validate:"dive"`
 }
 
-// CustomDict represents a single custom dictionary
+// ComputedColumn represents a user-defined column. Its value is computed by
+// the outlet using rules from the core configuration. Like for the static
+// columns, a column whose name starts with Src or InIf is duplicated as a Dst
+// or OutIf column.
+type ComputedColumn struct {
+	Name string `validate:"required,alphanum"`
+	Type string `validate:"required,oneof=String UInt8 UInt16 UInt32 UInt64"`
+}
+
+// CustomDict represents a single custom dictionary. Its content is either read
+// from a local CSV file (Source) or fetched periodically from a remote source
+// (RemoteSource). In the later case, each result should contain the keys and
+// the attributes of the dictionary.
 type CustomDict struct {
-	Keys       []CustomDictKey       `validate:"required,dive"`
-	Attributes []CustomDictAttribute `validate:"required,dive"`
-	Source     string                `validate:"required"`
-	Layout     CustomDictLayout      `validate:"required"`
-	Dimensions []string              `validate:"required"`
+	Keys         []CustomDictKey       `validate:"required,dive"`
+	Attributes   []CustomDictAttribute `validate:"required,dive"`
+	Source       string                `validate:"required_without=RemoteSource,excluded_with=RemoteSource"`
+	RemoteSource *remotedatasource.Source
+	Layout       CustomDictLayout `validate:"required"`
+	Dimensions   []string         `validate:"required"`
 }
 
 // CustomDictLayout is the layout of a custom dictionary. It uses the same names
@@ -172,6 +188,11 @@ func (c *Component) GetCustomDictConfig() map[string]CustomDict {
 	return c.c.CustomDictionaries
 }
 
+// GetComputedColumns returns the computed columns encoded in this schema
+func (c *Component) GetComputedColumns() []ComputedColumn {
+	return c.c.ComputedColumns
+}
+
 // GetSkipIndexes returns the configured data-skipping indexes.
 func (c *Component) GetSkipIndexes() map[ColumnKey]SkipIndexType {
 	return c.c.Indexes
@@ -184,6 +205,13 @@ func DefaultCustomDictConfiguration() CustomDict {
 	}
 }
 
+// DefaultComputedColumnConfiguration is the default config for a ComputedColumn
+func DefaultComputedColumnConfiguration() ComputedColumn {
+	return ComputedColumn{
+		Type: "String",
+	}
+}
+
 // DefaultCustomDictKeyConfiguration is the default config for a CustomDictKey
 func DefaultCustomDictKeyConfiguration() CustomDictKey {
 	return CustomDictKey{
@@ -205,4 +233,6 @@ func init() {
 		helpers.DefaultValuesUnmarshallerHook(DefaultCustomDictKeyConfiguration()))
 	helpers.RegisterMapstructureUnmarshallerHook(
 		helpers.DefaultValuesUnmarshallerHook(DefaultCustomDictAttributeConfiguration()))
+	helpers.RegisterMapstructureUnmarshallerHook(
+		helpers.DefaultValuesUnmarshallerHook(DefaultComputedColumnConfiguration()))
 }
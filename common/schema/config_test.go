@@ -5,8 +5,10 @@ package schema_test
 
 import (
 	"testing"
+	"time"
 
 	"akvorado/common/helpers"
+	"akvorado/common/remotedatasource"
 	"akvorado/common/schema"
 )
 
@@ -73,3 +75,71 @@ func TestCustomDictLayoutDecode(t *testing.T) {
 		},
 	})
 }
+
+func TestCustomDictRemoteSourceDecode(t *testing.T) {
+	customDict := func(extra helpers.M) func() any {
+		return func() any {
+			dict := helpers.M{
+				"keys":       []helpers.M{{"name": "addr"}},
+				"attributes": []helpers.M{{"name": "role"}},
+				"dimensions": []string{"SrcAddr"},
+			}
+			for k, v := range extra {
+				dict[k] = v
+			}
+			return helpers.M{"custom-dictionaries": helpers.M{"test": dict}}
+		}
+	}
+	expected := schema.DefaultConfiguration()
+	expected.CustomDictionaries = map[string]schema.CustomDict{
+		"test": {
+			Layout:     "hashed",
+			Keys:       []schema.CustomDictKey{{Name: "addr", Type: "String"}},
+			Attributes: []schema.CustomDictAttribute{{Name: "role", Type: "String"}},
+			RemoteSource: &remotedatasource.Source{
+				URL:      "https://example.net/customers",
+				Method:   "GET",
+				Headers:  map[string]string{"X-Foo": "hello"},
+				Timeout:  time.Minute,
+				Interval: 10 * time.Minute,
+				Transform: remotedatasource.MustParseTransformQuery(
+					".[] | {addr: .prefix, role: .name}"),
+			},
+			Dimensions: []string{"SrcAddr"},
+		},
+	}
+	helpers.TestConfigurationDecode(t, helpers.ConfigurationDecodeCases{
+		{
+			Pos:         helpers.Mark(),
+			Description: "remote source",
+			Initial:     func() any { return schema.DefaultConfiguration() },
+			Configuration: customDict(helpers.M{
+				"remote-source": helpers.M{
+					"url":       "https://example.net/customers",
+					"headers":   helpers.M{"X-Foo": "hello"},
+					"interval":  "10m",
+					"transform": ".[] | {addr: .prefix, role: .name}",
+				},
+			}),
+			Expected: expected,
+		}, {
+			Pos:           helpers.Mark(),
+			Description:   "no source",
+			Initial:       func() any { return schema.DefaultConfiguration() },
+			Configuration: customDict(nil),
+			Error:         true,
+		}, {
+			Pos:         helpers.Mark(),
+			Description: "both sources",
+			Initial:     func() any { return schema.DefaultConfiguration() },
+			Configuration: customDict(helpers.M{
+				"source": "test.csv",
+				"remote-source": helpers.M{
+					"url":      "https://example.net/customers",
+					"interval": "10m",
+				},
+			}),
+			Error: true,
+		},
+	})
+}
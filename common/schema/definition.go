@@ -127,6 +127,8 @@ const (
 	DictionaryTCP string = "tcp"
 	// DictionaryUDP is the name of the UDP clickhouse dictionary
 	DictionaryUDP string = "udp"
+	// DictionaryNetworks is the name of the networks clickhouse dictionary.
+	DictionaryNetworks string = "networks"
 )
 
 // revive:disable
@@ -222,6 +224,14 @@ const (
 	ColumnMPLS4thLabel
 	ColumnIngressVRFID
 	ColumnEgressVRFID
+	ColumnDuplicate
+	ColumnTimeFlowStart
+	ColumnTimeFlowEnd
+	ColumnFlowDuration
+	ColumnSrcAddrTunnel
+	ColumnDstAddrTunnel
+	ColumnTunnelProto
+	ColumnTunnelID
 
 	// ColumnLast points to after the last static column, custom dictionaries
 	// (dynamic columns) come after ColumnLast
@@ -232,6 +242,7 @@ const (
 	ColumnGroupL2 ColumnGroup = iota + 1
 	ColumnGroupNAT
 	ColumnGroupL3L4
+	ColumnGroupTunnel
 
 	ColumnGroupLast
 )
@@ -534,6 +545,64 @@ END`,
 			},
 			{Key: ColumnIngressVRFID, Disabled: true, ParserType: "uint", ClickHouseType: "UInt32"},
 			{Key: ColumnEgressVRFID, Disabled: true, ParserType: "uint", ClickHouseType: "UInt32"},
+			{
+				Key:                     ColumnDuplicate,
+				Disabled:                true,
+				ParserType:              "uint",
+				ClickHouseType:          "UInt8",
+				ClickHouseNotSortingKey: true,
+			},
+			{
+				Key:                 ColumnTimeFlowStart,
+				Disabled:            true,
+				ClickHouseMainOnly:  true,
+				ClickHouseType:      "DateTime",
+				ClickHouseCodec:     "DoubleDelta, LZ4",
+				ConsoleNotDimension: true,
+			},
+			{
+				Key:                 ColumnTimeFlowEnd,
+				Disabled:            true,
+				ClickHouseMainOnly:  true,
+				ClickHouseType:      "DateTime",
+				ClickHouseCodec:     "DoubleDelta, LZ4",
+				ConsoleNotDimension: true,
+			},
+			{
+				// Duration of the flow in milliseconds
+				Key:                ColumnFlowDuration,
+				Disabled:           true,
+				ClickHouseMainOnly: true,
+				ClickHouseType:     "UInt32",
+				ParserType:         "uint",
+			},
+			{
+				// Outer header when decapsulating
+				Key:                ColumnSrcAddrTunnel,
+				Disabled:           true,
+				Group:              ColumnGroupTunnel,
+				ParserType:         "ip",
+				ClickHouseType:     "IPv6",
+				ClickHouseMainOnly: true,
+				ConsoleTruncateIP:  true,
+			},
+			{
+				Key:                ColumnTunnelProto,
+				Disabled:           true,
+				Group:              ColumnGroupTunnel,
+				ParserType:         "uint",
+				ClickHouseType:     "UInt8",
+				ClickHouseMainOnly: true,
+			},
+			{
+				// VXLAN VNI or GRE key
+				Key:                ColumnTunnelID,
+				Disabled:           true,
+				Group:              ColumnGroupTunnel,
+				ParserType:         "uint",
+				ClickHouseType:     "UInt32",
+				ClickHouseMainOnly: true,
+			},
 		},
 	}.finalize()
 }
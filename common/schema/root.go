@@ -183,6 +183,37 @@ func New(config Configuration) (*Component, error) {
 
 	schema.columns = append(schema.columns, customDictColumns...)
 
+	// Add computed columns. Unlike the columns from custom dictionaries, they
+	// are populated by the outlet.
+	for _, cc := range config.ComputedColumns {
+		names := []string{cc.Name}
+		if strings.HasPrefix(cc.Name, "Src") {
+			names = append(names, "Dst"+cc.Name[3:])
+		} else if strings.HasPrefix(cc.Name, "InIf") {
+			names = append(names, "OutIf"+cc.Name[4:])
+		}
+		for _, name := range names {
+			for _, column := range schema.columns {
+				if column.Name == name {
+					return nil, fmt.Errorf("computed column %q conflicts with an existing column", name)
+				}
+			}
+			column := Column{
+				Key:            ColumnLast + schema.dynamicColumns,
+				Name:           name,
+				ClickHouseType: cc.Type,
+				ParserType:     "uint",
+			}
+			if cc.Type == "String" {
+				column.ClickHouseType = "LowCardinality(String)"
+				column.ParserType = "string"
+			}
+			schema.columns = append(schema.columns, column)
+			columnNameMap.Insert(column.Key, name)
+			schema.dynamicColumns++
+		}
+	}
+
 	return &Component{
 		c:      config,
 		Schema: schema.finalize(),
@@ -4,6 +4,7 @@
 package schema_test
 
 import (
+	"strings"
 	"testing"
 
 	"akvorado/common/helpers"
@@ -265,3 +266,51 @@ func TestSkipIndexInvalidColumn(t *testing.T) {
 		t.Fatal("New() did not error for unknown column")
 	}
 }
+
+func TestComputedColumns(t *testing.T) {
+	config := schema.DefaultConfiguration()
+	config.ComputedColumns = []schema.ComputedColumn{
+		{Name: "ServiceName", Type: "String"},
+		{Name: "SrcTier", Type: "UInt8"},
+	}
+	s, err := schema.New(config)
+	if err != nil {
+		t.Fatalf("New() error:\n%+v", err)
+	}
+
+	got := map[string][]string{}
+	for _, name := range []string{"ServiceName", "SrcTier", "DstTier"} {
+		column, ok := s.LookupColumnByName(name)
+		if !ok {
+			t.Fatalf("LookupColumnByName(%q) not found", name)
+		}
+		got[name] = []string{column.ClickHouseType, column.ParserType, column.ClickHouseGenerateFrom}
+	}
+	expected := map[string][]string{
+		"ServiceName": {"LowCardinality(String)", "string", ""},
+		"SrcTier":     {"UInt8", "uint", ""},
+		"DstTier":     {"UInt8", "uint", ""},
+	}
+	if diff := helpers.Diff(got, expected); diff != "" {
+		t.Fatalf("LookupColumnByName() (-got, +want):\n%s", diff)
+	}
+
+	// The outlet provides the values, so they are in the raw table
+	if !strings.Contains(s.ClickHouseCreateTable(schema.ClickHouseSkipGeneratedColumns), "`DstTier` UInt8") {
+		t.Fatal("ClickHouseCreateTable() does not contain DstTier")
+	}
+}
+
+func TestComputedColumnConflict(t *testing.T) {
+	config := schema.DefaultConfiguration()
+	config.ComputedColumns = []schema.ComputedColumn{
+		{Name: "SrcNetName", Type: "String"},
+	}
+	_, err := schema.New(config)
+	if err == nil {
+		t.Fatal("New() did not error")
+	}
+	if diff := helpers.Diff(err.Error(), `computed column "SrcNetName" conflicts with an existing column`); diff != "" {
+		t.Fatalf("New() did not error correctly\n %s", diff)
+	}
+}
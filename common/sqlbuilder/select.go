@@ -89,6 +89,16 @@ func (q *Query) GroupBy(exprs ...Expr) *Query {
 	return q
 }
 
+// Having sets the HAVING clause. An empty expression removes it.
+func (q *Query) Having(expr Expr) *Query {
+	if expr.IsZero() {
+		q.query.Having = nil
+		return q
+	}
+	q.query.Having = &parser.HavingClause{Expr: expr.node}
+	return q
+}
+
 // OrderBy sets the ORDER BY clause.
 func (q *Query) OrderBy(items ...OrderItem) *Query {
 	if len(items) == 0 {
@@ -120,6 +130,16 @@ func (q *Query) Limit(limit int) *Query {
 	return q
 }
 
+// Offset sets the OFFSET part of the LIMIT clause. It should be called after
+// Limit.
+func (q *Query) Offset(offset int) *Query {
+	if q.query.Limit == nil {
+		q.query.Limit = &parser.LimitClause{}
+	}
+	q.query.Limit.Offset = Int(int64(offset)).node
+	return q
+}
+
 // Setting adds one entry to the SETTINGS clause.
 func (q *Query) Setting(name string, value Expr) *Query {
 	if q.query.Settings == nil {
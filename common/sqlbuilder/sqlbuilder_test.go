@@ -268,6 +268,22 @@ func TestIdentifierQuoting(t *testing.T) {
 	}
 }
 
+func TestSelectOffset(t *testing.T) {
+	got := sb.Select(sb.Star()).
+		From(sb.Table("flows")).
+		Limit(11).
+		Offset(2).
+		String()
+	expected := `SELECT
+  *
+FROM
+  flows
+LIMIT 11 OFFSET 2`
+	if diff := helpers.Diff(got, expected); diff != "" {
+		t.Errorf("String() (-got, +want):\n%s", diff)
+	}
+}
+
 func TestSelect(t *testing.T) {
 	source := sb.Select(sb.Star()).
 		From(sb.Table("flows")).
@@ -340,11 +356,13 @@ func TestSelectEmptyClauses(t *testing.T) {
 		From(sb.Table("flows")).
 		Where(sb.Op(sb.Column("SrcAS"), "=", sb.Uint(12322))).
 		GroupBy(sb.Columns("SrcAS", "DstAS")...).
+		Having(sb.Op(sb.Function("SUM", sb.Column("Bytes")), ">", sb.Uint(0))).
 		OrderBy(sb.Order(sb.Column("SrcAS"))).
 		// Building the same query without a filter, a grouping or a sort must
 		// remove the clauses again.
 		Where(sb.Expr{}).
 		GroupBy().
+		Having(sb.Expr{}).
 		OrderBy().
 		String()
 	expected := `SELECT
@@ -388,6 +406,25 @@ FROM
 	}
 }
 
+func TestSelectHaving(t *testing.T) {
+	got := sb.Select(sb.Column("SrcAS"), sb.Alias(sb.Function("SUM", sb.Column("Bytes")), "bytes")).
+		From(sb.Table("flows")).
+		GroupBy(sb.Column("SrcAS")).
+		Having(sb.Op(sb.Column("bytes"), ">", sb.Uint(1000))).
+		String()
+	expected := `SELECT
+  SrcAS,
+  SUM(Bytes) AS bytes
+FROM
+  flows
+GROUP BY
+  SrcAS
+HAVING bytes > 1000`
+	if diff := helpers.Diff(got, expected); diff != "" {
+		t.Errorf("String() (-got, +want):\n%s", diff)
+	}
+}
+
 func TestSelectUnionAll(t *testing.T) {
 	first := sb.Select(sb.Alias(sb.Uint(1), "axis")).From(sb.Table("flows"))
 	second := sb.Select(sb.Alias(sb.Uint(2), "axis")).From(sb.Table("flows"))
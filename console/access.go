@@ -0,0 +1,109 @@
+// SPDX-FileCopyrightText: 2026 Free Mobile
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package console
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"slices"
+
+	"akvorado/common/helpers"
+	"akvorado/common/httpserver"
+	sb "akvorado/common/sqlbuilder"
+	"akvorado/console/authentication"
+	"akvorado/console/query"
+)
+
+// accessPolicy is a validated access policy.
+type accessPolicy struct {
+	AccessPolicyConfiguration
+	filter query.Filter
+}
+
+// matches tells if the access policy applies to the provided user. A policy
+// without users and groups applies to everyone.
+func (ap accessPolicy) matches(user authentication.UserInformation) bool {
+	if len(ap.Users) == 0 && len(ap.Groups) == 0 {
+		return true
+	}
+	if slices.Contains(ap.Users, user.Login) {
+		return true
+	}
+	for _, group := range user.Groups {
+		if slices.Contains(ap.Groups, group) {
+			return true
+		}
+	}
+	return false
+}
+
+// newAccessPolicies validates the access policies from the configuration.
+func (c *Component) newAccessPolicies() error {
+	c.accessPolicies = make([]accessPolicy, 0, len(c.config.AccessPolicies))
+	for idx, config := range c.config.AccessPolicies {
+		qf := query.NewFilter(config.Filter)
+		if err := qf.ValidateWithDefinitions(c.d.Schema, c.d.ClickHouseDB.DatabaseName(),
+			c.config.FilterDefinitions); err != nil {
+			return fmt.Errorf("invalid filter for access policy %d: %w", idx+1, err)
+		}
+		c.accessPolicies = append(c.accessPolicies, accessPolicy{
+			AccessPolicyConfiguration: config,
+			filter:                    qf,
+		})
+	}
+	c.unrestricted = query.NewFilter("")
+	c.unrestricted.Validate(c.d.Schema, c.d.ClickHouseDB.DatabaseName())
+	return nil
+}
+
+// accessContextKey is the key under which the access filter of the current
+// user is stored in the request context.
+type accessContextKey struct{}
+
+// accessControl is a middleware to find the access policy matching the current
+// user. It should be used after UserAuthentication. Cached responses are only
+// shared by users with the same policy. When policies are configured, users
+// matching none of them are denied access.
+func (c *Component) accessControl() httpserver.Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			if len(c.accessPolicies) == 0 {
+				next.ServeHTTP(w, req)
+				return
+			}
+			user := authentication.UserFromContext(req.Context())
+			for idx, policy := range c.accessPolicies {
+				if policy.matches(user) {
+					ctx := context.WithValue(req.Context(), accessContextKey{}, policy.filter)
+					ctx = httpserver.WithCacheScope(ctx, fmt.Sprintf("access-policy-%d", idx))
+					next.ServeHTTP(w, req.WithContext(ctx))
+					return
+				}
+			}
+			httpserver.WriteJSON(w, http.StatusForbidden,
+				helpers.M{"message": "No access policy matches this user."})
+		})
+	}
+}
+
+// accessFilter returns the filter to apply to all queries for the current
+// user. It is an empty filter when the user is not restricted.
+func (c *Component) accessFilter(ctx context.Context) query.Filter {
+	if qf, ok := ctx.Value(accessContextKey{}).(query.Filter); ok {
+		return qf
+	}
+	return c.unrestricted
+}
+
+// accessWhere returns the condition to add to a query on the flows for the
+// current user. It is empty when the user is not restricted.
+func (c *Component) accessWhere(ctx context.Context) sb.Expr {
+	return c.accessFilter(ctx).Direct()
+}
+
+// accessRestricted tells if the current user is restricted.
+func (c *Component) accessRestricted(ctx context.Context) bool {
+	return c.accessFilter(ctx).String() != ""
+}
@@ -0,0 +1,157 @@
+// SPDX-FileCopyrightText: 2026 Free Mobile
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package console
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"testing"
+
+	"go.uber.org/mock/gomock"
+
+	"akvorado/common/clickhousedb"
+	"akvorado/common/clickhousedb/mocks"
+	"akvorado/common/daemon"
+	"akvorado/common/helpers"
+	"akvorado/common/httpserver"
+	"akvorado/common/reporter"
+	"akvorado/common/schema"
+	"akvorado/console/authentication"
+	"akvorado/console/database"
+)
+
+func TestAccessPolicyMatches(t *testing.T) {
+	cases := []struct {
+		Pos      helpers.Pos
+		Policy   AccessPolicyConfiguration
+		User     authentication.UserInformation
+		Expected bool
+	}{
+		{
+			Pos:      helpers.Mark(),
+			Policy:   AccessPolicyConfiguration{},
+			User:     authentication.UserInformation{Login: "alfred"},
+			Expected: true,
+		}, {
+			Pos:      helpers.Mark(),
+			Policy:   AccessPolicyConfiguration{Users: []string{"alfred", "bruce"}},
+			User:     authentication.UserInformation{Login: "alfred"},
+			Expected: true,
+		}, {
+			Pos:      helpers.Mark(),
+			Policy:   AccessPolicyConfiguration{Users: []string{"bruce"}},
+			User:     authentication.UserInformation{Login: "alfred"},
+			Expected: false,
+		}, {
+			Pos:    helpers.Mark(),
+			Policy: AccessPolicyConfiguration{Groups: []string{"acme"}},
+			User: authentication.UserInformation{
+				Login:  "alfred",
+				Groups: []string{"butlers", "acme"},
+			},
+			Expected: true,
+		}, {
+			Pos:    helpers.Mark(),
+			Policy: AccessPolicyConfiguration{Users: []string{"bruce"}, Groups: []string{"acme"}},
+			User: authentication.UserInformation{
+				Login:  "alfred",
+				Groups: []string{"butlers"},
+			},
+			Expected: false,
+		},
+	}
+	for _, tc := range cases {
+		got := accessPolicy{AccessPolicyConfiguration: tc.Policy}.matches(tc.User)
+		if got != tc.Expected {
+			t.Errorf("%smatches() == %v, expected %v", tc.Pos, got, tc.Expected)
+		}
+	}
+}
+
+func TestAccessPolicyInvalidFilter(t *testing.T) {
+	r := reporter.NewMock(t)
+	ch, _ := clickhousedb.NewMock(t, r)
+	config := DefaultConfiguration()
+	config.AccessPolicies = []AccessPolicyConfiguration{
+		{Groups: []string{"acme"}, Filter: "NoColumn = 'acme'"},
+	}
+	_, err := New(r, config, Dependencies{
+		Daemon:       daemon.NewMock(t),
+		HTTP:         httpserver.NewMock(t, r),
+		ClickHouseDB: ch,
+		Auth:         authentication.NewMock(t, r),
+		Database:     database.NewMock(t, r, database.DefaultConfiguration()),
+		Schema:       schema.NewMock(t),
+	})
+	if err == nil {
+		t.Fatal("New() did not error")
+	}
+}
+
+func TestAccessControl(t *testing.T) {
+	config := DefaultConfiguration()
+	config.AccessPolicies = []AccessPolicyConfiguration{
+		{Users: []string{"alfred"}},
+		{Groups: []string{"acme"}, Filter: "ExporterTenant = 'acme'"},
+	}
+	_, h, mockConn, _ := NewMock(t, config)
+
+	ctrl := gomock.NewController(t)
+	queries := []string{}
+	mockConn.EXPECT().
+		QueryRow(gomock.Any(), gomock.Any()).
+		DoAndReturn(func(_ context.Context, query string, _ ...any) *mocks.MockRow {
+			queries = append(queries, strings.Join(strings.Fields(query), " "))
+			mockRow := mocks.NewMockRow(ctrl)
+			mockRow.EXPECT().Scan(gomock.Any()).SetArg(0, float64(100.1)).Return(nil)
+			return mockRow
+		}).
+		Times(2)
+
+	user := func(login, groups string) http.Header {
+		headers := make(http.Header)
+		headers.Add("Remote-User", login)
+		headers.Add("Remote-Groups", groups)
+		return headers
+	}
+	helpers.TestHTTPEndpoints(t, h.LocalAddr(), helpers.HTTPEndpointCases{
+		{
+			Description: "unrestricted user",
+			URL:         "/api/v0/console/widget/flow-rate",
+			Header:      user("alfred", "acme"),
+			JSONOutput:  helpers.M{"period": "second", "rate": 100.1},
+		}, {
+			Description: "restricted user",
+			URL:         "/api/v0/console/widget/flow-rate",
+			Header:      user("bruce", "acme"),
+			JSONOutput:  helpers.M{"period": "second", "rate": 100.1},
+		}, {
+			Description: "restricted user, cached",
+			URL:         "/api/v0/console/widget/flow-rate",
+			Header:      user("lucius", "acme"),
+			JSONOutput:  helpers.M{"period": "second", "rate": 100.1},
+		}, {
+			Description: "unmatched user",
+			URL:         "/api/v0/console/widget/flow-rate",
+			Header:      user("selina", "acne"),
+			StatusCode:  403,
+			JSONOutput:  helpers.M{"message": "No access policy matches this user."},
+		}, {
+			Description: "unmatched user without groups",
+			URL:         "/api/v0/console/widget/flow-last",
+			Header:      user("selina", ""),
+			StatusCode:  403,
+			JSONOutput:  helpers.M{"message": "No access policy matches this user."},
+		},
+	})
+
+	expected := []string{
+		"SELECT COUNT(*)/300 AS rate FROM flows WHERE TimeReceived > date_sub(minute, 5, now())",
+		"SELECT COUNT(*) / 300 AS rate FROM flows WHERE TimeReceived > date_sub(minute, 5, now()) AND ExporterTenant = 'acme'",
+	}
+	if diff := helpers.Diff(queries, expected); diff != "" {
+		t.Fatalf("QueryRow() (-got, +want):\n%s", diff)
+	}
+}
@@ -0,0 +1,207 @@
+// SPDX-FileCopyrightText: 2026 Free Mobile
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package console
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"akvorado/common/helpers"
+	"akvorado/common/httpserver"
+	sb "akvorado/common/sqlbuilder"
+)
+
+// maxAnnotations is the maximum number of anomalies added as annotations to a
+// line graph.
+const maxAnnotations = 20
+
+// anomaliesHandlerInput describes the input for the /anomalies endpoint.
+type anomaliesHandlerInput struct {
+	Start    time.Time `json:"start" validate:"required"`
+	End      time.Time `json:"end" validate:"required,gtfield=Start"`
+	Detector string    `json:"detector"`
+	Limit    int       `json:"limit" validate:"min=1"`
+}
+
+// anomaly is an unusual traffic rate detected by the orchestrator. Rates are
+// in bits per second. The score is the number of deviations between the rate
+// and the baseline.
+type anomaly struct {
+	Time       time.Time         `json:"t"`
+	Detector   string            `json:"detector"`
+	Dimensions map[string]string `json:"dimensions"`
+	Value      int               `json:"value"`
+	Baseline   int               `json:"baseline"`
+	Deviation  int               `json:"deviation"`
+	Score      float64           `json:"score"`
+}
+
+// anomalyRow is a row of the anomalies table.
+type anomalyRow struct {
+	TimeStart      time.Time `ch:"TimeStart"`
+	Detector       string    `ch:"Detector"`
+	DimensionNames []string  `ch:"DimensionNames"`
+	Dimensions     []string  `ch:"Dimensions"`
+	Value          float64   `ch:"Value"`
+	Baseline       float64   `ch:"Baseline"`
+	Deviation      float64   `ch:"Deviation"`
+	Score          float64   `ch:"Score"`
+}
+
+// anomaliesQuery returns the query to get the most significant anomalies
+// between start and end. When values is not nil, only the anomalies on a subset
+// of its dimensions, with one of the listed values for each of them, are
+// returned.
+func anomaliesQuery(start, end time.Time, detector string, values map[string][]string, limit int) string {
+	conditions := []sb.Expr{
+		sb.Op(sb.Column("TimeStart"), ">=", dateTime(start)),
+		sb.Op(sb.Column("TimeStart"), "<", dateTime(end)),
+	}
+	if detector != "" {
+		conditions = append(conditions, sb.Op(sb.Column("Detector"), "=", sb.String(detector)))
+	}
+	if values != nil && len(values) == 0 {
+		conditions = append(conditions, sb.Function("empty", sb.Column("DimensionNames")))
+	} else if values != nil {
+		matches := []sb.Expr{}
+		for _, name := range slices.Sorted(maps.Keys(values)) {
+			items := make([]sb.Expr, len(values[name]))
+			for idx, value := range values[name] {
+				items[idx] = sb.String(value)
+			}
+			matches = append(matches, sb.And(
+				sb.Op(sb.Index(sb.Column("DimensionNames"), sb.Column("i")), "=", sb.String(name)),
+				sb.Function("has", sb.Array(items...), sb.Index(sb.Column("Dimensions"), sb.Column("i")))))
+		}
+		conditions = append(conditions, sb.Function("arrayAll",
+			sb.Lambda("i", sb.Or(matches...)),
+			sb.Function("arrayEnumerate", sb.Column("DimensionNames"))))
+	}
+	return sb.Select(
+		sb.Column("TimeStart"),
+		sb.Column("Detector"),
+		sb.Column("DimensionNames"),
+		sb.Column("Dimensions"),
+		sb.Column("Value"),
+		sb.Column("Baseline"),
+		sb.Column("Deviation"),
+		sb.Column("Score"),
+	).
+		From(sb.Table("anomalies")).
+		Where(sb.And(conditions...)).
+		OrderBy(sb.Order(sb.Function("abs", sb.Column("Score"))).Desc()).
+		Limit(limit).
+		Setting("final", sb.Uint(1)).
+		String()
+}
+
+// queryAnomalies runs a query returned by anomaliesQuery.
+func (c *Component) queryAnomalies(ctx context.Context, sqlQuery string) ([]anomaly, error) {
+	results := []anomalyRow{}
+	if err := c.d.ClickHouseDB.Conn.Select(ctx, &results, sqlQuery); err != nil {
+		return nil, err
+	}
+	anomalies := make([]anomaly, 0, len(results))
+	for _, result := range results {
+		dimensions := make(map[string]string, len(result.DimensionNames))
+		for idx, name := range result.DimensionNames {
+			if idx < len(result.Dimensions) {
+				dimensions[name] = result.Dimensions[idx]
+			}
+		}
+		anomalies = append(anomalies, anomaly{
+			Time:       result.TimeStart,
+			Detector:   result.Detector,
+			Dimensions: dimensions,
+			Value:      int(result.Value),
+			Baseline:   int(result.Baseline),
+			Deviation:  int(result.Deviation),
+			Score:      result.Score,
+		})
+	}
+	return anomalies, nil
+}
+
+// annotated tells if a line graph is annotated with anomalies. Anomalies are
+// computed over all the flows, so they are not shown to restricted users nor
+// when the graph is filtered.
+func (c *Component) annotated(ctx context.Context, input graphLineHandlerInput) bool {
+	return c.config.Anomalies && !c.accessRestricted(ctx) && strings.TrimSpace(input.Filter.String()) == ""
+}
+
+// anomalyAnnotations returns the anomalies to annotate a line graph with. They
+// are sorted by time. Only the anomalies matching the dimensions of the
+// displayed rows are returned. The anomaly detection records the raw value of
+// each dimension, so the rows are the raw values, not the displayed ones.
+func (c *Component) anomalyAnnotations(ctx context.Context, input graphLineHandlerInput, rows [][]string) []anomaly {
+	values := map[string][]string{}
+	for idx, column := range input.Dimensions {
+		name := column.String()
+		values[name] = []string{}
+		for _, row := range rows {
+			if idx < len(row) && !slices.Contains(values[name], row[idx]) {
+				values[name] = append(values[name], row[idx])
+			}
+		}
+	}
+	sqlQuery := anomaliesQuery(input.Start, input.End, "", values, maxAnnotations)
+	ctx, done, rejection := c.prepareQuery(ctx, "anomalies", sqlQuery)
+	if rejection != nil {
+		c.r.Debug().Str("reason", rejection.message).Msg("skip anomaly annotations")
+		return nil
+	}
+	defer done()
+	anomalies, err := c.queryAnomalies(ctx, sqlQuery)
+	if err != nil {
+		c.r.Err(err).Msg("unable to query anomalies")
+		return nil
+	}
+	slices.SortStableFunc(anomalies, func(a, b anomaly) int {
+		return a.Time.Compare(b.Time)
+	})
+	return anomalies
+}
+
+func (c *Component) anomaliesHandlerFunc(w http.ResponseWriter, req *http.Request) {
+	ctx := c.t.Context(req.Context())
+	var input anomaliesHandlerInput
+	if err := httpserver.BindJSON(req, &input); err != nil {
+		httpserver.WriteJSON(w, http.StatusBadRequest, helpers.M{"message": helpers.Capitalize(err.Error())})
+		return
+	}
+	if input.Limit > c.config.DimensionsLimit {
+		httpserver.WriteJSON(w, http.StatusBadRequest,
+			helpers.M{"message": fmt.Sprintf("Limit is set beyond maximum value (%d)",
+				c.config.DimensionsLimit)})
+		return
+	}
+	if !c.config.Anomalies {
+		httpserver.WriteJSON(w, http.StatusNotFound, helpers.M{"message": "Anomaly detection is not enabled."})
+		return
+	}
+	if c.accessRestricted(req.Context()) {
+		httpserver.WriteJSON(w, http.StatusForbidden, helpers.M{"message": "Anomalies are not available to restricted users."})
+		return
+	}
+
+	sqlQuery := anomaliesQuery(input.Start, input.End, input.Detector, nil, input.Limit)
+	w.Header().Set("X-SQL-Query", sqlQuery)
+	ctx, done, ok := c.startQuery(ctx, w, "anomalies", sqlQuery)
+	if !ok {
+		return
+	}
+	defer done()
+	anomalies, err := c.queryAnomalies(ctx, sqlQuery)
+	if err != nil {
+		c.writeQueryError(ctx, w, err, sqlQuery)
+		return
+	}
+	auditRows(ctx, len(anomalies))
+	httpserver.WriteJSON(w, http.StatusOK, helpers.M{"anomalies": anomalies})
+}
@@ -0,0 +1,398 @@
+// SPDX-FileCopyrightText: 2026 Free Mobile
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package console
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"go.uber.org/mock/gomock"
+
+	"akvorado/common/helpers"
+	sb "akvorado/common/sqlbuilder"
+)
+
+func TestAnomaliesQuerySQL(t *testing.T) {
+	start := time.Date(2026, 10, 19, 10, 0, 0, 0, time.UTC)
+	end := time.Date(2026, 10, 19, 11, 0, 0, 0, time.UTC)
+	cases := []struct {
+		Description string
+		Pos         helpers.Pos
+		Detector    string
+		Values      map[string][]string
+		Expected    string
+	}{
+		{
+			Description: "all anomalies",
+			Pos:         helpers.Mark(),
+			Expected: `
+SELECT TimeStart, Detector, DimensionNames, Dimensions, Value, Baseline, Deviation, Score
+FROM anomalies
+WHERE TimeStart >= toDateTime('2026-10-19 10:00:00', 'UTC')
+ AND TimeStart < toDateTime('2026-10-19 11:00:00', 'UTC')
+ORDER BY abs(Score) DESC
+LIMIT 10
+SETTINGS final = 1`,
+		}, {
+			Description: "one detector",
+			Pos:         helpers.Mark(),
+			Detector:    "interfaces",
+			Expected: `
+SELECT TimeStart, Detector, DimensionNames, Dimensions, Value, Baseline, Deviation, Score
+FROM anomalies
+WHERE TimeStart >= toDateTime('2026-10-19 10:00:00', 'UTC')
+ AND TimeStart < toDateTime('2026-10-19 11:00:00', 'UTC')
+ AND Detector = 'interfaces'
+ORDER BY abs(Score) DESC
+LIMIT 10
+SETTINGS final = 1`,
+		}, {
+			Description: "no dimensions",
+			Pos:         helpers.Mark(),
+			Values:      map[string][]string{},
+			Expected: `
+SELECT TimeStart, Detector, DimensionNames, Dimensions, Value, Baseline, Deviation, Score
+FROM anomalies
+WHERE TimeStart >= toDateTime('2026-10-19 10:00:00', 'UTC')
+ AND TimeStart < toDateTime('2026-10-19 11:00:00', 'UTC')
+ AND empty(DimensionNames)
+ORDER BY abs(Score) DESC
+LIMIT 10
+SETTINGS final = 1`,
+		}, {
+			Description: "subset of dimensions",
+			Pos:         helpers.Mark(),
+			Values: map[string][]string{
+				"InIfName":     {"Gi0/0/0", "Gi0/0/1"},
+				"ExporterName": {"router1"},
+			},
+			Expected: `
+SELECT TimeStart, Detector, DimensionNames, Dimensions, Value, Baseline, Deviation, Score
+FROM anomalies
+WHERE TimeStart >= toDateTime('2026-10-19 10:00:00', 'UTC')
+ AND TimeStart < toDateTime('2026-10-19 11:00:00', 'UTC')
+ AND arrayAll(i -> DimensionNames[i] = 'ExporterName' AND has(['router1'], Dimensions[i])
+               OR DimensionNames[i] = 'InIfName' AND has(['Gi0/0/0', 'Gi0/0/1'], Dimensions[i]),
+              arrayEnumerate(DimensionNames))
+ORDER BY abs(Score) DESC
+LIMIT 10
+SETTINGS final = 1`,
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.Description, func(t *testing.T) {
+			got := sb.Normalize(t, anomaliesQuery(start, end, tc.Detector, tc.Values, 10))
+			if diff := helpers.Diff(got, sb.Normalize(t, tc.Expected)); diff != "" {
+				t.Errorf("%sanomaliesQuery (-got, +want):\n%s", tc.Pos, diff)
+			}
+		})
+	}
+}
+
+func TestAnomaliesHandler(t *testing.T) {
+	config := DefaultConfiguration()
+	config.Anomalies = true
+	config.AccessPolicies = []AccessPolicyConfiguration{
+		{Users: []string{"bruce"}, Filter: "ExporterTenant = 'acme'"},
+		{},
+	}
+	_, h, mockConn, _ := NewMock(t, config)
+	at := time.Date(2026, 10, 19, 10, 25, 0, 0, time.UTC)
+	mockConn.EXPECT().
+		Select(gomock.Any(), gomock.Any(), gomock.Any()).
+		SetArg(1, []anomalyRow{
+			{at, "interfaces", []string{"ExporterName", "InIfName"}, []string{"router1", "Gi0/0/0"},
+				4_000_000_000, 1_000_000_000, 100_000_000, 20.23},
+			{at.Add(-10 * time.Minute), "as", []string{"SrcAS"}, []string{"15169"},
+				0, 500_000_000, 50_000_000, -6.74},
+		}).
+		Return(nil)
+	bruce := make(http.Header)
+	bruce.Add("Remote-User", "bruce")
+
+	helpers.TestHTTPEndpoints(t, h.LocalAddr(), helpers.HTTPEndpointCases{
+		{
+			Description: "list anomalies",
+			URL:         "/api/v0/console/anomalies",
+			JSONInput: helpers.M{
+				"start": time.Date(2026, 10, 19, 10, 0, 0, 0, time.UTC),
+				"end":   time.Date(2026, 10, 19, 11, 0, 0, 0, time.UTC),
+				"limit": 10,
+			},
+			JSONOutput: helpers.M{"anomalies": []helpers.M{
+				{
+					"t":          "2026-10-19T10:25:00Z",
+					"detector":   "interfaces",
+					"dimensions": helpers.M{"ExporterName": "router1", "InIfName": "Gi0/0/0"},
+					"value":      4_000_000_000,
+					"baseline":   1_000_000_000,
+					"deviation":  100_000_000,
+					"score":      20.23,
+				}, {
+					"t":          "2026-10-19T10:15:00Z",
+					"detector":   "as",
+					"dimensions": helpers.M{"SrcAS": "15169"},
+					"value":      0,
+					"baseline":   500_000_000,
+					"deviation":  50_000_000,
+					"score":      -6.74,
+				},
+			}},
+		}, {
+			Description: "limit too high",
+			URL:         "/api/v0/console/anomalies",
+			JSONInput: helpers.M{
+				"start": time.Date(2026, 10, 19, 10, 0, 0, 0, time.UTC),
+				"end":   time.Date(2026, 10, 19, 11, 0, 0, 0, time.UTC),
+				"limit": 1000,
+			},
+			StatusCode: 400,
+			JSONOutput: helpers.M{"message": "Limit is set beyond maximum value (50)"},
+		}, {
+			Description: "restricted user",
+			URL:         "/api/v0/console/anomalies",
+			Header:      bruce,
+			JSONInput: helpers.M{
+				"start": time.Date(2026, 10, 19, 10, 0, 0, 0, time.UTC),
+				"end":   time.Date(2026, 10, 19, 11, 0, 0, 0, time.UTC),
+				"limit": 10,
+			},
+			StatusCode: 403,
+			JSONOutput: helpers.M{"message": "Anomalies are not available to restricted users."},
+		},
+	})
+}
+
+func TestAnomaliesDisabled(t *testing.T) {
+	_, h, _, _ := NewMock(t, DefaultConfiguration())
+	helpers.TestHTTPEndpoints(t, h.LocalAddr(), helpers.HTTPEndpointCases{
+		{
+			Description: "anomalies disabled",
+			URL:         "/api/v0/console/anomalies",
+			JSONInput: helpers.M{
+				"start": time.Date(2026, 10, 19, 10, 0, 0, 0, time.UTC),
+				"end":   time.Date(2026, 10, 19, 11, 0, 0, 0, time.UTC),
+				"limit": 10,
+			},
+			StatusCode: 404,
+			JSONOutput: helpers.M{"message": "Anomaly detection is not enabled."},
+		},
+	})
+}
+
+// compactSQL puts a SQL query on a single line.
+func compactSQL(sql string) string {
+	return strings.Join(strings.Fields(sql), " ")
+}
+
+func TestGraphLineAnnotations(t *testing.T) {
+	config := DefaultConfiguration()
+	config.Anomalies = true
+	_, h, mockConn, _ := NewMock(t, config)
+	base := time.Date(2026, 10, 19, 10, 0, 0, 0, time.UTC)
+	graph := func(annotated bool) *gomock.Call {
+		results := []struct {
+			Axis       uint8     `ch:"axis"`
+			Time       time.Time `ch:"time"`
+			Xps        float64   `ch:"xps"`
+			Dimensions []string  `ch:"dimensions"`
+		}{
+			{1, base, 1000, []string{"router1"}},
+			{1, base.Add(time.Minute), 2000, []string{"router1"}},
+		}
+		if annotated {
+			results = append(results, results[0])
+			results[2].Axis = 0
+		}
+		return mockConn.EXPECT().
+			Select(gomock.Any(), gomock.Any(), gomock.Cond(func(sql string) bool {
+				// The raw values are only requested for annotations.
+				return strings.Contains(compactSQL(sql), "[toString(ExporterName)] AS dimensions FROM rows") == annotated
+			})).
+			SetArg(1, results).
+			Return(nil)
+	}
+	gomock.InOrder(
+		graph(true),
+		// Only the anomalies for the displayed rows are requested.
+		mockConn.EXPECT().
+			Select(gomock.Any(), gomock.Any(), gomock.Cond(func(sql string) bool {
+				return strings.Contains(sql, "has(['router1'], Dimensions[i])")
+			})).
+			SetArg(1, []anomalyRow{
+				{base.Add(5 * time.Minute), "exporters", []string{"ExporterName"}, []string{"router1"},
+					2000, 1000, 100, 6.74},
+				{base, "exporters", []string{"ExporterName"}, []string{"router1"},
+					1000, 2000, 100, -6.74},
+			}).
+			Return(nil),
+		// No anomalies are requested for a filtered graph.
+		graph(false),
+	)
+
+	helpers.TestHTTPEndpoints(t, h.LocalAddr(), helpers.HTTPEndpointCases{
+		{
+			Description: "line graph with annotations",
+			URL:         "/api/v0/console/graph/line",
+			JSONInput: helpers.M{
+				"start":      base,
+				"end":        base.Add(10 * time.Minute),
+				"points":     5,
+				"limit":      10,
+				"dimensions": []string{"ExporterName"},
+				"filter":     "",
+				"units":      "l3bps",
+			},
+			JSONOutput: helpers.M{
+				"t":          []string{"2026-10-19T10:00:00Z", "2026-10-19T10:01:00Z"},
+				"rows":       [][]string{{"router1"}},
+				"points":     [][]int{{1000, 2000}},
+				"axis":       []int{1},
+				"axis-names": helpers.M{"1": "Direct"},
+				"average":    []int{1500},
+				"min":        []int{1000},
+				"max":        []int{2000},
+				"last":       []int{1000},
+				"95th":       []int{1950},
+				"total":      []int{180000},
+				"annotations": []helpers.M{
+					{
+						"t":          "2026-10-19T10:00:00Z",
+						"detector":   "exporters",
+						"dimensions": helpers.M{"ExporterName": "router1"},
+						"value":      1000,
+						"baseline":   2000,
+						"deviation":  100,
+						"score":      -6.74,
+					}, {
+						"t":          "2026-10-19T10:05:00Z",
+						"detector":   "exporters",
+						"dimensions": helpers.M{"ExporterName": "router1"},
+						"value":      2000,
+						"baseline":   1000,
+						"deviation":  100,
+						"score":      6.74,
+					},
+				},
+			},
+		}, {
+			Description: "filtered line graph without annotations",
+			URL:         "/api/v0/console/graph/line",
+			JSONInput: helpers.M{
+				"start":      base,
+				"end":        base.Add(10 * time.Minute),
+				"points":     5,
+				"limit":      10,
+				"dimensions": []string{"ExporterName"},
+				"filter":     "DstPort = 443",
+				"units":      "l3bps",
+			},
+			JSONOutput: helpers.M{
+				"t":          []string{"2026-10-19T10:00:00Z", "2026-10-19T10:01:00Z"},
+				"rows":       [][]string{{"router1"}},
+				"points":     [][]int{{1000, 2000}},
+				"axis":       []int{1},
+				"axis-names": helpers.M{"1": "Direct"},
+				"average":    []int{1500},
+				"min":        []int{1000},
+				"max":        []int{2000},
+				"last":       []int{1000},
+				"95th":       []int{1950},
+				"total":      []int{180000},
+			},
+		},
+	})
+}
+
+func TestGraphLineAnnotationsRawDimensions(t *testing.T) {
+	config := DefaultConfiguration()
+	config.Anomalies = true
+	_, h, mockConn, _ := NewMock(t, config)
+	base := time.Date(2026, 10, 19, 10, 0, 0, 0, time.UTC)
+	gomock.InOrder(
+		// The displayed rows are not the raw values recorded by the
+		// detectors.
+		mockConn.EXPECT().
+			Select(gomock.Any(), gomock.Any(), gomock.Cond(func(sql string) bool {
+				return strings.Contains(compactSQL(sql), "[toString(SrcAS), toString(Proto)] AS dimensions FROM rows")
+			})).
+			SetArg(1, []struct {
+				Axis       uint8     `ch:"axis"`
+				Time       time.Time `ch:"time"`
+				Xps        float64   `ch:"xps"`
+				Dimensions []string  `ch:"dimensions"`
+			}{
+				{1, base, 1000, []string{"64500: AS64500", "TCP"}},
+				{1, base, 500, []string{"64501: AS64501", "UDP"}},
+				{1, base.Add(time.Minute), 2000, []string{"64500: AS64500", "TCP"}},
+				{1, base.Add(time.Minute), 500, []string{"64501: AS64501", "UDP"}},
+				{0, base, 0, []string{"64500", "6"}},
+				{0, base, 0, []string{"64501", "17"}},
+			}).
+			Return(nil),
+		mockConn.EXPECT().
+			Select(gomock.Any(), gomock.Any(), gomock.Cond(func(sql string) bool {
+				sql = compactSQL(sql)
+				return strings.Contains(sql, "DimensionNames[i] = 'Proto' AND has(['6', '17'], Dimensions[i])") &&
+					strings.Contains(sql, "DimensionNames[i] = 'SrcAS' AND has(['64500', '64501'], Dimensions[i])")
+			})).
+			SetArg(1, []anomalyRow{
+				{base.Add(5 * time.Minute), "protocols", []string{"Proto"}, []string{"17"},
+					500, 5000, 100, -6.74},
+				{base, "ases", []string{"SrcAS"}, []string{"64500"},
+					1000, 100, 100, 6.74},
+			}).
+			Return(nil),
+	)
+
+	helpers.TestHTTPEndpoints(t, h.LocalAddr(), helpers.HTTPEndpointCases{
+		{
+			Description: "line graph with annotations on AS and protocol",
+			URL:         "/api/v0/console/graph/line",
+			JSONInput: helpers.M{
+				"start":      base,
+				"end":        base.Add(10 * time.Minute),
+				"points":     5,
+				"limit":      10,
+				"dimensions": []string{"SrcAS", "Proto"},
+				"filter":     "",
+				"units":      "l3bps",
+			},
+			JSONOutput: helpers.M{
+				"t":          []string{"2026-10-19T10:00:00Z", "2026-10-19T10:01:00Z"},
+				"rows":       [][]string{{"64500: AS64500", "TCP"}, {"64501: AS64501", "UDP"}},
+				"points":     [][]int{{1000, 2000}, {500, 500}},
+				"axis":       []int{1, 1},
+				"axis-names": helpers.M{"1": "Direct"},
+				"average":    []int{1500, 500},
+				"min":        []int{1000, 500},
+				"max":        []int{2000, 500},
+				"last":       []int{1000, 500},
+				"95th":       []int{1950, 500},
+				"total":      []int{180000, 60000},
+				"annotations": []helpers.M{
+					{
+						"t":          "2026-10-19T10:00:00Z",
+						"detector":   "ases",
+						"dimensions": helpers.M{"SrcAS": "64500"},
+						"value":      1000,
+						"baseline":   100,
+						"deviation":  100,
+						"score":      6.74,
+					}, {
+						"t":          "2026-10-19T10:05:00Z",
+						"detector":   "protocols",
+						"dimensions": helpers.M{"Proto": "17"},
+						"value":      500,
+						"baseline":   5000,
+						"deviation":  100,
+						"score":      -6.74,
+					},
+				},
+			},
+		},
+	})
+}
@@ -0,0 +1,172 @@
+// SPDX-FileCopyrightText: 2026 Free Mobile
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package console
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"akvorado/common/helpers"
+	"akvorado/common/httpserver"
+	"akvorado/console/authentication"
+	"akvorado/console/database"
+)
+
+// auditContextKey is the key under which the audit record of the current
+// request is stored in the request context.
+type auditContextKey struct{}
+
+// auditRecord is filled by the handlers once they have queried ClickHouse.
+type auditRecord struct {
+	queried bool
+	rows    int
+}
+
+// auditRequest is the subset of the request body recorded in the audit log.
+// All data endpoints share these fields.
+type auditRequest struct {
+	Start      time.Time `json:"start"`
+	End        time.Time `json:"end"`
+	Filter     string    `json:"filter"`
+	Dimensions []string  `json:"dimensions"`
+	Columns    []string  `json:"columns"`
+	Column     string    `json:"column"` // filter completion
+}
+
+// auditResponseWriter captures the status code of the response.
+type auditResponseWriter struct {
+	http.ResponseWriter
+	status int
+}
+
+func (w *auditResponseWriter) WriteHeader(status int) {
+	if w.status == 0 {
+		w.status = status
+	}
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *auditResponseWriter) Write(b []byte) (int, error) {
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+	return w.ResponseWriter.Write(b)
+}
+
+// auditLog is a middleware recording the data queries in the audit log. It
+// should be used after UserAuthentication and before any cache, so cached
+// answers are recorded too.
+func (c *Component) auditLog() httpserver.Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			// The body is decoded on a best-effort basis: the handler is
+			// in charge of rejecting invalid requests.
+			body, _ := io.ReadAll(req.Body)
+			req.Body = io.NopCloser(bytes.NewBuffer(body))
+			var input auditRequest
+			json.Unmarshal(body, &input)
+
+			record := &auditRecord{}
+			req = req.WithContext(context.WithValue(req.Context(), auditContextKey{}, record))
+			aw := &auditResponseWriter{ResponseWriter: w}
+			start := c.d.Clock.Now()
+			next.ServeHTTP(aw, req)
+
+			dimensions := input.Dimensions
+			if len(dimensions) == 0 {
+				dimensions = input.Columns
+			}
+			if len(dimensions) == 0 && input.Column != "" {
+				dimensions = []string{input.Column}
+			}
+			entry := database.AuditLogEntry{
+				Time:       start,
+				User:       authentication.UserFromContext(req.Context()).Login,
+				Endpoint:   req.URL.Path,
+				Filter:     input.Filter,
+				Dimensions: strings.Join(dimensions, ","),
+				Start:      input.Start,
+				End:        input.End,
+				Duration:   c.d.Clock.Since(start).Milliseconds(),
+				Rows:       record.rows,
+				Status:     aw.status,
+				Cached:     !record.queried && aw.status == http.StatusOK,
+			}
+			if err := c.d.Database.AddAuditLogEntry(c.t.Context(nil), entry); err != nil {
+				c.r.Err(err).Msg("cannot record query in audit log")
+			}
+		})
+	}
+}
+
+// auditRows records the number of rows returned by ClickHouse for the
+// current request.
+func auditRows(ctx context.Context, rows int) {
+	if record, ok := ctx.Value(auditContextKey{}).(*auditRecord); ok {
+		record.queried = true
+		record.rows += rows
+	}
+}
+
+// auditLogHandlerFunc searches the audit log. It is restricted to
+// administrators.
+func (c *Component) auditLogHandlerFunc(w http.ResponseWriter, req *http.Request) {
+	ctx := c.t.Context(req.Context())
+	params := req.URL.Query()
+	query := database.AuditLogQuery{
+		User:     params.Get("user"),
+		Endpoint: params.Get("endpoint"),
+		Filter:   params.Get("filter"),
+		Limit:    100,
+	}
+	for _, param := range []struct {
+		name string
+		dst  *time.Time
+	}{{"since", &query.Since}, {"until", &query.Until}} {
+		if value := params.Get(param.name); value != "" {
+			parsed, err := time.Parse(time.RFC3339, value)
+			if err != nil {
+				httpserver.WriteJSON(w, http.StatusBadRequest,
+					helpers.M{"message": "Invalid " + param.name + " parameter."})
+				return
+			}
+			*param.dst = parsed
+		}
+	}
+	if value := params.Get("limit"); value != "" {
+		limit, err := strconv.Atoi(value)
+		if err != nil || limit < 1 || limit > 1000 {
+			httpserver.WriteJSON(w, http.StatusBadRequest,
+				helpers.M{"message": "Invalid limit parameter."})
+			return
+		}
+		query.Limit = limit
+	}
+	entries, err := c.d.Database.SearchAuditLog(ctx, query)
+	if err != nil {
+		c.r.Err(err).Msg("unable to search audit log")
+		httpserver.WriteJSON(w, http.StatusInternalServerError,
+			helpers.M{"message": "Unable to search audit log."})
+		return
+	}
+	httpserver.WriteJSON(w, http.StatusOK, helpers.M{"entries": entries})
+}
+
+// pruneAuditLog removes the old entries of the audit log.
+func (c *Component) pruneAuditLog() {
+	count, err := c.d.Database.PruneAuditLog(c.t.Context(nil), c.d.Clock.Now())
+	if err != nil {
+		c.r.Err(err).Msg("cannot prune audit log")
+		return
+	}
+	if count > 0 {
+		c.r.Debug().Int64("count", count).Msg("pruned audit log")
+	}
+}
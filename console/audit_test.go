@@ -0,0 +1,219 @@
+// SPDX-FileCopyrightText: 2026 Free Mobile
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package console
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"go.uber.org/mock/gomock"
+
+	"akvorado/common/helpers"
+	"akvorado/console/database"
+)
+
+func TestAuditLog(t *testing.T) {
+	c, h, mockConn, mockClock := NewMock(t, DefaultConfiguration())
+	mockClock.Set(time.Date(2026, 10, 19, 10, 0, 0, 0, time.UTC))
+	results := []struct {
+		Axis       uint8    `ch:"axis"`
+		Xps        float64  `ch:"xps"`
+		Dimensions []string `ch:"dimensions"`
+	}{
+		{1, 9000, []string{"AS100"}},
+		{1, 7000, []string{"AS200"}},
+	}
+	mockConn.EXPECT().
+		Select(gomock.Any(), gomock.Any(), gomock.Any()).
+		SetArg(1, results).
+		Return(nil)
+
+	input := helpers.M{
+		"start":      time.Date(2022, 4, 10, 15, 45, 10, 0, time.UTC),
+		"end":        time.Date(2022, 4, 11, 15, 45, 10, 0, time.UTC),
+		"dimensions": []string{"SrcAS"},
+		"filter":     "DstCountry = 'FR'",
+		"limit":      10,
+		"units":      "l3bps",
+	}
+	output := helpers.M{
+		"rows":       [][]string{{"AS100"}, {"AS200"}},
+		"xps":        []int{9000, 7000},
+		"axis":       []int{1, 1},
+		"axis-names": helpers.M{"1": "Direct"},
+		"nodes":      []string{},
+		"links":      []helpers.M{},
+	}
+	helpers.TestHTTPEndpoints(t, h.LocalAddr(), helpers.HTTPEndpointCases{
+		{
+			Description: "sankey",
+			URL:         "/api/v0/console/graph/sankey",
+			JSONInput:   input,
+			JSONOutput:  output,
+		}, {
+			Description: "sankey from cache",
+			URL:         "/api/v0/console/graph/sankey",
+			JSONInput:   input,
+			JSONOutput:  output,
+		}, {
+			Description: "invalid flows query",
+			URL:         "/api/v0/console/flows",
+			JSONInput: helpers.M{
+				"start":   time.Date(2022, 4, 10, 15, 45, 10, 0, time.UTC),
+				"end":     time.Date(2022, 4, 10, 15, 55, 10, 0, time.UTC),
+				"columns": []string{"NoColumn"},
+				"limit":   10,
+			},
+			StatusCode: 400,
+			JSONOutput: helpers.M{"message": "Unknown column name NoColumn"},
+		}, {
+			Description: "search requires an administrator",
+			URL:         "/api/v0/console/audit",
+			StatusCode:  403,
+			JSONOutput:  helpers.M{"message": "Administrator access required."},
+		},
+	})
+
+	// Check what was recorded
+	got, err := c.d.Database.SearchAuditLog(t.Context(), database.AuditLogQuery{})
+	if err != nil {
+		t.Fatalf("SearchAuditLog() error:\n%+v", err)
+	}
+	now := mockClock.Now()
+	sankey := database.AuditLogEntry{
+		Time:       now,
+		User:       "__default",
+		Endpoint:   "/api/v0/console/graph/sankey",
+		Filter:     "DstCountry = 'FR'",
+		Dimensions: "SrcAS",
+		Start:      time.Date(2022, 4, 10, 15, 45, 10, 0, time.UTC),
+		End:        time.Date(2022, 4, 11, 15, 45, 10, 0, time.UTC),
+		Status:     200,
+	}
+	expected := []database.AuditLogEntry{
+		{
+			ID:         3,
+			Time:       now,
+			User:       "__default",
+			Endpoint:   "/api/v0/console/flows",
+			Dimensions: "NoColumn",
+			Start:      time.Date(2022, 4, 10, 15, 45, 10, 0, time.UTC),
+			End:        time.Date(2022, 4, 10, 15, 55, 10, 0, time.UTC),
+			Status:     400,
+		},
+	}
+	cached := sankey
+	cached.ID = 2
+	cached.Cached = true
+	expected = append(expected, cached)
+	sankey.ID = 1
+	sankey.Rows = 2
+	expected = append(expected, sankey)
+	if diff := helpers.Diff(got, expected); diff != "" {
+		t.Fatalf("SearchAuditLog() (-got, +want):\n%s", diff)
+	}
+
+	// Search handler
+	cases := []struct {
+		Description string
+		URL         string
+		StatusCode  int
+		IDs         []uint64
+	}{
+		{"everything", "/?", 200, []uint64{3, 2, 1}},
+		{"by endpoint", "/?endpoint=/api/v0/console/flows", 200, []uint64{3}},
+		{"with limit", "/?limit=2", 200, []uint64{3, 2}},
+		{"invalid limit", "/?limit=0", 400, nil},
+		{"invalid since", "/?since=yesterday", 400, nil},
+	}
+	for _, tc := range cases {
+		t.Run(tc.Description, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			c.auditLogHandlerFunc(w, httptest.NewRequest(http.MethodGet, tc.URL, nil))
+			if w.Code != tc.StatusCode {
+				t.Fatalf("auditLogHandlerFunc() status code %d, expected %d", w.Code, tc.StatusCode)
+			}
+			if tc.StatusCode != 200 {
+				return
+			}
+			var output struct {
+				Entries []database.AuditLogEntry `json:"entries"`
+			}
+			if err := json.NewDecoder(w.Body).Decode(&output); err != nil {
+				t.Fatalf("auditLogHandlerFunc() decode error:\n%+v", err)
+			}
+			ids := []uint64{}
+			for _, entry := range output.Entries {
+				ids = append(ids, entry.ID)
+			}
+			if diff := helpers.Diff(ids, tc.IDs); diff != "" {
+				t.Fatalf("auditLogHandlerFunc() (-got, +want):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestAuditLogCompletionsAndWidgets(t *testing.T) {
+	c, h, mockConn, mockClock := NewMock(t, DefaultConfiguration())
+	mockClock.Set(time.Date(2026, 10, 19, 10, 0, 0, 0, time.UTC))
+	gomock.InOrder(
+		mockConn.EXPECT().
+			Select(gomock.Any(), gomock.Any(), gomock.Any()).
+			SetArg(1, []struct {
+				Label string `ch:"label"`
+			}{{"th2-edge1"}, {"th2-edge2"}}).
+			Return(nil),
+		mockConn.EXPECT().
+			Select(gomock.Any(), gomock.Any(), gomock.Any()).
+			SetArg(1, []struct {
+				ExporterName string
+			}{{"th2-edge1"}, {"th2-edge2"}, {"th2-edge3"}}).
+			Return(nil),
+	)
+
+	helpers.TestHTTPEndpoints(t, h.LocalAddr(), helpers.HTTPEndpointCases{
+		{
+			Description: "complete exporter names",
+			URL:         "/api/v0/console/filter/complete",
+			JSONInput:   helpers.M{"what": "value", "column": "ExporterName", "prefix": "th2-"},
+			JSONOutput: helpers.M{"completions": []helpers.M{
+				{"label": "th2-edge1", "detail": "exporter name", "quoted": true},
+				{"label": "th2-edge2", "detail": "exporter name", "quoted": true},
+			}},
+		}, {
+			Description: "exporters widget",
+			URL:         "/api/v0/console/widget/exporters",
+			JSONOutput:  helpers.M{"exporters": []string{"th2-edge1", "th2-edge2", "th2-edge3"}},
+		},
+	})
+
+	got, err := c.d.Database.SearchAuditLog(t.Context(), database.AuditLogQuery{})
+	if err != nil {
+		t.Fatalf("SearchAuditLog() error:\n%+v", err)
+	}
+	expected := []database.AuditLogEntry{
+		{
+			ID:       2,
+			Time:     mockClock.Now(),
+			User:     "__default",
+			Endpoint: "/api/v0/console/widget/exporters",
+			Rows:     3,
+			Status:   200,
+		}, {
+			ID:         1,
+			Time:       mockClock.Now(),
+			User:       "__default",
+			Endpoint:   "/api/v0/console/filter/complete",
+			Dimensions: "ExporterName",
+			Rows:       2,
+			Status:     200,
+		},
+	}
+	if diff := helpers.Diff(got, expected); diff != "" {
+		t.Fatalf("SearchAuditLog() (-got, +want):\n%s", diff)
+	}
+}
@@ -3,6 +3,8 @@
 
 package authentication
 
+import "time"
+
 // Configuration describes the configuration for the authentication component.
 type Configuration struct {
 	// Headers define authentication headers
@@ -19,6 +21,57 @@ type Configuration struct {
 	// empty, it is templated from other information available about the user,
 	// including the one from the headers.
 	AvatarURL string
+	// Groups maps a user login to a list of groups. They are added to the
+	// groups provided by the headers.
+	Groups map[string][]string
+	// Admins are the users granted an administrator access.
+	Admins AdminsConfiguration
+	// OIDC enables a built-in OpenID Connect login flow. When disabled, the
+	// headers are used.
+	OIDC OIDCConfiguration
+}
+
+// AdminsConfiguration lists the users with an administrator access, either
+// by login or by group.
+type AdminsConfiguration struct {
+	Users  []string
+	Groups []string
+}
+
+// OIDCConfiguration describes the configuration for OpenID Connect.
+type OIDCConfiguration struct {
+	// Issuer is the URL of the OpenID Connect provider. Leave empty to
+	// disable OpenID Connect.
+	Issuer string `validate:"omitempty,url"`
+	// ClientID is the client identifier registered at the provider.
+	ClientID string `validate:"required_with=Issuer"`
+	// ClientSecret is the client secret registered at the provider.
+	ClientSecret string
+	// RedirectURL is the public URL of the callback endpoint
+	// (/api/v0/console/auth/callback).
+	RedirectURL string `validate:"required_with=Issuer,omitempty,url"`
+	// Scopes are the scopes to request.
+	Scopes []string
+	// Claims maps user information to claims.
+	Claims OIDCClaimsConfiguration
+	// SessionSecret is used to sign session cookies. When empty, a random
+	// secret is generated on start.
+	SessionSecret string
+	// SessionDuration is the lifetime of a session.
+	SessionDuration time.Duration `validate:"min=1m"`
+	// AllowHeaders accepts the authentication headers when no session is
+	// present. Only enable it when an authenticating proxy sets or strips
+	// them, otherwise any client can impersonate any user.
+	AllowHeaders bool
+}
+
+// OIDCClaimsConfiguration maps user information to claims.
+type OIDCClaimsConfiguration struct {
+	Login     string `validate:"required"`
+	Name      string
+	Email     string
+	AvatarURL string
+	Groups    string
 }
 
 // ConfigurationHeaders define headers used for authentication
@@ -28,6 +81,7 @@ type ConfigurationHeaders struct {
 	Email     string
 	LogoutURL string
 	AvatarURL string
+	Groups    string
 }
 
 // DefaultConfiguration represents the default configuration for the console component.
@@ -39,10 +93,22 @@ func DefaultConfiguration() Configuration {
 			Email:     "Remote-Email",
 			LogoutURL: "X-Logout-URL",
 			AvatarURL: "X-Avatar-URL",
+			Groups:    "Remote-Groups",
 		},
 		DefaultUser: UserInformation{
 			Login: "__default",
 			Name:  "Default User",
 		},
+		OIDC: OIDCConfiguration{
+			Scopes: []string{"openid", "profile", "email"},
+			Claims: OIDCClaimsConfiguration{
+				Login:     "preferred_username",
+				Name:      "name",
+				Email:     "email",
+				AvatarURL: "picture",
+				Groups:    "groups",
+			},
+			SessionDuration: 12 * time.Hour,
+		},
 	}
 }
@@ -15,7 +15,7 @@ import (
 func TestUserHandler(t *testing.T) {
 	r := reporter.NewMock(t)
 	h := httpserver.NewMock(t, r)
-	c, err := New(r, DefaultConfiguration())
+	c, err := New(r, DefaultConfiguration(), Dependencies{})
 	if err != nil {
 		t.Fatalf("New() error:\n%+v", err)
 	}
@@ -139,7 +139,7 @@ func TestUserHandlerWithTemplates(t *testing.T) {
 	config := DefaultConfiguration()
 	config.LogoutURL = "/sso/portals/main/logout"
 	config.AvatarURL = "https://avatars.githubusercontent.com/{{ .Login }}?s=80"
-	c, err := New(r, config)
+	c, err := New(r, config, Dependencies{})
 	if err != nil {
 		t.Fatalf("New() error:\n%+v", err)
 	}
@@ -208,3 +208,86 @@ func TestUserHandlerWithTemplates(t *testing.T) {
 		})
 	})
 }
+
+func TestUserHandlerWithGroups(t *testing.T) {
+	r := reporter.NewMock(t)
+	h := httpserver.NewMock(t, r)
+	config := DefaultConfiguration()
+	config.Groups = map[string][]string{
+		"bruce":     {"justice-league", "wayne-enterprises"},
+		"__default": {"guests"},
+	}
+	config.Admins = AdminsConfiguration{
+		Users:  []string{"alfred"},
+		Groups: []string{"justice-league"},
+	}
+	c, err := New(r, config, Dependencies{})
+	if err != nil {
+		t.Fatalf("New() error:\n%+v", err)
+	}
+
+	endpoint := h.APIRouter.Group("/api/v0/console/user", c.UserAuthentication())
+	endpoint.GET("/info", c.UserInfoHandlerFunc)
+	endpoint.GET("/admin", c.UserInfoHandlerFunc, c.RequireAdmin())
+
+	helpers.TestHTTPEndpoints(t, h.LocalAddr(), helpers.HTTPEndpointCases{
+		{
+			Description: "user info, groups from headers",
+			URL:         "/api/v0/console/user/info",
+			Header: func() http.Header {
+				headers := make(http.Header)
+				headers.Add("Remote-User", "alfred")
+				headers.Add("Remote-Groups", "butlers, wayne-enterprises,")
+				return headers
+			}(),
+			StatusCode: 200,
+			JSONOutput: helpers.M{
+				"login":  "alfred",
+				"groups": []string{"butlers", "wayne-enterprises"},
+				"admin":  true,
+			},
+		}, {
+			Description: "user info, groups from headers and configuration",
+			URL:         "/api/v0/console/user/info",
+			Header: func() http.Header {
+				headers := make(http.Header)
+				headers.Add("Remote-User", "bruce")
+				headers.Add("Remote-Groups", "wayne-enterprises")
+				return headers
+			}(),
+			StatusCode: 200,
+			JSONOutput: helpers.M{
+				"login":  "bruce",
+				"groups": []string{"wayne-enterprises", "justice-league"},
+				"admin":  true,
+			},
+		}, {
+			Description: "user info, default user",
+			URL:         "/api/v0/console/user/info",
+			StatusCode:  200,
+			JSONOutput: helpers.M{
+				"login":  "__default",
+				"name":   "Default User",
+				"groups": []string{"guests"},
+			},
+		}, {
+			Description: "admin endpoint, admin user",
+			URL:         "/api/v0/console/user/admin",
+			Header: func() http.Header {
+				headers := make(http.Header)
+				headers.Add("Remote-User", "alfred")
+				return headers
+			}(),
+			StatusCode: 200,
+			JSONOutput: helpers.M{
+				"login": "alfred",
+				"admin": true,
+			},
+		}, {
+			Description: "admin endpoint, regular user",
+			URL:         "/api/v0/console/user/admin",
+			StatusCode:  403,
+			JSONOutput:  helpers.M{"message": "Administrator access required."},
+		},
+	})
+}
@@ -6,6 +6,7 @@ package authentication
 import (
 	"context"
 	"net/http"
+	"slices"
 	"strings"
 	"text/template"
 
@@ -15,11 +16,14 @@ import (
 
 // UserInformation contains information about the current user.
 type UserInformation struct {
-	Login     string `json:"login"`
-	Name      string `json:"name,omitempty"`
-	Email     string `json:"email,omitempty" validate:"omitempty,email"`
-	LogoutURL string `json:"logout-url,omitempty" validate:"omitempty,uri"`
-	AvatarURL string `json:"avatar-url,omitempty" validate:"omitempty,uri"`
+	Login     string   `json:"login"`
+	Name      string   `json:"name,omitempty"`
+	Email     string   `json:"email,omitempty" validate:"omitempty,email"`
+	LogoutURL string   `json:"logout-url,omitempty" validate:"omitempty,uri"`
+	AvatarURL string   `json:"avatar-url,omitempty" validate:"omitempty,uri"`
+	Groups    []string `json:"groups,omitempty"`
+	ReadOnly  bool     `json:"read-only,omitempty"`
+	Admin     bool     `json:"admin,omitempty"`
 }
 
 // userContextKey is the key under which the current user is stored in the
@@ -33,7 +37,11 @@ func UserFromContext(ctx context.Context) UserInformation {
 }
 
 // UserAuthentication is a middleware to fill information about the current
-// user. It does not really perform authentication but relies on HTTP headers.
+// user. It does not really perform authentication but relies on HTTP headers,
+// except for API tokens which are checked against the database and for
+// sessions opened with OpenID Connect. When OpenID Connect is enabled, headers
+// are ignored unless explicitly allowed. The identity of the users is recorded
+// to be used with their API tokens.
 func (c *Component) UserAuthentication() httpserver.Middleware {
 	var logoutURLTmpl, avatarURLTmpl *template.Template
 	if c.config.LogoutURL != "" {
@@ -45,15 +53,50 @@ func (c *Component) UserAuthentication() httpserver.Middleware {
 
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
-			info := c.userFromHeaders(req)
+			var info UserInformation
+			token, fromToken := bearerAPIToken(req)
+			if fromToken {
+				var err error
+				info, err = c.userFromAPIToken(req.Context(), token)
+				if err != nil {
+					httpserver.WriteJSON(w, http.StatusUnauthorized,
+						helpers.M{"message": helpers.Capitalize(err.Error()) + "."})
+					return
+				}
+			} else if c.oidc != nil {
+				// Without a proxy in front of the console, headers are
+				// controlled by the client.
+				if user, err := c.oidc.userFromSession(req); err == nil {
+					info = user
+				} else if c.config.OIDC.AllowHeaders {
+					info = c.userFromHeaders(req)
+				}
+			} else {
+				info = c.userFromHeaders(req)
+			}
 			if info.Login == "" || helpers.Validate.Struct(info) != nil {
 				if c.config.DefaultUser.Login == "" {
-					httpserver.WriteJSON(w, http.StatusUnauthorized,
-						helpers.M{"message": "No user logged in."})
+					response := helpers.M{"message": "No user logged in."}
+					if c.oidc != nil {
+						response["login-url"] = c.oidc.root + oidcLoginPath
+					}
+					httpserver.WriteJSON(w, http.StatusUnauthorized, response)
 					return
 				}
 				info = c.config.DefaultUser
+			} else if !fromToken {
+				c.recordIdentity(req.Context(), info)
 			}
+			info.Groups = slices.Clone(info.Groups)
+			for _, group := range c.config.Groups[info.Login] {
+				if !slices.Contains(info.Groups, group) {
+					info.Groups = append(info.Groups, group)
+				}
+			}
+			info.Admin = slices.Contains(c.config.Admins.Users, info.Login) ||
+				slices.ContainsFunc(info.Groups, func(group string) bool {
+					return slices.Contains(c.config.Admins.Groups, group)
+				})
 
 			// Apply configured templates (they can access header values and choose to keep or override)
 			if logoutURLTmpl != nil {
@@ -84,11 +127,48 @@ func (c *Component) userFromHeaders(req *http.Request) UserInformation {
 		}
 		return req.Header.Get(name)
 	}
-	return UserInformation{
+	info := UserInformation{
 		Login:     get(c.config.Headers.Login),
 		Name:      get(c.config.Headers.Name),
 		Email:     get(c.config.Headers.Email),
 		LogoutURL: get(c.config.Headers.LogoutURL),
 		AvatarURL: get(c.config.Headers.AvatarURL),
 	}
+	// Groups are separated by commas
+	for group := range strings.SplitSeq(get(c.config.Headers.Groups), ",") {
+		if group = strings.TrimSpace(group); group != "" {
+			info.Groups = append(info.Groups, group)
+		}
+	}
+	return info
+}
+
+// RequireAdmin is a middleware rejecting users without an administrator
+// access. It should be used after UserAuthentication.
+func (c *Component) RequireAdmin() httpserver.Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			if !UserFromContext(req.Context()).Admin {
+				httpserver.WriteJSON(w, http.StatusForbidden,
+					helpers.M{"message": "Administrator access required."})
+				return
+			}
+			next.ServeHTTP(w, req)
+		})
+	}
+}
+
+// RequireWriteAccess is a middleware rejecting users with a read-only access.
+// It should be used after UserAuthentication.
+func (c *Component) RequireWriteAccess() httpserver.Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			if UserFromContext(req.Context()).ReadOnly {
+				httpserver.WriteJSON(w, http.StatusForbidden,
+					helpers.M{"message": "Read-only access."})
+				return
+			}
+			next.ServeHTTP(w, req)
+		})
+	}
 }
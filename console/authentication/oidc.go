@@ -0,0 +1,426 @@
+// SPDX-FileCopyrightText: 2026 Free Mobile
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package authentication
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/oauth2"
+
+	"akvorado/common/helpers"
+	"akvorado/common/httpserver"
+)
+
+const (
+	// oidcCallbackPath is the expected suffix of the redirect URL. The
+	// remaining part is the root of the console.
+	oidcCallbackPath = "api/v0/console/auth/callback"
+	// oidcLoginPath and oidcLogoutPath are relative to the root of the console.
+	oidcLoginPath  = "api/v0/console/auth/login"
+	oidcLogoutPath = "api/v0/console/auth/logout"
+
+	sessionCookieName   = "akvorado-session"
+	oidcStateCookieName = "akvorado-oidc"
+	oidcStateDuration   = 10 * time.Minute
+)
+
+var (
+	errInvalidCookie = errors.New("invalid cookie")
+	errExpiredCookie = errors.New("expired cookie")
+)
+
+// oidcProvider implements the OpenID Connect authorization code flow with
+// PKCE. Users are then tracked with a signed session cookie.
+type oidcProvider struct {
+	config OIDCConfiguration
+	secret []byte
+	root   string // path to the root of the console
+	origin string // scheme and host of the console
+	secure bool
+	client *http.Client
+
+	lock      sync.Mutex
+	discovery *oidcDiscovery
+}
+
+// oidcDiscovery is the subset of the discovery document we use.
+type oidcDiscovery struct {
+	Issuer                string `json:"issuer"`
+	AuthorizationEndpoint string `json:"authorization_endpoint"`
+	TokenEndpoint         string `json:"token_endpoint"`
+	EndSessionEndpoint    string `json:"end_session_endpoint"`
+}
+
+// oidcSession is the content of the session cookie.
+type oidcSession struct {
+	User    UserInformation `json:"user"`
+	Expires int64           `json:"exp"`
+}
+
+// oidcState is the content of the cookie used during the login flow.
+type oidcState struct {
+	State    string `json:"state"`
+	Nonce    string `json:"nonce"`
+	Verifier string `json:"verifier"`
+	Next     string `json:"next"`
+	Expires  int64  `json:"exp"`
+}
+
+// newOIDCProvider creates a new OpenID Connect provider.
+func newOIDCProvider(config OIDCConfiguration) (*oidcProvider, error) {
+	redirectURL, err := url.Parse(config.RedirectURL)
+	if err != nil {
+		return nil, fmt.Errorf("cannot parse redirect URL: %w", err)
+	}
+	root, ok := strings.CutSuffix(redirectURL.Path, oidcCallbackPath)
+	if !ok || !strings.HasSuffix(root, "/") {
+		return nil, fmt.Errorf("redirect URL should end with /%s", oidcCallbackPath)
+	}
+	p := oidcProvider{
+		config: config,
+		secret: []byte(config.SessionSecret),
+		root:   root,
+		origin: fmt.Sprintf("%s://%s", redirectURL.Scheme, redirectURL.Host),
+		secure: redirectURL.Scheme == "https",
+		client: &http.Client{Timeout: 10 * time.Second},
+	}
+	if len(p.secret) == 0 {
+		p.secret = make([]byte, 32)
+		rand.Read(p.secret)
+	}
+	return &p, nil
+}
+
+// getDiscovery returns the discovery document of the provider. It is fetched
+// on first use and cached once successfully retrieved.
+func (p *oidcProvider) getDiscovery(ctx context.Context) (*oidcDiscovery, error) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	if p.discovery != nil {
+		return p.discovery, nil
+	}
+	issuer := strings.TrimSuffix(p.config.Issuer, "/")
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet,
+		issuer+"/.well-known/openid-configuration", nil)
+	if err != nil {
+		return nil, fmt.Errorf("cannot build discovery request: %w", err)
+	}
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("cannot fetch discovery document: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("cannot fetch discovery document: unexpected status %s", resp.Status)
+	}
+	var discovery oidcDiscovery
+	if err := json.NewDecoder(resp.Body).Decode(&discovery); err != nil {
+		return nil, fmt.Errorf("cannot decode discovery document: %w", err)
+	}
+	if strings.TrimSuffix(discovery.Issuer, "/") != issuer {
+		return nil, fmt.Errorf("discovery document issuer %q does not match %q",
+			discovery.Issuer, p.config.Issuer)
+	}
+	if discovery.AuthorizationEndpoint == "" || discovery.TokenEndpoint == "" {
+		return nil, errors.New("discovery document without authorization or token endpoint")
+	}
+	p.discovery = &discovery
+	return p.discovery, nil
+}
+
+// oauth2Config returns the OAuth2 configuration for the provider.
+func (p *oidcProvider) oauth2Config(discovery *oidcDiscovery) *oauth2.Config {
+	return &oauth2.Config{
+		ClientID:     p.config.ClientID,
+		ClientSecret: p.config.ClientSecret,
+		Endpoint: oauth2.Endpoint{
+			AuthURL:  discovery.AuthorizationEndpoint,
+			TokenURL: discovery.TokenEndpoint,
+		},
+		RedirectURL: p.config.RedirectURL,
+		Scopes:      p.config.Scopes,
+	}
+}
+
+// sign encodes and signs the provided value for a cookie.
+func (p *oidcProvider) sign(v any) string {
+	payload, _ := json.Marshal(v)
+	mac := hmac.New(sha256.New, p.secret)
+	mac.Write(payload)
+	return fmt.Sprintf("%s.%s",
+		base64.RawURLEncoding.EncodeToString(payload),
+		base64.RawURLEncoding.EncodeToString(mac.Sum(nil)))
+}
+
+// verify checks the signature of a cookie value and decodes it.
+func (p *oidcProvider) verify(value string, v any) error {
+	encodedPayload, encodedSignature, ok := strings.Cut(value, ".")
+	if !ok {
+		return errInvalidCookie
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(encodedPayload)
+	if err != nil {
+		return errInvalidCookie
+	}
+	signature, err := base64.RawURLEncoding.DecodeString(encodedSignature)
+	if err != nil {
+		return errInvalidCookie
+	}
+	mac := hmac.New(sha256.New, p.secret)
+	mac.Write(payload)
+	if !hmac.Equal(signature, mac.Sum(nil)) {
+		return errInvalidCookie
+	}
+	if err := json.Unmarshal(payload, v); err != nil {
+		return errInvalidCookie
+	}
+	return nil
+}
+
+// setCookie sets a cookie. A negative duration removes it.
+func (p *oidcProvider) setCookie(w http.ResponseWriter, name, path, value string, duration time.Duration) {
+	cookie := http.Cookie{
+		Name:     name,
+		Value:    value,
+		Path:     path,
+		MaxAge:   int(duration.Seconds()),
+		Secure:   p.secure,
+		HttpOnly: true,
+		SameSite: http.SameSiteLaxMode,
+	}
+	if duration < 0 {
+		cookie.MaxAge = -1
+	}
+	http.SetCookie(w, &cookie)
+}
+
+// userFromSession returns the user from the session cookie.
+func (p *oidcProvider) userFromSession(req *http.Request) (UserInformation, error) {
+	cookie, err := req.Cookie(sessionCookieName)
+	if err != nil {
+		return UserInformation{}, errInvalidCookie
+	}
+	var session oidcSession
+	if err := p.verify(cookie.Value, &session); err != nil {
+		return UserInformation{}, err
+	}
+	if time.Now().Unix() > session.Expires {
+		return UserInformation{}, errExpiredCookie
+	}
+	session.User.LogoutURL = p.root + oidcLogoutPath
+	return session.User, nil
+}
+
+// userFromIDToken validates the claims of an ID token and maps them to user
+// information. The ID token is received directly from the token endpoint over
+// TLS, therefore its signature is not checked (OpenID Connect Core 1.0,
+// §3.1.3.7).
+func (p *oidcProvider) userFromIDToken(rawIDToken string, discovery *oidcDiscovery, nonce string) (UserInformation, error) {
+	parts := strings.Split(rawIDToken, ".")
+	if len(parts) != 3 {
+		return UserInformation{}, errors.New("malformed ID token")
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return UserInformation{}, fmt.Errorf("cannot decode ID token: %w", err)
+	}
+	var claims map[string]any
+	if err := json.Unmarshal(payload, &claims); err != nil {
+		return UserInformation{}, fmt.Errorf("cannot decode ID token: %w", err)
+	}
+
+	if iss, _ := claims["iss"].(string); iss != discovery.Issuer {
+		return UserInformation{}, fmt.Errorf("unexpected ID token issuer %q", iss)
+	}
+	audienceOK := false
+	switch aud := claims["aud"].(type) {
+	case string:
+		audienceOK = aud == p.config.ClientID
+	case []any:
+		audienceOK = slices.Contains(aud, any(p.config.ClientID))
+	}
+	if !audienceOK {
+		return UserInformation{}, errors.New("unexpected ID token audience")
+	}
+	if exp, _ := claims["exp"].(float64); time.Now().Unix() > int64(exp) {
+		return UserInformation{}, errors.New("expired ID token")
+	}
+	if got, _ := claims["nonce"].(string); got != nonce {
+		return UserInformation{}, errors.New("unexpected ID token nonce")
+	}
+
+	claim := func(name string) string {
+		if name == "" {
+			return ""
+		}
+		value, _ := claims[name].(string)
+		return value
+	}
+	info := UserInformation{
+		Login:     claim(p.config.Claims.Login),
+		Name:      claim(p.config.Claims.Name),
+		Email:     claim(p.config.Claims.Email),
+		AvatarURL: claim(p.config.Claims.AvatarURL),
+	}
+	// Groups are either a list or a single string
+	if p.config.Claims.Groups != "" {
+		switch groups := claims[p.config.Claims.Groups].(type) {
+		case string:
+			info.Groups = []string{groups}
+		case []any:
+			for _, group := range groups {
+				if group, ok := group.(string); ok {
+					info.Groups = append(info.Groups, group)
+				}
+			}
+		}
+	}
+	if info.Login == "" {
+		return UserInformation{}, fmt.Errorf("missing %q claim in ID token", p.config.Claims.Login)
+	}
+	if err := helpers.Validate.Struct(info); err != nil {
+		return UserInformation{}, fmt.Errorf("invalid user information: %w", err)
+	}
+	return info, nil
+}
+
+// randomString returns a random URL-safe string.
+func randomString() string {
+	random := make([]byte, 16)
+	rand.Read(random)
+	return base64.RawURLEncoding.EncodeToString(random)
+}
+
+// OIDCLoginHandlerFunc redirects the user to the OpenID Connect provider.
+func (c *Component) OIDCLoginHandlerFunc(w http.ResponseWriter, req *http.Request) {
+	p := c.oidc
+	if p == nil {
+		httpserver.WriteJSON(w, http.StatusNotFound, helpers.M{"message": "OpenID Connect is not enabled."})
+		return
+	}
+	discovery, err := p.getDiscovery(req.Context())
+	if err != nil {
+		c.r.Err(err).Msg("cannot get OpenID Connect discovery document")
+		httpserver.WriteJSON(w, http.StatusBadGateway,
+			helpers.M{"message": "Cannot reach OpenID Connect provider."})
+		return
+	}
+
+	// Only accept local paths to redirect to after login
+	next := req.URL.Query().Get("next")
+	if !strings.HasPrefix(next, "/") || strings.HasPrefix(next, "//") || strings.HasPrefix(next, "/\\") {
+		next = "/"
+	}
+	state := oidcState{
+		State:    randomString(),
+		Nonce:    randomString(),
+		Verifier: oauth2.GenerateVerifier(),
+		Next:     next,
+		Expires:  time.Now().Add(oidcStateDuration).Unix(),
+	}
+	p.setCookie(w, oidcStateCookieName, p.root+"api/v0/console/auth/",
+		p.sign(state), oidcStateDuration)
+	http.Redirect(w, req, p.oauth2Config(discovery).AuthCodeURL(state.State,
+		oauth2.S256ChallengeOption(state.Verifier),
+		oauth2.SetAuthURLParam("nonce", state.Nonce)), http.StatusFound)
+}
+
+// OIDCCallbackHandlerFunc handles the redirection from the OpenID Connect
+// provider and opens a session.
+func (c *Component) OIDCCallbackHandlerFunc(w http.ResponseWriter, req *http.Request) {
+	p := c.oidc
+	if p == nil {
+		httpserver.WriteJSON(w, http.StatusNotFound, helpers.M{"message": "OpenID Connect is not enabled."})
+		return
+	}
+	var state oidcState
+	cookie, err := req.Cookie(oidcStateCookieName)
+	if err == nil {
+		err = p.verify(cookie.Value, &state)
+	}
+	if err != nil || time.Now().Unix() > state.Expires || req.URL.Query().Get("state") != state.State {
+		httpserver.WriteJSON(w, http.StatusBadRequest, helpers.M{"message": "Invalid login state."})
+		return
+	}
+	p.setCookie(w, oidcStateCookieName, p.root+"api/v0/console/auth/", "", -1)
+	if errorCode := req.URL.Query().Get("error"); errorCode != "" {
+		message := req.URL.Query().Get("error_description")
+		if message == "" {
+			message = errorCode
+		}
+		httpserver.WriteJSON(w, http.StatusUnauthorized,
+			helpers.M{"message": fmt.Sprintf("Login failed: %s.", message)})
+		return
+	}
+
+	discovery, err := p.getDiscovery(req.Context())
+	if err != nil {
+		c.r.Err(err).Msg("cannot get OpenID Connect discovery document")
+		httpserver.WriteJSON(w, http.StatusBadGateway,
+			helpers.M{"message": "Cannot reach OpenID Connect provider."})
+		return
+	}
+	ctx := context.WithValue(req.Context(), oauth2.HTTPClient, p.client)
+	token, err := p.oauth2Config(discovery).Exchange(ctx, req.URL.Query().Get("code"),
+		oauth2.VerifierOption(state.Verifier))
+	if err != nil {
+		c.r.Err(err).Msg("cannot exchange OpenID Connect authorization code")
+		httpserver.WriteJSON(w, http.StatusUnauthorized,
+			helpers.M{"message": "Cannot exchange authorization code."})
+		return
+	}
+	rawIDToken, _ := token.Extra("id_token").(string)
+	info, err := p.userFromIDToken(rawIDToken, discovery, state.Nonce)
+	if err != nil {
+		c.r.Err(err).Msg("invalid OpenID Connect ID token")
+		httpserver.WriteJSON(w, http.StatusUnauthorized,
+			helpers.M{"message": "Invalid ID token."})
+		return
+	}
+
+	p.setCookie(w, sessionCookieName, p.root, p.sign(oidcSession{
+		User:    info,
+		Expires: time.Now().Add(p.config.SessionDuration).Unix(),
+	}), p.config.SessionDuration)
+	http.Redirect(w, req, p.root+strings.TrimPrefix(state.Next, "/"), http.StatusFound)
+}
+
+// OIDCLogoutHandlerFunc closes the session and redirects the user to the
+// logout endpoint of the OpenID Connect provider, if any.
+func (c *Component) OIDCLogoutHandlerFunc(w http.ResponseWriter, req *http.Request) {
+	p := c.oidc
+	if p == nil {
+		httpserver.WriteJSON(w, http.StatusNotFound, helpers.M{"message": "OpenID Connect is not enabled."})
+		return
+	}
+	p.setCookie(w, sessionCookieName, p.root, "", -1)
+	discovery, err := p.getDiscovery(req.Context())
+	if err != nil || discovery.EndSessionEndpoint == "" {
+		http.Redirect(w, req, p.root, http.StatusFound)
+		return
+	}
+	logoutURL, err := url.Parse(discovery.EndSessionEndpoint)
+	if err != nil {
+		http.Redirect(w, req, p.root, http.StatusFound)
+		return
+	}
+	query := logoutURL.Query()
+	query.Set("client_id", p.config.ClientID)
+	query.Set("post_logout_redirect_uri", p.origin+p.root)
+	logoutURL.RawQuery = query.Encode()
+	http.Redirect(w, req, logoutURL.String(), http.StatusFound)
+}
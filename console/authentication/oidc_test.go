@@ -0,0 +1,258 @@
+// SPDX-FileCopyrightText: 2026 Free Mobile
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package authentication
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/cookiejar"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"akvorado/common/helpers"
+	"akvorado/common/httpserver"
+	"akvorado/common/reporter"
+)
+
+// mockIssuer is a minimal OpenID Connect provider.
+type mockIssuer struct {
+	*httptest.Server
+	t      *testing.T
+	claims map[string]any
+	// Parameters of the last authorization request
+	challenge string
+	nonce     string
+}
+
+func newMockIssuer(t *testing.T) *mockIssuer {
+	m := &mockIssuer{t: t}
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /.well-known/openid-configuration", func(w http.ResponseWriter, _ *http.Request) {
+		json.NewEncoder(w).Encode(helpers.M{
+			"issuer":                 m.URL,
+			"authorization_endpoint": m.URL + "/authorize",
+			"token_endpoint":         m.URL + "/token",
+			"end_session_endpoint":   m.URL + "/logout",
+		})
+	})
+	mux.HandleFunc("POST /token", func(w http.ResponseWriter, req *http.Request) {
+		req.ParseForm()
+		sum := sha256.Sum256([]byte(req.Form.Get("code_verifier")))
+		if req.Form.Get("code") != "code1" ||
+			base64.RawURLEncoding.EncodeToString(sum[:]) != m.challenge {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(helpers.M{"error": "invalid_grant"})
+			return
+		}
+		claims := map[string]any{
+			"iss":   m.URL,
+			"aud":   "akvorado",
+			"exp":   time.Now().Add(time.Hour).Unix(),
+			"nonce": m.nonce,
+		}
+		for k, v := range m.claims {
+			claims[k] = v
+		}
+		payload, _ := json.Marshal(claims)
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(helpers.M{
+			"access_token": "access1",
+			"token_type":   "Bearer",
+			"id_token": fmt.Sprintf("eyJhbGciOiJub25lIn0.%s.",
+				base64.RawURLEncoding.EncodeToString(payload)),
+		})
+	})
+	m.Server = httptest.NewServer(mux)
+	t.Cleanup(m.Close)
+	return m
+}
+
+// authorize simulates the user logging in at the provider and returns the
+// redirection to the callback.
+func (m *mockIssuer) authorize(location string) string {
+	m.t.Helper()
+	u, err := url.Parse(location)
+	if err != nil {
+		m.t.Fatalf("Parse(%q) error:\n%+v", location, err)
+	}
+	query := u.Query()
+	if u.Path != "/authorize" ||
+		query.Get("client_id") != "akvorado" ||
+		query.Get("response_type") != "code" ||
+		query.Get("code_challenge_method") != "S256" ||
+		query.Get("scope") != "openid profile email" {
+		m.t.Fatalf("unexpected authorization request %q", location)
+	}
+	m.challenge = query.Get("code_challenge")
+	m.nonce = query.Get("nonce")
+	return fmt.Sprintf("%s?code=code1&state=%s",
+		query.Get("redirect_uri"), url.QueryEscape(query.Get("state")))
+}
+
+func TestOIDC(t *testing.T) {
+	r := reporter.NewMock(t)
+	h := httpserver.NewMock(t, r)
+	issuer := newMockIssuer(t)
+	issuer.claims = map[string]any{
+		"preferred_username": "alfred",
+		"name":               "Alfred Pennyworth",
+		"email":              "alfred@example.com",
+		"groups":             []string{"butlers", "admins"},
+	}
+	config := DefaultConfiguration()
+	config.DefaultUser = UserInformation{}
+	config.OIDC.Issuer = issuer.URL
+	config.OIDC.ClientID = "akvorado"
+	// Cookies are not stored for unspecified addresses
+	base := fmt.Sprintf("http://127.0.0.1:%d", h.LocalAddr().(*net.TCPAddr).Port)
+	config.OIDC.RedirectURL = fmt.Sprintf("%s/%s", base, oidcCallbackPath)
+	if err := helpers.Validate.Struct(config); err != nil {
+		t.Fatalf("validate.Struct() error:\n%+v", err)
+	}
+	c, err := New(r, config, Dependencies{})
+	if err != nil {
+		t.Fatalf("New() error:\n%+v", err)
+	}
+	h.APIRouter.GET("/api/v0/console/auth/login", c.OIDCLoginHandlerFunc)
+	h.APIRouter.GET("/api/v0/console/auth/callback", c.OIDCCallbackHandlerFunc)
+	h.APIRouter.GET("/api/v0/console/auth/logout", c.OIDCLogoutHandlerFunc)
+	endpoint := h.APIRouter.Group("/api/v0/console/user", c.UserAuthentication())
+	endpoint.GET("/info", c.UserInfoHandlerFunc)
+
+	jar, _ := cookiejar.New(nil)
+	client := &http.Client{
+		Jar: jar,
+		CheckRedirect: func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+	get := func(u string, expectedStatus int) *http.Response {
+		t.Helper()
+		resp, err := client.Get(u)
+		if err != nil {
+			t.Fatalf("GET %s error:\n%+v", u, err)
+		}
+		t.Cleanup(func() { resp.Body.Close() })
+		if resp.StatusCode != expectedStatus {
+			body, _ := io.ReadAll(resp.Body)
+			t.Fatalf("GET %s: got status code %d, not %d (%s)", u, resp.StatusCode, expectedStatus, body)
+		}
+		return resp
+	}
+	userInfo := func() helpers.M {
+		t.Helper()
+		var got helpers.M
+		resp := get(base+"/api/v0/console/user/info", http.StatusOK)
+		if err := json.NewDecoder(resp.Body).Decode(&got); err != nil {
+			t.Fatalf("Decode() error:\n%+v", err)
+		}
+		return got
+	}
+
+	// Not logged in: the login URL is provided
+	resp := get(base+"/api/v0/console/user/info", http.StatusUnauthorized)
+	var got helpers.M
+	json.NewDecoder(resp.Body).Decode(&got)
+	if diff := helpers.Diff(got, helpers.M{
+		"message":   "No user logged in.",
+		"login-url": "/api/v0/console/auth/login",
+	}); diff != "" {
+		t.Fatalf("GET /api/v0/console/user/info (-got, +want):\n%s", diff)
+	}
+
+	// Spoofed headers are rejected, unless explicitly allowed
+	headers := func(expectedStatus int) {
+		t.Helper()
+		req, _ := http.NewRequest("GET", base+"/api/v0/console/user/info", nil)
+		req.Header.Set("Remote-User", "bruce")
+		req.Header.Set("Remote-Groups", "admins")
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("GET /api/v0/console/user/info error:\n%+v", err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != expectedStatus {
+			t.Fatalf("GET /api/v0/console/user/info with headers: got status code %d, not %d",
+				resp.StatusCode, expectedStatus)
+		}
+	}
+	headers(http.StatusUnauthorized)
+	c.config.OIDC.AllowHeaders = true
+	headers(http.StatusOK)
+	c.config.OIDC.AllowHeaders = false
+
+	// Callback without a login flow
+	get(base+"/api/v0/console/auth/callback?code=code1&state=nope", http.StatusBadRequest)
+
+	// Full login flow
+	resp = get(base+"/api/v0/console/auth/login?next=/visualize", http.StatusFound)
+	callback := issuer.authorize(resp.Header.Get("Location"))
+	resp = get(callback, http.StatusFound)
+	if location := resp.Header.Get("Location"); location != "/visualize" {
+		t.Fatalf("GET /api/v0/console/auth/callback redirected to %q", location)
+	}
+	if diff := helpers.Diff(userInfo(), helpers.M{
+		"login":      "alfred",
+		"name":       "Alfred Pennyworth",
+		"email":      "alfred@example.com",
+		"groups":     []any{"butlers", "admins"},
+		"logout-url": "/api/v0/console/auth/logout",
+	}); diff != "" {
+		t.Fatalf("GET /api/v0/console/user/info (-got, +want):\n%s", diff)
+	}
+
+	// Logout
+	resp = get(base+"/api/v0/console/auth/logout", http.StatusFound)
+	expected := fmt.Sprintf("%s/logout?client_id=akvorado&post_logout_redirect_uri=%s",
+		issuer.URL, url.QueryEscape(base+"/"))
+	if location := resp.Header.Get("Location"); location != expected {
+		t.Fatalf("GET /api/v0/console/auth/logout redirected to %q, not %q", location, expected)
+	}
+	get(base+"/api/v0/console/user/info", http.StatusUnauthorized)
+
+	// Open redirect and replayed state are rejected
+	resp = get(base+"/api/v0/console/auth/login?next=//example.com", http.StatusFound)
+	callback = issuer.authorize(resp.Header.Get("Location"))
+	resp = get(callback, http.StatusFound)
+	if location := resp.Header.Get("Location"); location != "/" {
+		t.Fatalf("GET /api/v0/console/auth/callback redirected to %q", location)
+	}
+	get(callback, http.StatusBadRequest)
+
+	// Tampered session
+	u, _ := url.Parse(base)
+	cookies := jar.Cookies(u)
+	for _, cookie := range cookies {
+		if cookie.Name == sessionCookieName {
+			cookie.Value = strings.Replace(cookie.Value, ".", "x.", 1)
+			jar.SetCookies(u, []*http.Cookie{cookie})
+		}
+	}
+	get(base+"/api/v0/console/user/info", http.StatusUnauthorized)
+
+	// Wrong nonce
+	resp = get(base+"/api/v0/console/auth/login", http.StatusFound)
+	callback = issuer.authorize(resp.Header.Get("Location"))
+	issuer.nonce = "nope"
+	get(callback, http.StatusUnauthorized)
+}
+
+func TestOIDCInvalidRedirectURL(t *testing.T) {
+	r := reporter.NewMock(t)
+	config := DefaultConfiguration()
+	config.OIDC.Issuer = "https://sso.example.com"
+	config.OIDC.ClientID = "akvorado"
+	config.OIDC.RedirectURL = "https://akvorado.example.com/callback"
+	if _, err := New(r, config, Dependencies{}); err == nil {
+		t.Fatal("New() did not error")
+	}
+}
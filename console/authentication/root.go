@@ -4,19 +4,49 @@
 // Package authentication handles user authentication for the console.
 package authentication
 
-import "akvorado/common/reporter"
+import (
+	"fmt"
+	"sync/atomic"
+
+	"akvorado/common/helpers/cache"
+	"akvorado/common/reporter"
+	"akvorado/console/database"
+)
 
 // Component represents the authentication compomenent.
 type Component struct {
 	r      *reporter.Reporter
+	d      *Dependencies
 	config Configuration
+	oidc   *oidcProvider
+
+	// identities caches the identity last recorded for each user.
+	identities       *cache.Cache[string, string]
+	identitiesPruned atomic.Int64
+}
+
+// Dependencies define the dependencies of the authentication component.
+type Dependencies struct {
+	// Database stores API tokens and the identity of their owners. When nil,
+	// API tokens are not accepted.
+	Database *database.Component
 }
 
 // New creates a new authentication component.
-func New(r *reporter.Reporter, configuration Configuration) (*Component, error) {
+func New(r *reporter.Reporter, configuration Configuration, dependencies Dependencies) (*Component, error) {
 	c := Component{
 		r:      r,
+		d:      &dependencies,
 		config: configuration,
+
+		identities: cache.New[string, string](),
+	}
+	if configuration.OIDC.Issuer != "" {
+		var err error
+		c.oidc, err = newOIDCProvider(configuration.OIDC)
+		if err != nil {
+			return nil, fmt.Errorf("cannot configure OpenID Connect: %w", err)
+		}
 	}
 
 	return &c, nil
@@ -14,7 +14,7 @@ import (
 // NewMock instantiantes a new authentication component
 func NewMock(t *testing.T, r *reporter.Reporter) *Component {
 	t.Helper()
-	c, err := New(r, DefaultConfiguration())
+	c, err := New(r, DefaultConfiguration(), Dependencies{})
 	if err != nil {
 		t.Fatalf("New() error:\n%+v", err)
 	}
@@ -0,0 +1,238 @@
+// SPDX-FileCopyrightText: 2026 Free Mobile
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package authentication
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"akvorado/common/helpers"
+	"akvorado/common/httpserver"
+	"akvorado/console/database"
+)
+
+// apiTokenPrefix is the prefix of API tokens. Bearer tokens without this
+// prefix are ignored as they may come from an authenticating proxy.
+const apiTokenPrefix = "akv_"
+
+var (
+	errInvalidAPIToken = errors.New("invalid API token")
+	errExpiredAPIToken = errors.New("expired API token")
+)
+
+// bearerAPIToken returns the API token from the Authorization header.
+func bearerAPIToken(req *http.Request) (string, bool) {
+	scheme, token, ok := strings.Cut(req.Header.Get("Authorization"), " ")
+	if !ok || !strings.EqualFold(scheme, "Bearer") {
+		return "", false
+	}
+	token = strings.TrimSpace(token)
+	if !strings.HasPrefix(token, apiTokenPrefix) {
+		return "", false
+	}
+	return token, true
+}
+
+// hashAPIToken returns the hash of an API token as stored in database. API
+// tokens are random, a fast hash is enough.
+func hashAPIToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// identityCacheDuration is how long the identity recorded for a user is kept in
+// memory without being used.
+const identityCacheDuration = time.Hour
+
+// recordIdentity records the identity of a user, as provided by the headers or
+// by OpenID Connect, if the user owns API tokens. When using an API token, the
+// identity of its owner is the one from their last request without a token.
+// Database writes are only done when the identity changes.
+func (c *Component) recordIdentity(ctx context.Context, info UserInformation) {
+	if c.d.Database == nil {
+		return
+	}
+	now := time.Now()
+	identity := strings.Join([]string{info.Name, info.Email, strings.Join(info.Groups, ",")}, "\x00")
+	if cached, ok := c.identities.Get(now, info.Login); ok && cached == identity {
+		return
+	}
+	if err := c.d.Database.UpdateUser(ctx, userFromInformation(now, info)); err != nil {
+		c.r.Err(err).Str("user", info.Login).Msg("cannot record user identity")
+		return
+	}
+	c.identities.Put(now, info.Login, identity)
+	if last := c.identitiesPruned.Load(); now.Unix()-last > int64(identityCacheDuration.Seconds()) &&
+		c.identitiesPruned.CompareAndSwap(last, now.Unix()) {
+		c.identities.DeleteLastAccessedBefore(now.Add(-identityCacheDuration))
+	}
+}
+
+// userFromInformation converts a UserInformation to a database.User.
+func userFromInformation(now time.Time, info UserInformation) database.User {
+	return database.User{
+		Login:   info.Login,
+		Name:    info.Name,
+		Email:   info.Email,
+		Groups:  strings.Join(info.Groups, ","),
+		Updated: now.UTC().Truncate(time.Second),
+	}
+}
+
+// userFromAPIToken returns the owner of the provided API token. Groups from
+// the configuration are added later by the middleware.
+func (c *Component) userFromAPIToken(ctx context.Context, token string) (UserInformation, error) {
+	if c.d.Database == nil {
+		return UserInformation{}, errInvalidAPIToken
+	}
+	apiToken, err := c.d.Database.LookupAPIToken(ctx, hashAPIToken(token))
+	if errors.Is(err, database.ErrAPITokenNotFound) {
+		return UserInformation{}, errInvalidAPIToken
+	} else if err != nil {
+		c.r.Err(err).Msg("cannot lookup API token")
+		return UserInformation{}, errInvalidAPIToken
+	}
+	if !apiToken.Expires.IsZero() && time.Now().After(apiToken.Expires) {
+		return UserInformation{}, errExpiredAPIToken
+	}
+	user, err := c.d.Database.GetUser(ctx, apiToken.User)
+	if err != nil {
+		c.r.Err(err).Str("user", apiToken.User).Msg("cannot get owner of API token")
+		return UserInformation{}, errInvalidAPIToken
+	}
+	info := UserInformation{
+		Login:    user.Login,
+		Name:     user.Name,
+		Email:    user.Email,
+		ReadOnly: apiToken.ReadOnly,
+	}
+	if user.Groups != "" {
+		info.Groups = strings.Split(user.Groups, ",")
+	}
+	return info, nil
+}
+
+// apiTokenCreateHandlerInput describes the input of the token creation
+// endpoint.
+type apiTokenCreateHandlerInput struct {
+	Name     string    `json:"name" validate:"required"`
+	ReadOnly bool      `json:"read-only"`
+	Expires  time.Time `json:"expires"`
+}
+
+// apiTokenCreateHandlerOutput describes the output of the token creation
+// endpoint. This is the only time the token is returned.
+type apiTokenCreateHandlerOutput struct {
+	database.APIToken
+	Token string `json:"token"`
+}
+
+// APITokensListHandlerFunc lists the API tokens of the current user.
+func (c *Component) APITokensListHandlerFunc(w http.ResponseWriter, req *http.Request) {
+	if c.d.Database == nil {
+		httpserver.WriteJSON(w, http.StatusNotFound, helpers.M{"message": "API tokens are not enabled."})
+		return
+	}
+	user := UserFromContext(req.Context()).Login
+	tokens, err := c.d.Database.ListAPITokens(req.Context(), user)
+	if err != nil {
+		c.r.Err(err).Msg("unable to list API tokens")
+		httpserver.WriteJSON(w, http.StatusInternalServerError, helpers.M{"message": "Unable to list API tokens."})
+		return
+	}
+	httpserver.WriteJSON(w, http.StatusOK, helpers.M{"tokens": tokens})
+}
+
+// APITokenCreateHandlerFunc creates a new API token for the current user.
+func (c *Component) APITokenCreateHandlerFunc(w http.ResponseWriter, req *http.Request) {
+	if c.d.Database == nil {
+		httpserver.WriteJSON(w, http.StatusNotFound, helpers.M{"message": "API tokens are not enabled."})
+		return
+	}
+	user := UserFromContext(req.Context())
+	if c.config.DefaultUser.Login != "" && user.Login == c.config.DefaultUser.Login {
+		httpserver.WriteJSON(w, http.StatusForbidden,
+			helpers.M{"message": "API tokens are not available without authentication."})
+		return
+	}
+	var input apiTokenCreateHandlerInput
+	if err := httpserver.BindJSON(req, &input); err != nil {
+		httpserver.WriteJSON(w, http.StatusBadRequest, helpers.M{"message": helpers.Capitalize(err.Error())})
+		return
+	}
+	now := time.Now()
+	if !input.Expires.IsZero() && !input.Expires.After(now) {
+		httpserver.WriteJSON(w, http.StatusBadRequest, helpers.M{"message": "Expiration should be in the future."})
+		return
+	}
+
+	// The middleware added the groups from the configuration, they are
+	// resolved again when using the token.
+	identity := user
+	identity.Groups = slices.DeleteFunc(slices.Clone(user.Groups), func(group string) bool {
+		return slices.Contains(c.config.Groups[user.Login], group)
+	})
+	if err := c.d.Database.SetUser(req.Context(), userFromInformation(now, identity)); err != nil {
+		c.r.Err(err).Msg("cannot record API token owner")
+		httpserver.WriteJSON(w, http.StatusInternalServerError, helpers.M{"message": "Cannot create API token."})
+		return
+	}
+
+	random := make([]byte, 32)
+	rand.Read(random)
+	token := apiTokenPrefix + base64.RawURLEncoding.EncodeToString(random)
+	apiToken, err := c.d.Database.CreateAPIToken(req.Context(), database.APIToken{
+		User: user.Login,
+		Name: input.Name,
+		Hash: hashAPIToken(token),
+		// A token created with a read-only token is read-only too.
+		ReadOnly: input.ReadOnly || user.ReadOnly,
+		Created:  now.UTC().Truncate(time.Second),
+		Expires:  input.Expires.UTC(),
+	})
+	if err != nil {
+		c.r.Err(err).Msg("cannot create API token")
+		httpserver.WriteJSON(w, http.StatusInternalServerError, helpers.M{"message": "Cannot create API token."})
+		return
+	}
+	httpserver.WriteJSON(w, http.StatusOK, apiTokenCreateHandlerOutput{
+		APIToken: apiToken,
+		Token:    token,
+	})
+}
+
+// APITokenDeleteHandlerFunc revokes an API token of the current user.
+func (c *Component) APITokenDeleteHandlerFunc(w http.ResponseWriter, req *http.Request) {
+	if c.d.Database == nil {
+		httpserver.WriteJSON(w, http.StatusNotFound, helpers.M{"message": "API tokens are not enabled."})
+		return
+	}
+	user := UserFromContext(req.Context()).Login
+	id, err := strconv.ParseUint(req.PathValue("id"), 10, 64)
+	if err != nil {
+		httpserver.WriteJSON(w, http.StatusBadRequest, helpers.M{"message": "Bad ID format."})
+		return
+	}
+	if err := c.d.Database.DeleteAPIToken(req.Context(), database.APIToken{
+		ID:   id,
+		User: user,
+	}); errors.Is(err, database.ErrAPITokenNotFound) {
+		httpserver.WriteJSON(w, http.StatusNotFound, helpers.M{"message": "API token not found."})
+		return
+	} else if err != nil {
+		c.r.Err(err).Msg("cannot delete API token")
+		httpserver.WriteJSON(w, http.StatusInternalServerError, helpers.M{"message": "Cannot delete API token."})
+		return
+	}
+	httpserver.WriteJSON(w, http.StatusNoContent, nil)
+}
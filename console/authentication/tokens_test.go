@@ -0,0 +1,265 @@
+// SPDX-FileCopyrightText: 2026 Free Mobile
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package authentication
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"akvorado/common/helpers"
+	"akvorado/common/httpserver"
+	"akvorado/common/reporter"
+	"akvorado/console/database"
+)
+
+func TestAPITokens(t *testing.T) {
+	r := reporter.NewMock(t)
+	h := httpserver.NewMock(t, r)
+	db := database.NewMock(t, r, database.DefaultConfiguration())
+	config := DefaultConfiguration()
+	config.Groups = map[string][]string{"lucius": {"board"}}
+	c, err := New(r, config, Dependencies{Database: db})
+	if err != nil {
+		t.Fatalf("New() error:\n%+v", err)
+	}
+
+	endpoint := h.APIRouter.Group("/api/v0/console/user", c.UserAuthentication())
+	endpoint.GET("/info", c.UserInfoHandlerFunc)
+	endpoint.GET("/tokens", c.APITokensListHandlerFunc)
+	endpoint.POST("/tokens", c.APITokenCreateHandlerFunc, c.RequireWriteAccess())
+	endpoint.DELETE("/tokens/{id}", c.APITokenDeleteHandlerFunc, c.RequireWriteAccess())
+
+	// Existing tokens
+	created := time.Date(2025, 10, 19, 10, 0, 0, 0, time.UTC)
+	for _, token := range []database.APIToken{
+		{
+			User: "alfred", Name: "read-only", Hash: hashAPIToken("akv_alfred1"),
+			ReadOnly: true, Created: created,
+		}, {
+			User: "alfred", Name: "expired", Hash: hashAPIToken("akv_alfred2"),
+			Created: created, Expires: created.Add(time.Hour),
+		}, {
+			User: "bruce", Name: "automation", Hash: hashAPIToken("akv_bruce1"),
+			Created: created,
+		}, {
+			User: "selina", Name: "unknown owner", Hash: hashAPIToken("akv_selina1"),
+			Created: created,
+		},
+	} {
+		if _, err := db.CreateAPIToken(t.Context(), token); err != nil {
+			t.Fatalf("CreateAPIToken() error:\n%+v", err)
+		}
+	}
+	for _, user := range []database.User{
+		{Login: "alfred", Name: "Alfred Pennyworth", Groups: "butlers"},
+		{Login: "bruce"},
+	} {
+		if err := db.SetUser(t.Context(), user); err != nil {
+			t.Fatalf("SetUser() error:\n%+v", err)
+		}
+	}
+	header := func(kv ...string) http.Header {
+		headers := make(http.Header)
+		for i := 0; i < len(kv); i += 2 {
+			headers.Add(kv[i], kv[i+1])
+		}
+		return headers
+	}
+
+	helpers.TestHTTPEndpoints(t, h.LocalAddr(), helpers.HTTPEndpointCases{
+		{
+			Description: "user info, read-only token",
+			URL:         "/api/v0/console/user/info",
+			Header:      header("Authorization", "Bearer akv_alfred1"),
+			JSONOutput: helpers.M{
+				"login":     "alfred",
+				"name":      "Alfred Pennyworth",
+				"groups":    []string{"butlers"},
+				"read-only": true,
+			},
+		}, {
+			Description: "user info, token has precedence over headers",
+			URL:         "/api/v0/console/user/info",
+			Header:      header("Authorization", "Bearer akv_bruce1", "Remote-User", "alfred"),
+			JSONOutput:  helpers.M{"login": "bruce"},
+		}, {
+			Description: "user info, unknown token",
+			URL:         "/api/v0/console/user/info",
+			Header:      header("Authorization", "Bearer akv_joker"),
+			StatusCode:  401,
+			JSONOutput:  helpers.M{"message": "Invalid API token."},
+		}, {
+			Description: "user info, token without owner",
+			URL:         "/api/v0/console/user/info",
+			Header:      header("Authorization", "Bearer akv_selina1"),
+			StatusCode:  401,
+			JSONOutput:  helpers.M{"message": "Invalid API token."},
+		}, {
+			Description: "user info, expired token",
+			URL:         "/api/v0/console/user/info",
+			Header:      header("Authorization", "Bearer akv_alfred2"),
+			StatusCode:  401,
+			JSONOutput:  helpers.M{"message": "Expired API token."},
+		}, {
+			Description: "user info, foreign bearer token",
+			URL:         "/api/v0/console/user/info",
+			Header:      header("Authorization", "Bearer eyJhbGciOi", "Remote-User", "alfred"),
+			JSONOutput:  helpers.M{"login": "alfred"},
+		}, {
+			Description: "list tokens",
+			URL:         "/api/v0/console/user/tokens",
+			Header:      header("Remote-User", "alfred"),
+			JSONOutput: helpers.M{
+				"tokens": []helpers.M{
+					{
+						"id":        1,
+						"user":      "alfred",
+						"name":      "read-only",
+						"read-only": true,
+						"created":   "2025-10-19T10:00:00Z",
+					}, {
+						"id":        2,
+						"user":      "alfred",
+						"name":      "expired",
+						"read-only": false,
+						"created":   "2025-10-19T10:00:00Z",
+						"expires":   "2025-10-19T11:00:00Z",
+					},
+				},
+			},
+		}, {
+			Description: "create token with read-only token",
+			URL:         "/api/v0/console/user/tokens",
+			Header:      header("Authorization", "Bearer akv_alfred1"),
+			JSONInput:   helpers.M{"name": "new"},
+			StatusCode:  403,
+			JSONOutput:  helpers.M{"message": "Read-only access."},
+		}, {
+			Description: "create token for default user",
+			URL:         "/api/v0/console/user/tokens",
+			JSONInput:   helpers.M{"name": "new"},
+			StatusCode:  403,
+			JSONOutput:  helpers.M{"message": "API tokens are not available without authentication."},
+		}, {
+			Description: "create token without name",
+			URL:         "/api/v0/console/user/tokens",
+			Header:      header("Remote-User", "alfred"),
+			JSONInput:   helpers.M{"read-only": true},
+			StatusCode:  400,
+			JSONOutput: helpers.M{
+				"message": "Key: 'apiTokenCreateHandlerInput.Name' Error:Field validation for 'Name' failed on the 'required' tag",
+			},
+		}, {
+			Description: "create expired token",
+			URL:         "/api/v0/console/user/tokens",
+			Header:      header("Remote-User", "alfred"),
+			JSONInput:   helpers.M{"name": "new", "expires": "2020-01-01T00:00:00Z"},
+			StatusCode:  400,
+			JSONOutput:  helpers.M{"message": "Expiration should be in the future."},
+		}, {
+			Description: "delete token of another user",
+			Method:      "DELETE",
+			URL:         "/api/v0/console/user/tokens/3",
+			Header:      header("Remote-User", "alfred"),
+			StatusCode:  404,
+			JSONOutput:  helpers.M{"message": "API token not found."},
+		}, {
+			Description: "delete token with read-only token",
+			Method:      "DELETE",
+			URL:         "/api/v0/console/user/tokens/1",
+			Header:      header("Authorization", "Bearer akv_alfred1"),
+			StatusCode:  403,
+			JSONOutput:  helpers.M{"message": "Read-only access."},
+		}, {
+			Description: "delete token",
+			Method:      "DELETE",
+			URL:         "/api/v0/console/user/tokens/1",
+			Header:      header("Remote-User", "alfred"),
+			StatusCode:  204,
+			ContentType: "application/json; charset=utf-8",
+		}, {
+			Description: "user info, deleted token",
+			URL:         "/api/v0/console/user/info",
+			Header:      header("Authorization", "Bearer akv_alfred1"),
+			StatusCode:  401,
+			JSONOutput:  helpers.M{"message": "Invalid API token."},
+		},
+	})
+
+	// Create a new token and use it
+	req, _ := http.NewRequest("POST",
+		fmt.Sprintf("http://%s/api/v0/console/user/tokens", h.LocalAddr()),
+		strings.NewReader(`{"name": "new", "read-only": true}`))
+	req.Header.Add("Remote-User", "lucius")
+	req.Header.Add("Remote-Name", "Lucius Fox")
+	req.Header.Add("Remote-Groups", "wayne-enterprises")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("POST /api/v0/console/user/tokens error:\n%+v", err)
+	}
+	defer resp.Body.Close()
+	var got struct {
+		ID       uint64 `json:"id"`
+		Name     string `json:"name"`
+		ReadOnly bool   `json:"read-only"`
+		Token    string `json:"token"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&got); err != nil {
+		t.Fatalf("Decode() error:\n%+v", err)
+	}
+	if got.ID != 5 || got.Name != "new" || !got.ReadOnly || !strings.HasPrefix(got.Token, "akv_") {
+		t.Fatalf("POST /api/v0/console/user/tokens returned %+v", got)
+	}
+	helpers.TestHTTPEndpoints(t, h.LocalAddr(), helpers.HTTPEndpointCases{
+		{
+			Description: "user info, new token",
+			URL:         "/api/v0/console/user/info",
+			Header:      header("Authorization", "Bearer "+got.Token),
+			JSONOutput: helpers.M{
+				"login":     "lucius",
+				"name":      "Lucius Fox",
+				"groups":    []string{"wayne-enterprises", "board"},
+				"read-only": true,
+			},
+		}, {
+			Description: "user info, group removed by the proxy",
+			URL:         "/api/v0/console/user/info",
+			Header:      header("Remote-User", "lucius", "Remote-Name", "Lucius Fox"),
+			JSONOutput: helpers.M{
+				"login":  "lucius",
+				"name":   "Lucius Fox",
+				"groups": []string{"board"},
+			},
+		}, {
+			Description: "user info, new token after group removal",
+			URL:         "/api/v0/console/user/info",
+			Header:      header("Authorization", "Bearer "+got.Token),
+			JSONOutput: helpers.M{
+				"login":     "lucius",
+				"name":      "Lucius Fox",
+				"groups":    []string{"board"},
+				"read-only": true,
+			},
+		},
+	})
+
+	// Groups from the configuration are resolved when using the token
+	delete(c.config.Groups, "lucius")
+	helpers.TestHTTPEndpoints(t, h.LocalAddr(), helpers.HTTPEndpointCases{
+		{
+			Description: "user info, new token after configuration change",
+			URL:         "/api/v0/console/user/info",
+			Header:      header("Authorization", "Bearer "+got.Token),
+			JSONOutput: helpers.M{
+				"login":     "lucius",
+				"name":      "Lucius Fox",
+				"read-only": true,
+			},
+		},
+	})
+}
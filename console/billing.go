@@ -0,0 +1,303 @@
+// SPDX-FileCopyrightText: 2026 Free Mobile
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package console
+
+import (
+	"fmt"
+	"math"
+	"net/http"
+	"slices"
+	"time"
+
+	"akvorado/common/helpers"
+	"akvorado/common/httpserver"
+	sb "akvorado/common/sqlbuilder"
+	"akvorado/console/parquet"
+	"akvorado/console/query"
+)
+
+// billingInterval is the interval between two samples for billing. It is also
+// the resolution of the table used for billing.
+const billingInterval = 5 * time.Minute
+
+// billingEntity is a validated billing entity.
+type billingEntity struct {
+	name      string
+	filter    query.Filter
+	dimension *query.Column
+}
+
+// billingHandlerOutput describes the output of the /billing endpoint.
+type billingHandlerOutput struct {
+	Start   time.Time    `json:"start"`
+	End     time.Time    `json:"end"`
+	Samples int          `json:"samples"`
+	Rows    []billingRow `json:"rows"`
+}
+
+// billingRow is the report for one entity (and one value of its dimension).
+type billingRow struct {
+	Entity string         `json:"entity"`
+	Value  string         `json:"value,omitempty"`
+	In     billingTraffic `json:"in"`
+	Out    billingTraffic `json:"out"`
+}
+
+// billingTraffic is the billed traffic in one direction.
+type billingTraffic struct {
+	NinetyFivePercentile int `json:"95th"`   // bps
+	Max                  int `json:"max"`    // bps
+	Volume               int `json:"volume"` // bytes
+}
+
+// newBillingEntities validates the billing entities from the configuration.
+func (c *Component) newBillingEntities() error {
+	c.billingEntities = make([]billingEntity, 0, len(c.config.BillingEntities))
+	for idx, config := range c.config.BillingEntities {
+		if slices.ContainsFunc(c.config.BillingEntities[:idx], func(other BillingEntityConfiguration) bool {
+			return other.Name == config.Name
+		}) {
+			return fmt.Errorf("duplicate billing entity %q", config.Name)
+		}
+		entity := billingEntity{name: config.Name, filter: query.NewFilter(config.Filter)}
+		if err := entity.filter.ValidateWithDefinitions(c.d.Schema, c.d.ClickHouseDB.DatabaseName(),
+			c.config.FilterDefinitions); err != nil {
+			return fmt.Errorf("invalid filter for billing entity %q: %w", config.Name, err)
+		}
+		if config.Dimension != "" {
+			column := query.NewColumn(config.Dimension)
+			if err := column.Validate(c.d.Schema); err != nil {
+				return fmt.Errorf("invalid dimension for billing entity %q: %w", config.Name, err)
+			}
+			entity.dimension = &column
+		}
+		c.billingEntities = append(c.billingEntities, entity)
+	}
+	return nil
+}
+
+// billingPeriod returns the start and the end of the provided month (in UTC)
+// and the number of samples in this period. For the current month, the period
+// stops at the last complete sample.
+func billingPeriod(month string, now time.Time) (time.Time, time.Time, int, error) {
+	start, err := time.Parse("2006-01", month)
+	if err != nil {
+		return time.Time{}, time.Time{}, 0, fmt.Errorf("invalid month %q", month)
+	}
+	end := start.AddDate(0, 1, 0)
+	if now := now.UTC().Truncate(billingInterval); now.Before(end) {
+		end = now
+	}
+	samples := int(end.Sub(start) / billingInterval)
+	if samples <= 0 {
+		return time.Time{}, time.Time{}, 0, fmt.Errorf("month %q has not started yet", month)
+	}
+	return start, end, samples, nil
+}
+
+// nearestRankPercentile is an aggregate expression computing a percentile of
+// the provided samples with the nearest-rank method. Up to the expected number
+// of samples, missing samples count as zero.
+func nearestRankPercentile(sample sb.Expr, samples int, percentile float64) sb.Expr {
+	rank := uint64(max(math.Ceil(percentile*float64(samples)), 1))
+	padded := sb.Function("arrayConcat",
+		sb.Function("groupArray", sample),
+		sb.Function("arrayWithConstant",
+			sb.Op(sb.Uint(uint64(samples)), "-", sb.Function("count")),
+			sb.Uint(0)))
+	return sb.Index(sb.Function("arraySort", padded), sb.Uint(rank))
+}
+
+// billingSQL builds the query to compute the billing report. The 95th
+// percentile uses the nearest-rank method on all the samples of the period:
+// missing samples count as zero.
+func (c *Component) billingSQL(table string, start, end time.Time, samples int, where sb.Expr) string {
+	var source *sb.Query
+	for _, entity := range c.billingEntities {
+		for _, direction := range []string{"in", "out"} {
+			filter := entity.filter.Direct()
+			value := sb.String("")
+			if direction == "out" {
+				filter = entity.filter.Reverse()
+			}
+			if entity.dimension != nil {
+				column := *entity.dimension
+				if direction == "out" {
+					column.Reverse(c.d.Schema)
+				}
+				value = sb.Function("toString", column.ToSQLSelect(c.d.Schema, c.d.ClickHouseDB.DatabaseName()))
+			}
+			q := sb.Select(
+				sb.Alias(sb.String(entity.name), "entity"),
+				sb.Alias(value, "value"),
+				sb.Alias(sb.String(direction), "direction"),
+				sb.Alias(sb.Function("SUM", sb.Op(sb.Column("Bytes"), "*", sb.Column("SamplingRate"))), "bytes"),
+				sb.Alias(sb.Op(sb.Op(sb.Column("bytes"), "*", sb.Uint(8)), "/",
+					sb.Uint(uint64(billingInterval.Seconds()))), "bps"),
+			).
+				From(sb.Table(table)).
+				Where(sb.And(
+					sb.Op(sb.Column("TimeReceived"), ">=", dateTime(start)),
+					sb.Op(sb.Column("TimeReceived"), "<", dateTime(end)),
+					filter,
+					where,
+				)).
+				GroupBy(sb.Column("TimeReceived"), sb.Column("value"))
+			if source == nil {
+				source = q
+			} else {
+				source.UnionAll(q)
+			}
+		}
+	}
+	return sb.Select(
+		sb.Column("entity"),
+		sb.Column("value"),
+		sb.Column("direction"),
+		sb.Alias(nearestRankPercentile(sb.Column("bps"), samples, 0.95), "p95"),
+		sb.Alias(sb.Function("max", sb.Column("bps")), "max"),
+		sb.Alias(sb.Function("sum", sb.Column("bytes")), "volume"),
+	).
+		FromSelect(source).
+		GroupBy(sb.Column("entity"), sb.Column("value"), sb.Column("direction")).
+		OrderBy(
+			sb.Order(sb.Column("entity")),
+			sb.Order(sb.Column("value")),
+			sb.Order(sb.Column("direction")),
+		).
+		String()
+}
+
+// billingOutput computes the billing report for the requested month. On
+// error, it writes the response and returns false.
+func (c *Component) billingOutput(w http.ResponseWriter, req *http.Request) (billingHandlerOutput, bool) {
+	ctx := c.t.Context(req.Context())
+	if len(c.billingEntities) == 0 {
+		httpserver.WriteJSON(w, http.StatusNotFound, helpers.M{"message": "No billing entity configured."})
+		return billingHandlerOutput{}, false
+	}
+	start, end, samples, err := billingPeriod(req.PathValue("month"), c.d.Clock.Now())
+	if err != nil {
+		httpserver.WriteJSON(w, http.StatusBadRequest, helpers.M{"message": helpers.Capitalize(err.Error()) + "."})
+		return billingHandlerOutput{}, false
+	}
+	c.flowsTablesLock.RLock()
+	idx := slices.IndexFunc(c.flowsTables, func(table flowsTable) bool {
+		return table.Resolution == billingInterval
+	})
+	var table string
+	if idx >= 0 {
+		table = c.flowsTables[idx].Name
+	}
+	c.flowsTablesLock.RUnlock()
+	if table == "" {
+		httpserver.WriteJSON(w, http.StatusServiceUnavailable,
+			helpers.M{"message": "No table with a 5-minute resolution."})
+		return billingHandlerOutput{}, false
+	}
+
+	sqlQuery := c.billingSQL(table, start, end, samples, c.accessWhere(req.Context()))
+	w.Header().Set("X-SQL-Query", sqlQuery)
+	results := []struct {
+		Entity    string  `ch:"entity"`
+		Value     string  `ch:"value"`
+		Direction string  `ch:"direction"`
+		P95       float64 `ch:"p95"`
+		Max       float64 `ch:"max"`
+		Volume    uint64  `ch:"volume"`
+	}{}
+	ctx, done, ok := c.startQuery(ctx, w, table, sqlQuery)
+	if !ok {
+		return billingHandlerOutput{}, false
+	}
+	defer done()
+	if err := c.d.ClickHouseDB.Conn.Select(ctx, &results, sqlQuery); err != nil {
+		c.writeQueryError(ctx, w, err, sqlQuery)
+		return billingHandlerOutput{}, false
+	}
+	auditRows(ctx, len(results))
+
+	// Merge directions. Entities without dimension are always present, even
+	// without traffic. The order of the configuration is kept.
+	output := billingHandlerOutput{Start: start, End: end, Samples: samples, Rows: []billingRow{}}
+	for _, entity := range c.billingEntities {
+		rows := map[string]*billingRow{}
+		values := []string{}
+		if entity.dimension == nil {
+			rows[""] = &billingRow{Entity: entity.name}
+			values = append(values, "")
+		}
+		for _, result := range results {
+			if result.Entity != entity.name {
+				continue
+			}
+			row, ok := rows[result.Value]
+			if !ok {
+				row = &billingRow{Entity: entity.name, Value: result.Value}
+				rows[result.Value] = row
+				values = append(values, result.Value)
+			}
+			traffic := billingTraffic{
+				NinetyFivePercentile: int(result.P95),
+				Max:                  int(result.Max),
+				Volume:               int(result.Volume),
+			}
+			if result.Direction == "in" {
+				row.In = traffic
+			} else {
+				row.Out = traffic
+			}
+		}
+		for _, value := range values {
+			output.Rows = append(output.Rows, *rows[value])
+		}
+	}
+	return output, true
+}
+
+func (c *Component) billingHandlerFunc(w http.ResponseWriter, req *http.Request) {
+	output, ok := c.billingOutput(w, req)
+	if !ok {
+		return
+	}
+	httpserver.WriteJSON(w, http.StatusOK, output)
+}
+
+func (c *Component) billingExportHandlerFunc(w http.ResponseWriter, req *http.Request) {
+	format, mode, ok := exportParameters(w, req, "rows")
+	if !ok {
+		return
+	}
+	output, ok := c.billingOutput(w, req)
+	if !ok {
+		return
+	}
+	columns := []parquet.Column{
+		{Name: "entity", Type: parquet.String},
+		{Name: "value", Type: parquet.String},
+	}
+	for _, direction := range []string{"in", "out"} {
+		for _, name := range []string{"95th", "max", "volume"} {
+			columns = append(columns, parquet.Column{Name: direction + "-" + name, Type: parquet.Int64})
+		}
+	}
+	ew := startExport(w, "billing", mode, format, output.Start, columns)
+	var err error
+	for _, row := range output.Rows {
+		err = ew.Write([]any{
+			row.Entity, row.Value,
+			row.In.NinetyFivePercentile, row.In.Max, row.In.Volume,
+			row.Out.NinetyFivePercentile, row.Out.Max, row.Out.Volume,
+		})
+		if err != nil {
+			break
+		}
+	}
+	if err == nil {
+		err = ew.Close()
+	}
+	if err != nil {
+		c.r.Err(err).Msg("unable to export billing report")
+	}
+}
@@ -0,0 +1,247 @@
+// SPDX-FileCopyrightText: 2026 Free Mobile
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package console
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"go.uber.org/mock/gomock"
+
+	"akvorado/common/helpers"
+	sb "akvorado/common/sqlbuilder"
+)
+
+func TestBillingPeriod(t *testing.T) {
+	now := time.Date(2026, 10, 19, 10, 7, 12, 0, time.UTC)
+	cases := []struct {
+		Month         string
+		ExpectedStart time.Time
+		ExpectedEnd   time.Time
+		Samples       int
+		Error         bool
+	}{
+		{
+			Month:         "2026-09",
+			ExpectedStart: time.Date(2026, 9, 1, 0, 0, 0, 0, time.UTC),
+			ExpectedEnd:   time.Date(2026, 10, 1, 0, 0, 0, 0, time.UTC),
+			Samples:       30 * 288,
+		}, {
+			Month:         "2026-02",
+			ExpectedStart: time.Date(2026, 2, 1, 0, 0, 0, 0, time.UTC),
+			ExpectedEnd:   time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC),
+			Samples:       28 * 288,
+		}, {
+			Month:         "2026-10",
+			ExpectedStart: time.Date(2026, 10, 1, 0, 0, 0, 0, time.UTC),
+			ExpectedEnd:   time.Date(2026, 10, 19, 10, 5, 0, 0, time.UTC),
+			Samples:       18*288 + 10*12 + 1,
+		},
+		{Month: "2026-11", Error: true},
+		{Month: "october", Error: true},
+	}
+	for _, tc := range cases {
+		t.Run(tc.Month, func(t *testing.T) {
+			start, end, samples, err := billingPeriod(tc.Month, now)
+			if err != nil && tc.Error {
+				return
+			} else if err != nil {
+				t.Fatalf("billingPeriod() error:\n%+v", err)
+			} else if tc.Error {
+				t.Fatal("billingPeriod() did not error")
+			}
+			if diff := helpers.Diff([]any{start, end, samples},
+				[]any{tc.ExpectedStart, tc.ExpectedEnd, tc.Samples}); diff != "" {
+				t.Fatalf("billingPeriod() (-got, +want):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestBillingSQL(t *testing.T) {
+	config := DefaultConfiguration()
+	config.BillingEntities = []BillingEntityConfiguration{
+		{Name: "transit", Dimension: "InIfProvider", Filter: "InIfConnectivity = 'transit'"},
+		{Name: "customer1", Filter: "InIfName = 'et-0/0/1'"},
+	}
+	c, _, _, _ := NewMock(t, config)
+	got := c.billingSQL("flows_5m0s",
+		time.Date(2026, 9, 1, 0, 0, 0, 0, time.UTC),
+		time.Date(2026, 10, 1, 0, 0, 0, 0, time.UTC),
+		8640, sb.Expr{})
+	expected := `
+SELECT entity, value, direction,
+ arraySort(arrayConcat(groupArray(bps), arrayWithConstant(8640 - count(), 0)))[8208] AS p95,
+ max(bps) AS max, sum(bytes) AS volume
+FROM (
+ SELECT 'transit' AS entity, toString(InIfProvider) AS value, 'in' AS direction,
+  SUM(Bytes*SamplingRate) AS bytes, bytes*8/300 AS bps
+ FROM flows_5m0s
+ WHERE TimeReceived >= toDateTime('2026-09-01 00:00:00', 'UTC')
+ AND TimeReceived < toDateTime('2026-10-01 00:00:00', 'UTC')
+ AND InIfConnectivity = 'transit'
+ GROUP BY TimeReceived, value
+ UNION ALL
+ SELECT 'transit' AS entity, toString(OutIfProvider) AS value, 'out' AS direction,
+  SUM(Bytes*SamplingRate) AS bytes, bytes*8/300 AS bps
+ FROM flows_5m0s
+ WHERE TimeReceived >= toDateTime('2026-09-01 00:00:00', 'UTC')
+ AND TimeReceived < toDateTime('2026-10-01 00:00:00', 'UTC')
+ AND OutIfConnectivity = 'transit'
+ GROUP BY TimeReceived, value
+ UNION ALL
+ SELECT 'customer1' AS entity, '' AS value, 'in' AS direction,
+  SUM(Bytes*SamplingRate) AS bytes, bytes*8/300 AS bps
+ FROM flows_5m0s
+ WHERE TimeReceived >= toDateTime('2026-09-01 00:00:00', 'UTC')
+ AND TimeReceived < toDateTime('2026-10-01 00:00:00', 'UTC')
+ AND InIfName = 'et-0/0/1'
+ GROUP BY TimeReceived, value
+ UNION ALL
+ SELECT 'customer1' AS entity, '' AS value, 'out' AS direction,
+  SUM(Bytes*SamplingRate) AS bytes, bytes*8/300 AS bps
+ FROM flows_5m0s
+ WHERE TimeReceived >= toDateTime('2026-09-01 00:00:00', 'UTC')
+ AND TimeReceived < toDateTime('2026-10-01 00:00:00', 'UTC')
+ AND OutIfName = 'et-0/0/1'
+ GROUP BY TimeReceived, value
+)
+GROUP BY entity, value, direction
+ORDER BY entity, value, direction`
+	if diff := helpers.Diff(sb.Normalize(t, got), sb.Normalize(t, expected)); diff != "" {
+		t.Fatalf("billingSQL() (-got, +want):\n%s", diff)
+	}
+}
+
+func TestBillingHandler(t *testing.T) {
+	config := DefaultConfiguration()
+	config.BillingEntities = []BillingEntityConfiguration{
+		{Name: "transit", Dimension: "InIfProvider", Filter: "InIfConnectivity = 'transit'"},
+		{Name: "customer1", Filter: "InIfName = 'et-0/0/1'"},
+	}
+	c, h, mockConn, mockClock := NewMock(t, config)
+	mockClock.Set(time.Date(2026, 10, 19, 10, 0, 0, 0, time.UTC))
+
+	helpers.TestHTTPEndpoints(t, h.LocalAddr(), helpers.HTTPEndpointCases{
+		{
+			Description: "no 5-minute table",
+			URL:         "/api/v0/console/billing/2026-09",
+			StatusCode:  503,
+			JSONOutput:  helpers.M{"message": "No table with a 5-minute resolution."},
+		},
+	})
+
+	c.flowsTablesLock.Lock()
+	c.flowsTables = []flowsTable{
+		{"flows", 0, time.Date(2026, 9, 15, 0, 0, 0, 0, time.UTC)},
+		{"flows_5m0s", 5 * time.Minute, time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)},
+	}
+	c.flowsTablesLock.Unlock()
+	results := []struct {
+		Entity    string  `ch:"entity"`
+		Value     string  `ch:"value"`
+		Direction string  `ch:"direction"`
+		P95       float64 `ch:"p95"`
+		Max       float64 `ch:"max"`
+		Volume    uint64  `ch:"volume"`
+	}{
+		{"transit", "cogent", "in", 8_000_000_000, 9_500_000_000, 2_000_000_000_000_000},
+		{"transit", "cogent", "out", 1_000_000_000, 1_500_000_000, 300_000_000_000_000},
+		{"transit", "telia", "in", 4_000_000_000, 6_000_000_000, 1_000_000_000_000_000},
+		{"customer1", "", "out", 100_000_000, 200_000_000, 30_000_000_000_000},
+	}
+	mockConn.EXPECT().
+		Select(gomock.Any(), gomock.Any(), gomock.Any()).
+		SetArg(1, results).
+		Return(nil).
+		Times(2)
+
+	helpers.TestHTTPEndpoints(t, h.LocalAddr(), helpers.HTTPEndpointCases{
+		{
+			Description: "report",
+			URL:         "/api/v0/console/billing/2026-09",
+			JSONOutput: helpers.M{
+				"start":   "2026-09-01T00:00:00Z",
+				"end":     "2026-10-01T00:00:00Z",
+				"samples": 8640,
+				"rows": []helpers.M{
+					{
+						"entity": "transit",
+						"value":  "cogent",
+						"in":     helpers.M{"95th": 8_000_000_000, "max": 9_500_000_000, "volume": 2_000_000_000_000_000},
+						"out":    helpers.M{"95th": 1_000_000_000, "max": 1_500_000_000, "volume": 300_000_000_000_000},
+					}, {
+						"entity": "transit",
+						"value":  "telia",
+						"in":     helpers.M{"95th": 4_000_000_000, "max": 6_000_000_000, "volume": 1_000_000_000_000_000},
+						"out":    helpers.M{"95th": 0, "max": 0, "volume": 0},
+					}, {
+						"entity": "customer1",
+						"in":     helpers.M{"95th": 0, "max": 0, "volume": 0},
+						"out":    helpers.M{"95th": 100_000_000, "max": 200_000_000, "volume": 30_000_000_000_000},
+					},
+				},
+			},
+		}, {
+			Description: "export",
+			URL:         "/api/v0/console/billing/2026-09/export",
+			ContentType: "text/csv; charset=utf-8",
+			FirstLines: []string{
+				"entity,value,in-95th,in-max,in-volume,out-95th,out-max,out-volume",
+				"transit,cogent,8000000000,9500000000,2000000000000000,1000000000,1500000000,300000000000000",
+				"transit,telia,4000000000,6000000000,1000000000000000,0,0,0",
+				"customer1,,0,0,0,100000000,200000000,30000000000000",
+			},
+		}, {
+			Description: "invalid month",
+			URL:         "/api/v0/console/billing/september",
+			StatusCode:  400,
+			JSONOutput:  helpers.M{"message": `Invalid month "september".`},
+		}, {
+			Description: "future month",
+			URL:         "/api/v0/console/billing/2026-11",
+			StatusCode:  400,
+			JSONOutput:  helpers.M{"message": `Month "2026-11" has not started yet.`},
+		},
+	})
+}
+
+func TestBillingEntitiesConfiguration(t *testing.T) {
+	cases := []struct {
+		Description string
+		Entities    []BillingEntityConfiguration
+		Error       string
+	}{
+		{
+			Description: "invalid filter",
+			Entities:    []BillingEntityConfiguration{{Name: "transit", Filter: "InIfProvider =="}},
+			Error:       `invalid filter for billing entity "transit"`,
+		}, {
+			Description: "invalid dimension",
+			Entities:    []BillingEntityConfiguration{{Name: "transit", Dimension: "Unknown"}},
+			Error:       `invalid dimension for billing entity "transit"`,
+		}, {
+			Description: "duplicate",
+			Entities: []BillingEntityConfiguration{
+				{Name: "transit", Dimension: "InIfProvider"},
+				{Name: "transit", Filter: "InIfBoundary = external"},
+			},
+			Error: `duplicate billing entity "transit"`,
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.Description, func(t *testing.T) {
+			c, _, _, _ := NewMock(t, DefaultConfiguration())
+			c.config.BillingEntities = tc.Entities
+			err := c.newBillingEntities()
+			if err == nil {
+				t.Fatal("newBillingEntities() did not error")
+			}
+			if !strings.HasPrefix(err.Error(), tc.Error) {
+				t.Fatalf("newBillingEntities() error:\n%s\nexpected prefix:\n%s", err, tc.Error)
+			}
+		})
+	}
+}
@@ -4,13 +4,21 @@
 package console
 
 import (
+	"context"
 	"errors"
 	"fmt"
+	"math"
+	"net/http"
 	"sort"
 	"strings"
 	"time"
 
+	"github.com/ClickHouse/clickhouse-go/v2"
+
+	"akvorado/common/helpers"
+	"akvorado/common/httpserver"
 	sb "akvorado/common/sqlbuilder"
+	"akvorado/console/authentication"
 	"akvorado/console/query"
 )
 
@@ -248,6 +256,11 @@ func (r resolution) forRange(start, end time.Time) resolved {
 	}
 }
 
+// points returns the number of intervals in the resolved range.
+func (r resolved) points() int {
+	return int(r.End.Sub(r.Start) / (time.Duration(r.Interval) * time.Second))
+}
+
 // shiftBack moves a resolved range back in time. Both ends move by the same
 // amount, so the range keeps its length and therefore its number of points.
 // This is how the previous period is drawn on the time axis of the main one.
@@ -333,3 +346,222 @@ func (c *Component) getBestTable(start time.Time, targetInterval time.Duration)
 	}
 	return table, computedInterval
 }
+
+// queryUser is the state kept for each user to enforce the query limits.
+type queryUser struct {
+	running    int
+	tokens     float64
+	lastRefill time.Time
+}
+
+// refill adds the tokens earned since the last refill to the bucket of the
+// user.
+func (u *queryUser) refill(now time.Time, rate, burst float64) {
+	if u.lastRefill.IsZero() {
+		u.tokens = burst
+	} else {
+		u.tokens = min(burst, u.tokens+now.Sub(u.lastRefill).Seconds()*rate)
+	}
+	u.lastRefill = now
+}
+
+// idle tells if the user has no running query and a full bucket. In this case,
+// its state can be forgotten.
+func (u *queryUser) idle(now time.Time, rate, burst float64) bool {
+	return u.running == 0 &&
+		(rate == 0 || u.lastRefill.IsZero() || u.tokens+now.Sub(u.lastRefill).Seconds()*rate >= burst)
+}
+
+// queryBurst returns the size of the bucket of each user.
+func (c *Component) queryBurst() float64 {
+	limits := c.config.QueryLimits
+	if limits.Burst == 0 {
+		return math.Ceil(limits.Rate)
+	}
+	return float64(limits.Burst)
+}
+
+// querySettings returns the ClickHouse settings applied to the queries run on
+// behalf of users.
+func (c *Component) querySettings() clickhouse.Settings {
+	settings := clickhouse.Settings{}
+	limits := c.config.QueryLimits
+	if limits.MaxExecutionTime > 0 {
+		settings["max_execution_time"] = int(math.Ceil(limits.MaxExecutionTime.Seconds()))
+	}
+	if limits.MaxRowsToRead > 0 {
+		settings["max_rows_to_read"] = limits.MaxRowsToRead
+	}
+	if limits.MaxMemoryUsage > 0 {
+		settings["max_memory_usage"] = limits.MaxMemoryUsage
+	}
+	return settings
+}
+
+// acquireQuery checks the rate and the concurrency limits of a user. It
+// returns the reason when the query is rejected. Otherwise, the query is
+// accounted as running until releaseQuery is called.
+func (c *Component) acquireQuery(user string) (string, bool) {
+	limits := c.config.QueryLimits
+	burst := c.queryBurst()
+	now := c.d.Clock.Now()
+	c.queryUsersLock.Lock()
+	defer c.queryUsersLock.Unlock()
+	u, ok := c.queryUsers[user]
+	if !ok {
+		// Users come and go, forget the idle ones before adding a new one.
+		for login, other := range c.queryUsers {
+			if other.idle(now, limits.Rate, burst) {
+				delete(c.queryUsers, login)
+			}
+		}
+		u = &queryUser{}
+		c.queryUsers[user] = u
+	}
+	if limits.MaxConcurrentQueries > 0 && u.running >= limits.MaxConcurrentQueries {
+		return "concurrency", false
+	}
+	if limits.Rate > 0 {
+		u.refill(now, limits.Rate, burst)
+		if u.tokens < 1 {
+			return "rate", false
+		}
+		u.tokens--
+	}
+	u.running++
+	return "", true
+}
+
+// releaseQuery releases a query acquired with acquireQuery. The state of the
+// user is forgotten once idle.
+func (c *Component) releaseQuery(user string) {
+	limits := c.config.QueryLimits
+	now := c.d.Clock.Now()
+	c.queryUsersLock.Lock()
+	defer c.queryUsersLock.Unlock()
+	u, ok := c.queryUsers[user]
+	if !ok {
+		return
+	}
+	u.running--
+	if u.idle(now, limits.Rate, c.queryBurst()) {
+		delete(c.queryUsers, user)
+	}
+}
+
+// estimateQuery returns the number of rows ClickHouse expects to read to
+// execute the provided query.
+func (c *Component) estimateQuery(ctx context.Context, sqlQuery string) (uint64, error) {
+	var estimates []struct {
+		Rows uint64 `ch:"rows"`
+	}
+	if err := c.d.ClickHouseDB.Conn.Select(ctx, &estimates, "EXPLAIN ESTIMATE "+sqlQuery); err != nil {
+		return 0, err
+	}
+	var total uint64
+	for _, estimate := range estimates {
+		total += estimate.Rows
+	}
+	return total, nil
+}
+
+// queryRejection explains why a query cannot be run.
+type queryRejection struct {
+	status     int
+	message    string
+	retryAfter bool
+}
+
+// prepareQuery checks a query can be run on behalf of the current user. On
+// success, it returns a context carrying the ClickHouse settings for the query
+// and a function to call once the query is done. When a handler runs several
+// queries, each of them is provided and the estimates are added.
+func (c *Component) prepareQuery(ctx context.Context, table string, sqlQueries ...string) (context.Context, func(), *queryRejection) {
+	user := authentication.UserFromContext(ctx).Login
+	reason, ok := c.acquireQuery(user)
+	if !ok {
+		c.metrics.rejectedQueries.WithLabelValues(user, reason).Inc()
+		if reason == "concurrency" {
+			return nil, nil, &queryRejection{
+				status:  http.StatusTooManyRequests,
+				message: "Too many queries running at the same time, please retry later.",
+			}
+		}
+		return nil, nil, &queryRejection{
+			status:     http.StatusTooManyRequests,
+			message:    "Too many queries, please retry later.",
+			retryAfter: true,
+		}
+	}
+	release := func() { c.releaseQuery(user) }
+
+	if limit := c.config.QueryLimits.MaxEstimatedRows; limit > 0 {
+		var estimate uint64
+		for _, sqlQuery := range sqlQueries {
+			rows, err := c.estimateQuery(ctx, sqlQuery)
+			if err != nil {
+				// Do not block the query, it will be limited by ClickHouse anyway.
+				c.r.Err(err).Str("query", sqlQuery).Msg("unable to estimate query")
+				continue
+			}
+			estimate += rows
+		}
+		if estimate > limit {
+			release()
+			c.metrics.rejectedQueries.WithLabelValues(user, "cost").Inc()
+			return nil, nil, &queryRejection{
+				status: http.StatusBadRequest,
+				message: fmt.Sprintf(
+					"Query is too expensive (about %d rows to read, maximum is %d), reduce the time range or add filters.",
+					estimate, limit),
+			}
+		}
+	}
+
+	c.metrics.clickhouseQueries.WithLabelValues(table).Inc()
+	c.metrics.userQueries.WithLabelValues(user).Inc()
+	return clickhouse.Context(ctx, clickhouse.WithSettings(c.querySettings())), release, nil
+}
+
+// startQuery is like prepareQuery but, when the query cannot be run, it writes
+// the error to the client and returns false.
+func (c *Component) startQuery(ctx context.Context, w http.ResponseWriter, table string, sqlQueries ...string) (context.Context, func(), bool) {
+	ctx, done, rejection := c.prepareQuery(ctx, table, sqlQueries...)
+	if rejection != nil {
+		rejection.write(w)
+		return nil, nil, false
+	}
+	return ctx, done, true
+}
+
+// write writes the rejection to the client.
+func (rejection *queryRejection) write(w http.ResponseWriter) {
+	if rejection.retryAfter {
+		w.Header().Set("Retry-After", "1")
+	}
+	httpserver.WriteJSON(w, rejection.status, helpers.M{"message": rejection.message})
+}
+
+// queryLimitMessages are the messages returned when ClickHouse aborts a query
+// because of one of the configured limits, keyed by the exception code.
+var queryLimitMessages = map[int32]string{
+	158: "Query reads too many rows, reduce the time range or add filters.",
+	159: "Query takes too long, reduce the time range or add filters.",
+	241: "Query uses too much memory, reduce the time range, the number of dimensions, or add filters.",
+}
+
+// writeQueryError writes the error of a failed query to the client. Queries
+// aborted because of the configured limits get a clear message.
+func (c *Component) writeQueryError(ctx context.Context, w http.ResponseWriter, err error, sqlQuery string) {
+	var exception *clickhouse.Exception
+	if errors.As(err, &exception) {
+		if message, ok := queryLimitMessages[exception.Code]; ok {
+			user := authentication.UserFromContext(ctx).Login
+			c.metrics.rejectedQueries.WithLabelValues(user, "limit").Inc()
+			httpserver.WriteJSON(w, http.StatusBadRequest, helpers.M{"message": message})
+			return
+		}
+	}
+	c.r.Err(err).Str("query", sqlQuery).Msg("unable to query database")
+	httpserver.WriteJSON(w, http.StatusInternalServerError, helpers.M{"message": "Unable to query database."})
+}
@@ -4,11 +4,15 @@
 package console
 
 import (
+	"maps"
+	"slices"
+	"strings"
 	"testing"
 	"time"
 
 	"akvorado/common/helpers"
 
+	"github.com/ClickHouse/clickhouse-go/v2"
 	"go.uber.org/mock/gomock"
 )
 
@@ -474,3 +478,134 @@ func TestComputeBestTableAndInterval(t *testing.T) {
 		})
 	}
 }
+
+func TestQuerySettings(t *testing.T) {
+	config := DefaultConfiguration()
+	c, _, _, _ := NewMock(t, config)
+	if diff := helpers.Diff(c.querySettings(), clickhouse.Settings{}); diff != "" {
+		t.Errorf("querySettings() (-got, +want):\n%s", diff)
+	}
+
+	config.QueryLimits = QueryLimitsConfiguration{
+		MaxExecutionTime: 90 * time.Second,
+		MaxRowsToRead:    1_000_000_000,
+		MaxMemoryUsage:   10_000_000_000,
+	}
+	c, _, _, _ = NewMock(t, config)
+	expected := clickhouse.Settings{
+		"max_execution_time": 90,
+		"max_rows_to_read":   uint64(1_000_000_000),
+		"max_memory_usage":   uint64(10_000_000_000),
+	}
+	if diff := helpers.Diff(c.querySettings(), expected); diff != "" {
+		t.Errorf("querySettings() (-got, +want):\n%s", diff)
+	}
+}
+
+func TestAcquireQuery(t *testing.T) {
+	config := DefaultConfiguration()
+	config.QueryLimits.MaxConcurrentQueries = 2
+	config.QueryLimits.Rate = 0.5
+	config.QueryLimits.Burst = 3
+	c, _, _, mockClock := NewMock(t, config)
+	mockClock.Set(time.Date(2026, 10, 19, 10, 0, 0, 0, time.UTC))
+
+	expect := func(user, expectedReason string) {
+		t.Helper()
+		reason, _ := c.acquireQuery(user)
+		if reason != expectedReason {
+			t.Fatalf("acquireQuery(%q) reason: got %q, want %q", user, reason, expectedReason)
+		}
+	}
+	expect("alfred", "")
+	expect("alfred", "")
+	expect("alfred", "concurrency")
+	expect("bob", "") // other users are not impacted
+	c.releaseQuery("alfred")
+	expect("alfred", "") // last token of the burst
+	c.releaseQuery("alfred")
+	expect("alfred", "rate")
+	mockClock.Add(2 * time.Second)
+	expect("alfred", "")
+	c.releaseQuery("alfred")
+	c.releaseQuery("alfred")
+	expect("alfred", "rate")
+
+	// Idle users are forgotten
+	users := func() []string {
+		c.queryUsersLock.Lock()
+		defer c.queryUsersLock.Unlock()
+		return slices.Sorted(maps.Keys(c.queryUsers))
+	}
+	if diff := helpers.Diff(users(), []string{"alfred", "bob"}); diff != "" {
+		t.Fatalf("queryUsers (-got, +want):\n%s", diff)
+	}
+	mockClock.Add(10 * time.Second)
+	c.releaseQuery("bob")
+	if diff := helpers.Diff(users(), []string{"alfred"}); diff != "" {
+		t.Fatalf("queryUsers after release (-got, +want):\n%s", diff)
+	}
+	expect("charlie", "")
+	if diff := helpers.Diff(users(), []string{"charlie"}); diff != "" {
+		t.Fatalf("queryUsers after new user (-got, +want):\n%s", diff)
+	}
+	c.releaseQuery("unknown")
+}
+
+func TestQueryGuardrails(t *testing.T) {
+	config := DefaultConfiguration()
+	config.QueryLimits.MaxEstimatedRows = 1_000_000
+	_, h, mockConn, _ := NewMock(t, config)
+
+	// First query: estimate is too high
+	mockConn.EXPECT().
+		Select(gomock.Any(), gomock.Any(), gomock.Cond(func(sql string) bool {
+			return strings.HasPrefix(sql, "EXPLAIN ESTIMATE ")
+		})).
+		SetArg(1, []struct {
+			Rows uint64 `ch:"rows"`
+		}{{800_000}, {400_000}}).
+		Return(nil)
+	// Second query: estimate is OK, but ClickHouse aborts the query
+	mockConn.EXPECT().
+		Select(gomock.Any(), gomock.Any(), gomock.Cond(func(sql string) bool {
+			return strings.HasPrefix(sql, "EXPLAIN ESTIMATE ")
+		})).
+		SetArg(1, []struct {
+			Rows uint64 `ch:"rows"`
+		}{{800_000}}).
+		Return(nil)
+	mockConn.EXPECT().
+		Select(gomock.Any(), gomock.Any(), gomock.Cond(func(sql string) bool {
+			return !strings.HasPrefix(sql, "EXPLAIN ESTIMATE ")
+		})).
+		Return(&clickhouse.Exception{Code: 159, Name: "TIMEOUT_EXCEEDED"})
+
+	input := helpers.M{
+		"start":      time.Date(2022, 4, 10, 15, 45, 10, 0, time.UTC),
+		"end":        time.Date(2022, 4, 11, 15, 45, 10, 0, time.UTC),
+		"points":     100,
+		"limit":      20,
+		"dimensions": []string{"SrcAddr"},
+		"units":      "l3bps",
+	}
+	helpers.TestHTTPEndpoints(t, h.LocalAddr(), helpers.HTTPEndpointCases{
+		{
+			Description: "query too expensive",
+			URL:         "/api/v0/console/graph/line",
+			JSONInput:   input,
+			StatusCode:  400,
+			JSONOutput: helpers.M{
+				"message": "Query is too expensive (about 1200000 rows to read, maximum is 1000000), reduce the time range or add filters.",
+			},
+		}, {
+			Description: "query too long",
+			URL:         "/api/v0/console/graph/sankey",
+			JSONInput:   input,
+			StatusCode:  400,
+			JSONOutput: helpers.M{
+				"message": "Query takes too long, reduce the time range or add filters.",
+			},
+		},
+	})
+}
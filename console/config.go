@@ -10,6 +10,8 @@ import (
 
 	"akvorado/common/helpers"
 	"akvorado/common/httpserver"
+	"akvorado/common/schema"
+	"akvorado/console/filter"
 	"akvorado/console/query"
 )
 
@@ -33,10 +35,90 @@ type Configuration struct {
 	HomepageGraphTimeRange time.Duration `validate:"min=1m"`
 	// DimensionsLimit put an upper limit to the number of dimensions to return.
 	DimensionsLimit int `validate:"min=10"`
+	// FlowsTimeRangeLimit put an upper limit to the time range scanned when
+	// browsing individual flows.
+	FlowsTimeRangeLimit time.Duration `validate:"min=1m"`
 	// Branding enables some branding on the console
 	Branding bool
 	// CacheTTL tells how long to keep the most costly requests in cache.
 	CacheTTL time.Duration `validate:"min=5s"`
+	// QueryLimits restricts the queries run on behalf of each user.
+	QueryLimits QueryLimitsConfiguration
+	// AccessPolicies restricts the flows a user can see. The first policy
+	// matching the user applies. When policies are configured and none
+	// matches, the user is denied access.
+	AccessPolicies []AccessPolicyConfiguration
+	// BillingEntities defines the entities billed on the 95th percentile.
+	BillingEntities []BillingEntityConfiguration `validate:"dive"`
+	// PeeringReport tells how interfaces are classified for the peering
+	// opportunity report.
+	PeeringReport PeeringReportConfiguration
+	// FilterDefinitions defines the named sets and macros every user can
+	// reference in filters. Users can add their own.
+	FilterDefinitions filter.Definitions
+	// Networks tells if the orchestrator manages a networks dictionary to
+	// group address dimensions by their most specific matching network. This
+	// is set by the orchestrator.
+	Networks bool
+	// Anomalies tells if the orchestrator detects anomalies. This is set by
+	// the orchestrator.
+	Anomalies bool
+}
+
+// AccessPolicyConfiguration restricts the flows seen by some users.
+type AccessPolicyConfiguration struct {
+	// Users is the list of logins this policy applies to.
+	Users []string
+	// Groups is the list of groups this policy applies to.
+	Groups []string
+	// Filter is the filter added to all the queries of the matching users.
+	// When empty, the users are not restricted.
+	Filter string
+}
+
+// BillingEntityConfiguration defines an entity billed on the 95th percentile of
+// its traffic, like a transit provider or a customer.
+type BillingEntityConfiguration struct {
+	// Name is the name of the entity in reports.
+	Name string `validate:"required"`
+	// Filter selects the inbound traffic of the entity. The outbound traffic
+	// is selected with the reverse filter.
+	Filter string `validate:"required_without=Dimension"`
+	// Dimension splits the entity with one report line for each value of
+	// this column (for example, InIfProvider). The outbound traffic uses the
+	// reverse column.
+	Dimension string
+}
+
+// PeeringReportConfiguration tells how interfaces are classified for the
+// peering opportunity report, using their connectivity.
+type PeeringReportConfiguration struct {
+	// Transit lists the connectivity of transit interfaces.
+	Transit []string `validate:"min=1"`
+	// Peering lists the connectivity of private and public peering interfaces.
+	Peering []string `validate:"min=1"`
+}
+
+// QueryLimitsConfiguration restricts the queries run on behalf of each user. A
+// zero value disables the matching limit.
+type QueryLimitsConfiguration struct {
+	// MaxExecutionTime is the maximum execution time of a query.
+	MaxExecutionTime time.Duration `validate:"min=0"`
+	// MaxRowsToRead is the maximum number of rows a query can read.
+	MaxRowsToRead uint64
+	// MaxMemoryUsage is the maximum memory usage of a query, in bytes.
+	MaxMemoryUsage uint64
+	// MaxEstimatedRows rejects a query before running it when ClickHouse
+	// estimates it would read more rows.
+	MaxEstimatedRows uint64
+	// MaxConcurrentQueries is the maximum number of queries a user can run
+	// at the same time.
+	MaxConcurrentQueries int `validate:"min=0"`
+	// Rate is the number of queries per second a user can run.
+	Rate float64 `validate:"min=0"`
+	// Burst is the number of queries a user can run at once above the rate.
+	// It defaults to the rate rounded up.
+	Burst int `validate:"min=0"`
 }
 
 // HomepageTopWidget represents a top widget on the homepage.
@@ -106,9 +188,14 @@ func DefaultConfiguration() Configuration {
 			HomepageTopWidgetEtype,
 		},
 		DimensionsLimit:        50,
+		FlowsTimeRangeLimit:    time.Hour,
 		CacheTTL:               3 * time.Hour,
 		HomepageGraphFilter:    "InIfBoundary = 'external'",
 		HomepageGraphTimeRange: 24 * time.Hour,
+		PeeringReport: PeeringReportConfiguration{
+			Transit: []string{"transit"},
+			Peering: []string{"pni", "ppni", "ix"},
+		},
 	}
 }
 
@@ -139,12 +226,22 @@ func (c *Component) configHandlerFunc(w http.ResponseWriter, _ *http.Request) {
 			truncatable = append(truncatable, column.Name)
 		}
 	}
+	deduplication := false
+	if column, ok := c.d.Schema.LookupColumnByKey(schema.ColumnDuplicate); ok && !column.Disabled {
+		deduplication = true
+	}
+	if c.config.Networks {
+		for _, name := range truncatable {
+			dimensions = append(dimensions, name+"Network")
+		}
+	}
 	httpserver.WriteJSON(w, http.StatusOK, helpers.M{
 		"version":                 helpers.AkvoradoVersion,
 		"defaultVisualizeOptions": c.config.DefaultVisualizeOptions,
 		"dimensionsLimit":         c.config.DimensionsLimit,
 		"dimensions":              dimensions,
 		"truncatable":             truncatable,
+		"deduplication":           deduplication,
 		"homepageTopWidgets":      c.config.HomepageTopWidgets,
 		"branding":                c.config.Branding,
 	})
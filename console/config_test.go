@@ -140,8 +140,9 @@ func TestConfigHandler(t *testing.T) {
 					"ForwardingStatus",
 					"FlowDirection",
 				},
-				"truncatable": []string{"SrcAddr", "DstAddr"},
-				"branding":    false,
+				"truncatable":   []string{"SrcAddr", "DstAddr"},
+				"deduplication": false,
+				"branding":      false,
 			},
 		},
 	})
@@ -0,0 +1,270 @@
+// SPDX-FileCopyrightText: 2026 Free Mobile
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package console
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"akvorado/common/helpers"
+	"akvorado/common/httpserver"
+	"akvorado/console/authentication"
+	"akvorado/console/database"
+	"akvorado/console/filter"
+)
+
+// dashboardPanel is a panel of a dashboard. The input is the input of the
+// /graph/line or the /graph/sankey endpoint, without the start and the end
+// which are computed from the relative time range.
+type dashboardPanel struct {
+	Title     string          `json:"title"`
+	GraphType string          `json:"graphType" validate:"oneof=stacked stacked100 lines grid sankey"`
+	Range     string          `json:"range" validate:"required"` // relative time range (like 6h)
+	Input     json.RawMessage `json:"input" validate:"required"`
+}
+
+// dashboardHandlerInput describes the input for the creation or the update
+// of a dashboard.
+type dashboardHandlerInput struct {
+	Name   string           `json:"name" validate:"required"`
+	Shared bool             `json:"shared"`
+	Panels []dashboardPanel `json:"panels" validate:"dive"`
+}
+
+// dashboardHandlerOutput describes a dashboard with its panels.
+type dashboardHandlerOutput struct {
+	database.Dashboard
+	Panels []dashboardPanel `json:"panels"`
+}
+
+// resolve returns the input of the panel with the start and the end computed
+// from the provided time.
+func (panel dashboardPanel) resolve(now time.Time) (json.RawMessage, error) {
+	timeRange, err := time.ParseDuration(panel.Range)
+	if err != nil {
+		return nil, fmt.Errorf("invalid range %q: %w", panel.Range, err)
+	}
+	if timeRange <= 0 {
+		return nil, fmt.Errorf("invalid range %q: should be positive", panel.Range)
+	}
+	var input map[string]any
+	if err := json.Unmarshal(panel.Input, &input); err != nil {
+		return nil, fmt.Errorf("invalid input: %w", err)
+	}
+	input["start"] = now.Add(-timeRange).UTC()
+	input["end"] = now.UTC()
+	return json.Marshal(input)
+}
+
+// validateDashboardPanel checks a panel is valid by resolving it and
+// validating it as the matching graph handler would do. The filter can
+// reference the provided named sets and macros.
+func (c *Component) validateDashboardPanel(panel dashboardPanel, definitions filter.Definitions) error {
+	if err := helpers.Validate.Struct(panel); err != nil {
+		return err
+	}
+	resolved, err := panel.resolve(c.d.Clock.Now())
+	if err != nil {
+		return err
+	}
+	common := graphCommonHandlerInput{
+		schema:      c.d.Schema,
+		database:    c.d.ClickHouseDB.DatabaseName(),
+		definitions: definitions,
+	}
+	var input any
+	if panel.GraphType == "sankey" {
+		input = &graphSankeyHandlerInput{graphCommonHandlerInput: common}
+	} else {
+		input = &graphLineHandlerInput{graphCommonHandlerInput: common}
+	}
+	if err := json.Unmarshal(resolved, input); err != nil {
+		return fmt.Errorf("invalid input: %w", err)
+	}
+	if err := helpers.Validate.Struct(input); err != nil {
+		return err
+	}
+	switch input := input.(type) {
+	case *graphSankeyHandlerInput:
+		common = input.graphCommonHandlerInput
+		if len(common.Dimensions) == 0 {
+			return errors.New("at least one dimension is required")
+		}
+	case *graphLineHandlerInput:
+		common = input.graphCommonHandlerInput
+	}
+	if err := common.validateDimensions(); err != nil {
+		return err
+	}
+	if err := common.Filter.ValidateWithDefinitions(common.schema, common.database, common.definitions); err != nil {
+		return err
+	}
+	if err := common.applyDeduplication(); err != nil {
+		return err
+	}
+	if common.Limit > c.config.DimensionsLimit {
+		return fmt.Errorf("limit is set beyond maximum value (%d)", c.config.DimensionsLimit)
+	}
+	return nil
+}
+
+// validateBuiltinDashboards checks the builtin dashboards of the database
+// component are valid.
+func (c *Component) validateBuiltinDashboards() error {
+	for _, dashboard := range c.d.Database.BuiltinDashboards() {
+		encoded, err := json.Marshal(dashboard.Panels)
+		if err != nil {
+			return fmt.Errorf("cannot encode panels of dashboard %q: %w", dashboard.Name, err)
+		}
+		var panels []dashboardPanel
+		if err := json.Unmarshal(encoded, &panels); err != nil {
+			return fmt.Errorf("cannot decode panels of dashboard %q: %w", dashboard.Name, err)
+		}
+		for idx, panel := range panels {
+			if err := c.validateDashboardPanel(panel, c.config.FilterDefinitions); err != nil {
+				return fmt.Errorf("invalid panel %d of dashboard %q: %w", idx+1, dashboard.Name, err)
+			}
+		}
+	}
+	return nil
+}
+
+// bindDashboard decodes and validates a dashboard from a request.
+func (c *Component) bindDashboard(w http.ResponseWriter, req *http.Request) (database.Dashboard, bool) {
+	var input dashboardHandlerInput
+	if err := httpserver.BindJSON(req, &input); err != nil {
+		httpserver.WriteJSON(w, http.StatusBadRequest, helpers.M{"message": helpers.Capitalize(err.Error())})
+		return database.Dashboard{}, false
+	}
+	for idx, panel := range input.Panels {
+		if err := c.validateDashboardPanel(panel, c.filterDefinitions(req.Context())); err != nil {
+			httpserver.WriteJSON(w, http.StatusBadRequest,
+				helpers.M{"message": fmt.Sprintf("Invalid panel %d: %s", idx+1, err)})
+			return database.Dashboard{}, false
+		}
+	}
+	if input.Panels == nil {
+		input.Panels = []dashboardPanel{}
+	}
+	panels, _ := json.Marshal(input.Panels)
+	return database.Dashboard{
+		User:   authentication.UserFromContext(req.Context()).Login,
+		Shared: input.Shared,
+		Name:   input.Name,
+		Panels: string(panels),
+	}, true
+}
+
+func (c *Component) dashboardsListHandlerFunc(w http.ResponseWriter, req *http.Request) {
+	ctx := c.t.Context(req.Context())
+	user := authentication.UserFromContext(req.Context()).Login
+	dashboards, err := c.d.Database.ListDashboards(ctx, user)
+	if err != nil {
+		c.r.Err(err).Msg("unable to list dashboards")
+		httpserver.WriteJSON(w, http.StatusInternalServerError, helpers.M{"message": "Unable to list dashboards."})
+		return
+	}
+	httpserver.WriteJSON(w, http.StatusOK, helpers.M{"dashboards": dashboards})
+}
+
+func (c *Component) dashboardGetHandlerFunc(w http.ResponseWriter, req *http.Request) {
+	ctx := c.t.Context(req.Context())
+	user := authentication.UserFromContext(req.Context()).Login
+	id, err := strconv.ParseUint(req.PathValue("id"), 10, 64)
+	if err != nil {
+		httpserver.WriteJSON(w, http.StatusBadRequest, helpers.M{"message": "Bad ID format."})
+		return
+	}
+	dashboard, err := c.d.Database.GetDashboard(ctx, id, user)
+	if errors.Is(err, database.ErrDashboardNotFound) {
+		httpserver.WriteJSON(w, http.StatusNotFound, helpers.M{"message": "Dashboard not found."})
+		return
+	} else if err != nil {
+		c.r.Err(err).Msg("unable to get dashboard")
+		httpserver.WriteJSON(w, http.StatusInternalServerError, helpers.M{"message": "Unable to get dashboard."})
+		return
+	}
+	output := dashboardHandlerOutput{Dashboard: dashboard}
+	if err := json.Unmarshal([]byte(dashboard.Panels), &output.Panels); err != nil {
+		c.r.Err(err).Msgf("cannot decode panels of dashboard %d", dashboard.ID)
+		httpserver.WriteJSON(w, http.StatusInternalServerError, helpers.M{"message": "Unable to get dashboard."})
+		return
+	}
+	// Resolve the time range of each panel.
+	now := c.d.Clock.Now().Truncate(time.Minute)
+	for idx := range output.Panels {
+		resolved, err := output.Panels[idx].resolve(now)
+		if err != nil {
+			c.r.Err(err).Msgf("cannot resolve panel %d of dashboard %d", idx+1, dashboard.ID)
+			httpserver.WriteJSON(w, http.StatusInternalServerError, helpers.M{"message": "Unable to get dashboard."})
+			return
+		}
+		output.Panels[idx].Input = resolved
+	}
+	httpserver.WriteJSON(w, http.StatusOK, output)
+}
+
+func (c *Component) dashboardAddHandlerFunc(w http.ResponseWriter, req *http.Request) {
+	ctx := c.t.Context(req.Context())
+	dashboard, ok := c.bindDashboard(w, req)
+	if !ok {
+		return
+	}
+	dashboard, err := c.d.Database.CreateDashboard(ctx, dashboard)
+	if err != nil {
+		c.r.Err(err).Msg("cannot create dashboard")
+		httpserver.WriteJSON(w, http.StatusInternalServerError, helpers.M{"message": "Cannot create dashboard."})
+		return
+	}
+	httpserver.WriteJSON(w, http.StatusOK, helpers.M{"id": dashboard.ID})
+}
+
+func (c *Component) dashboardUpdateHandlerFunc(w http.ResponseWriter, req *http.Request) {
+	ctx := c.t.Context(req.Context())
+	id, err := strconv.ParseUint(req.PathValue("id"), 10, 64)
+	if err != nil {
+		httpserver.WriteJSON(w, http.StatusBadRequest, helpers.M{"message": "Bad ID format."})
+		return
+	}
+	dashboard, ok := c.bindDashboard(w, req)
+	if !ok {
+		return
+	}
+	dashboard.ID = id
+	if err := c.d.Database.UpdateDashboard(ctx, dashboard); errors.Is(err, database.ErrDashboardNotFound) {
+		httpserver.WriteJSON(w, http.StatusNotFound, helpers.M{"message": "Dashboard not found."})
+		return
+	} else if err != nil {
+		c.r.Err(err).Msg("cannot update dashboard")
+		httpserver.WriteJSON(w, http.StatusInternalServerError, helpers.M{"message": "Cannot update dashboard."})
+		return
+	}
+	httpserver.WriteJSON(w, http.StatusNoContent, nil)
+}
+
+func (c *Component) dashboardDeleteHandlerFunc(w http.ResponseWriter, req *http.Request) {
+	ctx := c.t.Context(req.Context())
+	user := authentication.UserFromContext(req.Context()).Login
+	id, err := strconv.ParseUint(req.PathValue("id"), 10, 64)
+	if err != nil {
+		httpserver.WriteJSON(w, http.StatusBadRequest, helpers.M{"message": "Bad ID format."})
+		return
+	}
+	if err := c.d.Database.DeleteDashboard(ctx, database.Dashboard{
+		ID:   id,
+		User: user,
+	}); errors.Is(err, database.ErrDashboardNotFound) {
+		httpserver.WriteJSON(w, http.StatusNotFound, helpers.M{"message": "Dashboard not found."})
+		return
+	} else if err != nil {
+		c.r.Err(err).Msg("cannot delete dashboard")
+		httpserver.WriteJSON(w, http.StatusInternalServerError, helpers.M{"message": "Cannot delete dashboard."})
+		return
+	}
+	httpserver.WriteJSON(w, http.StatusNoContent, nil)
+}
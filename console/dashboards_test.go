@@ -0,0 +1,228 @@
+// SPDX-FileCopyrightText: 2026 Free Mobile
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package console
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"akvorado/common/clickhousedb"
+	"akvorado/common/daemon"
+	"akvorado/common/helpers"
+	"akvorado/common/httpserver"
+	"akvorado/common/reporter"
+	"akvorado/common/schema"
+	"akvorado/console/authentication"
+	"akvorado/console/database"
+)
+
+func TestDashboardHandlers(t *testing.T) {
+	_, h, _, mockClock := NewMock(t, DefaultConfiguration())
+	mockClock.Set(time.Date(2026, 10, 19, 10, 17, 32, 0, time.UTC))
+
+	alfred := make(http.Header)
+	alfred.Add("Remote-User", "alfred")
+	lineInput := helpers.M{
+		"dimensions": []string{"SrcAS"},
+		"limit":      10,
+		"filter":     "InIfBoundary = external",
+		"units":      "l3bps",
+		"points":     200,
+	}
+	helpers.TestHTTPEndpoints(t, h.LocalAddr(), helpers.HTTPEndpointCases{
+		{
+			Description: "list, no dashboards",
+			URL:         "/api/v0/console/dashboards",
+			JSONOutput:  helpers.M{"dashboards": []helpers.M{}},
+		}, {
+			Description: "create dashboard",
+			URL:         "/api/v0/console/dashboards",
+			JSONInput: helpers.M{
+				"name": "Peering",
+				"panels": []helpers.M{
+					{
+						"title":     "Top AS",
+						"graphType": "stacked",
+						"range":     "6h",
+						"input":     lineInput,
+					}, {
+						"title":     "Exporters to AS",
+						"graphType": "sankey",
+						"range":     "24h",
+						"input": helpers.M{
+							"dimensions": []string{"ExporterName", "DstAS"},
+							"limit":      10,
+							"units":      "l3bps",
+						},
+					},
+				},
+			},
+			JSONOutput: helpers.M{"id": 1},
+		}, {
+			Description: "create dashboard with an invalid range",
+			URL:         "/api/v0/console/dashboards",
+			JSONInput: helpers.M{
+				"name": "Invalid",
+				"panels": []helpers.M{
+					{"graphType": "stacked", "range": "6 hours", "input": lineInput},
+				},
+			},
+			StatusCode: 400,
+			JSONOutput: helpers.M{
+				"message": `Invalid panel 1: invalid range "6 hours": time: unknown unit " hours" in duration "6 hours"`,
+			},
+		}, {
+			Description: "create dashboard with an invalid filter",
+			URL:         "/api/v0/console/dashboards",
+			JSONInput: helpers.M{
+				"name": "Invalid",
+				"panels": []helpers.M{
+					{"graphType": "lines", "range": "1h", "input": helpers.M{
+						"dimensions": []string{"SrcAS"},
+						"limit":      10,
+						"filter":     "NoColumn = 1",
+						"units":      "l3bps",
+						"points":     200,
+					}},
+				},
+			},
+			StatusCode: 400,
+			JSONOutput: helpers.M{
+				"message": "Invalid panel 1: cannot parse filter: at line 1, position 9: no match found, expected: [A-Za-z0-9] or [A-Za-z_]",
+			},
+		}, {
+			Description: "list dashboards",
+			URL:         "/api/v0/console/dashboards",
+			JSONOutput: helpers.M{"dashboards": []helpers.M{
+				{"id": 1, "user": "__default", "shared": false, "name": "Peering"},
+			}},
+		}, {
+			Description: "list dashboards as another user",
+			URL:         "/api/v0/console/dashboards",
+			Header:      alfred,
+			JSONOutput:  helpers.M{"dashboards": []helpers.M{}},
+		}, {
+			Description: "get dashboard",
+			URL:         "/api/v0/console/dashboards/1",
+			JSONOutput: helpers.M{
+				"id":     1,
+				"user":   "__default",
+				"shared": false,
+				"name":   "Peering",
+				"panels": []helpers.M{
+					{
+						"title":     "Top AS",
+						"graphType": "stacked",
+						"range":     "6h",
+						"input": helpers.M{
+							"start":      "2026-10-19T04:17:00Z",
+							"end":        "2026-10-19T10:17:00Z",
+							"dimensions": []string{"SrcAS"},
+							"limit":      10,
+							"filter":     "InIfBoundary = external",
+							"units":      "l3bps",
+							"points":     200,
+						},
+					}, {
+						"title":     "Exporters to AS",
+						"graphType": "sankey",
+						"range":     "24h",
+						"input": helpers.M{
+							"start":      "2026-10-18T10:17:00Z",
+							"end":        "2026-10-19T10:17:00Z",
+							"dimensions": []string{"ExporterName", "DstAS"},
+							"limit":      10,
+							"units":      "l3bps",
+						},
+					},
+				},
+			},
+		}, {
+			Description: "get dashboard as another user",
+			URL:         "/api/v0/console/dashboards/1",
+			Header:      alfred,
+			StatusCode:  404,
+			JSONOutput:  helpers.M{"message": "Dashboard not found."},
+		}, {
+			Description: "update dashboard as another user",
+			Method:      "PUT",
+			URL:         "/api/v0/console/dashboards/1",
+			Header:      alfred,
+			JSONInput:   helpers.M{"name": "Peering", "shared": true},
+			StatusCode:  404,
+			JSONOutput:  helpers.M{"message": "Dashboard not found."},
+		}, {
+			Description: "share dashboard",
+			Method:      "PUT",
+			URL:         "/api/v0/console/dashboards/1",
+			JSONInput:   helpers.M{"name": "Peering (shared)", "shared": true},
+			StatusCode:  204,
+			ContentType: "application/json; charset=utf-8",
+		}, {
+			Description: "get shared dashboard as another user",
+			URL:         "/api/v0/console/dashboards/1",
+			Header:      alfred,
+			JSONOutput: helpers.M{
+				"id":     1,
+				"user":   "__default",
+				"shared": true,
+				"name":   "Peering (shared)",
+				"panels": []helpers.M{},
+			},
+		}, {
+			Description: "delete dashboard as another user",
+			Method:      "DELETE",
+			URL:         "/api/v0/console/dashboards/1",
+			Header:      alfred,
+			StatusCode:  404,
+			JSONOutput:  helpers.M{"message": "Dashboard not found."},
+		}, {
+			Description: "delete dashboard with invalid ID",
+			Method:      "DELETE",
+			URL:         "/api/v0/console/dashboards/kjgdfhgh",
+			StatusCode:  400,
+			JSONOutput:  helpers.M{"message": "Bad ID format."},
+		}, {
+			Description: "delete dashboard",
+			Method:      "DELETE",
+			URL:         "/api/v0/console/dashboards/1",
+			StatusCode:  204,
+			ContentType: "application/json; charset=utf-8",
+		}, {
+			Description: "list dashboards after delete",
+			URL:         "/api/v0/console/dashboards",
+			JSONOutput:  helpers.M{"dashboards": []helpers.M{}},
+		},
+	})
+}
+
+func TestInvalidBuiltinDashboard(t *testing.T) {
+	r := reporter.NewMock(t)
+	ch, _ := clickhousedb.NewMock(t, r)
+	dbConfig := database.DefaultConfiguration()
+	dbConfig.Dashboards = []database.BuiltinDashboard{
+		{
+			Name: "Invalid",
+			Panels: []map[string]any{
+				{
+					"graphType": "sankey",
+					"range":     "1h",
+					"input":     map[string]any{"limit": 10, "units": "l3bps"},
+				},
+			},
+		},
+	}
+	_, err := New(r, DefaultConfiguration(), Dependencies{
+		Daemon:       daemon.NewMock(t),
+		HTTP:         httpserver.NewMock(t, r),
+		ClickHouseDB: ch,
+		Auth:         authentication.NewMock(t, r),
+		Database:     database.NewMock(t, r, dbConfig),
+		Schema:       schema.NewMock(t),
+	})
+	if err == nil {
+		t.Fatal("New() did not error")
+	}
+}
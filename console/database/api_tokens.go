@@ -0,0 +1,90 @@
+// SPDX-FileCopyrightText: 2026 Free Mobile
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/uptrace/bun"
+)
+
+// APIToken represents an API token in database. Only a hash of the token is
+// stored. The identity of its owner is in the users table.
+type APIToken struct {
+	bun.BaseModel `bun:"table:api_tokens" json:"-"`
+
+	ID       uint64    `bun:",pk,autoincrement" json:"id"`
+	User     string    `json:"user"`
+	Name     string    `json:"name" validate:"required"`
+	Hash     string    `bun:",unique" json:"-"`
+	ReadOnly bool      `json:"read-only"`
+	Created  time.Time `json:"created"`
+	Expires  time.Time `bun:",nullzero" json:"expires,omitzero"`
+}
+
+// ErrAPITokenNotFound is returned when an API token does not exist.
+var ErrAPITokenNotFound = errors.New("API token not found")
+
+// CreateAPIToken creates a new API token in database. It returns the token
+// with its ID.
+func (c *Component) CreateAPIToken(ctx context.Context, t APIToken) (APIToken, error) {
+	t.ID = 0
+	if _, err := c.db.NewInsert().Model(&t).Exec(ctx); err != nil {
+		return APIToken{}, fmt.Errorf("unable to create new API token: %w", err)
+	}
+	return t, nil
+}
+
+// ListAPITokens lists all API tokens for the provided user.
+func (c *Component) ListAPITokens(ctx context.Context, user string) ([]APIToken, error) {
+	results := []APIToken{}
+	if err := c.db.NewSelect().
+		Model(&results).
+		Where("? = ?", bun.Ident("user"), user).
+		Order("id").
+		Scan(ctx); err != nil {
+		return nil, fmt.Errorf("unable to retrieve API tokens: %w", err)
+	}
+	return results, nil
+}
+
+// LookupAPIToken returns the API token matching the provided hash.
+func (c *Component) LookupAPIToken(ctx context.Context, hash string) (APIToken, error) {
+	var result APIToken
+	err := c.db.NewSelect().
+		Model(&result).
+		Where("? = ?", bun.Ident("hash"), hash).
+		Limit(1).
+		Scan(ctx)
+	if errors.Is(err, sql.ErrNoRows) {
+		return APIToken{}, ErrAPITokenNotFound
+	} else if err != nil {
+		return APIToken{}, fmt.Errorf("unable to lookup API token: %w", err)
+	}
+	return result, nil
+}
+
+// DeleteAPIToken deletes the API token matching t.ID and t.User.
+func (c *Component) DeleteAPIToken(ctx context.Context, t APIToken) error {
+	res, err := c.db.NewDelete().
+		Model((*APIToken)(nil)).
+		Where("? = ?", bun.Ident("id"), t.ID).
+		Where("? = ?", bun.Ident("user"), t.User).
+		Exec(ctx)
+	if err != nil {
+		return fmt.Errorf("cannot delete API token: %w", err)
+	}
+	rows, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("cannot delete API token: %w", err)
+	}
+	if rows == 0 {
+		return ErrAPITokenNotFound
+	}
+	return nil
+}
@@ -0,0 +1,83 @@
+// SPDX-FileCopyrightText: 2026 Free Mobile
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package database
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"akvorado/common/helpers"
+	"akvorado/common/reporter"
+)
+
+func TestAPITokens(t *testing.T) {
+	r := reporter.NewMock(t)
+	c := NewMock(t, r, DefaultConfiguration())
+	created := time.Date(2026, 10, 19, 10, 0, 0, 0, time.UTC)
+
+	// Create
+	token1, err := c.CreateAPIToken(t.Context(), APIToken{
+		ID:       17,
+		User:     "marty",
+		Name:     "automation",
+		Hash:     "hash1",
+		ReadOnly: true,
+		Created:  created,
+	})
+	if err != nil {
+		t.Fatalf("CreateAPIToken() error:\n%+v", err)
+	}
+	if token1.ID != 1 {
+		t.Fatalf("CreateAPIToken() ID = %d, expected 1", token1.ID)
+	}
+	if _, err := c.CreateAPIToken(t.Context(), APIToken{
+		User:    "judith",
+		Name:    "grafana",
+		Hash:    "hash2",
+		Created: created,
+		Expires: created.Add(24 * time.Hour),
+	}); err != nil {
+		t.Fatalf("CreateAPIToken() error:\n%+v", err)
+	}
+	if _, err := c.CreateAPIToken(t.Context(), APIToken{
+		User: "marty",
+		Name: "duplicate",
+		Hash: "hash1",
+	}); err == nil {
+		t.Fatal("CreateAPIToken() did not error on duplicate hash")
+	}
+
+	// List
+	got, err := c.ListAPITokens(t.Context(), "marty")
+	if err != nil {
+		t.Fatalf("ListAPITokens() error:\n%+v", err)
+	}
+	if diff := helpers.Diff(got, []APIToken{token1}); diff != "" {
+		t.Fatalf("ListAPITokens() (-got, +want):\n%s", diff)
+	}
+
+	// Lookup
+	token2, err := c.LookupAPIToken(t.Context(), "hash2")
+	if err != nil {
+		t.Fatalf("LookupAPIToken() error:\n%+v", err)
+	}
+	if token2.User != "judith" || !token2.Expires.Equal(created.Add(24*time.Hour)) {
+		t.Fatalf("LookupAPIToken() returned %+v", token2)
+	}
+	if _, err := c.LookupAPIToken(t.Context(), "hash3"); !errors.Is(err, ErrAPITokenNotFound) {
+		t.Fatalf("LookupAPIToken() error:\n%+v", err)
+	}
+
+	// Delete
+	if err := c.DeleteAPIToken(t.Context(), APIToken{ID: token2.ID, User: "marty"}); !errors.Is(err, ErrAPITokenNotFound) {
+		t.Fatalf("DeleteAPIToken() error:\n%+v", err)
+	}
+	if err := c.DeleteAPIToken(t.Context(), APIToken{ID: token2.ID, User: "judith"}); err != nil {
+		t.Fatalf("DeleteAPIToken() error:\n%+v", err)
+	}
+	if _, err := c.LookupAPIToken(t.Context(), "hash2"); !errors.Is(err, ErrAPITokenNotFound) {
+		t.Fatalf("LookupAPIToken() error:\n%+v", err)
+	}
+}
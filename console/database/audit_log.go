@@ -0,0 +1,102 @@
+// SPDX-FileCopyrightText: 2026 Free Mobile
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package database
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/uptrace/bun"
+)
+
+// AuditLogEntry represents a console query recorded in the audit log.
+type AuditLogEntry struct {
+	bun.BaseModel `bun:"table:audit_log" json:"-"`
+
+	ID         uint64    `bun:",pk,autoincrement" json:"id"`
+	Time       time.Time `json:"time"`
+	User       string    `json:"user"`
+	Endpoint   string    `json:"endpoint"`
+	Filter     string    `bun:"type:text" json:"filter"`
+	Dimensions string    `bun:"type:text" json:"dimensions"`
+	Start      time.Time `json:"start"`
+	End        time.Time `json:"end"`
+	Duration   int64     `json:"duration"` // in milliseconds
+	Rows       int       `json:"rows"`
+	Status     int       `json:"status"`
+	Cached     bool      `json:"cached"`
+}
+
+// AuditLogQuery restricts the entries returned by SearchAuditLog. Empty
+// fields are ignored.
+type AuditLogQuery struct {
+	User     string
+	Endpoint string
+	Filter   string // substring of the filter
+	Since    time.Time
+	Until    time.Time
+	Limit    int
+}
+
+// AddAuditLogEntry records a new entry in the audit log.
+func (c *Component) AddAuditLogEntry(ctx context.Context, entry AuditLogEntry) error {
+	entry.ID = 0
+	if _, err := c.db.NewInsert().Model(&entry).Exec(ctx); err != nil {
+		return fmt.Errorf("unable to record audit log entry: %w", err)
+	}
+	return nil
+}
+
+// likeEscaper escapes the wildcards of a LIKE pattern.
+var likeEscaper = strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`)
+
+// SearchAuditLog returns the entries of the audit log matching the provided
+// query, the most recent first.
+func (c *Component) SearchAuditLog(ctx context.Context, query AuditLogQuery) ([]AuditLogEntry, error) {
+	results := []AuditLogEntry{}
+	q := c.db.NewSelect().Model(&results)
+	if query.User != "" {
+		q = q.Where("? = ?", bun.Ident("user"), query.User)
+	}
+	if query.Endpoint != "" {
+		q = q.Where("? = ?", bun.Ident("endpoint"), query.Endpoint)
+	}
+	if query.Filter != "" {
+		// The search is literal: wildcards in the provided text are escaped.
+		pattern := likeEscaper.Replace(query.Filter)
+		q = q.Where("? LIKE ? ESCAPE ?", bun.Ident("filter"), "%"+pattern+"%", `\`)
+	}
+	if !query.Since.IsZero() {
+		q = q.Where("? >= ?", bun.Ident("time"), query.Since)
+	}
+	if !query.Until.IsZero() {
+		q = q.Where("? < ?", bun.Ident("time"), query.Until)
+	}
+	if query.Limit > 0 {
+		q = q.Limit(query.Limit)
+	}
+	if err := q.Order("time DESC", "id DESC").Scan(ctx); err != nil {
+		return nil, fmt.Errorf("unable to search audit log: %w", err)
+	}
+	return results, nil
+}
+
+// PruneAuditLog removes the entries of the audit log older than the configured
+// retention. It returns the number of removed entries.
+func (c *Component) PruneAuditLog(ctx context.Context, now time.Time) (int64, error) {
+	if c.config.AuditLogRetention == 0 {
+		return 0, nil
+	}
+	result, err := c.db.NewDelete().
+		Model((*AuditLogEntry)(nil)).
+		Where("? < ?", bun.Ident("time"), now.Add(-c.config.AuditLogRetention)).
+		Exec(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("unable to prune audit log: %w", err)
+	}
+	count, _ := result.RowsAffected()
+	return count, nil
+}
@@ -0,0 +1,165 @@
+// SPDX-FileCopyrightText: 2026 Free Mobile
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package database
+
+import (
+	"testing"
+	"time"
+
+	"akvorado/common/helpers"
+	"akvorado/common/reporter"
+)
+
+func TestAuditLog(t *testing.T) {
+	r := reporter.NewMock(t)
+	config := DefaultConfiguration()
+	config.AuditLogRetention = 24 * time.Hour
+	c := NewMock(t, r, config)
+
+	now := time.Date(2026, 10, 19, 10, 0, 0, 0, time.UTC)
+	entries := []AuditLogEntry{
+		{
+			Time:       now.Add(-48 * time.Hour),
+			User:       "marty",
+			Endpoint:   "/api/v0/console/graph/line",
+			Filter:     "DstAddr = 2001:db8::1",
+			Dimensions: "SrcAS",
+			Start:      now.Add(-54 * time.Hour),
+			End:        now.Add(-48 * time.Hour),
+			Duration:   120,
+			Rows:       1000,
+			Status:     200,
+		}, {
+			Time:       now.Add(-time.Hour),
+			User:       "judith",
+			Endpoint:   "/api/v0/console/graph/sankey",
+			Filter:     "InIfBoundary = external",
+			Dimensions: "SrcAS,ExporterName",
+			Start:      now.Add(-7 * time.Hour),
+			End:        now.Add(-time.Hour),
+			Duration:   80,
+			Rows:       20,
+			Status:     200,
+		}, {
+			Time:     now.Add(-time.Minute),
+			User:     "marty",
+			Endpoint: "/api/v0/console/flows",
+			Filter:   "SrcAddr = 2001:db8::1",
+			Start:    now.Add(-11 * time.Minute),
+			End:      now.Add(-time.Minute),
+			Duration: 10,
+			Rows:     100,
+			Status:   200,
+		},
+	}
+	for _, entry := range entries {
+		if err := c.AddAuditLogEntry(t.Context(), entry); err != nil {
+			t.Fatalf("AddAuditLogEntry() error:\n%+v", err)
+		}
+	}
+
+	cases := []struct {
+		Description string
+		Query       AuditLogQuery
+		Expected    []uint64
+	}{
+		{"everything", AuditLogQuery{}, []uint64{3, 2, 1}},
+		{"by user", AuditLogQuery{User: "marty"}, []uint64{3, 1}},
+		{"by endpoint", AuditLogQuery{Endpoint: "/api/v0/console/flows"}, []uint64{3}},
+		{"by filter", AuditLogQuery{Filter: "2001:db8::1"}, []uint64{3, 1}},
+		{"since", AuditLogQuery{Since: now.Add(-2 * time.Hour)}, []uint64{3, 2}},
+		{"until", AuditLogQuery{Until: now.Add(-2 * time.Hour)}, []uint64{1}},
+		{"limit", AuditLogQuery{Limit: 1}, []uint64{3}},
+	}
+	for _, tc := range cases {
+		t.Run(tc.Description, func(t *testing.T) {
+			got, err := c.SearchAuditLog(t.Context(), tc.Query)
+			if err != nil {
+				t.Fatalf("SearchAuditLog() error:\n%+v", err)
+			}
+			ids := []uint64{}
+			for _, entry := range got {
+				ids = append(ids, entry.ID)
+			}
+			if diff := helpers.Diff(ids, tc.Expected); diff != "" {
+				t.Fatalf("SearchAuditLog() (-got, +want):\n%s", diff)
+			}
+		})
+	}
+
+	// Check an entry is stored as is
+	got, err := c.SearchAuditLog(t.Context(), AuditLogQuery{Endpoint: "/api/v0/console/graph/sankey"})
+	if err != nil {
+		t.Fatalf("SearchAuditLog() error:\n%+v", err)
+	}
+	expected := entries[1]
+	expected.ID = 2
+	if diff := helpers.Diff(got, []AuditLogEntry{expected}); diff != "" {
+		t.Fatalf("SearchAuditLog() (-got, +want):\n%s", diff)
+	}
+
+	// Prune
+	count, err := c.PruneAuditLog(t.Context(), now)
+	if err != nil {
+		t.Fatalf("PruneAuditLog() error:\n%+v", err)
+	}
+	if count != 1 {
+		t.Fatalf("PruneAuditLog() removed %d entries, expected 1", count)
+	}
+	got, err = c.SearchAuditLog(t.Context(), AuditLogQuery{})
+	if err != nil {
+		t.Fatalf("SearchAuditLog() error:\n%+v", err)
+	}
+	if len(got) != 2 {
+		t.Fatalf("SearchAuditLog() after prune returned %d entries, expected 2", len(got))
+	}
+}
+
+func TestAuditLogLiteralSearch(t *testing.T) {
+	r := reporter.NewMock(t)
+	c := NewMock(t, r, DefaultConfiguration())
+
+	now := time.Date(2026, 10, 19, 10, 0, 0, 0, time.UTC)
+	for _, filter := range []string{
+		`ExporterName = "edge_1"`,
+		`ExporterName = "edge-1"`,
+		`ExporterName = "edge%1"`,
+		`ExporterName = "edge\\1"`,
+	} {
+		if err := c.AddAuditLogEntry(t.Context(), AuditLogEntry{
+			Time:     now,
+			User:     "marty",
+			Endpoint: "/api/v0/console/graph/line",
+			Filter:   filter,
+			Status:   200,
+		}); err != nil {
+			t.Fatalf("AddAuditLogEntry() error:\n%+v", err)
+		}
+	}
+
+	cases := []struct {
+		Query    string
+		Expected []uint64
+	}{
+		{"edge_", []uint64{1}},
+		{"edge%", []uint64{3}},
+		{`edge\\`, []uint64{4}},
+		{"edge", []uint64{4, 3, 2, 1}},
+	}
+	for _, tc := range cases {
+		t.Run(tc.Query, func(t *testing.T) {
+			got, err := c.SearchAuditLog(t.Context(), AuditLogQuery{Filter: tc.Query})
+			if err != nil {
+				t.Fatalf("SearchAuditLog() error:\n%+v", err)
+			}
+			ids := []uint64{}
+			for _, entry := range got {
+				ids = append(ids, entry.ID)
+			}
+			if diff := helpers.Diff(ids, tc.Expected); diff != "" {
+				t.Fatalf("SearchAuditLog() (-got, +want):\n%s", diff)
+			}
+		})
+	}
+}
@@ -3,6 +3,8 @@
 
 package database
 
+import "time"
+
 // Configuration describes the configuration for the authentication component.
 type Configuration struct {
 	// Driver defines the driver for the database
@@ -11,6 +13,11 @@ type Configuration struct {
 	DSN string `validate:"required"`
 	// SavedFilters is a list of saved filters to include for all users
 	SavedFilters []BuiltinSavedFilter `validate:"dive"`
+	// Dashboards is a list of dashboards to include for all users
+	Dashboards []BuiltinDashboard `validate:"dive"`
+	// AuditLogRetention is how long the entries of the audit log are kept. 0
+	// means they are kept forever.
+	AuditLogRetention time.Duration `validate:"min=0"`
 }
 
 // DefaultConfiguration represents the default configuration for the console component.
@@ -18,6 +25,8 @@ func DefaultConfiguration() Configuration {
 	return Configuration{
 		Driver: "sqlite",
 		DSN:    "file::memory:?cache=shared",
+
+		AuditLogRetention: 90 * 24 * time.Hour,
 	}
 }
 
@@ -26,3 +35,10 @@ type BuiltinSavedFilter struct {
 	Description string `validate:"required"`
 	Content     string `validate:"required"`
 }
+
+// BuiltinDashboard is a dashboard. Panels are validated by the console
+// component.
+type BuiltinDashboard struct {
+	Name   string           `validate:"required"`
+	Panels []map[string]any `validate:"min=1"`
+}
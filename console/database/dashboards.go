@@ -0,0 +1,186 @@
+// SPDX-FileCopyrightText: 2026 Free Mobile
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package database
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/uptrace/bun"
+)
+
+// Dashboard represents a dashboard in database. Panels are opaque to the
+// database component and stored as JSON.
+type Dashboard struct {
+	bun.BaseModel `json:"-"`
+
+	ID     uint64 `bun:",pk,autoincrement" json:"id"`
+	User   string `json:"user"`
+	Shared bool   `json:"shared"`
+	Name   string `json:"name" validate:"required"`
+	Panels string `bun:"type:text" json:"-"`
+}
+
+// ErrDashboardNotFound is returned when a dashboard does not exist or is not
+// accessible.
+var ErrDashboardNotFound = errors.New("dashboard not found")
+
+// CreateDashboard creates a new dashboard in database. It returns the
+// dashboard with its ID.
+func (c *Component) CreateDashboard(ctx context.Context, d Dashboard) (Dashboard, error) {
+	d.ID = 0
+	if _, err := c.db.NewInsert().Model(&d).Exec(ctx); err != nil {
+		return Dashboard{}, fmt.Errorf("unable to create new dashboard: %w", err)
+	}
+	return d, nil
+}
+
+// ListDashboards lists all dashboards for the provided user, including the
+// shared ones.
+func (c *Component) ListDashboards(ctx context.Context, user string) ([]Dashboard, error) {
+	results := []Dashboard{}
+	if err := c.db.NewSelect().
+		Model(&results).
+		Where("? = ?", bun.Ident("user"), user).
+		WhereOr("? = ?", bun.Ident("shared"), true).
+		Order("id").
+		Scan(ctx); err != nil {
+		return nil, fmt.Errorf("unable to retrieve dashboards: %w", err)
+	}
+	return results, nil
+}
+
+// GetDashboard returns the dashboard matching the provided ID if it belongs to
+// the provided user or if it is shared.
+func (c *Component) GetDashboard(ctx context.Context, id uint64, user string) (Dashboard, error) {
+	var result Dashboard
+	err := c.db.NewSelect().
+		Model(&result).
+		Where("? = ?", bun.Ident("id"), id).
+		WhereGroup(" AND ", func(q *bun.SelectQuery) *bun.SelectQuery {
+			return q.
+				Where("? = ?", bun.Ident("user"), user).
+				WhereOr("? = ?", bun.Ident("shared"), true)
+		}).
+		Limit(1).
+		Scan(ctx)
+	if errors.Is(err, sql.ErrNoRows) {
+		return Dashboard{}, ErrDashboardNotFound
+	} else if err != nil {
+		return Dashboard{}, fmt.Errorf("unable to retrieve dashboard: %w", err)
+	}
+	return result, nil
+}
+
+// UpdateDashboard updates the dashboard matching d.ID and d.User.
+func (c *Component) UpdateDashboard(ctx context.Context, d Dashboard) error {
+	res, err := c.db.NewUpdate().
+		Model(&d).
+		Column("shared", "name", "panels").
+		Where("? = ?", bun.Ident("id"), d.ID).
+		Where("? = ?", bun.Ident("user"), d.User).
+		Exec(ctx)
+	if err != nil {
+		return fmt.Errorf("cannot update dashboard: %w", err)
+	}
+	rows, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("cannot update dashboard: %w", err)
+	}
+	if rows == 0 {
+		return ErrDashboardNotFound
+	}
+	return nil
+}
+
+// DeleteDashboard deletes the dashboard matching d.ID and d.User.
+func (c *Component) DeleteDashboard(ctx context.Context, d Dashboard) error {
+	res, err := c.db.NewDelete().
+		Model((*Dashboard)(nil)).
+		Where("? = ?", bun.Ident("id"), d.ID).
+		Where("? = ?", bun.Ident("user"), d.User).
+		Exec(ctx)
+	if err != nil {
+		return fmt.Errorf("cannot delete dashboard: %w", err)
+	}
+	rows, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("cannot delete dashboard: %w", err)
+	}
+	if rows == 0 {
+		return ErrDashboardNotFound
+	}
+	return nil
+}
+
+// BuiltinDashboards returns the builtin dashboards from the configuration.
+func (c *Component) BuiltinDashboards() []BuiltinDashboard {
+	return c.config.Dashboards
+}
+
+// populateDashboards populates the database with the builtin dashboards.
+func (c *Component) populateDashboards() error {
+	ctx := context.Background()
+
+	// Add new dashboards
+	expected := make([]Dashboard, 0, len(c.config.Dashboards))
+	for _, dashboard := range c.config.Dashboards {
+		panels, err := json.Marshal(dashboard.Panels)
+		if err != nil {
+			return fmt.Errorf("cannot encode builtin dashboard %q: %w", dashboard.Name, err)
+		}
+		expected = append(expected, Dashboard{
+			User:   systemUser,
+			Shared: true,
+			Name:   dashboard.Name,
+			Panels: string(panels),
+		})
+	}
+	for _, dashboard := range expected {
+		c.r.Debug().Msgf("add builtin dashboard %q", dashboard.Name)
+		var existing Dashboard
+		err := c.db.NewSelect().Model(&existing).
+			Where("? = ?", bun.Ident("user"), systemUser).
+			Where("? = ?", bun.Ident("name"), dashboard.Name).
+			Where("? = ?", bun.Ident("panels"), dashboard.Panels).
+			Limit(1).
+			Scan(ctx)
+		if errors.Is(err, sql.ErrNoRows) {
+			if _, err := c.db.NewInsert().Model(&dashboard).Exec(ctx); err != nil {
+				return fmt.Errorf("unable add builtin dashboard: %w", err)
+			}
+		} else if err != nil {
+			return fmt.Errorf("cannot lookup builtin dashboard: %w", err)
+		}
+	}
+
+	// Remove old dashboards
+	var results []Dashboard
+	if err := c.db.NewSelect().
+		Model(&results).
+		Where("? = ?", bun.Ident("user"), systemUser).
+		Scan(ctx); err != nil {
+		return fmt.Errorf("cannot get existing builtin dashboards: %w", err)
+	}
+outer:
+	for _, result := range results {
+		for _, dashboard := range expected {
+			if dashboard.Name == result.Name && dashboard.Panels == result.Panels {
+				continue outer
+			}
+		}
+		c.r.Info().Msgf("remove old builtin dashboard %q", result.Name)
+		if _, err := c.db.NewDelete().
+			Model((*Dashboard)(nil)).
+			Where("? = ?", bun.Ident("id"), result.ID).
+			Exec(ctx); err != nil {
+			return fmt.Errorf("cannot delete old builtin dashboard: %w", err)
+		}
+	}
+
+	return nil
+}
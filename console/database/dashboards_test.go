@@ -0,0 +1,143 @@
+// SPDX-FileCopyrightText: 2026 Free Mobile
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package database
+
+import (
+	"errors"
+	"testing"
+
+	"akvorado/common/helpers"
+	"akvorado/common/reporter"
+)
+
+func TestDashboards(t *testing.T) {
+	r := reporter.NewMock(t)
+	c := NewMock(t, r, DefaultConfiguration())
+
+	// Create
+	dashboard1, err := c.CreateDashboard(t.Context(), Dashboard{
+		ID:     17,
+		User:   "marty",
+		Name:   "marty's dashboard",
+		Panels: `[{"title":"Top AS"}]`,
+	})
+	if err != nil {
+		t.Fatalf("CreateDashboard() error:\n%+v", err)
+	}
+	if dashboard1.ID != 1 {
+		t.Fatalf("CreateDashboard() ID = %d, expected 1", dashboard1.ID)
+	}
+	dashboard2, err := c.CreateDashboard(t.Context(), Dashboard{
+		User:   "judith",
+		Shared: true,
+		Name:   "judith's dashboard",
+		Panels: `[]`,
+	})
+	if err != nil {
+		t.Fatalf("CreateDashboard() error:\n%+v", err)
+	}
+	dashboard3, err := c.CreateDashboard(t.Context(), Dashboard{
+		User:   "judith",
+		Name:   "judith's private dashboard",
+		Panels: `[]`,
+	})
+	if err != nil {
+		t.Fatalf("CreateDashboard() error:\n%+v", err)
+	}
+
+	// List
+	got, err := c.ListDashboards(t.Context(), "marty")
+	if err != nil {
+		t.Fatalf("ListDashboards() error:\n%+v", err)
+	}
+	if diff := helpers.Diff(got, []Dashboard{dashboard1, dashboard2}); diff != "" {
+		t.Fatalf("ListDashboards() (-got, +want):\n%s", diff)
+	}
+
+	// Get
+	if got, err := c.GetDashboard(t.Context(), dashboard2.ID, "marty"); err != nil {
+		t.Fatalf("GetDashboard() error:\n%+v", err)
+	} else if diff := helpers.Diff(got, dashboard2); diff != "" {
+		t.Fatalf("GetDashboard() (-got, +want):\n%s", diff)
+	}
+	if _, err := c.GetDashboard(t.Context(), dashboard3.ID, "marty"); !errors.Is(err, ErrDashboardNotFound) {
+		t.Fatalf("GetDashboard() error:\n%+v", err)
+	}
+
+	// Update
+	dashboard1.Shared = true
+	dashboard1.Panels = `[{"title":"Top ports"}]`
+	if err := c.UpdateDashboard(t.Context(), dashboard1); err != nil {
+		t.Fatalf("UpdateDashboard() error:\n%+v", err)
+	}
+	if got, err := c.GetDashboard(t.Context(), dashboard1.ID, "judith"); err != nil {
+		t.Fatalf("GetDashboard() error:\n%+v", err)
+	} else if diff := helpers.Diff(got, dashboard1); diff != "" {
+		t.Fatalf("GetDashboard() (-got, +want):\n%s", diff)
+	}
+	dashboard2.User = "marty"
+	if err := c.UpdateDashboard(t.Context(), dashboard2); !errors.Is(err, ErrDashboardNotFound) {
+		t.Fatalf("UpdateDashboard() error:\n%+v", err)
+	}
+
+	// Delete
+	if err := c.DeleteDashboard(t.Context(), Dashboard{ID: dashboard3.ID, User: "marty"}); !errors.Is(err, ErrDashboardNotFound) {
+		t.Fatalf("DeleteDashboard() error:\n%+v", err)
+	}
+	if err := c.DeleteDashboard(t.Context(), Dashboard{ID: dashboard3.ID, User: "judith"}); err != nil {
+		t.Fatalf("DeleteDashboard() error:\n%+v", err)
+	}
+	if _, err := c.GetDashboard(t.Context(), dashboard3.ID, "judith"); !errors.Is(err, ErrDashboardNotFound) {
+		t.Fatalf("GetDashboard() error:\n%+v", err)
+	}
+}
+
+func TestBuiltinDashboards(t *testing.T) {
+	r := reporter.NewMock(t)
+	config := DefaultConfiguration()
+	config.Dashboards = []BuiltinDashboard{
+		{
+			Name:   "Peering",
+			Panels: []map[string]any{{"title": "Top AS", "type": "stacked"}},
+		},
+	}
+	c := NewMock(t, r, config)
+
+	got, err := c.ListDashboards(t.Context(), "marty")
+	if err != nil {
+		t.Fatalf("ListDashboards() error:\n%+v", err)
+	}
+	if diff := helpers.Diff(got, []Dashboard{
+		{
+			ID:     1,
+			User:   "__system",
+			Shared: true,
+			Name:   "Peering",
+			Panels: `[{"title":"Top AS","type":"stacked"}]`,
+		},
+	}); diff != "" {
+		t.Fatalf("ListDashboards() (-got, +want):\n%s", diff)
+	}
+
+	// Populating again does not duplicate dashboards but removes old ones
+	c.config.Dashboards[0].Panels[0]["title"] = "Top ASN"
+	if err := c.populateDashboards(); err != nil {
+		t.Fatalf("populateDashboards() error:\n%+v", err)
+	}
+	if err := c.populateDashboards(); err != nil {
+		t.Fatalf("populateDashboards() error:\n%+v", err)
+	}
+	got, _ = c.ListDashboards(t.Context(), "marty")
+	if diff := helpers.Diff(got, []Dashboard{
+		{
+			ID:     2,
+			User:   "__system",
+			Shared: true,
+			Name:   "Peering",
+			Panels: `[{"title":"Top ASN","type":"stacked"}]`,
+		},
+	}); diff != "" {
+		t.Fatalf("ListDashboards() (-got, +want):\n%s", diff)
+	}
+}
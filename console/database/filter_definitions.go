@@ -0,0 +1,85 @@
+// SPDX-FileCopyrightText: 2026 Free Mobile
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package database
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/uptrace/bun"
+)
+
+// FilterDefinition represents a named set or a macro defined by a user for the
+// filter language.
+type FilterDefinition struct {
+	bun.BaseModel `json:"-"`
+
+	ID      uint64 `bun:",pk,autoincrement" json:"id"`
+	User    string `json:"user"`
+	Kind    string `json:"kind" validate:"oneof=set macro"`
+	Name    string `json:"name" validate:"required"`
+	Content string `bun:"type:text" json:"content" validate:"required"`
+}
+
+// ErrFilterDefinitionNotFound is returned when a filter definition does not
+// exist or does not belong to the user.
+var ErrFilterDefinitionNotFound = errors.New("filter definition not found")
+
+// SetFilterDefinition creates a new filter definition in database. An existing
+// definition of the same kind and with the same name for the user is replaced.
+// It returns the definition with its ID.
+func (c *Component) SetFilterDefinition(ctx context.Context, d FilterDefinition) (FilterDefinition, error) {
+	d.ID = 0
+	err := c.db.RunInTx(ctx, nil, func(ctx context.Context, tx bun.Tx) error {
+		if _, err := tx.NewDelete().
+			Model((*FilterDefinition)(nil)).
+			Where("? = ?", bun.Ident("user"), d.User).
+			Where("? = ?", bun.Ident("kind"), d.Kind).
+			Where("? = ?", bun.Ident("name"), d.Name).
+			Exec(ctx); err != nil {
+			return err
+		}
+		_, err := tx.NewInsert().Model(&d).Exec(ctx)
+		return err
+	})
+	if err != nil {
+		return FilterDefinition{}, fmt.Errorf("unable to set filter definition: %w", err)
+	}
+	return d, nil
+}
+
+// ListFilterDefinitions lists the filter definitions of the provided user.
+func (c *Component) ListFilterDefinitions(ctx context.Context, user string) ([]FilterDefinition, error) {
+	results := []FilterDefinition{}
+	if err := c.db.NewSelect().
+		Model(&results).
+		Where("? = ?", bun.Ident("user"), user).
+		Order("kind", "name").
+		Scan(ctx); err != nil {
+		return nil, fmt.Errorf("unable to retrieve filter definitions: %w", err)
+	}
+	return results, nil
+}
+
+// DeleteFilterDefinition deletes the filter definition matching d.ID and
+// d.User.
+func (c *Component) DeleteFilterDefinition(ctx context.Context, d FilterDefinition) error {
+	res, err := c.db.NewDelete().
+		Model((*FilterDefinition)(nil)).
+		Where("? = ?", bun.Ident("id"), d.ID).
+		Where("? = ?", bun.Ident("user"), d.User).
+		Exec(ctx)
+	if err != nil {
+		return fmt.Errorf("cannot delete filter definition: %w", err)
+	}
+	rows, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("cannot delete filter definition: %w", err)
+	}
+	if rows == 0 {
+		return ErrFilterDefinitionNotFound
+	}
+	return nil
+}
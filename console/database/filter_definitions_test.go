@@ -0,0 +1,81 @@
+// SPDX-FileCopyrightText: 2026 Free Mobile
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package database
+
+import (
+	"errors"
+	"testing"
+
+	"akvorado/common/helpers"
+	"akvorado/common/reporter"
+)
+
+func TestFilterDefinitions(t *testing.T) {
+	r := reporter.NewMock(t)
+	c := NewMock(t, r, DefaultConfiguration())
+
+	// Set
+	cdn, err := c.SetFilterDefinition(t.Context(), FilterDefinition{
+		ID:      17,
+		User:    "marty",
+		Kind:    "set",
+		Name:    "cdn",
+		Content: "192.0.2.0/24",
+	})
+	if err != nil {
+		t.Fatalf("SetFilterDefinition() error:\n%+v", err)
+	}
+	if cdn.ID != 1 {
+		t.Fatalf("SetFilterDefinition() ID = %d, expected 1", cdn.ID)
+	}
+	web, err := c.SetFilterDefinition(t.Context(), FilterDefinition{
+		User:    "marty",
+		Kind:    "macro",
+		Name:    "web",
+		Content: "DstPort IN (80, 443)",
+	})
+	if err != nil {
+		t.Fatalf("SetFilterDefinition() error:\n%+v", err)
+	}
+	if _, err := c.SetFilterDefinition(t.Context(), FilterDefinition{
+		User:    "judith",
+		Kind:    "set",
+		Name:    "cdn",
+		Content: "198.51.100.0/24",
+	}); err != nil {
+		t.Fatalf("SetFilterDefinition() error:\n%+v", err)
+	}
+
+	// Replace
+	cdn, err = c.SetFilterDefinition(t.Context(), FilterDefinition{
+		User:    "marty",
+		Kind:    "set",
+		Name:    "cdn",
+		Content: "192.0.2.0/24, 2001:db8::/48",
+	})
+	if err != nil {
+		t.Fatalf("SetFilterDefinition() error:\n%+v", err)
+	}
+
+	// List
+	got, err := c.ListFilterDefinitions(t.Context(), "marty")
+	if err != nil {
+		t.Fatalf("ListFilterDefinitions() error:\n%+v", err)
+	}
+	if diff := helpers.Diff(got, []FilterDefinition{web, cdn}); diff != "" {
+		t.Fatalf("ListFilterDefinitions() (-got, +want):\n%s", diff)
+	}
+
+	// Delete
+	if err := c.DeleteFilterDefinition(t.Context(), FilterDefinition{ID: web.ID, User: "judith"}); !errors.Is(err, ErrFilterDefinitionNotFound) {
+		t.Fatalf("DeleteFilterDefinition() error:\n%+v", err)
+	}
+	if err := c.DeleteFilterDefinition(t.Context(), FilterDefinition{ID: web.ID, User: "marty"}); err != nil {
+		t.Fatalf("DeleteFilterDefinition() error:\n%+v", err)
+	}
+	got, _ = c.ListFilterDefinitions(t.Context(), "marty")
+	if diff := helpers.Diff(got, []FilterDefinition{cdn}); diff != "" {
+		t.Fatalf("ListFilterDefinitions() (-got, +want):\n%s", diff)
+	}
+}
@@ -80,7 +80,72 @@ func (c *Component) Start() error {
 		Exec(ctx); err != nil {
 		return fmt.Errorf("cannot migrate database: %w", err)
 	}
-	return c.populate()
+	if _, err := c.db.NewCreateTable().
+		Model((*APIToken)(nil)).
+		IfNotExists().
+		Exec(ctx); err != nil {
+		return fmt.Errorf("cannot migrate database: %w", err)
+	}
+	if _, err := c.db.NewCreateIndex().
+		Model((*APIToken)(nil)).
+		Index("idx_api_tokens_user").
+		Column("user").
+		IfNotExists().
+		Exec(ctx); err != nil {
+		return fmt.Errorf("cannot migrate database: %w", err)
+	}
+	if _, err := c.db.NewCreateTable().
+		Model((*User)(nil)).
+		IfNotExists().
+		Exec(ctx); err != nil {
+		return fmt.Errorf("cannot migrate database: %w", err)
+	}
+	if _, err := c.db.NewCreateTable().
+		Model((*Dashboard)(nil)).
+		IfNotExists().
+		Exec(ctx); err != nil {
+		return fmt.Errorf("cannot migrate database: %w", err)
+	}
+	if _, err := c.db.NewCreateIndex().
+		Model((*Dashboard)(nil)).
+		Index("idx_dashboards_user").
+		Column("user").
+		IfNotExists().
+		Exec(ctx); err != nil {
+		return fmt.Errorf("cannot migrate database: %w", err)
+	}
+	if _, err := c.db.NewCreateTable().
+		Model((*AuditLogEntry)(nil)).
+		IfNotExists().
+		Exec(ctx); err != nil {
+		return fmt.Errorf("cannot migrate database: %w", err)
+	}
+	if _, err := c.db.NewCreateIndex().
+		Model((*AuditLogEntry)(nil)).
+		Index("idx_audit_log_time").
+		Column("time").
+		IfNotExists().
+		Exec(ctx); err != nil {
+		return fmt.Errorf("cannot migrate database: %w", err)
+	}
+	if _, err := c.db.NewCreateTable().
+		Model((*FilterDefinition)(nil)).
+		IfNotExists().
+		Exec(ctx); err != nil {
+		return fmt.Errorf("cannot migrate database: %w", err)
+	}
+	if _, err := c.db.NewCreateIndex().
+		Model((*FilterDefinition)(nil)).
+		Index("idx_filter_definitions_user").
+		Column("user").
+		IfNotExists().
+		Exec(ctx); err != nil {
+		return fmt.Errorf("cannot migrate database: %w", err)
+	}
+	if err := c.populate(); err != nil {
+		return err
+	}
+	return c.populateDashboards()
 }
 
 // Stop stops the database component.
@@ -0,0 +1,76 @@
+// SPDX-FileCopyrightText: 2026 Free Mobile
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/uptrace/bun"
+)
+
+// User represents the identity of a user owning API tokens, as provided by the
+// authentication layer on the last request of the user. The groups of a user
+// are not known when using an API token, so they are taken from here.
+type User struct {
+	bun.BaseModel `bun:"table:users"`
+
+	Login   string `bun:",pk"`
+	Name    string
+	Email   string
+	Groups  string // comma-separated
+	Updated time.Time
+}
+
+// ErrUserNotFound is returned when a user does not exist.
+var ErrUserNotFound = errors.New("user not found")
+
+// SetUser creates or replaces a user in database.
+func (c *Component) SetUser(ctx context.Context, u User) error {
+	err := c.db.RunInTx(ctx, nil, func(ctx context.Context, tx bun.Tx) error {
+		if _, err := tx.NewDelete().
+			Model((*User)(nil)).
+			Where("? = ?", bun.Ident("login"), u.Login).
+			Exec(ctx); err != nil {
+			return err
+		}
+		_, err := tx.NewInsert().Model(&u).Exec(ctx)
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("unable to set user: %w", err)
+	}
+	return nil
+}
+
+// UpdateUser updates an existing user in database. Nothing is done if the user
+// does not exist.
+func (c *Component) UpdateUser(ctx context.Context, u User) error {
+	if _, err := c.db.NewUpdate().
+		Model(&u).
+		WherePK().
+		Exec(ctx); err != nil {
+		return fmt.Errorf("unable to update user: %w", err)
+	}
+	return nil
+}
+
+// GetUser returns the user with the provided login.
+func (c *Component) GetUser(ctx context.Context, login string) (User, error) {
+	var result User
+	err := c.db.NewSelect().
+		Model(&result).
+		Where("? = ?", bun.Ident("login"), login).
+		Limit(1).
+		Scan(ctx)
+	if errors.Is(err, sql.ErrNoRows) {
+		return User{}, ErrUserNotFound
+	} else if err != nil {
+		return User{}, fmt.Errorf("unable to get user: %w", err)
+	}
+	return result, nil
+}
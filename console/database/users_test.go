@@ -0,0 +1,65 @@
+// SPDX-FileCopyrightText: 2026 Free Mobile
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package database
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"akvorado/common/helpers"
+	"akvorado/common/reporter"
+)
+
+func TestUsers(t *testing.T) {
+	r := reporter.NewMock(t)
+	c := NewMock(t, r, DefaultConfiguration())
+	updated := time.Date(2026, 10, 19, 10, 0, 0, 0, time.UTC)
+
+	if _, err := c.GetUser(t.Context(), "marty"); !errors.Is(err, ErrUserNotFound) {
+		t.Fatalf("GetUser() error:\n%+v", err)
+	}
+
+	// Updating a missing user does nothing
+	if err := c.UpdateUser(t.Context(), User{Login: "marty", Groups: "time-travelers"}); err != nil {
+		t.Fatalf("UpdateUser() error:\n%+v", err)
+	}
+	if _, err := c.GetUser(t.Context(), "marty"); !errors.Is(err, ErrUserNotFound) {
+		t.Fatalf("GetUser() error:\n%+v", err)
+	}
+
+	// Create and replace
+	user := User{
+		Login:   "marty",
+		Name:    "Marty McFly",
+		Groups:  "time-travelers,hill-valley",
+		Updated: updated,
+	}
+	for range 2 {
+		if err := c.SetUser(t.Context(), user); err != nil {
+			t.Fatalf("SetUser() error:\n%+v", err)
+		}
+	}
+	got, err := c.GetUser(t.Context(), "marty")
+	if err != nil {
+		t.Fatalf("GetUser() error:\n%+v", err)
+	}
+	if diff := helpers.Diff(got, user); diff != "" {
+		t.Fatalf("GetUser() (-got, +want):\n%s", diff)
+	}
+
+	// Update
+	user.Groups = "hill-valley"
+	user.Updated = updated.Add(time.Hour)
+	if err := c.UpdateUser(t.Context(), user); err != nil {
+		t.Fatalf("UpdateUser() error:\n%+v", err)
+	}
+	got, err = c.GetUser(t.Context(), "marty")
+	if err != nil {
+		t.Fatalf("GetUser() error:\n%+v", err)
+	}
+	if diff := helpers.Diff(got, user); diff != "" {
+		t.Fatalf("GetUser() (-got, +want):\n%s", diff)
+	}
+}
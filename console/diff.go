@@ -0,0 +1,214 @@
+// SPDX-FileCopyrightText: 2026 Free Mobile
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package console
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"akvorado/common/helpers"
+	"akvorado/common/httpserver"
+	sb "akvorado/common/sqlbuilder"
+)
+
+// diffHandlerInput describes the input for the /diff endpoint. The time range
+// of graphCommonHandlerInput is the current one. It is compared to the
+// reference one, which defaults to the previous period.
+type diffHandlerInput struct {
+	graphCommonHandlerInput
+	ReferenceStart time.Time `json:"reference-start" validate:"required_with=ReferenceEnd"`
+	ReferenceEnd   time.Time `json:"reference-end" validate:"required_with=ReferenceStart,omitempty,gtfield=ReferenceStart"`
+	OrderBy        string    `json:"order-by" validate:"omitempty,oneof=absolute relative"`
+}
+
+// diffHandlerOutput describes the output for the /diff endpoint. The time
+// ranges are the ones used after aligning them on the selected table.
+type diffHandlerOutput struct {
+	Start          time.Time `json:"start"`
+	End            time.Time `json:"end"`
+	ReferenceStart time.Time `json:"reference-start"`
+	ReferenceEnd   time.Time `json:"reference-end"`
+	Rows           []diffRow `json:"rows"`
+}
+
+// diffRow is the change of the traffic for a set of dimension values. The
+// traffic is averaged over each time range, in the requested units. The
+// relative change is missing for new rows.
+type diffRow struct {
+	Dimensions []string `json:"dimensions"`
+	Current    int      `json:"current"`
+	Reference  int      `json:"reference"`
+	Change     int      `json:"change"`
+	Relative   *float64 `json:"relative"`
+	Status     string   `json:"status"`
+}
+
+// diffStatus tells how the traffic of a row evolved.
+func diffStatus(current, reference float64) string {
+	switch {
+	case reference == 0:
+		return "new"
+	case current == 0:
+		return "gone"
+	case current > reference:
+		return "increase"
+	case current < reference:
+		return "decrease"
+	default:
+		return "unchanged"
+	}
+}
+
+// toSQL converts the input to an SQL query. Both time ranges are read in a
+// single pass, using the same table. The end of each time range is excluded.
+func (input diffHandlerInput) toSQL(current, reference resolved) string {
+	in := func(r resolved) sb.Expr {
+		return sb.And(
+			sb.Op(sb.Column("TimeReceived"), ">=", r.timefilterStart()),
+			sb.Op(sb.Column("TimeReceived"), "<", r.timefilterEnd()))
+	}
+	average := func(r resolved) sb.Expr {
+		return sb.Op(
+			sb.Function("sumIf", unitsWeightExpr(input.Units), in(r)),
+			"/", sb.Uint(uint64(r.End.Sub(r.Start).Seconds())))
+	}
+	dimensions := []sb.Expr{}
+	for _, column := range input.Dimensions {
+		dimensions = append(dimensions, column.ToSQLSelect(input.schema, input.database))
+	}
+	order := []sb.OrderItem{}
+	if input.OrderBy == "relative" {
+		order = append(order,
+			sb.Order(sb.Function("isNull", sb.Column("relative"))).Desc(),
+			sb.Order(sb.Function("abs", sb.Column("relative"))).Desc())
+	}
+	order = append(order, sb.Order(sb.Function("abs", sb.Column("change"))).Desc())
+
+	return sb.Select(
+		sb.Alias(sb.Array(dimensions...), "dimensions"),
+		sb.Alias(average(current), "current"),
+		sb.Alias(average(reference), "reference"),
+		sb.Alias(sb.Op(sb.Column("current"), "-", sb.Column("reference")), "change"),
+		sb.Alias(sb.Op(sb.Column("change"), "/",
+			sb.Function("nullIf", sb.Column("reference"), sb.Uint(0))), "relative"),
+	).
+		With("source", input.sourceSelect(current.Table)).
+		From(sb.Table("source")).
+		Where(sb.And(sb.Or(in(current), in(reference)), input.Filter.Direct())).
+		GroupBy(sb.Column("dimensions")).
+		Having(sb.Or(
+			sb.Op(sb.Column("current"), ">", sb.Uint(0)),
+			sb.Op(sb.Column("reference"), ">", sb.Uint(0)))).
+		OrderBy(order...).
+		Limit(input.Limit).
+		String()
+}
+
+func (c *Component) diffHandlerFunc(w http.ResponseWriter, req *http.Request) {
+	ctx := c.t.Context(req.Context())
+	input := diffHandlerInput{graphCommonHandlerInput: graphCommonHandlerInput{
+		schema:      c.d.Schema,
+		database:    c.d.ClickHouseDB.DatabaseName(),
+		definitions: c.filterDefinitions(req.Context()),
+	}}
+	if err := httpserver.BindJSON(req, &input); err != nil {
+		httpserver.WriteJSON(w, http.StatusBadRequest, helpers.M{"message": helpers.Capitalize(err.Error())})
+		return
+	}
+	if err := input.validateDimensions(); err != nil {
+		httpserver.WriteJSON(w, http.StatusBadRequest, helpers.M{"message": helpers.Capitalize(err.Error())})
+		return
+	}
+	if err := input.Filter.ValidateWithDefinitions(input.schema, input.database, input.definitions); err != nil {
+		httpserver.WriteJSON(w, http.StatusBadRequest, helpers.M{"message": helpers.Capitalize(err.Error())})
+		return
+	}
+	if err := input.applyDeduplication(); err != nil {
+		httpserver.WriteJSON(w, http.StatusBadRequest, helpers.M{"message": helpers.Capitalize(err.Error())})
+		return
+	}
+	input.Filter = input.Filter.And(c.accessFilter(req.Context()))
+	if input.Limit > c.config.DimensionsLimit {
+		httpserver.WriteJSON(w, http.StatusBadRequest,
+			helpers.M{"message": fmt.Sprintf("Limit is set beyond maximum value (%d)",
+				c.config.DimensionsLimit)})
+		return
+	}
+	if len(input.Dimensions) == 0 {
+		httpserver.WriteJSON(w, http.StatusBadRequest,
+			helpers.M{"message": "At least one dimension is required."})
+		return
+	}
+	if unitsWeightExpr(input.Units).IsZero() {
+		// An interface usage cannot be summed over rows.
+		httpserver.WriteJSON(w, http.StatusBadRequest,
+			helpers.M{"message": fmt.Sprintf("Units %s are not supported.", input.Units)})
+		return
+	}
+	if input.ReferenceStart.IsZero() {
+		previous, _ := graphLineHandlerInput{graphCommonHandlerInput: input.graphCommonHandlerInput}.previousPeriod()
+		input.ReferenceStart, input.ReferenceEnd = previous.Start, previous.End
+	}
+
+	// The table has to cover the earliest range, with enough points for the
+	// shortest one. There is no time axis, so the number of points only steers
+	// the table selection, like for sankey graphs.
+	start := input.Start
+	if input.ReferenceStart.Before(start) {
+		start = input.ReferenceStart
+	}
+	length := min(input.End.Sub(input.Start), input.ReferenceEnd.Sub(input.ReferenceStart))
+	res := c.resolve(inputContext{
+		Start:             start,
+		End:               start.Add(length),
+		MainTableRequired: requireMainTable(input.schema, input.Dimensions, input.Filter),
+		Points:            20,
+	})
+	current := res.forRange(input.Start, input.End)
+	reference := res.forRange(input.ReferenceStart, input.ReferenceEnd)
+	if current.points() == 0 || reference.points() == 0 {
+		httpserver.WriteJSON(w, http.StatusBadRequest, helpers.M{"message": "Time range is too short."})
+		return
+	}
+
+	sqlQuery := input.toSQL(current, reference)
+	w.Header().Set("X-SQL-Query", sqlQuery)
+	results := []struct {
+		Dimensions []string `ch:"dimensions"`
+		Current    float64  `ch:"current"`
+		Reference  float64  `ch:"reference"`
+		Change     float64  `ch:"change"`
+		Relative   *float64 `ch:"relative"`
+	}{}
+	ctx, done, ok := c.startQuery(ctx, w, current.Table, sqlQuery)
+	if !ok {
+		return
+	}
+	defer done()
+	if err := c.d.ClickHouseDB.Conn.Select(ctx, &results, sqlQuery); err != nil {
+		c.writeQueryError(ctx, w, err, sqlQuery)
+		return
+	}
+	auditRows(ctx, len(results))
+
+	output := diffHandlerOutput{
+		Start:          current.Start,
+		End:            current.End,
+		ReferenceStart: reference.Start,
+		ReferenceEnd:   reference.End,
+		Rows:           make([]diffRow, 0, len(results)),
+	}
+	for _, result := range results {
+		output.Rows = append(output.Rows, diffRow{
+			Dimensions: result.Dimensions,
+			Current:    int(result.Current),
+			Reference:  int(result.Reference),
+			Change:     int(result.Change),
+			Relative:   result.Relative,
+			Status:     diffStatus(result.Current, result.Reference),
+		})
+	}
+	httpserver.WriteJSON(w, http.StatusOK, output)
+}
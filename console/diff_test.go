@@ -0,0 +1,254 @@
+// SPDX-FileCopyrightText: 2026 Free Mobile
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package console
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"go.uber.org/mock/gomock"
+
+	"akvorado/common/helpers"
+	"akvorado/common/schema"
+	sb "akvorado/common/sqlbuilder"
+	"akvorado/console/query"
+)
+
+func TestDiffQuerySQL(t *testing.T) {
+	start := time.Date(2026, 10, 2, 10, 0, 0, 0, time.UTC)
+	end := time.Date(2026, 10, 2, 11, 0, 0, 0, time.UTC)
+	cases := []struct {
+		Description string
+		Pos         helpers.Pos
+		Input       diffHandlerInput
+		Expected    string
+	}{
+		{
+			Description: "absolute change",
+			Pos:         helpers.Mark(),
+			Input: diffHandlerInput{
+				graphCommonHandlerInput: graphCommonHandlerInput{
+					Start:      start,
+					End:        end,
+					Dimensions: []query.Column{query.NewColumn("SrcAS")},
+					Filter:     query.NewFilter("InIfBoundary = external"),
+					Limit:      10,
+					Units:      "l3bps",
+				},
+			},
+			Expected: `
+WITH source AS (SELECT * FROM flows_1m0s SETTINGS asterisk_include_alias_columns = 1)
+SELECT
+ [concat(toString(SrcAS), ': ', dictGetOrDefault('default.asns', 'name', SrcAS, '???'))] AS dimensions,
+ sumIf(Bytes*SamplingRate*8, TimeReceived >= toDateTime('2026-10-02 10:00:00', 'UTC') AND TimeReceived < toDateTime('2026-10-02 11:00:00', 'UTC')) / 3600 AS current,
+ sumIf(Bytes*SamplingRate*8, TimeReceived >= toDateTime('2026-10-01 10:00:00', 'UTC') AND TimeReceived < toDateTime('2026-10-01 11:00:00', 'UTC')) / 3600 AS reference,
+ current - reference AS change,
+ change / nullIf(reference, 0) AS relative
+FROM source
+WHERE (TimeReceived >= toDateTime('2026-10-02 10:00:00', 'UTC') AND TimeReceived < toDateTime('2026-10-02 11:00:00', 'UTC')
+ OR TimeReceived >= toDateTime('2026-10-01 10:00:00', 'UTC') AND TimeReceived < toDateTime('2026-10-01 11:00:00', 'UTC'))
+ AND InIfBoundary = 'external'
+GROUP BY dimensions
+HAVING current > 0 OR reference > 0
+ORDER BY abs(change) DESC
+LIMIT 10`,
+		}, {
+			Description: "relative change",
+			Pos:         helpers.Mark(),
+			Input: diffHandlerInput{
+				graphCommonHandlerInput: graphCommonHandlerInput{
+					Start:      start,
+					End:        end,
+					Dimensions: []query.Column{query.NewColumn("ExporterName"), query.NewColumn("InIfName")},
+					Filter:     query.NewFilter(""),
+					Limit:      20,
+					Units:      "pps",
+				},
+				OrderBy: "relative",
+			},
+			Expected: `
+WITH source AS (SELECT * FROM flows_1m0s SETTINGS asterisk_include_alias_columns = 1)
+SELECT
+ [ExporterName, InIfName] AS dimensions,
+ sumIf(Packets*SamplingRate, TimeReceived >= toDateTime('2026-10-02 10:00:00', 'UTC') AND TimeReceived < toDateTime('2026-10-02 11:00:00', 'UTC')) / 3600 AS current,
+ sumIf(Packets*SamplingRate, TimeReceived >= toDateTime('2026-10-01 10:00:00', 'UTC') AND TimeReceived < toDateTime('2026-10-01 11:00:00', 'UTC')) / 3600 AS reference,
+ current - reference AS change,
+ change / nullIf(reference, 0) AS relative
+FROM source
+WHERE TimeReceived >= toDateTime('2026-10-02 10:00:00', 'UTC') AND TimeReceived < toDateTime('2026-10-02 11:00:00', 'UTC')
+ OR TimeReceived >= toDateTime('2026-10-01 10:00:00', 'UTC') AND TimeReceived < toDateTime('2026-10-01 11:00:00', 'UTC')
+GROUP BY dimensions
+HAVING current > 0 OR reference > 0
+ORDER BY isNull(relative) DESC, abs(relative) DESC, abs(change) DESC
+LIMIT 20`,
+		},
+	}
+	sch := schema.NewMock(t)
+	res := resolution{Table: "flows_1m0s", Interval: 60, TableInterval: time.Minute}
+	for _, tc := range cases {
+		tc.Input.schema = sch
+		tc.Input.database = "default"
+		if err := tc.Input.validateDimensions(); err != nil {
+			t.Fatalf("%svalidateDimensions() error:\n%+v", tc.Pos, err)
+		}
+		if err := tc.Input.Filter.Validate(tc.Input.schema, tc.Input.database); err != nil {
+			t.Fatalf("%sValidate() error:\n%+v", tc.Pos, err)
+		}
+		t.Run(tc.Description, func(t *testing.T) {
+			current := res.forRange(start, end)
+			reference := res.forRange(start.Add(-24*time.Hour), end.Add(-24*time.Hour))
+			got := sb.Normalize(t, tc.Input.toSQL(current, reference))
+			if diff := helpers.Diff(got, sb.Normalize(t, tc.Expected)); diff != "" {
+				t.Errorf("%stoSQL (-got, +want):\n%s", tc.Pos, diff)
+			}
+		})
+	}
+}
+
+func TestDiffHandler(t *testing.T) {
+	_, h, mockConn, _ := NewMock(t, DefaultConfiguration())
+	increase, decrease := 1.5, -1.0
+	results := []struct {
+		Dimensions []string `ch:"dimensions"`
+		Current    float64  `ch:"current"`
+		Reference  float64  `ch:"reference"`
+		Change     float64  `ch:"change"`
+		Relative   *float64 `ch:"relative"`
+	}{
+		{[]string{"2906: Netflix"}, 2_000_000_000, 0, 2_000_000_000, nil},
+		{[]string{"15169: Google"}, 2_500_000_000, 1_000_000_000, 1_500_000_000, &increase},
+		{[]string{"16509: Amazon"}, 0, 500_000_000, -500_000_000, &decrease},
+	}
+	mockConn.EXPECT().
+		Select(gomock.Any(), gomock.Any(), gomock.Any()).
+		SetArg(1, results).
+		Return(nil)
+
+	helpers.TestHTTPEndpoints(t, h.LocalAddr(), helpers.HTTPEndpointCases{
+		{
+			Description: "previous period",
+			URL:         "/api/v0/console/diff",
+			JSONInput: helpers.M{
+				"start":      time.Date(2026, 10, 2, 10, 0, 0, 0, time.UTC),
+				"end":        time.Date(2026, 10, 2, 11, 0, 0, 0, time.UTC),
+				"dimensions": []string{"SrcAS"},
+				"limit":      10,
+				"units":      "l3bps",
+			},
+			JSONOutput: helpers.M{
+				"start":           "2026-10-02T10:00:00Z",
+				"end":             "2026-10-02T11:00:00Z",
+				"reference-start": "2026-10-02T09:00:00Z",
+				"reference-end":   "2026-10-02T10:00:00Z",
+				"rows": []helpers.M{
+					{
+						"dimensions": []string{"2906: Netflix"},
+						"current":    2_000_000_000,
+						"reference":  0,
+						"change":     2_000_000_000,
+						"relative":   nil,
+						"status":     "new",
+					}, {
+						"dimensions": []string{"15169: Google"},
+						"current":    2_500_000_000,
+						"reference":  1_000_000_000,
+						"change":     1_500_000_000,
+						"relative":   1.5,
+						"status":     "increase",
+					}, {
+						"dimensions": []string{"16509: Amazon"},
+						"current":    0,
+						"reference":  500_000_000,
+						"change":     -500_000_000,
+						"relative":   -1,
+						"status":     "gone",
+					},
+				},
+			},
+		}, {
+			Description: "no dimension",
+			URL:         "/api/v0/console/diff",
+			JSONInput: helpers.M{
+				"start":  time.Date(2026, 10, 2, 10, 0, 0, 0, time.UTC),
+				"end":    time.Date(2026, 10, 2, 11, 0, 0, 0, time.UTC),
+				"limit":  10,
+				"units":  "l3bps",
+				"filter": "",
+			},
+			StatusCode: 400,
+			JSONOutput: helpers.M{"message": "At least one dimension is required."},
+		}, {
+			Description: "unsupported units",
+			URL:         "/api/v0/console/diff",
+			JSONInput: helpers.M{
+				"start":      time.Date(2026, 10, 2, 10, 0, 0, 0, time.UTC),
+				"end":        time.Date(2026, 10, 2, 11, 0, 0, 0, time.UTC),
+				"dimensions": []string{"SrcAS"},
+				"limit":      10,
+				"units":      "inl2%",
+			},
+			StatusCode: 400,
+			JSONOutput: helpers.M{"message": "Units inl2% are not supported."},
+		}, {
+			Description: "reference end without start",
+			URL:         "/api/v0/console/diff",
+			JSONInput: helpers.M{
+				"start":         time.Date(2026, 10, 2, 10, 0, 0, 0, time.UTC),
+				"end":           time.Date(2026, 10, 2, 11, 0, 0, 0, time.UTC),
+				"reference-end": time.Date(2026, 10, 1, 11, 0, 0, 0, time.UTC),
+				"dimensions":    []string{"SrcAS"},
+				"limit":         10,
+				"units":         "l3bps",
+			},
+			StatusCode: 400,
+			JSONOutput: helpers.M{"message": "Key: 'diffHandlerInput.ReferenceStart' Error:Field validation for 'ReferenceStart' failed on the 'required_with' tag"},
+		}, {
+			Description: "deduplicated without Duplicate column",
+			URL:         "/api/v0/console/diff",
+			JSONInput: helpers.M{
+				"start":        time.Date(2026, 10, 2, 10, 0, 0, 0, time.UTC),
+				"end":          time.Date(2026, 10, 2, 11, 0, 0, 0, time.UTC),
+				"dimensions":   []string{"SrcAS"},
+				"limit":        10,
+				"units":        "l3bps",
+				"deduplicated": true,
+			},
+			StatusCode: 400,
+			JSONOutput: helpers.M{"message": "Deduplicated results require the Duplicate column"},
+		},
+	})
+}
+
+func TestDiffHandlerDeduplicated(t *testing.T) {
+	c, h, mockConn, _ := NewMock(t, DefaultConfiguration())
+	c.d.Schema = schema.NewMock(t).EnableAllColumns()
+	mockConn.EXPECT().
+		Select(gomock.Any(), gomock.Any(), gomock.Cond(func(sql string) bool {
+			return strings.Contains(sql, "Duplicate = 0")
+		})).
+		Return(nil)
+
+	helpers.TestHTTPEndpoints(t, h.LocalAddr(), helpers.HTTPEndpointCases{
+		{
+			Description: "deduplicated",
+			URL:         "/api/v0/console/diff",
+			JSONInput: helpers.M{
+				"start":        time.Date(2026, 10, 2, 10, 0, 0, 0, time.UTC),
+				"end":          time.Date(2026, 10, 2, 11, 0, 0, 0, time.UTC),
+				"dimensions":   []string{"SrcAS"},
+				"limit":        10,
+				"units":        "l3bps",
+				"deduplicated": true,
+			},
+			JSONOutput: helpers.M{
+				"start":           "2026-10-02T10:00:00Z",
+				"end":             "2026-10-02T11:00:00Z",
+				"reference-start": "2026-10-02T09:00:00Z",
+				"reference-end":   "2026-10-02T10:00:00Z",
+				"rows":            []helpers.M{},
+			},
+		},
+	})
+}
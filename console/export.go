@@ -0,0 +1,286 @@
+// SPDX-FileCopyrightText: 2026 Free Mobile
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package console
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	"akvorado/common/helpers"
+	"akvorado/common/httpserver"
+	"akvorado/console/parquet"
+	"akvorado/console/query"
+)
+
+// exportFormat describes a format for exported graphs.
+type exportFormat struct {
+	contentType string
+	newWriter   func(w io.Writer, columns []parquet.Column) exportWriter
+}
+
+var exportFormats = map[string]exportFormat{
+	"csv":     {"text/csv; charset=utf-8", newCSVExportWriter},
+	"jsonl":   {"application/x-ndjson", newJSONLinesExportWriter},
+	"parquet": {"application/vnd.apache.parquet", newParquetExportWriter},
+}
+
+// exportWriter writes the rows of an export. Values are strings, integers or
+// timestamps, as described by the columns.
+type exportWriter interface {
+	Write(row []any) error
+	Close() error
+}
+
+// csvExportWriter writes rows as CSV, with a header.
+type csvExportWriter struct {
+	w       *csv.Writer
+	columns []parquet.Column
+	record  []string
+}
+
+func newCSVExportWriter(w io.Writer, columns []parquet.Column) exportWriter {
+	cw := &csvExportWriter{w: csv.NewWriter(w), columns: columns, record: make([]string, len(columns))}
+	for idx, column := range columns {
+		cw.record[idx] = column.Name
+	}
+	cw.w.Write(cw.record)
+	return cw
+}
+
+func (cw *csvExportWriter) Write(row []any) error {
+	if len(row) != len(cw.columns) {
+		return fmt.Errorf("got %d values for %d columns", len(row), len(cw.columns))
+	}
+	for idx, value := range row {
+		switch value := value.(type) {
+		case string:
+			cw.record[idx] = value
+		case int:
+			cw.record[idx] = strconv.Itoa(value)
+		case int64:
+			cw.record[idx] = strconv.FormatInt(value, 10)
+		case float64:
+			cw.record[idx] = strconv.FormatFloat(value, 'f', -1, 64)
+		case time.Time:
+			cw.record[idx] = value.UTC().Format(time.RFC3339)
+		default:
+			return fmt.Errorf("unsupported value %T for column %q", value, cw.columns[idx].Name)
+		}
+	}
+	return cw.w.Write(cw.record)
+}
+
+func (cw *csvExportWriter) Close() error {
+	cw.w.Flush()
+	return cw.w.Error()
+}
+
+// jsonLinesExportWriter writes rows as JSON objects, one per line. Keys are
+// kept in the order of the columns.
+type jsonLinesExportWriter struct {
+	w    *bufio.Writer
+	keys [][]byte
+}
+
+func newJSONLinesExportWriter(w io.Writer, columns []parquet.Column) exportWriter {
+	jw := &jsonLinesExportWriter{w: bufio.NewWriter(w), keys: make([][]byte, len(columns))}
+	for idx, column := range columns {
+		jw.keys[idx], _ = json.Marshal(column.Name)
+	}
+	return jw
+}
+
+func (jw *jsonLinesExportWriter) Write(row []any) error {
+	jw.w.WriteByte('{')
+	for idx, value := range row {
+		if idx > 0 {
+			jw.w.WriteByte(',')
+		}
+		encoded, err := json.Marshal(value)
+		if err != nil {
+			return err
+		}
+		jw.w.Write(jw.keys[idx])
+		jw.w.WriteByte(':')
+		jw.w.Write(encoded)
+	}
+	_, err := jw.w.WriteString("}\n")
+	return err
+}
+
+func (jw *jsonLinesExportWriter) Close() error {
+	return jw.w.Flush()
+}
+
+// newParquetExportWriter writes rows as a Parquet file. Rows are written by
+// row groups, the footer is written on close.
+func newParquetExportWriter(w io.Writer, columns []parquet.Column) exportWriter {
+	return parquet.NewWriter(w, columns)
+}
+
+// exportParameters extracts the format and the mode of an export from the
+// query string. The first allowed mode is the default one. On error, it writes
+// the response and returns false.
+func exportParameters(w http.ResponseWriter, req *http.Request, modes ...string) (string, string, bool) {
+	format := req.URL.Query().Get("format")
+	if format == "" {
+		format = "csv"
+	}
+	if _, ok := exportFormats[format]; !ok {
+		httpserver.WriteJSON(w, http.StatusBadRequest,
+			helpers.M{"message": fmt.Sprintf("Unknown export format %q.", format)})
+		return "", "", false
+	}
+	mode := req.URL.Query().Get("mode")
+	if mode == "" {
+		mode = modes[0]
+	}
+	for _, allowed := range modes {
+		if mode == allowed {
+			return format, mode, true
+		}
+	}
+	httpserver.WriteJSON(w, http.StatusBadRequest,
+		helpers.M{"message": fmt.Sprintf("Unknown export mode %q.", mode)})
+	return "", "", false
+}
+
+// startExport sets the headers for an export to be downloaded by a browser
+// and returns a writer for its rows.
+func startExport(w http.ResponseWriter, graph, mode, format string, start time.Time, columns []parquet.Column) exportWriter {
+	f := exportFormats[format]
+	w.Header().Set("Content-Type", f.contentType)
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="akvorado-%s-%s-%s.%s"`,
+		graph, mode, start.UTC().Format("20060102T150405"), format))
+	w.WriteHeader(http.StatusOK)
+	return f.newWriter(w, columns)
+}
+
+// exportColumns returns the columns for an export: the axis, the dimensions
+// and the provided integer columns.
+func exportColumns(dimensions []query.Column, values ...string) []parquet.Column {
+	columns := []parquet.Column{{Name: "axis", Type: parquet.String}}
+	for _, dimension := range dimensions {
+		columns = append(columns, parquet.Column{Name: dimension.String(), Type: parquet.String})
+	}
+	for _, value := range values {
+		columns = append(columns, parquet.Column{Name: value, Type: parquet.Int64})
+	}
+	return columns
+}
+
+// exportRow builds a row for an export from the axis name, the dimensions and
+// the provided values.
+func exportRow(axis string, dimensions int, row []string, values ...any) []any {
+	result := make([]any, 0, 1+dimensions+len(values))
+	result = append(result, axis)
+	for idx := range dimensions {
+		if idx < len(row) {
+			result = append(result, row[idx])
+		} else {
+			result = append(result, "")
+		}
+	}
+	return append(result, values...)
+}
+
+func (c *Component) graphLineExportHandlerFunc(w http.ResponseWriter, req *http.Request) {
+	ctx := c.t.Context(req.Context())
+	format, mode, ok := exportParameters(w, req, "rows", "series")
+	if !ok {
+		return
+	}
+	input, ok := c.bindGraphLineInput(w, req)
+	if !ok {
+		return
+	}
+	output, ok := c.graphLineOutput(ctx, w, input)
+	if !ok {
+		return
+	}
+
+	var columns []parquet.Column
+	if mode == "series" {
+		columns = append([]parquet.Column{{Name: "time", Type: parquet.Timestamp}},
+			exportColumns(input.Dimensions, "xps")...)
+	} else {
+		values := []string{"average", "min", "max", "last", "95th"}
+		if output.Total != nil {
+			values = append(values, "total")
+		}
+		columns = exportColumns(input.Dimensions, values...)
+	}
+	ew := startExport(w, "line", mode, format, input.Start, columns)
+	var err error
+	if mode == "series" {
+	outer:
+		for t, ts := range output.Time {
+			for i, row := range output.Rows {
+				err = ew.Write(append([]any{ts},
+					exportRow(output.AxisNames[output.Axis[i]], len(input.Dimensions), row,
+						output.Points[i][t])...))
+				if err != nil {
+					break outer
+				}
+			}
+		}
+	} else {
+		for i, row := range output.Rows {
+			values := []any{
+				output.Average[i], output.Min[i], output.Max[i],
+				output.Last[i], output.NinetyFivePercentile[i],
+			}
+			if output.Total != nil {
+				values = append(values, output.Total[i])
+			}
+			err = ew.Write(exportRow(output.AxisNames[output.Axis[i]], len(input.Dimensions), row, values...))
+			if err != nil {
+				break
+			}
+		}
+	}
+	if err == nil {
+		err = ew.Close()
+	}
+	if err != nil {
+		c.r.Err(err).Msg("unable to export line graph")
+	}
+}
+
+func (c *Component) graphSankeyExportHandlerFunc(w http.ResponseWriter, req *http.Request) {
+	ctx := c.t.Context(req.Context())
+	format, mode, ok := exportParameters(w, req, "rows")
+	if !ok {
+		return
+	}
+	input, ok := c.bindGraphSankeyInput(w, req)
+	if !ok {
+		return
+	}
+	output, ok := c.graphSankeyOutput(ctx, w, input)
+	if !ok {
+		return
+	}
+
+	ew := startExport(w, "sankey", mode, format, input.Start, exportColumns(input.Dimensions, "xps"))
+	var err error
+	for i, row := range output.Rows {
+		err = ew.Write(exportRow(output.AxisNames[output.Axis[i]], len(input.Dimensions), row, output.Xps[i]))
+		if err != nil {
+			break
+		}
+	}
+	if err == nil {
+		err = ew.Close()
+	}
+	if err != nil {
+		c.r.Err(err).Msg("unable to export sankey graph")
+	}
+}
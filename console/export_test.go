@@ -0,0 +1,202 @@
+// SPDX-FileCopyrightText: 2026 Free Mobile
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package console
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"testing"
+	"time"
+
+	"go.uber.org/mock/gomock"
+
+	"akvorado/common/helpers"
+	"akvorado/console/parquet"
+)
+
+func TestGraphLineExport(t *testing.T) {
+	_, h, mockConn, _ := NewMock(t, DefaultConfiguration())
+	base := time.Date(2022, 4, 10, 15, 45, 0, 0, time.UTC)
+	results := []struct {
+		Axis       uint8     `ch:"axis"`
+		Time       time.Time `ch:"time"`
+		Xps        float64   `ch:"xps"`
+		Dimensions []string  `ch:"dimensions"`
+	}{
+		{1, base, 1000, []string{"router1", "provider1"}},
+		{1, base, 100, []string{"Other", "Other"}},
+		{1, base.Add(time.Minute), 2000, []string{"router1", "provider1"}},
+		{1, base.Add(time.Minute), 200, []string{"Other", "Other"}},
+		{1, base.Add(2 * time.Minute), 1500, []string{"router1", "provider1"}},
+		{1, base.Add(2 * time.Minute), 300, []string{"Other", "Other"}},
+	}
+	mockConn.EXPECT().
+		Select(gomock.Any(), gomock.Any(), gomock.Any()).
+		SetArg(1, results).
+		Return(nil).
+		Times(3)
+
+	input := helpers.M{
+		"start":      time.Date(2022, 4, 10, 15, 45, 10, 0, time.UTC),
+		"end":        time.Date(2022, 4, 11, 15, 45, 10, 0, time.UTC),
+		"points":     100,
+		"limit":      20,
+		"dimensions": []string{"ExporterName", "InIfProvider"},
+		"filter":     "DstCountry = 'FR' AND SrcCountry = 'US'",
+		"units":      "l3bps",
+	}
+	helpers.TestHTTPEndpoints(t, h.LocalAddr(), helpers.HTTPEndpointCases{
+		{
+			Description: "rows as CSV",
+			URL:         "/api/v0/console/graph/line/export",
+			JSONInput:   input,
+			ContentType: "text/csv; charset=utf-8",
+			FirstLines: []string{
+				"axis,ExporterName,InIfProvider,average,min,max,last,95th,total",
+				"Direct,router1,provider1,1500,1000,2000,2000,1950,270000",
+				"Direct,Other,Other,200,100,300,200,290,36000",
+			},
+		}, {
+			Description: "time series as JSON lines",
+			URL:         "/api/v0/console/graph/line/export?format=jsonl&mode=series",
+			JSONInput:   input,
+			ContentType: "application/x-ndjson",
+			FirstLines: []string{
+				`{"time":"2022-04-10T15:45:00Z","axis":"Direct","ExporterName":"router1","InIfProvider":"provider1","xps":1000}`,
+				`{"time":"2022-04-10T15:45:00Z","axis":"Direct","ExporterName":"Other","InIfProvider":"Other","xps":100}`,
+				`{"time":"2022-04-10T15:46:00Z","axis":"Direct","ExporterName":"router1","InIfProvider":"provider1","xps":2000}`,
+				`{"time":"2022-04-10T15:46:00Z","axis":"Direct","ExporterName":"Other","InIfProvider":"Other","xps":200}`,
+			},
+		}, {
+			Description: "unknown format",
+			URL:         "/api/v0/console/graph/line/export?format=xlsx",
+			JSONInput:   input,
+			StatusCode:  400,
+			JSONOutput:  helpers.M{"message": `Unknown export format "xlsx".`},
+		}, {
+			Description: "unknown mode",
+			URL:         "/api/v0/console/graph/line/export?mode=links",
+			JSONInput:   input,
+			StatusCode:  400,
+			JSONOutput:  helpers.M{"message": `Unknown export mode "links".`},
+		},
+	})
+
+	// Check Parquet output and headers
+	payload, _ := json.Marshal(input)
+	resp, err := http.Post(fmt.Sprintf("http://%s/api/v0/console/graph/line/export?format=parquet", h.LocalAddr()),
+		"application/json", bytes.NewReader(payload))
+	if err != nil {
+		t.Fatalf("POST /graph/line/export error:\n%+v", err)
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(resp.Body)
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("POST /graph/line/export: got status code %d", resp.StatusCode)
+	}
+	if diff := helpers.Diff(resp.Header.Get("Content-Disposition"),
+		`attachment; filename="akvorado-line-rows-20220410T154510.parquet"`); diff != "" {
+		t.Errorf("POST /graph/line/export Content-Disposition (-got, +want):\n%s", diff)
+	}
+	if !bytes.HasPrefix(body, []byte("PAR1")) || !bytes.HasSuffix(body, []byte("PAR1")) {
+		t.Errorf("POST /graph/line/export did not return a Parquet file")
+	}
+}
+
+func TestGraphSankeyExport(t *testing.T) {
+	_, h, mockConn, _ := NewMock(t, DefaultConfiguration())
+	results := []struct {
+		Axis       uint8    `ch:"axis"`
+		Xps        float64  `ch:"xps"`
+		Dimensions []string `ch:"dimensions"`
+	}{
+		{1, 9000, []string{"AS100", "provider1"}},
+		{1, 7000, []string{"AS200", "provider1"}},
+		{2, 8000, []string{"AS300", "provider1"}},
+	}
+	mockConn.EXPECT().
+		Select(gomock.Any(), gomock.Any(), gomock.Any()).
+		SetArg(1, results).
+		Return(nil)
+
+	input := helpers.M{
+		"start":         time.Date(2022, 4, 10, 15, 45, 10, 0, time.UTC),
+		"end":           time.Date(2022, 4, 11, 15, 45, 10, 0, time.UTC),
+		"dimensions":    []string{"SrcAS", "InIfProvider"},
+		"limit":         10,
+		"units":         "l3bps",
+		"bidirectional": true,
+	}
+	helpers.TestHTTPEndpoints(t, h.LocalAddr(), helpers.HTTPEndpointCases{
+		{
+			Description: "rows as CSV",
+			URL:         "/api/v0/console/graph/sankey/export?format=csv",
+			JSONInput:   input,
+			ContentType: "text/csv; charset=utf-8",
+			FirstLines: []string{
+				"axis,SrcAS,InIfProvider,xps",
+				"Direct,AS100,provider1,9000",
+				"Direct,AS200,provider1,7000",
+				"Reverse,AS300,provider1,8000",
+			},
+		}, {
+			Description: "time series are not available",
+			URL:         "/api/v0/console/graph/sankey/export?mode=series",
+			JSONInput:   input,
+			StatusCode:  400,
+			JSONOutput:  helpers.M{"message": `Unknown export mode "series".`},
+		}, {
+			Description: "no dimension",
+			URL:         "/api/v0/console/graph/sankey/export",
+			JSONInput: helpers.M{
+				"start":      time.Date(2022, 4, 10, 15, 45, 10, 0, time.UTC),
+				"end":        time.Date(2022, 4, 11, 15, 45, 10, 0, time.UTC),
+				"dimensions": []string{},
+				"limit":      10,
+				"units":      "l3bps",
+			},
+			StatusCode: 400,
+			JSONOutput: helpers.M{"message": "At least one dimension is required."},
+		},
+	})
+}
+
+func TestCSVExportWriter(t *testing.T) {
+	var out bytes.Buffer
+	ew := newCSVExportWriter(&out, []parquet.Column{
+		{Name: "time", Type: parquet.Timestamp},
+		{Name: "name", Type: parquet.String},
+		{Name: "value", Type: parquet.Int64},
+	})
+	t1 := time.Date(2026, 10, 19, 10, 0, 0, 0, time.UTC)
+	for _, row := range [][]any{
+		{t1, "alpha", 1000},
+		{t1, "beta", int64(-4)},
+		{t1, "gamma", 1.5},
+	} {
+		if err := ew.Write(row); err != nil {
+			t.Fatalf("Write() error:\n%+v", err)
+		}
+	}
+	if err := ew.Write([]any{t1, "delta", uint8(1)}); err == nil {
+		t.Fatal("Write() with unsupported value did not error")
+	}
+	if err := ew.Write([]any{t1}); err == nil {
+		t.Fatal("Write() with missing values did not error")
+	}
+	if err := ew.Close(); err != nil {
+		t.Fatalf("Close() error:\n%+v", err)
+	}
+	expected := `time,name,value
+2026-10-19T10:00:00Z,alpha,1000
+2026-10-19T10:00:00Z,beta,-4
+2026-10-19T10:00:00Z,gamma,1.5
+`
+	if diff := helpers.Diff(out.String(), expected); diff != "" {
+		t.Fatalf("Write() (-got, +want):\n%s", diff)
+	}
+}
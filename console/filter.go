@@ -5,6 +5,7 @@ package console
 
 import (
 	"fmt"
+	"maps"
 	"net/http"
 	"slices"
 	"sort"
@@ -45,6 +46,28 @@ func mostUsedFirst() sb.OrderItem {
 	return sb.Order(sb.Function("COUNT", sb.Star())).Desc()
 }
 
+// recentValues builds a query returning the values of a column in the recent
+// flows matching the provided condition. The values are named after alias.
+func recentValues(column, alias string, where sb.Expr, prefix string, limit int) *sb.Query {
+	name := sb.Column(column)
+	return sb.Select(sb.Alias(name, alias)).
+		From(sb.Table("flows")).
+		Where(sb.And(recentFlows(10), where, matchPrefix(name, prefix))).
+		GroupBy(name).
+		OrderBy(mostUsedFirst()).
+		Limit(limit)
+}
+
+// symbolCompletions proposes the names the filter language accepts in place of
+// numeric values.
+func symbolCompletions(symbols []filter.Symbol, detail string) []filterCompletion {
+	completions := make([]filterCompletion, len(symbols))
+	for i, symbol := range symbols {
+		completions[i] = filterCompletion{Label: symbol.Name, Detail: detail}
+	}
+	return completions
+}
+
 // filterValidateHandlerInput describes the input for the /filter/validate endpoint.
 type filterValidateHandlerInput struct {
 	Filter string `json:"filter"`
@@ -72,8 +95,9 @@ func (c *Component) filterValidateHandlerFunc(w http.ResponseWriter, req *http.R
 	}
 	got, err := filter.Parse("", []byte(input.Filter),
 		filter.GlobalStore("meta", &filter.Meta{
-			Schema:   c.d.Schema,
-			Database: c.d.ClickHouseDB.DatabaseName(),
+			Schema:      c.d.Schema,
+			Database:    c.d.ClickHouseDB.DatabaseName(),
+			Definitions: c.filterDefinitions(req.Context()),
 		}))
 	if err == nil {
 		httpserver.WriteJSON(w, http.StatusOK, filterValidateHandlerOutput{
@@ -115,6 +139,27 @@ func (c *Component) filterCompleteHandlerFunc(w http.ResponseWriter, req *http.R
 		return
 	}
 
+	// Completions from the flows are restricted to the flows the user can see.
+	access := c.accessWhere(req.Context())
+	restricted := c.accessRestricted(req.Context())
+	// Completion queries are subject to the query limits. On error, the
+	// completions are incomplete. When a query is rejected, the rejection is
+	// sent to the client.
+	var rejection *queryRejection
+	selectCompletions := func(table string, results any, sqlQuery string) bool {
+		ctx, done, r := c.prepareQuery(ctx, table, sqlQuery)
+		if r != nil {
+			rejection = r
+			return false
+		}
+		defer done()
+		if err := c.d.ClickHouseDB.Conn.Select(ctx, results, sqlQuery); err != nil {
+			c.r.Err(err).Msg("unable to query database")
+			return false
+		}
+		return true
+	}
+	definitions := c.filterDefinitions(req.Context())
 	completions := []filterCompletion{}
 	switch input.What {
 	case "column":
@@ -127,6 +172,10 @@ func (c *Component) filterCompleteHandlerFunc(w http.ResponseWriter, req *http.R
 			if strings.HasPrefix(strings.ToLower(column.Name), strings.ToLower(input.Prefix)) {
 				columns = append(columns, column.Name)
 			}
+			// DSCP is derived from IPTos.
+			if column.Key == schema.ColumnIPTos && strings.HasPrefix("dscp", strings.ToLower(input.Prefix)) {
+				columns = append(columns, "DSCP")
+			}
 		}
 		sort.Strings(columns)
 		for _, column := range columns {
@@ -135,6 +184,13 @@ func (c *Component) filterCompleteHandlerFunc(w http.ResponseWriter, req *http.R
 				Detail: "column name",
 			})
 		}
+		// Macros can be used in place of a condition.
+		for _, name := range slices.Sorted(maps.Keys(definitions.Macros)) {
+			completions = append(completions, filterCompletion{
+				Label:  "@" + name,
+				Detail: "macro",
+			})
+		}
 	case "operator":
 		_, err := filter.Parse("",
 			fmt.Appendf(nil, "%s ", input.Column),
@@ -159,10 +215,32 @@ func (c *Component) filterCompleteHandlerFunc(w http.ResponseWriter, req *http.R
 						Label:  candidate,
 						Detail: "comparison operator",
 					})
+					if candidate == "HAS" {
+						completions = append(completions, filterCompletion{
+							Label:  "HAS ALL (",
+							Detail: "comparison operator",
+						}, filterCompletion{
+							Label:  "HAS ANY (",
+							Detail: "comparison operator",
+						})
+					}
 				}
 			}
 		}
 	case "value":
+		// Named sets can be used in place of a list. As some completions
+		// below handle the prefix internally, they are filtered here.
+		if operator := strings.ToUpper(input.Operator); operator == "IN" || operator == "NOTIN" {
+			for _, name := range slices.Sorted(maps.Keys(definitions.Sets)) {
+				label := "$" + name
+				if strings.HasPrefix(strings.ToLower(label), strings.ToLower(input.Prefix)) {
+					completions = append(completions, filterCompletion{
+						Label:  label,
+						Detail: "named set",
+					})
+				}
+			}
+		}
 		var column, detail string
 		inputColumn := strings.ToLower(input.Column)
 		otherColumns := c.filterComparableColumns(input.Column, input.Operator, input.Prefix)
@@ -197,6 +275,14 @@ func (c *Component) filterCompleteHandlerFunc(w http.ResponseWriter, req *http.R
 				Label:  "IPv6",
 				Detail: "ethernet type",
 			})
+		case "tcpflags":
+			completions = append(completions, symbolCompletions(filter.TCPFlags, "TCP flag")...)
+		case "dscp":
+			completions = append(completions, symbolCompletions(filter.DSCPs, "DSCP value")...)
+		case "icmpv4type":
+			completions = append(completions, symbolCompletions(filter.ICMPv4Types, "ICMPv4 type")...)
+		case "icmpv6type":
+			completions = append(completions, symbolCompletions(filter.ICMPv6Types, "ICMPv6 type")...)
 		case "proto":
 			// Do not complete from ClickHouse, we want a subset of options
 			completions = append(completions,
@@ -224,15 +310,16 @@ func (c *Component) filterCompleteHandlerFunc(w http.ResponseWriter, req *http.R
 				From(sb.Table("flows")).
 				Where(sb.And(
 					recentFlows(1),
+					access,
 					matchPrefix(sb.Column("label"), input.Prefix))).
 				GroupBy(column).
 				OrderBy(mostUsedFirst()).
 				Limit(input.Limit).
 				String()
-			if err := c.d.ClickHouseDB.Conn.Select(ctx, &results, sqlQuery); err != nil {
-				c.r.Err(err).Msg("unable to query database")
+			if !selectCompletions("flows", &results, sqlQuery) {
 				break
 			}
+			auditRows(ctx, len(results))
 			for _, result := range results {
 				completions = append(completions, filterCompletion{
 					Label:  result.Label,
@@ -252,7 +339,7 @@ func (c *Component) filterCompleteHandlerFunc(w http.ResponseWriter, req *http.R
 				return sb.Select(sb.Alias(
 					sb.Function("arrayJoin", sb.Function("arrayJoin", sb.Column(column))), "c")).
 					From(sb.Table("flows")).
-					Where(recentFlows(1)).
+					Where(sb.And(recentFlows(1), access)).
 					GroupBy(sb.Column("c")).
 					OrderBy(mostUsedFirst())
 			}
@@ -269,10 +356,10 @@ func (c *Component) filterCompleteHandlerFunc(w http.ResponseWriter, req *http.R
 				Where(sb.Function("startsWith", sb.Column("label"), sb.String(input.Prefix))).
 				Limit(input.Limit).
 				String()
-			if err := c.d.ClickHouseDB.Conn.Select(ctx, &results, sqlQuery); err != nil {
-				c.r.Err(err).Msg("unable to query database")
+			if !selectCompletions("flows", &results, sqlQuery) {
 				break
 			}
+			auditRows(ctx, len(results))
 			for _, result := range results {
 				completions = append(completions, filterCompletion{
 					Label:  result.Label,
@@ -302,6 +389,7 @@ func (c *Component) filterCompleteHandlerFunc(w http.ResponseWriter, req *http.R
 				From(sb.Table("flows")).
 				Where(sb.And(
 					recentFlows(1),
+					access,
 					sb.Op(sb.Column("detail"), "!=", sb.String("")),
 					matchPrefix(sb.Column("detail"), input.Prefix))).
 				GroupBy(column).
@@ -327,10 +415,10 @@ func (c *Component) filterCompleteHandlerFunc(w http.ResponseWriter, req *http.R
 					sb.Order(sb.Function("MIN", sb.Function("rowNumberInBlock")))).
 				Limit(input.Limit).
 				String()
-			if err := c.d.ClickHouseDB.Conn.Select(ctx, &results, sqlQuery); err != nil {
-				c.r.Err(err).Msg("unable to query database")
+			if !selectCompletions("flows", &results, sqlQuery) {
 				break
 			}
+			auditRows(ctx, len(results))
 			for _, result := range results {
 				completions = append(completions, filterCompletion{
 					Label:  result.Label,
@@ -365,6 +453,7 @@ func (c *Component) filterCompleteHandlerFunc(w http.ResponseWriter, req *http.R
 					sb.Op(sb.Column("Proto"), "IN", sb.Tuple(
 						sb.Uint(constants.ProtoTCP), sb.Uint(constants.ProtoUDP))),
 					recentFlows(1),
+					access,
 					sb.Op(sb.Column("detail"), "!=", sb.String("")),
 					matchPrefix(sb.Column("detail"), input.Prefix))).
 				GroupBy(column, sb.Column("Proto")).
@@ -393,10 +482,10 @@ func (c *Component) filterCompleteHandlerFunc(w http.ResponseWriter, req *http.R
 					sb.Order(sb.Function("MIN", sb.Function("rowNumberInBlock")))).
 				Limit(input.Limit).
 				String()
-			if err := c.d.ClickHouseDB.Select(ctx, &results, sqlQuery); err != nil {
-				c.r.Err(err).Msg("unable to query database")
+			if !selectCompletions("flows", &results, sqlQuery) {
 				break
 			}
+			auditRows(ctx, len(results))
 			for _, result := range results {
 				completions = append(completions, filterCompletion{
 					Label:  result.Label,
@@ -411,17 +500,23 @@ func (c *Component) filterCompleteHandlerFunc(w http.ResponseWriter, req *http.R
 				Attribute string `ch:"attribute"`
 			}{}
 			attribute := sb.Column(attributeName)
+			table := "networks"
 			sqlQuery := sb.Select(sb.Alias(attribute, "attribute")).
 				Distinct().
 				From(sb.Table("networks")).
 				Where(matchPrefix(attribute, input.Prefix)).
 				OrderBy(sb.Order(attribute)).
-				Limit(input.Limit).
-				String()
-			if err := c.d.ClickHouseDB.Conn.Select(ctx, &results, sqlQuery); err != nil {
-				c.r.Err(err).Msg("unable to query database")
+				Limit(input.Limit)
+			if restricted {
+				// The networks table is not restricted, use the recent flows.
+				table = "flows"
+				sqlQuery = recentValues(c.fixQueryColumnName(inputColumn), "attribute",
+					access, input.Prefix, input.Limit)
+			}
+			if !selectCompletions(table, &results, sqlQuery.String()) {
 				break
 			}
+			auditRows(ctx, len(results))
 			for _, result := range results {
 				completions = append(completions, filterCompletion{
 					Label:  result.Attribute,
@@ -447,6 +542,7 @@ func (c *Component) filterCompleteHandlerFunc(w http.ResponseWriter, req *http.R
 				From(sb.Table("flows")).
 				Where(sb.And(
 					recentFlows(1),
+					access,
 					sb.Op(sb.Column("Proto"), "=", sb.Uint(proto)),
 					matchPrefix(sb.Column("label"), input.Prefix))).
 				GroupBy(column).
@@ -473,11 +569,10 @@ func (c *Component) filterCompleteHandlerFunc(w http.ResponseWriter, req *http.R
 					sb.Order(sb.Function("MIN", sb.Function("rowNumberInBlock")))).
 				Limit(input.Limit).
 				String()
-			err := c.d.ClickHouseDB.Conn.Select(ctx, &results, sqlQuery)
-			if err != nil {
-				c.r.Err(err).Msg("unable to query database")
+			if !selectCompletions("flows", &results, sqlQuery) {
 				break
 			}
+			auditRows(ctx, len(results))
 			for _, result := range results {
 				completions = append(completions, filterCompletion{
 					Label:  result.Label,
@@ -505,6 +600,7 @@ func (c *Component) filterCompleteHandlerFunc(w http.ResponseWriter, req *http.R
 		if column != "" {
 			// Query "exporter" table
 			name := sb.Column(column)
+			table := "exporters"
 			sqlQuery := sb.Select(sb.Alias(name, "label")).
 				From(sb.Table("exporters")).
 				Where(matchPrefix(name, input.Prefix)).
@@ -512,15 +608,20 @@ func (c *Component) filterCompleteHandlerFunc(w http.ResponseWriter, req *http.R
 				OrderBy(
 					sb.Order(prefixPosition(name, input.Prefix)),
 					sb.Order(name)).
-				Limit(input.Limit).
-				String()
+				Limit(input.Limit)
+			if restricted {
+				// The exporters table is not restricted, use the recent flows.
+				table = "flows"
+				sqlQuery = recentValues(c.fixQueryColumnName(inputColumn), "label",
+					access, input.Prefix, input.Limit)
+			}
 			results := []struct {
 				Label string `ch:"label"`
 			}{}
-			if err := c.d.ClickHouseDB.Conn.Select(ctx, &results, sqlQuery); err != nil {
-				c.r.Err(err).Msg("unable to query database")
+			if !selectCompletions(table, &results, sqlQuery.String()) {
 				break
 			}
+			auditRows(ctx, len(results))
 			for _, result := range results {
 				completions = append(completions, filterCompletion{
 					Label:  result.Label,
@@ -547,15 +648,16 @@ func (c *Component) filterCompleteHandlerFunc(w http.ResponseWriter, req *http.R
 					From(sb.Table("flows")).
 					Where(sb.And(
 						recentFlows(10),
+						access,
 						sb.Function("startsWith",
 							sb.Column("attribute"), sb.String(input.Prefix)))).
 					OrderBy(sb.Order(name)).
 					Limit(input.Limit).
 					String()
-				if err := c.d.ClickHouseDB.Conn.Select(ctx, &results, sqlQuery); err != nil {
-					c.r.Err(err).Msg("unable to query database")
+				if !selectCompletions("flows", &results, sqlQuery) {
 					break
 				}
+				auditRows(ctx, len(results))
 				for _, result := range results {
 					completions = append(completions, filterCompletion{
 						Label:  result.Attribute,
@@ -568,6 +670,10 @@ func (c *Component) filterCompleteHandlerFunc(w http.ResponseWriter, req *http.R
 		completions = append(completions, otherColumns...)
 	}
 
+	if rejection != nil {
+		rejection.write(w)
+		return
+	}
 	filteredCompletions := []filterCompletion{}
 	for _, completion := range completions {
 		if strings.HasPrefix(strings.ToLower(completion.Label), strings.ToLower(input.Prefix)) {
@@ -0,0 +1,147 @@
+// SPDX-FileCopyrightText: 2026 Free Mobile
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package filter
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"slices"
+
+	sb "akvorado/common/sqlbuilder"
+)
+
+// Definitions contains the named sets and the macros a filter can reference.
+type Definitions struct {
+	// Sets maps a name to a list of values, written like between the
+	// parentheses of an IN operator. They are referenced as $name.
+	Sets map[string]string `json:"sets"`
+	// Macros maps a name to a filter expression. They are referenced as
+	// @name.
+	Macros map[string]string `json:"macros"`
+}
+
+// parseDefinition parses the content of a named set or a macro, starting from
+// the provided rule. It is set on initialization as the actions of the grammar
+// cannot refer to Parse directly.
+var parseDefinition func(filename string, content []byte, rule string, opts ...Option) (any, error)
+
+func init() {
+	parseDefinition = func(filename string, content []byte, rule string, opts ...Option) (any, error) {
+		return Parse(filename, content, append(opts, Entrypoint(rule))...)
+	}
+}
+
+var definitionNameRegexp = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// ValidDefinitionName tells if the provided name can be used for a named set
+// or a macro.
+func ValidDefinitionName(name string) bool {
+	return definitionNameRegexp.MatchString(name)
+}
+
+// Merge returns the definitions completed with the other ones. On conflict,
+// the other definitions win.
+func (d Definitions) Merge(other Definitions) Definitions {
+	merge := func(a, b map[string]string) map[string]string {
+		if len(b) == 0 {
+			return a
+		}
+		result := make(map[string]string, len(a)+len(b))
+		for k, v := range a {
+			result[k] = v
+		}
+		for k, v := range b {
+			result[k] = v
+		}
+		return result
+	}
+	return Definitions{
+		Sets:   merge(d.Sets, other.Sets),
+		Macros: merge(d.Macros, other.Macros),
+	}
+}
+
+// maxMacroExpansions is the maximum number of macros expanded while parsing a
+// filter. Without a limit, macros referencing other macros several times could
+// expand exponentially.
+const maxMacroExpansions = 100
+
+// setRules are the rules a named set can be parsed with.
+var setRules = []string{
+	"SetIPOrSubnet", "SetMAC", "SetASN", "SetTCPFlag", "SetDSCP",
+	"SetICMPType", "SetString", "SetUnsigned8", "SetUnsigned64",
+}
+
+// ValidateSet checks the content of a named set can be compared with at least
+// one kind of column.
+func ValidateSet(content string) error {
+	for _, rule := range setRules {
+		_, err := parseDefinition("", []byte(content), rule, GlobalStore("meta", &Meta{}))
+		if err == nil {
+			return nil
+		}
+	}
+	return errors.New("not a list of IP addresses, subnets, MAC addresses, AS numbers, TCP flags, DSCP values, ICMP types, strings or integers")
+}
+
+// expansions returns the counter of expanded macros, shared with the nested
+// parsers.
+func (c *current) expansions() *int {
+	expansions, ok := c.globalStore["expansions"].(*int)
+	if !ok {
+		expansions = new(int)
+		c.globalStore["expansions"] = expansions
+	}
+	return expansions
+}
+
+// expanding returns the macros being expanded, to detect loops.
+func (c *current) expanding() []string {
+	expanding, _ := c.globalStore["expanding"].([]string)
+	return expanding
+}
+
+// expandSet parses the values of a named set with the provided rule. The rule
+// depends on the column the set is compared with.
+func (c *current) expandSet(name string, rule string) ([]any, error) {
+	content, ok := c.meta().Definitions.Sets[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown named set $%s", name)
+	}
+	values, err := parseDefinition("$"+name, []byte(content), rule,
+		GlobalStore("meta", c.meta()),
+		GlobalStore("expanding", c.expanding()),
+		GlobalStore("expansions", c.expansions()))
+	if err != nil {
+		return nil, fmt.Errorf("in named set $%s, %s", name, HumanError(err))
+	}
+	return toSlice(values), nil
+}
+
+// expandMacro parses the expression of a macro. The macros it references are
+// expanded as well.
+func (c *current) expandMacro(name string) (sb.Expr, error) {
+	content, ok := c.meta().Definitions.Macros[name]
+	if !ok {
+		return sb.Expr{}, fmt.Errorf("unknown macro @%s", name)
+	}
+	expanding := c.expanding()
+	if slices.Contains(expanding, name) {
+		return sb.Expr{}, fmt.Errorf("macro @%s references itself", name)
+	}
+	expansions := c.expansions()
+	*expansions++
+	if *expansions > maxMacroExpansions {
+		return sb.Expr{}, fmt.Errorf("too many macro expansions (more than %d)", maxMacroExpansions)
+	}
+	expr, err := parseDefinition("@"+name, []byte(content), "Input",
+		GlobalStore("meta", c.meta()),
+		GlobalStore("expanding", append(slices.Clone(expanding), name)),
+		GlobalStore("expansions", expansions))
+	if err != nil {
+		return sb.Expr{}, fmt.Errorf("in macro @%s, %s", name, HumanError(err))
+	}
+	return sb.Parens(expr.(sb.Expr)), nil
+}
@@ -0,0 +1,206 @@
+// SPDX-FileCopyrightText: 2026 Free Mobile
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package filter
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"akvorado/common/helpers"
+	"akvorado/common/schema"
+	sb "akvorado/common/sqlbuilder"
+)
+
+var testDefinitions = Definitions{
+	Sets: map[string]string{
+		"cdn":       "192.0.2.0/24, 2001:db8::/48, 198.51.100.1",
+		"transit":   "AS174, AS1299, 3356",
+		"providers": `"cogent", 'lumen'`,
+		"web":       "80, 443",
+		"broken":    "192.0.2.0/24, AS174",
+	},
+	Macros: map[string]string{
+		"transit_customers": "InIfBoundary = external AND SrcAS IN $transit",
+		"from_cdn":          "SrcAddr IN $cdn -- CDN ranges",
+		"web_from_cdn":      "@from_cdn AND DstPort IN $web",
+		"loop":              "@loop2",
+		"loop2":             "Proto = 6 OR @loop",
+		"broken":            "Proto = 1000",
+		"unknown":           "@nothing",
+	},
+}
+
+func TestDefinitions(t *testing.T) {
+	cases := []struct {
+		Input   string
+		Output  string
+		MetaIn  Meta
+		MetaOut Meta
+	}{
+		{
+			Input:   `DstAddr IN $cdn`,
+			Output:  `(DstAddr IN (toIPv6('198.51.100.1')) OR DstAddr BETWEEN toIPv6('192.0.2.0') AND toIPv6('192.0.2.255') OR DstAddr BETWEEN toIPv6('2001:db8::') AND toIPv6('2001:db8:0:ffff:ffff:ffff:ffff:ffff'))`,
+			MetaOut: Meta{MainTableRequired: true},
+		},
+		{
+			Input:  `SrcAS NOTIN $transit`,
+			Output: `SrcAS NOT IN (174, 1299, 3356)`,
+		},
+		{
+			Input:  `InIfProvider IN $providers`,
+			Output: `InIfProvider IN ('cogent', 'lumen')`,
+		},
+		{
+			Input:  `@transit_customers`,
+			Output: `(InIfBoundary = 'external' AND SrcAS IN (174, 1299, 3356))`,
+		},
+		{
+			Input:   `@transit_customers`,
+			Output:  `(OutIfBoundary = 'external' AND DstAS IN (174, 1299, 3356))`,
+			MetaIn:  Meta{ReverseDirection: true},
+			MetaOut: Meta{ReverseDirection: true},
+		},
+		{
+			Input:   `NOT @web_from_cdn OR Proto = 17`,
+			Output:  `NOT (((SrcAddr IN (toIPv6('198.51.100.1')) OR SrcAddr BETWEEN toIPv6('192.0.2.0') AND toIPv6('192.0.2.255') OR SrcAddr BETWEEN toIPv6('2001:db8::') AND toIPv6('2001:db8:0:ffff:ffff:ffff:ffff:ffff'))) AND DstPort IN (80, 443)) OR Proto = 17`,
+			MetaOut: Meta{MainTableRequired: true},
+		},
+	}
+	for _, tc := range cases {
+		tc.MetaIn.Schema = schema.NewMock(t)
+		tc.MetaIn.Definitions = testDefinitions
+		tc.MetaOut.Schema = tc.MetaIn.Schema
+		tc.MetaOut.Definitions = testDefinitions
+		got, err := Parse("", []byte(tc.Input), GlobalStore("meta", &tc.MetaIn))
+		if err != nil {
+			t.Errorf("Parse(%q) error:\n%+v", tc.Input, err)
+			continue
+		}
+		sql := got.(sb.Expr).String()
+		if diff := helpers.Diff(sql, tc.Output); diff != "" {
+			t.Errorf("Parse(%q) (-got, +want):\n%s", tc.Input, diff)
+		}
+		checkWhereParses(t, sql)
+		if diff := helpers.Diff(tc.MetaIn, tc.MetaOut); diff != "" {
+			t.Errorf("Parse(%q) meta (-got, +want):\n%s", tc.Input, diff)
+		}
+	}
+}
+
+func TestInvalidDefinitions(t *testing.T) {
+	cases := []struct {
+		Input    string
+		Expected string
+	}{
+		{
+			Input:    `DstAddr IN $nothing`,
+			Expected: "at line 1, position 12: unknown named set $nothing",
+		},
+		{
+			Input:    `Proto = 6 AND DstAddr IN $broken`,
+			Expected: "at line 1, position 26: in named set $broken, at line 1, position 16: no match found, expected: ![A-Za-z_], \"/\" or [0-9A-Fa-f:.]",
+		},
+		{
+			Input:    `DstAddr IN $transit`,
+			Expected: "at line 1, position 12: in named set $transit, at line 1, position 2: no match found, expected: ![A-Za-z_], \"/\" or [0-9A-Fa-f:.]",
+		},
+		{
+			Input:    `Proto = 6 AND @nothing`,
+			Expected: "at line 1, position 15: unknown macro @nothing",
+		},
+		{
+			Input:    `@unknown`,
+			Expected: "at line 1, position 1: in macro @unknown, at line 1, position 1: unknown macro @nothing",
+		},
+		{
+			Input:    `Proto = 17 OR @broken`,
+			Expected: "at line 1, position 15: in macro @broken, at line 1, position 9: expecting an unsigned 8-bit integer",
+		},
+		{
+			Input:    `@loop`,
+			Expected: "at line 1, position 1: in macro @loop, at line 1, position 1: in macro @loop2, at line 1, position 14: macro @loop references itself",
+		},
+	}
+	for _, tc := range cases {
+		_, err := Parse("", []byte(tc.Input), GlobalStore("meta", &Meta{
+			Schema:      schema.NewMock(t),
+			Definitions: testDefinitions,
+		}))
+		if err == nil {
+			t.Errorf("Parse(%q) didn't throw an error", tc.Input)
+			continue
+		}
+		if diff := helpers.Diff(HumanError(err), tc.Expected); diff != "" {
+			t.Errorf("Parse(%q) error (-got, +want):\n%s", tc.Input, diff)
+		}
+	}
+}
+
+func TestMacroExpansionsLimit(t *testing.T) {
+	// Each macro references the previous one twice.
+	definitions := Definitions{Macros: map[string]string{"m0": "Proto = 6"}}
+	for i := 1; i <= 7; i++ {
+		definitions.Macros[fmt.Sprintf("m%d", i)] = fmt.Sprintf("@m%d OR @m%d", i-1, i-1)
+	}
+	meta := Meta{Schema: schema.NewMock(t), Definitions: definitions}
+	if _, err := Parse("", []byte("@m5"), GlobalStore("meta", &meta)); err != nil {
+		t.Fatalf("Parse(%q) error:\n%+v", "@m5", err)
+	}
+	_, err := Parse("", []byte("@m7"), GlobalStore("meta", &meta))
+	if err == nil {
+		t.Fatalf("Parse(%q) didn't throw an error", "@m7")
+	}
+	if !strings.Contains(HumanError(err), "too many macro expansions (more than 100)") {
+		t.Fatalf("Parse(%q) error:\n%s", "@m7", HumanError(err))
+	}
+}
+
+func TestValidateSet(t *testing.T) {
+	for content, valid := range map[string]bool{
+		"192.0.2.0/24, 2001:db8::/48": true,
+		"AS174, AS1299":               true,
+		`"cogent", 'lumen'`:           true,
+		"80, 443":                     true,
+		"00:11:22:33:44:55":           true,
+		"192.0.2.0/24, AS174":         false,
+		"(80, 443)":                   false,
+		"":                            false,
+	} {
+		if err := ValidateSet(content); (err == nil) != valid {
+			t.Errorf("ValidateSet(%q) == %v, expected valid: %v", content, err, valid)
+		}
+	}
+}
+
+func TestDefinitionsMerge(t *testing.T) {
+	global := Definitions{
+		Sets:   map[string]string{"cdn": "192.0.2.0/24", "transit": "AS174"},
+		Macros: map[string]string{"web": "DstPort = 443"},
+	}
+	user := Definitions{
+		Sets: map[string]string{"cdn": "198.51.100.0/24"},
+	}
+	expected := Definitions{
+		Sets:   map[string]string{"cdn": "198.51.100.0/24", "transit": "AS174"},
+		Macros: map[string]string{"web": "DstPort = 443"},
+	}
+	if diff := helpers.Diff(global.Merge(user), expected); diff != "" {
+		t.Errorf("Merge() (-got, +want):\n%s", diff)
+	}
+
+	for name, expected := range map[string]bool{
+		"cdn":               true,
+		"transit_customers": true,
+		"_private":          true,
+		"tier1":             true,
+		"1tier":             false,
+		"tier-1":            false,
+		"":                  false,
+	} {
+		if got := ValidDefinitionName(name); got != expected {
+			t.Errorf("ValidDefinitionName(%q) == %v, expected %v", name, got, expected)
+		}
+	}
+}
@@ -23,6 +23,8 @@ type Meta struct {
 	Database string
 	// ReverseDirection tells if we require the reverse direction for the provided filter (used as input)
 	ReverseDirection bool
+	// Definitions are the named sets and macros the filter can reference (used as input)
+	Definitions Definitions
 	// MainTableRequired tells if the main table is required to execute the expression (used as output)
 	MainTableRequired bool
 }
@@ -154,6 +156,26 @@ func (c *current) columnIsOfType(name any, types ...string) (bool, error) {
 	return false, nil
 }
 
+// columnIsOneOf returns true if the column is one of the provided columns. It
+// should be used in predicate code blocks.
+func (c *current) columnIsOneOf(name any, keys ...schema.ColumnKey) (bool, error) {
+	columnName := name.(string)
+	sch := c.meta().Schema
+	for _, column := range sch.Columns() {
+		if strings.EqualFold(columnName, column.Name) {
+			return slices.Contains(keys, column.Key), nil
+		}
+	}
+	return false, nil
+}
+
+// columnEnabled returns true if the provided column is enabled. It should be
+// used in predicate code blocks.
+func (c *current) columnEnabled(key schema.ColumnKey) (bool, error) {
+	column, ok := c.meta().Schema.LookupColumnByKey(key)
+	return ok && !column.Disabled, nil
+}
+
 // getColumn gets a column by its name.
 func (c *current) getColumn(name string) schema.Column {
 	sch := c.meta().Schema
@@ -230,6 +252,44 @@ func (c *current) ipOrSubnetCondition(col schema.Column, operator string, items
 	return expr, nil
 }
 
+// tcpFlagsCondition checks the provided TCP flags. With all, each flag should
+// be set. Otherwise, one of them is enough.
+func (c *current) tcpFlagsCondition(col schema.Column, flags []any, all bool) sb.Expr {
+	var mask uint64
+	for _, flag := range flags {
+		value, _ := flag.(uint64)
+		mask |= value
+	}
+	masked := sb.Function("bitAnd", c.column(col), sb.Uint(mask))
+	if all {
+		return sb.Op(masked, "=", sb.Uint(mask))
+	}
+	return sb.Op(masked, "!=", sb.Uint(0))
+}
+
+// dscp extracts the DSCP value from the IPTos column.
+func (c *current) dscp(col schema.Column) sb.Expr {
+	return sb.Function("bitShiftRight", c.column(col), sb.Uint(2))
+}
+
+// icmpTypes turns ICMP type names into their values. The names depend on the
+// version of ICMP the column is about.
+func (c *current) icmpTypes(col schema.Column, names []any) ([]sb.Expr, error) {
+	symbols := ICMPv4Types
+	if col.Key == schema.ColumnICMPv6Type {
+		symbols = ICMPv6Types
+	}
+	exprs := make([]sb.Expr, len(names))
+	for i, name := range names {
+		value, ok := lookupSymbol(symbols, toString(name))
+		if !ok {
+			return nil, fmt.Errorf("unknown ICMP type %q", toString(name))
+		}
+		exprs[i] = sb.Uint(value)
+	}
+	return exprs, nil
+}
+
 // protocolName turns a protocol number into its name, so a filter can compare
 // it with a string. An unknown protocol becomes "???", like in the columns the
 // console displays. The dictionary is qualified with the database it lives in,
@@ -462,6 +462,22 @@ output provider */ = 'telia'`,
 			Input: `DstPortNAT = 22`, Output: `DstPortNAT = 22`,
 			MetaOut: Meta{MainTableRequired: true},
 		},
+		{
+			Input: `SrcAddrTunnel = 203.0.113.4`, Output: `SrcAddrTunnel = toIPv6('203.0.113.4')`,
+			MetaOut: Meta{MainTableRequired: true},
+		},
+		{
+			Input: `DstAddrTunnel << 2001:db8::/32`, Output: `DstAddrTunnel BETWEEN toIPv6('2001:db8::') AND toIPv6('2001:db8:ffff:ffff:ffff:ffff:ffff:ffff')`,
+			MetaOut: Meta{MainTableRequired: true},
+		},
+		{
+			Input: `TunnelProto = 47`, Output: `TunnelProto = 47`,
+			MetaOut: Meta{MainTableRequired: true},
+		},
+		{
+			Input: `TunnelID = 100`, Output: `TunnelID = 100`,
+			MetaOut: Meta{MainTableRequired: true},
+		},
 		{Input: `SrcMAC = 00:11:22:33:44:55`, Output: `SrcMAC = MACStringToNum('00:11:22:33:44:55')`},
 		{Input: `DstMAC = 00:11:22:33:44:55`, Output: `DstMAC = MACStringToNum('00:11:22:33:44:55')`},
 		{Input: `SrcMAC != 00:0c:fF:33:44:55`, Output: `SrcMAC != MACStringToNum('00:0c:ff:33:44:55')`},
@@ -487,6 +503,21 @@ output provider */ = 'telia'`,
 		{Input: `icmpv4type = 8 AND icmpv4code = 0`, Output: `ICMPv4Type = 8 AND ICMPv4Code = 0`},
 		{Input: `icmpv6type = 8 or icmpv6code = 0`, Output: `ICMPv6Type = 8 OR ICMPv6Code = 0`},
 		{Input: `icmpv6 = "echo-reply"`, Output: `ICMPv6 = 'echo-reply'`},
+		{Input: `TCPFlags HAS SYN`, Output: `bitAnd(TCPFlags, 2) = 2`},
+		{Input: `tcpflags has rst`, Output: `bitAnd(TCPFlags, 4) = 4`},
+		{Input: `TCPFlags HAS ALL (SYN, ACK)`, Output: `bitAnd(TCPFlags, 18) = 18`},
+		{Input: `TCPFlags HAS ANY (FIN, RST, NS)`, Output: `bitAnd(TCPFlags, 261) != 0`},
+		{Input: `NOT TCPFlags HAS ACK`, Output: `NOT (bitAnd(TCPFlags, 16) = 16)`},
+		{Input: `DSCP = EF`, Output: `bitShiftRight(IPTos, 2) = 46`},
+		{Input: `dscp != af41`, Output: `bitShiftRight(IPTos, 2) != 34`},
+		{Input: `DSCP = 10`, Output: `bitShiftRight(IPTos, 2) = 10`},
+		{Input: `DSCP IN (CS1, LE, 0)`, Output: `bitShiftRight(IPTos, 2) IN (8, 1, 0)`},
+		{Input: `ICMPv4Type = echo-request`, Output: `ICMPv4Type = 8`},
+		{Input: `ICMPv6Type != echo-request`, Output: `ICMPv6Type != 128`},
+		{
+			Input:  `ICMPv4Type IN (echo-request, echo-reply)`,
+			Output: `ICMPv4Type IN (8, 0)`,
+		},
 		{Input: `SrcAddrDimensionAttribute = "Test"`, Output: `SrcAddrDimensionAttribute = 'Test'`},
 		{Input: `DstAddrDimensionAttribute = "Test"`, Output: `DstAddrDimensionAttribute = 'Test'`},
 		{Input: `DstAddrRole = "Test"`, Output: `DstAddrRole = 'Test'`},
@@ -498,8 +529,15 @@ output provider */ = 'telia'`,
 		{Input: `MPLS1stLabel = 76876`, Output: `MPLS1stLabel = 76876`, MetaOut: Meta{MainTableRequired: true}},
 		{Input: `MPLS2ndLabel > 76876`, Output: `MPLS2ndLabel > 76876`, MetaOut: Meta{MainTableRequired: true}},
 		{Input: `MPLS3rdLabel < 76876`, Output: `MPLS3rdLabel < 76876`, MetaOut: Meta{MainTableRequired: true}},
+		{Input: `ServiceName = "https"`, Output: `ServiceName = 'https'`},
+		{Input: `DstTier > 2`, Output: `DstTier > 2`},
+		{Input: `SrcTier = 1`, Output: `DstTier = 1`, MetaIn: Meta{ReverseDirection: true}, MetaOut: Meta{ReverseDirection: true}},
 	}
 	config := schema.DefaultConfiguration()
+	config.ComputedColumns = []schema.ComputedColumn{
+		{Name: "ServiceName", Type: "String"},
+		{Name: "SrcTier", Type: "UInt8"},
+	}
 	config.CustomDictionaries = make(map[string]schema.CustomDict)
 	config.CustomDictionaries["test"] = schema.CustomDict{
 		Keys: []schema.CustomDictKey{
@@ -674,6 +712,12 @@ func TestInvalidFilter(t *testing.T) {
 		{Input: `SrcAS IN (AS12322, 29447`},
 		{Input: `SrcAS IN (AS12322 29447)`},
 		{Input: `SrcAS IN (AS12322,`},
+		{Input: `TCPFlags HAS XMAS`},
+		{Input: `TCPFlags HAS ALL (SYN ACK)`},
+		{Input: `DSCP = 64`},
+		{Input: `DSCP = AF44`},
+		{Input: `ICMPv4Type = neighbor-solicitation`},
+		{Input: `ICMPv6Type IN (echo-request, source-quench)`},
 		{Input: `SrcVlan = 1000`},
 		{Input: `DstVlan = 1000`},
 		{Input: `SrcMAC = 00:11:22:33:44:55:66`, EnableAll: true},
@@ -0,0 +1,101 @@
+// SPDX-FileCopyrightText: 2026 Free Mobile
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package filter
+
+import "strings"
+
+// Symbol is a name the filter language accepts in place of a numeric value.
+type Symbol struct {
+	Name  string
+	Value uint64
+}
+
+// TCPFlags are the names of the TCP flags. The value is the mask of the flag in
+// the TCPFlags column.
+var TCPFlags = []Symbol{
+	{"FIN", 1 << 0},
+	{"SYN", 1 << 1},
+	{"RST", 1 << 2},
+	{"PSH", 1 << 3},
+	{"ACK", 1 << 4},
+	{"URG", 1 << 5},
+	{"ECE", 1 << 6},
+	{"CWR", 1 << 7},
+	{"NS", 1 << 8},
+}
+
+// DSCPs are the names of the well-known DSCP values (RFC 4594, RFC 5865 and
+// RFC 8622).
+var DSCPs = []Symbol{
+	{"CS0", 0},
+	{"LE", 1},
+	{"CS1", 8},
+	{"AF11", 10},
+	{"AF12", 12},
+	{"AF13", 14},
+	{"CS2", 16},
+	{"AF21", 18},
+	{"AF22", 20},
+	{"AF23", 22},
+	{"CS3", 24},
+	{"AF31", 26},
+	{"AF32", 28},
+	{"AF33", 30},
+	{"CS4", 32},
+	{"AF41", 34},
+	{"AF42", 36},
+	{"AF43", 38},
+	{"CS5", 40},
+	{"VA", 44},
+	{"EF", 46},
+	{"CS6", 48},
+	{"CS7", 56},
+}
+
+// ICMPv4Types are the names of the ICMPv4 types.
+var ICMPv4Types = []Symbol{
+	{"echo-reply", 0},
+	{"destination-unreachable", 3},
+	{"source-quench", 4},
+	{"redirect", 5},
+	{"echo-request", 8},
+	{"router-advertisement", 9},
+	{"router-solicitation", 10},
+	{"time-exceeded", 11},
+	{"parameter-problem", 12},
+	{"timestamp-request", 13},
+	{"timestamp-reply", 14},
+	{"information-request", 15},
+	{"information-reply", 16},
+	{"address-mask-request", 17},
+	{"address-mask-reply", 18},
+}
+
+// ICMPv6Types are the names of the ICMPv6 types.
+var ICMPv6Types = []Symbol{
+	{"destination-unreachable", 1},
+	{"packet-too-big", 2},
+	{"time-exceeded", 3},
+	{"parameter-problem", 4},
+	{"echo-request", 128},
+	{"echo-reply", 129},
+	{"multicast-query", 130},
+	{"multicast-report", 131},
+	{"multicast-done", 132},
+	{"router-solicitation", 133},
+	{"router-advertisement", 134},
+	{"neighbor-solicitation", 135},
+	{"neighbor-advertisement", 136},
+	{"redirect", 137},
+}
+
+// lookupSymbol returns the value of the provided name, whatever its case.
+func lookupSymbol(symbols []Symbol, name string) (uint64, bool) {
+	for _, symbol := range symbols {
+		if strings.EqualFold(symbol.Name, name) {
+			return symbol.Value, true
+		}
+	}
+	return 0, false
+}
@@ -0,0 +1,196 @@
+// SPDX-FileCopyrightText: 2026 Free Mobile
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package console
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"akvorado/common/helpers"
+	"akvorado/common/httpserver"
+	"akvorado/console/authentication"
+	"akvorado/console/database"
+	"akvorado/console/filter"
+)
+
+// newFilterDefinitions validates the named sets and macros from the
+// configuration.
+func (c *Component) newFilterDefinitions() error {
+	for name, content := range c.config.FilterDefinitions.Sets {
+		if !filter.ValidDefinitionName(name) {
+			return fmt.Errorf("invalid name for named set %q", name)
+		}
+		if err := filter.ValidateSet(content); err != nil {
+			return fmt.Errorf("invalid named set $%s: %w", name, err)
+		}
+	}
+	for name, content := range c.config.FilterDefinitions.Macros {
+		if !filter.ValidDefinitionName(name) {
+			return fmt.Errorf("invalid name for macro %q", name)
+		}
+		if err := c.validateMacro(content, c.config.FilterDefinitions); err != nil {
+			return fmt.Errorf("invalid macro @%s: %w", name, err)
+		}
+	}
+	return nil
+}
+
+// validateMacro checks the expression of a macro is valid, including the named
+// sets and macros it references.
+func (c *Component) validateMacro(content string, definitions filter.Definitions) error {
+	_, err := filter.Parse("", []byte(content),
+		filter.GlobalStore("meta", &filter.Meta{
+			Schema:      c.d.Schema,
+			Database:    c.d.ClickHouseDB.DatabaseName(),
+			Definitions: definitions,
+		}))
+	if err != nil {
+		return errors.New(filter.HumanError(err))
+	}
+	return nil
+}
+
+// filterDefinitionsContextKey is the key under which the filter definitions of
+// the current user are stored in the request context.
+type filterDefinitionsContextKey struct{}
+
+// filterDefinitionsMiddleware is a middleware to retrieve the named sets and
+// macros of the current user. It should be used after accessControl and only
+// on routes parsing filters. Cached responses are only shared between requests
+// using the same definitions.
+func (c *Component) filterDefinitionsMiddleware() httpserver.Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			user := authentication.UserFromContext(req.Context()).Login
+			definitions, err := c.d.Database.ListFilterDefinitions(req.Context(), user)
+			if err != nil {
+				c.r.Err(err).Str("user", user).Msg("unable to retrieve filter definitions")
+			} else if len(definitions) > 0 {
+				merged := c.config.FilterDefinitions.Merge(userFilterDefinitions(definitions))
+				ctx := context.WithValue(req.Context(), filterDefinitionsContextKey{}, merged)
+				scope := fmt.Sprintf("definitions-%s", filterDefinitionsHash(merged))
+				if current := httpserver.CacheScope(ctx); current != "" {
+					scope = fmt.Sprintf("%s-%s", current, scope)
+				}
+				req = req.WithContext(httpserver.WithCacheScope(ctx, scope))
+			}
+			next.ServeHTTP(w, req)
+		})
+	}
+}
+
+// filterDefinitionsHash returns a hash of the provided definitions.
+func filterDefinitionsHash(definitions filter.Definitions) string {
+	// Maps are encoded with sorted keys.
+	encoded, _ := json.Marshal(definitions)
+	sum := sha256.Sum256(encoded)
+	return hex.EncodeToString(sum[:8])
+}
+
+// userFilterDefinitions turns the filter definitions of a user into
+// definitions for the filter parser.
+func userFilterDefinitions(definitions []database.FilterDefinition) filter.Definitions {
+	result := filter.Definitions{
+		Sets:   map[string]string{},
+		Macros: map[string]string{},
+	}
+	for _, definition := range definitions {
+		switch definition.Kind {
+		case "set":
+			result.Sets[definition.Name] = definition.Content
+		case "macro":
+			result.Macros[definition.Name] = definition.Content
+		}
+	}
+	return result
+}
+
+// filterDefinitions returns the named sets and macros the current user can
+// reference in filters.
+func (c *Component) filterDefinitions(ctx context.Context) filter.Definitions {
+	if definitions, ok := ctx.Value(filterDefinitionsContextKey{}).(filter.Definitions); ok {
+		return definitions
+	}
+	return c.config.FilterDefinitions
+}
+
+func (c *Component) filterDefinitionsListHandlerFunc(w http.ResponseWriter, req *http.Request) {
+	ctx := c.t.Context(req.Context())
+	user := authentication.UserFromContext(req.Context()).Login
+	definitions, err := c.d.Database.ListFilterDefinitions(ctx, user)
+	if err != nil {
+		c.r.Err(err).Msg("unable to list filter definitions")
+		httpserver.WriteJSON(w, http.StatusInternalServerError, helpers.M{"message": "unable to list filter definitions"})
+		return
+	}
+	httpserver.WriteJSON(w, http.StatusOK, helpers.M{
+		"global":      c.config.FilterDefinitions,
+		"definitions": definitions,
+	})
+}
+
+func (c *Component) filterDefinitionsSetHandlerFunc(w http.ResponseWriter, req *http.Request) {
+	ctx := c.t.Context(req.Context())
+	user := authentication.UserFromContext(req.Context()).Login
+	var definition database.FilterDefinition
+	if err := httpserver.BindJSON(req, &definition); err != nil {
+		httpserver.WriteJSON(w, http.StatusBadRequest, helpers.M{"message": helpers.Capitalize(err.Error())})
+		return
+	}
+	if !filter.ValidDefinitionName(definition.Name) {
+		httpserver.WriteJSON(w, http.StatusBadRequest, helpers.M{"message": fmt.Sprintf("Invalid name %q.", definition.Name)})
+		return
+	}
+	if definition.Kind == "set" {
+		if err := filter.ValidateSet(definition.Content); err != nil {
+			httpserver.WriteJSON(w, http.StatusBadRequest, helpers.M{"message": fmt.Sprintf("Invalid named set: %s.", err)})
+			return
+		}
+	}
+	if definition.Kind == "macro" {
+		definitions := c.filterDefinitions(req.Context()).Merge(filter.Definitions{
+			Macros: map[string]string{definition.Name: definition.Content},
+		})
+		if err := c.validateMacro(definition.Content, definitions); err != nil {
+			httpserver.WriteJSON(w, http.StatusBadRequest, helpers.M{"message": fmt.Sprintf("Invalid macro: %s.", err)})
+			return
+		}
+	}
+	definition.User = user
+	definition, err := c.d.Database.SetFilterDefinition(ctx, definition)
+	if err != nil {
+		c.r.Err(err).Msg("cannot set filter definition")
+		httpserver.WriteJSON(w, http.StatusInternalServerError, helpers.M{"message": "Cannot set filter definition."})
+		return
+	}
+	httpserver.WriteJSON(w, http.StatusOK, helpers.M{"id": definition.ID})
+}
+
+func (c *Component) filterDefinitionsDeleteHandlerFunc(w http.ResponseWriter, req *http.Request) {
+	ctx := c.t.Context(req.Context())
+	user := authentication.UserFromContext(req.Context()).Login
+	id, err := strconv.ParseUint(req.PathValue("id"), 10, 64)
+	if err != nil {
+		httpserver.WriteJSON(w, http.StatusBadRequest, helpers.M{"message": "bad ID format"})
+		return
+	}
+	if err := c.d.Database.DeleteFilterDefinition(ctx, database.FilterDefinition{
+		ID:   id,
+		User: user,
+	}); errors.Is(err, database.ErrFilterDefinitionNotFound) {
+		httpserver.WriteJSON(w, http.StatusNotFound, helpers.M{"message": "filter definition not found"})
+		return
+	} else if err != nil {
+		c.r.Err(err).Msg("cannot delete filter definition")
+		httpserver.WriteJSON(w, http.StatusInternalServerError, helpers.M{"message": "cannot delete filter definition"})
+		return
+	}
+	httpserver.WriteJSON(w, http.StatusNoContent, nil)
+}
@@ -0,0 +1,214 @@
+// SPDX-FileCopyrightText: 2026 Free Mobile
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package console
+
+import (
+	"net/http"
+	"testing"
+
+	"akvorado/common/clickhousedb"
+	"akvorado/common/daemon"
+	"akvorado/common/helpers"
+	"akvorado/common/reporter"
+	"akvorado/common/schema"
+	"akvorado/console/filter"
+)
+
+func TestFilterDefinitionsInvalid(t *testing.T) {
+	cases := []struct {
+		Description string
+		Definitions filter.Definitions
+		Expected    string
+	}{
+		{
+			Description: "invalid set name",
+			Definitions: filter.Definitions{Sets: map[string]string{"tier-1": "AS174"}},
+			Expected:    `invalid name for named set "tier-1"`,
+		}, {
+			Description: "invalid macro name",
+			Definitions: filter.Definitions{Macros: map[string]string{"1web": "DstPort = 443"}},
+			Expected:    `invalid name for macro "1web"`,
+		}, {
+			Description: "invalid named set",
+			Definitions: filter.Definitions{Sets: map[string]string{"tier1": "AS174, 192.0.2.1"}},
+			Expected:    "invalid named set $tier1: not a list of IP addresses, subnets, MAC addresses, AS numbers, TCP flags, DSCP values, ICMP types, strings or integers",
+		}, {
+			Description: "invalid macro",
+			Definitions: filter.Definitions{Macros: map[string]string{"tier1": "SrcAS IN $tier1"}},
+			Expected:    "invalid macro @tier1: at line 1, position 10: unknown named set $tier1",
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.Description, func(t *testing.T) {
+			r := reporter.NewMock(t)
+			ch, _ := clickhousedb.NewMock(t, r)
+			config := DefaultConfiguration()
+			config.FilterDefinitions = tc.Definitions
+			_, err := New(r, config, Dependencies{
+				Daemon:       daemon.NewMock(t),
+				ClickHouseDB: ch,
+				Schema:       schema.NewMock(t),
+			})
+			if err == nil {
+				t.Fatal("New() did not error")
+			}
+			if diff := helpers.Diff(err.Error(), tc.Expected); diff != "" {
+				t.Fatalf("New() error (-got, +want):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestFilterDefinitionsHandlers(t *testing.T) {
+	config := DefaultConfiguration()
+	config.FilterDefinitions = filter.Definitions{
+		Sets:   map[string]string{"transit": "AS174, AS1299"},
+		Macros: map[string]string{"from_transit": "SrcAS IN $transit"},
+	}
+	_, h, _, _ := NewMock(t, config)
+	alfred := make(http.Header)
+	alfred.Add("Remote-User", "alfred")
+
+	helpers.TestHTTPEndpoints(t, h.LocalAddr(), helpers.HTTPEndpointCases{
+		{
+			Description: "validate with a global macro",
+			URL:         "/api/v0/console/filter/validate",
+			JSONInput:   helpers.M{"filter": `@from_transit AND DstPort = 443`},
+			JSONOutput: helpers.M{
+				"message": "ok",
+				"parsed":  `(SrcAS IN (174, 1299)) AND DstPort = 443`,
+			},
+		}, {
+			Description: "validate with an unknown named set",
+			URL:         "/api/v0/console/filter/validate",
+			JSONInput:   helpers.M{"filter": `DstAddr IN $cdn`},
+			JSONOutput: helpers.M{
+				"message": "at line 1, position 12: unknown named set $cdn",
+				"errors": []helpers.M{{
+					"line":    1,
+					"column":  12,
+					"offset":  11,
+					"message": "unknown named set $cdn",
+				}},
+			},
+		}, {
+			Description: "list, no user definitions",
+			URL:         "/api/v0/console/filter/definitions",
+			JSONOutput: helpers.M{
+				"global": helpers.M{
+					"sets":   helpers.M{"transit": "AS174, AS1299"},
+					"macros": helpers.M{"from_transit": "SrcAS IN $transit"},
+				},
+				"definitions": []helpers.M{},
+			},
+		}, {
+			Description: "set a named set",
+			URL:         "/api/v0/console/filter/definitions",
+			JSONInput:   helpers.M{"kind": "set", "name": "cdn", "content": "192.0.2.0/24"},
+			JSONOutput:  helpers.M{"id": 1},
+		}, {
+			Description: "set a definition with an invalid name",
+			URL:         "/api/v0/console/filter/definitions",
+			JSONInput:   helpers.M{"kind": "set", "name": "my-cdn", "content": "192.0.2.0/24"},
+			StatusCode:  400,
+			JSONOutput:  helpers.M{"message": `Invalid name "my-cdn".`},
+		}, {
+			Description: "set an invalid named set",
+			URL:         "/api/v0/console/filter/definitions",
+			JSONInput:   helpers.M{"kind": "set", "name": "cdn", "content": "192.0.2.0/24, AS174"},
+			StatusCode:  400,
+			JSONOutput:  helpers.M{"message": "Invalid named set: not a list of IP addresses, subnets, MAC addresses, AS numbers, TCP flags, DSCP values, ICMP types, strings or integers."},
+		}, {
+			Description: "set an invalid macro",
+			URL:         "/api/v0/console/filter/definitions",
+			JSONInput:   helpers.M{"kind": "macro", "name": "icmp", "content": "Proto = 1000"},
+			StatusCode:  400,
+			JSONOutput:  helpers.M{"message": "Invalid macro: at line 1, position 9: expecting an unsigned 8-bit integer."},
+		}, {
+			Description: "set a macro",
+			URL:         "/api/v0/console/filter/definitions",
+			JSONInput:   helpers.M{"kind": "macro", "name": "web_cdn", "content": "SrcAddr IN $cdn AND SrcPort = 443"},
+			JSONOutput:  helpers.M{"id": 2},
+		}, {
+			Description: "validate with a user macro",
+			URL:         "/api/v0/console/filter/validate",
+			JSONInput:   helpers.M{"filter": `@web_cdn`},
+			JSONOutput: helpers.M{
+				"message": "ok",
+				"parsed":  `(SrcAddr BETWEEN toIPv6('192.0.2.0') AND toIPv6('192.0.2.255') AND SrcPort = 443)`,
+			},
+		}, {
+			Description: "validate with a user macro as another user",
+			URL:         "/api/v0/console/filter/validate",
+			Header:      alfred,
+			JSONInput:   helpers.M{"filter": `@web_cdn`},
+			JSONOutput: helpers.M{
+				"message": "at line 1, position 1: unknown macro @web_cdn",
+				"errors": []helpers.M{{
+					"line":    1,
+					"column":  1,
+					"offset":  0,
+					"message": "unknown macro @web_cdn",
+				}},
+			},
+		}, {
+			Description: "complete macros",
+			URL:         "/api/v0/console/filter/complete",
+			JSONInput:   helpers.M{"what": "column", "prefix": "@"},
+			JSONOutput: helpers.M{"completions": []helpers.M{
+				{"label": "@from_transit", "detail": "macro", "quoted": false},
+				{"label": "@web_cdn", "detail": "macro", "quoted": false},
+			}},
+		}, {
+			Description: "complete named sets",
+			URL:         "/api/v0/console/filter/complete",
+			JSONInput:   helpers.M{"what": "value", "column": "etype", "operator": "IN", "prefix": "$"},
+			JSONOutput: helpers.M{"completions": []helpers.M{
+				{"label": "$cdn", "detail": "named set", "quoted": false},
+				{"label": "$transit", "detail": "named set", "quoted": false},
+			}},
+		}, {
+			Description: "do not complete named sets without IN",
+			URL:         "/api/v0/console/filter/complete",
+			JSONInput:   helpers.M{"what": "value", "column": "etype", "operator": "=", "prefix": "$"},
+			JSONOutput:  helpers.M{"completions": []helpers.M{}},
+		}, {
+			Description: "delete a definition as another user",
+			Method:      "DELETE",
+			URL:         "/api/v0/console/filter/definitions/2",
+			Header:      alfred,
+			StatusCode:  404,
+			JSONOutput:  helpers.M{"message": "filter definition not found"},
+		}, {
+			Description: "delete a definition",
+			Method:      "DELETE",
+			URL:         "/api/v0/console/filter/definitions/2",
+			StatusCode:  204,
+			ContentType: "application/json; charset=utf-8",
+		}, {
+			Description: "complete macros after a change",
+			URL:         "/api/v0/console/filter/complete",
+			JSONInput:   helpers.M{"what": "column", "prefix": "@"},
+			JSONOutput: helpers.M{"completions": []helpers.M{
+				{"label": "@from_transit", "detail": "macro", "quoted": false},
+			}},
+		}, {
+			Description: "list user definitions",
+			URL:         "/api/v0/console/filter/definitions",
+			JSONOutput: helpers.M{
+				"global": helpers.M{
+					"sets":   helpers.M{"transit": "AS174, AS1299"},
+					"macros": helpers.M{"from_transit": "SrcAS IN $transit"},
+				},
+				"definitions": []helpers.M{{
+					"id":      1,
+					"user":    "__default",
+					"kind":    "set",
+					"name":    "cdn",
+					"content": "192.0.2.0/24",
+				}},
+			},
+		},
+	})
+}
@@ -570,6 +570,74 @@ UNION DISTINCT
 				{"label": "echo-reply", "detail": "ICMPv6", "quoted": true},
 			}},
 		},
+		{
+			URL:        "/api/v0/console/filter/complete",
+			StatusCode: 200,
+			JSONInput:  helpers.M{"what": "column", "prefix": "dsc"},
+			JSONOutput: helpers.M{"completions": []helpers.M{
+				{"label": "DSCP", "detail": "column name", "quoted": false},
+			}},
+		},
+		{
+			URL:        "/api/v0/console/filter/complete",
+			StatusCode: 200,
+			JSONInput:  helpers.M{"what": "operator", "column": "TCPFlags"},
+			JSONOutput: helpers.M{"completions": []helpers.M{
+				{"label": "!=", "detail": "comparison operator", "quoted": false},
+				{"label": "<", "detail": "comparison operator", "quoted": false},
+				{"label": "<=", "detail": "comparison operator", "quoted": false},
+				{"label": "=", "detail": "comparison operator", "quoted": false},
+				{"label": ">", "detail": "comparison operator", "quoted": false},
+				{"label": ">=", "detail": "comparison operator", "quoted": false},
+				{"label": "HAS", "detail": "comparison operator", "quoted": false},
+				{"label": "HAS ALL (", "detail": "comparison operator", "quoted": false},
+				{"label": "HAS ANY (", "detail": "comparison operator", "quoted": false},
+				{"label": "IN (", "detail": "comparison operator", "quoted": false},
+				{"label": "NOTIN (", "detail": "comparison operator", "quoted": false},
+			}},
+		},
+		{
+			URL:        "/api/v0/console/filter/complete",
+			StatusCode: 200,
+			JSONInput:  helpers.M{"what": "operator", "column": "DSCP"},
+			JSONOutput: helpers.M{"completions": []helpers.M{
+				{"label": "!=", "detail": "comparison operator", "quoted": false},
+				{"label": "<", "detail": "comparison operator", "quoted": false},
+				{"label": "<=", "detail": "comparison operator", "quoted": false},
+				{"label": "=", "detail": "comparison operator", "quoted": false},
+				{"label": ">", "detail": "comparison operator", "quoted": false},
+				{"label": ">=", "detail": "comparison operator", "quoted": false},
+				{"label": "IN (", "detail": "comparison operator", "quoted": false},
+				{"label": "NOTIN (", "detail": "comparison operator", "quoted": false},
+			}},
+		},
+		{
+			URL:        "/api/v0/console/filter/complete",
+			StatusCode: 200,
+			JSONInput:  helpers.M{"what": "value", "column": "tcpflags", "operator": "HAS", "prefix": "s"},
+			JSONOutput: helpers.M{"completions": []helpers.M{
+				{"label": "SYN", "detail": "TCP flag", "quoted": false},
+			}},
+		},
+		{
+			URL:        "/api/v0/console/filter/complete",
+			StatusCode: 200,
+			JSONInput:  helpers.M{"what": "value", "column": "dscp", "prefix": "af4"},
+			JSONOutput: helpers.M{"completions": []helpers.M{
+				{"label": "AF41", "detail": "DSCP value", "quoted": false},
+				{"label": "AF42", "detail": "DSCP value", "quoted": false},
+				{"label": "AF43", "detail": "DSCP value", "quoted": false},
+			}},
+		},
+		{
+			URL:        "/api/v0/console/filter/complete",
+			StatusCode: 200,
+			JSONInput:  helpers.M{"what": "value", "column": "icmpv4type", "prefix": "echo"},
+			JSONOutput: helpers.M{"completions": []helpers.M{
+				{"label": "echo-reply", "detail": "ICMPv4 type", "quoted": false},
+				{"label": "echo-request", "detail": "ICMPv4 type", "quoted": false},
+			}},
+		},
 	})
 }
 
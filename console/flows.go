@@ -0,0 +1,262 @@
+// SPDX-FileCopyrightText: 2026 Free Mobile
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package console
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"reflect"
+	"time"
+
+	"akvorado/common/helpers"
+	"akvorado/common/httpserver"
+	"akvorado/common/schema"
+	sb "akvorado/common/sqlbuilder"
+	"akvorado/console/filter"
+	"akvorado/console/query"
+)
+
+// flowsHandlerInput describes the input for the /flows endpoint.
+type flowsHandlerInput struct {
+	schema      *schema.Component
+	database    string
+	definitions filter.Definitions
+	Start       time.Time    `json:"start" validate:"required"`
+	End         time.Time    `json:"end" validate:"required,gtfield=Start"`
+	Filter      query.Filter `json:"filter"`
+	Columns     []string     `json:"columns"`
+	OrderBy     string       `json:"orderBy" validate:"omitempty,oneof=time bytes"`
+	Limit       int          `json:"limit" validate:"min=1,max=1000"`
+	Cursor      string       `json:"cursor"`
+}
+
+// flowsHandlerOutput describes the output for the /flows endpoint.
+type flowsHandlerOutput struct {
+	Flows []helpers.M `json:"flows"`
+	Next  string      `json:"next,omitempty"`
+}
+
+// flowsCursor is the position of the last returned flow. Flows are sorted by
+// decreasing bytes (when sorting by bytes), time and hash of the whole row.
+// Identical flows share the same position: Skip is the number of flows at
+// this position already returned.
+type flowsCursor struct {
+	OrderBy string `json:"o"`
+	Bytes   uint64 `json:"b,omitempty"`
+	Time    uint64 `json:"t"`
+	Hash    uint64 `json:"h"`
+	Skip    int    `json:"s,omitempty"`
+}
+
+// samePosition tells if two cursors point to the same position.
+func (cursor flowsCursor) samePosition(other flowsCursor) bool {
+	return cursor.Bytes == other.Bytes && cursor.Time == other.Time && cursor.Hash == other.Hash
+}
+
+// defaultFlowsColumns are the columns returned when none are requested.
+var defaultFlowsColumns = []string{
+	"TimeReceived", "ExporterName", "InIfName", "OutIfName",
+	"SrcAddr", "DstAddr", "Proto", "SrcPort", "DstPort",
+	"Bytes", "Packets", "SamplingRate",
+}
+
+func (cursor flowsCursor) encode() string {
+	encoded, _ := json.Marshal(cursor)
+	return base64.RawURLEncoding.EncodeToString(encoded)
+}
+
+func decodeFlowsCursor(input string) (flowsCursor, error) {
+	var cursor flowsCursor
+	decoded, err := base64.RawURLEncoding.DecodeString(input)
+	if err != nil {
+		return cursor, err
+	}
+	err = json.Unmarshal(decoded, &cursor)
+	return cursor, err
+}
+
+// flowsColumns returns the expressions to select the requested columns.
+func (input flowsHandlerInput) flowsColumns() ([]sb.Expr, error) {
+	names := input.Columns
+	if len(names) == 0 {
+		names = []string{}
+		for _, name := range defaultFlowsColumns {
+			if column, ok := input.schema.LookupColumnByName(name); ok && !column.Disabled {
+				names = append(names, name)
+			}
+		}
+	}
+	exprs := make([]sb.Expr, 0, len(names))
+outer:
+	for _, name := range names {
+		column, ok := input.schema.LookupColumnByName(name)
+		if !ok || column.Disabled {
+			return nil, fmt.Errorf("unknown column name %s", name)
+		}
+		for _, r := range readableFlowColumns {
+			if r.key == column.Key {
+				exprs = append(exprs, sb.Alias(r.replaceWith, name))
+				continue outer
+			}
+		}
+		exprs = append(exprs, sb.Column(name))
+	}
+	return exprs, nil
+}
+
+// toSQL converts the input to an SQL query. The last columns are used to
+// build the cursor.
+func (input flowsHandlerInput) toSQL(columns []sb.Expr, cursor *flowsCursor, where sb.Expr) string {
+	hash := sb.Function("cityHash64", sb.Star())
+	bytes := sb.Op(sb.Column("Bytes"), "*", sb.Column("SamplingRate"))
+	end := input.End
+	if cursor != nil && cursor.OrderBy == "time" && time.Unix(int64(cursor.Time), 0).Before(end) {
+		end = time.Unix(int64(cursor.Time), 0)
+	}
+	conditions := []sb.Expr{
+		sb.Between(sb.Column("TimeReceived"), dateTime(input.Start), dateTime(end)),
+		input.Filter.Direct(),
+		where,
+	}
+	q := sb.Select(columns...)
+	if input.OrderBy == "bytes" {
+		q.Item(sb.Alias(bytes, "_cursor_bytes"))
+	}
+	q.Item(sb.Alias(sb.Function("toUnixTimestamp", sb.Column("TimeReceived")), "_cursor_time"))
+	q.Item(sb.Alias(hash, "_cursor_hash"))
+	keys := []sb.Expr{sb.Column("TimeReceived"), hash}
+	if input.OrderBy == "bytes" {
+		keys = append([]sb.Expr{bytes}, keys...)
+	}
+	if cursor != nil {
+		values := []sb.Expr{dateTime(time.Unix(int64(cursor.Time), 0)), sb.Uint(cursor.Hash)}
+		if input.OrderBy == "bytes" {
+			values = append([]sb.Expr{sb.Uint(cursor.Bytes)}, values...)
+		}
+		conditions = append(conditions, sb.Op(sb.Tuple(keys...), "<=", sb.Tuple(values...)))
+	}
+	order := make([]sb.OrderItem, len(keys))
+	for idx, key := range keys {
+		order[idx] = sb.Order(key).Desc()
+	}
+	q = q.From(sb.Table("flows")).
+		Where(sb.And(conditions...)).
+		OrderBy(order...).
+		Limit(input.Limit + 1)
+	if cursor != nil && cursor.Skip > 0 {
+		// The flows at the position of the cursor come first, skip the ones
+		// already returned.
+		q = q.Offset(cursor.Skip)
+	}
+	return q.String()
+}
+
+func (c *Component) flowsHandlerFunc(w http.ResponseWriter, req *http.Request) {
+	ctx := c.t.Context(req.Context())
+	input := flowsHandlerInput{
+		schema:      c.d.Schema,
+		database:    c.d.ClickHouseDB.DatabaseName(),
+		definitions: c.filterDefinitions(req.Context()),
+	}
+	if err := httpserver.BindJSON(req, &input); err != nil {
+		httpserver.WriteJSON(w, http.StatusBadRequest, helpers.M{"message": helpers.Capitalize(err.Error())})
+		return
+	}
+	if input.OrderBy == "" {
+		input.OrderBy = "time"
+	}
+	if input.End.Sub(input.Start) > c.config.FlowsTimeRangeLimit {
+		httpserver.WriteJSON(w, http.StatusBadRequest,
+			helpers.M{"message": fmt.Sprintf("Time range is beyond maximum value (%s)",
+				c.config.FlowsTimeRangeLimit)})
+		return
+	}
+	columns, err := input.flowsColumns()
+	if err != nil {
+		httpserver.WriteJSON(w, http.StatusBadRequest, helpers.M{"message": helpers.Capitalize(err.Error())})
+		return
+	}
+	if err := input.Filter.ValidateWithDefinitions(input.schema, input.database, input.definitions); err != nil {
+		httpserver.WriteJSON(w, http.StatusBadRequest, helpers.M{"message": helpers.Capitalize(err.Error())})
+		return
+	}
+	var cursor *flowsCursor
+	if input.Cursor != "" {
+		decoded, err := decodeFlowsCursor(input.Cursor)
+		if err != nil || decoded.OrderBy != input.OrderBy {
+			httpserver.WriteJSON(w, http.StatusBadRequest, helpers.M{"message": "Invalid cursor."})
+			return
+		}
+		cursor = &decoded
+	}
+
+	sqlQuery := input.toSQL(columns, cursor, c.accessWhere(req.Context()))
+	w.Header().Set("X-SQL-Query", sqlQuery)
+	ctx, done, ok := c.startQuery(ctx, w, "flows", sqlQuery)
+	if !ok {
+		return
+	}
+	defer done()
+	rows, err := c.d.ClickHouseDB.Conn.Query(ctx, sqlQuery)
+	if err != nil {
+		c.writeQueryError(ctx, w, err, sqlQuery)
+		return
+	}
+	defer rows.Close()
+
+	var (
+		output      = flowsHandlerOutput{Flows: []helpers.M{}}
+		names       = rows.Columns()
+		columnTypes = rows.ColumnTypes()
+		last        flowsCursor
+	)
+	if cursor != nil {
+		last = *cursor
+	}
+	cursorColumns := 2
+	if input.OrderBy == "bytes" {
+		cursorColumns = 3
+	}
+	for rows.Next() {
+		if len(output.Flows) == input.Limit {
+			output.Next = last.encode()
+			break
+		}
+		vars := make([]any, len(columnTypes))
+		for i := range columnTypes {
+			vars[i] = reflect.New(columnTypes[i].ScanType()).Interface()
+		}
+		if err := rows.Scan(vars...); err != nil {
+			c.r.Err(err).Msg("unable to parse flow")
+			httpserver.WriteJSON(w, http.StatusInternalServerError, helpers.M{"message": "Unable to parse flow."})
+			return
+		}
+		flow := helpers.M{}
+		for index, column := range names[:len(names)-cursorColumns] {
+			flow[column] = vars[index]
+		}
+		output.Flows = append(output.Flows, flow)
+
+		cursorVars := vars[len(vars)-cursorColumns:]
+		current := flowsCursor{OrderBy: input.OrderBy, Skip: 1}
+		if input.OrderBy == "bytes" {
+			current.Bytes = reflect.ValueOf(cursorVars[0]).Elem().Uint()
+			cursorVars = cursorVars[1:]
+		}
+		current.Time = reflect.ValueOf(cursorVars[0]).Elem().Uint()
+		current.Hash = reflect.ValueOf(cursorVars[1]).Elem().Uint()
+		if current.samePosition(last) {
+			current.Skip = last.Skip + 1
+		}
+		last = current
+	}
+	if err := rows.Err(); err != nil {
+		c.writeQueryError(ctx, w, err, sqlQuery)
+		return
+	}
+	auditRows(ctx, len(output.Flows))
+	httpserver.WriteJSON(w, http.StatusOK, output)
+}
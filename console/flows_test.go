@@ -0,0 +1,321 @@
+// SPDX-FileCopyrightText: 2026 Free Mobile
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package console
+
+import (
+	"net"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ClickHouse/clickhouse-go/v2/lib/driver"
+	"go.uber.org/mock/gomock"
+
+	"akvorado/common/clickhousedb/mocks"
+	"akvorado/common/helpers"
+	"akvorado/common/schema"
+	sb "akvorado/common/sqlbuilder"
+	"akvorado/console/query"
+)
+
+func TestFlowsQuerySQL(t *testing.T) {
+	start := time.Date(2022, 4, 10, 15, 45, 10, 0, time.UTC)
+	end := time.Date(2022, 4, 10, 15, 55, 10, 0, time.UTC)
+	cases := []struct {
+		Description string
+		Pos         helpers.Pos
+		Input       flowsHandlerInput
+		Cursor      *flowsCursor
+		Expected    string
+	}{
+		{
+			Description: "default columns, ordered by time",
+			Pos:         helpers.Mark(),
+			Input: flowsHandlerInput{
+				Start:   start,
+				End:     end,
+				Filter:  query.NewFilter("DstAddr = 2001:db8::1"),
+				OrderBy: "time",
+				Limit:   100,
+			},
+			Expected: `
+SELECT TimeReceived, ExporterName, InIfName, OutIfName, SrcAddr, DstAddr, Proto, SrcPort, DstPort,
+ Bytes, Packets, SamplingRate,
+ toUnixTimestamp(TimeReceived) AS _cursor_time, cityHash64(*) AS _cursor_hash
+FROM flows
+WHERE TimeReceived BETWEEN toDateTime('2022-04-10 15:45:10', 'UTC') AND toDateTime('2022-04-10 15:55:10', 'UTC')
+AND DstAddr = toIPv6('2001:db8::1')
+ORDER BY TimeReceived DESC, cityHash64(*) DESC
+LIMIT 101`,
+		}, {
+			Description: "ordered by time, with cursor",
+			Pos:         helpers.Mark(),
+			Input: flowsHandlerInput{
+				Start:   start,
+				End:     end,
+				Columns: []string{"TimeReceived", "SrcAddr", "SrcCommunities"},
+				OrderBy: "time",
+				Limit:   10,
+			},
+			Cursor: &flowsCursor{OrderBy: "time", Time: 1649605800, Hash: 42},
+			Expected: `
+SELECT TimeReceived, SrcAddr,
+ arrayMap(c -> concat(toString(bitShiftRight(c, 16)), ':', toString(bitAnd(c, 0xffff))), SrcCommunities) AS SrcCommunities,
+ toUnixTimestamp(TimeReceived) AS _cursor_time, cityHash64(*) AS _cursor_hash
+FROM flows
+WHERE TimeReceived BETWEEN toDateTime('2022-04-10 15:45:10', 'UTC') AND toDateTime('2022-04-10 15:50:00', 'UTC')
+AND (TimeReceived, cityHash64(*)) <= (toDateTime('2022-04-10 15:50:00', 'UTC'), 42)
+ORDER BY TimeReceived DESC, cityHash64(*) DESC
+LIMIT 11`,
+		}, {
+			Description: "ordered by bytes, with cursor",
+			Pos:         helpers.Mark(),
+			Input: flowsHandlerInput{
+				Start:   start,
+				End:     end,
+				Columns: []string{"TimeReceived", "DstAS"},
+				OrderBy: "bytes",
+				Limit:   10,
+			},
+			Cursor: &flowsCursor{OrderBy: "bytes", Bytes: 1000, Time: 1649605800, Hash: 42},
+			Expected: `
+SELECT TimeReceived, DstAS,
+ Bytes*SamplingRate AS _cursor_bytes, toUnixTimestamp(TimeReceived) AS _cursor_time, cityHash64(*) AS _cursor_hash
+FROM flows
+WHERE TimeReceived BETWEEN toDateTime('2022-04-10 15:45:10', 'UTC') AND toDateTime('2022-04-10 15:55:10', 'UTC')
+AND (Bytes*SamplingRate, TimeReceived, cityHash64(*)) <= (1000, toDateTime('2022-04-10 15:50:00', 'UTC'), 42)
+ORDER BY Bytes*SamplingRate DESC, TimeReceived DESC, cityHash64(*) DESC
+LIMIT 11`,
+		}, {
+			Description: "ordered by time, with cursor skipping identical flows",
+			Pos:         helpers.Mark(),
+			Input: flowsHandlerInput{
+				Start:   start,
+				End:     end,
+				Columns: []string{"TimeReceived", "SrcAddr"},
+				OrderBy: "time",
+				Limit:   10,
+			},
+			Cursor: &flowsCursor{OrderBy: "time", Time: 1649605800, Hash: 42, Skip: 3},
+			Expected: `
+SELECT TimeReceived, SrcAddr,
+ toUnixTimestamp(TimeReceived) AS _cursor_time, cityHash64(*) AS _cursor_hash
+FROM flows
+WHERE TimeReceived BETWEEN toDateTime('2022-04-10 15:45:10', 'UTC') AND toDateTime('2022-04-10 15:50:00', 'UTC')
+AND (TimeReceived, cityHash64(*)) <= (toDateTime('2022-04-10 15:50:00', 'UTC'), 42)
+ORDER BY TimeReceived DESC, cityHash64(*) DESC
+LIMIT 11 OFFSET 3`,
+		},
+	}
+	sch := schema.NewMock(t).EnableAllColumns()
+	for _, tc := range cases {
+		tc.Input.schema = sch
+		if err := tc.Input.Filter.Validate(tc.Input.schema, tc.Input.database); err != nil {
+			t.Fatalf("%sValidate() error:\n%+v", tc.Pos, err)
+		}
+		t.Run(tc.Description, func(t *testing.T) {
+			columns, err := tc.Input.flowsColumns()
+			if err != nil {
+				t.Fatalf("%sflowsColumns() error:\n%+v", tc.Pos, err)
+			}
+			got := sb.Normalize(t, tc.Input.toSQL(columns, tc.Cursor, sb.Expr{}))
+			if diff := helpers.Diff(got, sb.Normalize(t, tc.Expected)); diff != "" {
+				t.Errorf("%stoSQL (-got, +want):\n%s", tc.Pos, diff)
+			}
+		})
+	}
+}
+
+func TestFlowsHandler(t *testing.T) {
+	_, h, mockConn, _ := NewMock(t, DefaultConfiguration())
+
+	ctrl := gomock.NewController(t)
+	mockRows := mocks.NewMockRows(ctrl)
+	mockConn.EXPECT().Query(gomock.Any(), gomock.Any()).Return(mockRows, nil)
+	mockRows.EXPECT().Close()
+	mockRows.EXPECT().Err().Return(nil).AnyTimes()
+	mockRows.EXPECT().Columns().Return([]string{
+		"TimeReceived", "SrcAddr", "_cursor_time", "_cursor_hash",
+	}).AnyTimes()
+	colTimeReceived := mocks.NewMockColumnType(ctrl)
+	colSrcAddr := mocks.NewMockColumnType(ctrl)
+	colCursorTime := mocks.NewMockColumnType(ctrl)
+	colCursorHash := mocks.NewMockColumnType(ctrl)
+	colTimeReceived.EXPECT().ScanType().Return(reflect.TypeFor[time.Time]()).AnyTimes()
+	colSrcAddr.EXPECT().ScanType().Return(reflect.TypeFor[net.IP]()).AnyTimes()
+	colCursorTime.EXPECT().ScanType().Return(reflect.TypeFor[uint32]()).AnyTimes()
+	colCursorHash.EXPECT().ScanType().Return(reflect.TypeFor[uint64]()).AnyTimes()
+	mockRows.EXPECT().ColumnTypes().Return([]driver.ColumnType{
+		colTimeReceived, colSrcAddr, colCursorTime, colCursorHash,
+	}).AnyTimes()
+
+	// Two flows are returned, one more than the limit.
+	first := time.Date(2022, 4, 10, 15, 50, 0, 0, time.UTC)
+	mockRows.EXPECT().Next().Return(true).Times(2)
+	mockRows.EXPECT().Scan(gomock.Any()).
+		DoAndReturn(func(args ...any) any {
+			*args[0].(*time.Time) = first
+			*args[1].(*net.IP) = net.ParseIP("2001:db8::22")
+			*args[2].(*uint32) = uint32(first.Unix())
+			*args[3].(*uint64) = 42
+			return nil
+		})
+
+	input := helpers.M{
+		"start":   time.Date(2022, 4, 10, 15, 45, 10, 0, time.UTC),
+		"end":     time.Date(2022, 4, 10, 15, 55, 10, 0, time.UTC),
+		"columns": []string{"TimeReceived", "SrcAddr"},
+		"filter":  "DstAddr = 2001:db8::1",
+		"limit":   1,
+	}
+	next := flowsCursor{OrderBy: "time", Time: uint64(first.Unix()), Hash: 42, Skip: 1}.encode()
+	helpers.TestHTTPEndpoints(t, h.LocalAddr(), helpers.HTTPEndpointCases{
+		{
+			Description: "first page",
+			URL:         "/api/v0/console/flows",
+			JSONInput:   input,
+			JSONOutput: helpers.M{
+				"flows": []helpers.M{
+					{"TimeReceived": "2022-04-10T15:50:00Z", "SrcAddr": "2001:db8::22"},
+				},
+				"next": next,
+			},
+		}, {
+			Description: "time range too large",
+			URL:         "/api/v0/console/flows",
+			JSONInput: helpers.M{
+				"start": time.Date(2022, 4, 10, 15, 45, 10, 0, time.UTC),
+				"end":   time.Date(2022, 4, 10, 17, 45, 10, 0, time.UTC),
+				"limit": 10,
+			},
+			StatusCode: 400,
+			JSONOutput: helpers.M{"message": "Time range is beyond maximum value (1h0m0s)"},
+		}, {
+			Description: "unknown column",
+			URL:         "/api/v0/console/flows",
+			JSONInput: helpers.M{
+				"start":   time.Date(2022, 4, 10, 15, 45, 10, 0, time.UTC),
+				"end":     time.Date(2022, 4, 10, 15, 55, 10, 0, time.UTC),
+				"columns": []string{"NoColumn"},
+				"limit":   10,
+			},
+			StatusCode: 400,
+			JSONOutput: helpers.M{"message": "Unknown column name NoColumn"},
+		}, {
+			Description: "cursor for another order",
+			URL:         "/api/v0/console/flows",
+			JSONInput: helpers.M{
+				"start":   time.Date(2022, 4, 10, 15, 45, 10, 0, time.UTC),
+				"end":     time.Date(2022, 4, 10, 15, 55, 10, 0, time.UTC),
+				"orderBy": "bytes",
+				"limit":   10,
+				"cursor":  next,
+			},
+			StatusCode: 400,
+			JSONOutput: helpers.M{"message": "Invalid cursor."},
+		}, {
+			Description: "invalid cursor",
+			URL:         "/api/v0/console/flows",
+			JSONInput: helpers.M{
+				"start":  time.Date(2022, 4, 10, 15, 45, 10, 0, time.UTC),
+				"end":    time.Date(2022, 4, 10, 15, 55, 10, 0, time.UTC),
+				"limit":  10,
+				"cursor": "!!!",
+			},
+			StatusCode: 400,
+			JSONOutput: helpers.M{"message": "Invalid cursor."},
+		},
+	})
+}
+
+func TestFlowsHandlerIdenticalFlows(t *testing.T) {
+	_, h, mockConn, _ := NewMock(t, DefaultConfiguration())
+	ctrl := gomock.NewController(t)
+
+	// The table contains A, B, B, B, C, the B flows being identical.
+	type flow struct {
+		name string
+		hash uint64
+	}
+	at := time.Date(2022, 4, 10, 15, 50, 0, 0, time.UTC)
+	a, b, c := flow{"A", 30}, flow{"B", 20}, flow{"C", 10}
+	rowsFor := func(flows ...flow) *mocks.MockRows {
+		mockRows := mocks.NewMockRows(ctrl)
+		mockRows.EXPECT().Close()
+		mockRows.EXPECT().Err().Return(nil).AnyTimes()
+		mockRows.EXPECT().Columns().Return([]string{
+			"ExporterName", "_cursor_time", "_cursor_hash",
+		}).AnyTimes()
+		colName := mocks.NewMockColumnType(ctrl)
+		colCursorTime := mocks.NewMockColumnType(ctrl)
+		colCursorHash := mocks.NewMockColumnType(ctrl)
+		colName.EXPECT().ScanType().Return(reflect.TypeFor[string]()).AnyTimes()
+		colCursorTime.EXPECT().ScanType().Return(reflect.TypeFor[uint32]()).AnyTimes()
+		colCursorHash.EXPECT().ScanType().Return(reflect.TypeFor[uint64]()).AnyTimes()
+		mockRows.EXPECT().ColumnTypes().Return([]driver.ColumnType{
+			colName, colCursorTime, colCursorHash,
+		}).AnyTimes()
+		idx := 0
+		mockRows.EXPECT().Next().DoAndReturn(func() bool {
+			return idx < len(flows)
+		}).AnyTimes()
+		mockRows.EXPECT().Scan(gomock.Any()).DoAndReturn(func(args ...any) any {
+			*args[0].(*string) = flows[idx].name
+			*args[1].(*uint32) = uint32(at.Unix())
+			*args[2].(*uint64) = flows[idx].hash
+			idx++
+			return nil
+		}).AnyTimes()
+		return mockRows
+	}
+	matchSQL := func(suffix string) any {
+		return gomock.Cond(func(sql string) bool {
+			return strings.HasSuffix(sql, suffix)
+		})
+	}
+	gomock.InOrder(
+		mockConn.EXPECT().Query(gomock.Any(), matchSQL("LIMIT 3")).Return(rowsFor(a, b, b), nil),
+		mockConn.EXPECT().Query(gomock.Any(), matchSQL("LIMIT 3 OFFSET 1")).Return(rowsFor(b, b, c), nil),
+		mockConn.EXPECT().Query(gomock.Any(), matchSQL("LIMIT 3 OFFSET 3")).Return(rowsFor(c), nil),
+	)
+
+	input := func(cursor string) helpers.M {
+		return helpers.M{
+			"start":   time.Date(2022, 4, 10, 15, 45, 10, 0, time.UTC),
+			"end":     time.Date(2022, 4, 10, 15, 55, 10, 0, time.UTC),
+			"columns": []string{"ExporterName"},
+			"limit":   2,
+			"cursor":  cursor,
+		}
+	}
+	second := flowsCursor{OrderBy: "time", Time: uint64(at.Unix()), Hash: 20, Skip: 1}.encode()
+	third := flowsCursor{OrderBy: "time", Time: uint64(at.Unix()), Hash: 20, Skip: 3}.encode()
+	helpers.TestHTTPEndpoints(t, h.LocalAddr(), helpers.HTTPEndpointCases{
+		{
+			Description: "first page",
+			URL:         "/api/v0/console/flows",
+			JSONInput:   input(""),
+			JSONOutput: helpers.M{
+				"flows": []helpers.M{{"ExporterName": "A"}, {"ExporterName": "B"}},
+				"next":  second,
+			},
+		}, {
+			Description: "second page",
+			URL:         "/api/v0/console/flows",
+			JSONInput:   input(second),
+			JSONOutput: helpers.M{
+				"flows": []helpers.M{{"ExporterName": "B"}, {"ExporterName": "B"}},
+				"next":  third,
+			},
+		}, {
+			Description: "third page",
+			URL:         "/api/v0/console/flows",
+			JSONInput:   input(third),
+			JSONOutput: helpers.M{
+				"flows": []helpers.M{{"ExporterName": "C"}},
+			},
+		},
+	})
+}
@@ -92,7 +92,7 @@ export const complete = async (ctx: CompletionContext) => {
   let n: SyntaxNode | null = null;
   if (["LineComment", "BlockComment"].includes(nodeBefore.name)) {
     // Do not complete !
-  } else if ((n = nodeAncestor(nodeBefore, ["Column"]))) {
+  } else if ((n = nodeAncestor(nodeBefore, ["Column", "Macro"]))) {
     completion.from = n.from;
     completion.to = n.to;
     await remote({
@@ -169,7 +169,7 @@ export const complete = async (ctx: CompletionContext) => {
           operator: ctx.state.sliceDoc(n.from, n.to),
         });
       }
-    } else if ((n = nodeAncestor(nodeBefore, ["Value"]))) {
+    } else if ((n = nodeAncestor(nodeBefore, ["Value", "Macro"]))) {
       completion.options = [
         { label: "AND", detail: "logic operator" },
         { label: "OR", detail: "logic operator" },
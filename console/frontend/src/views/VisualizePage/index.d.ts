@@ -12,6 +12,7 @@ export type GraphSankeyHandlerInput = {
   filter: string;
   units: Units;
   bidirectional: boolean;
+  deduplicated?: boolean;
 };
 export type GraphLineHandlerInput = GraphSankeyHandlerInput & {
   points: number;
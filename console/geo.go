@@ -0,0 +1,118 @@
+// SPDX-FileCopyrightText: 2026 Free Mobile
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package console
+
+import (
+	"encoding/csv"
+	"fmt"
+	"math"
+	"net/netip"
+	"strconv"
+
+	"akvorado/outlet/geoip"
+)
+
+// coordinates are the latitude and the longitude of a location.
+type coordinates struct {
+	Latitude  float64 `json:"latitude"`
+	Longitude float64 `json:"longitude"`
+}
+
+// cityKey identifies a city. The same name can be used by cities in several
+// states or countries.
+type cityKey struct {
+	Country string
+	State   string
+	City    string
+}
+
+// loadCountries reads the coordinates of the centroid of each country from
+// the bundled table.
+func (c *Component) loadCountries() error {
+	f, err := c.embedOrLiveFS("data").Open("countries.csv")
+	if err != nil {
+		return fmt.Errorf("cannot open countries table: %w", err)
+	}
+	defer f.Close()
+	rd := csv.NewReader(f)
+	rd.FieldsPerRecord = 3
+	records, err := rd.ReadAll()
+	if err != nil {
+		return fmt.Errorf("cannot read countries table: %w", err)
+	}
+	countries := make(map[string]coordinates, len(records))
+	for line, record := range records {
+		if line == 0 {
+			continue
+		}
+		latitude, err := strconv.ParseFloat(record[1], 64)
+		if err != nil {
+			return fmt.Errorf("invalid latitude in countries table (line %d): %w", line+1, err)
+		}
+		longitude, err := strconv.ParseFloat(record[2], 64)
+		if err != nil {
+			return fmt.Errorf("invalid longitude in countries table (line %d): %w", line+1, err)
+		}
+		countries[record[0]] = coordinates{Latitude: latitude, Longitude: longitude}
+	}
+	c.countries = countries
+	return nil
+}
+
+// countryCoordinates returns the coordinates of a country or nil if it is
+// unknown.
+func (c *Component) countryCoordinates(country string) *coordinates {
+	if coords, ok := c.countries[country]; ok {
+		return &coords
+	}
+	return nil
+}
+
+// rebuildCities computes the coordinates of the cities from the GeoIP
+// databases. A city is located at the average of the coordinates of its
+// networks, rounded to about one meter. Networks without a city or without
+// coordinates are ignored.
+func (c *Component) rebuildCities() {
+	type sum struct {
+		latitude  float64
+		longitude float64
+		count     int
+	}
+	sums := map[cityKey]*sum{}
+	c.d.GeoIP.IterGeoDatabases(func(_ netip.Prefix, info geoip.GeoInfo) {
+		if info.City == "" || (info.Latitude == 0 && info.Longitude == 0) {
+			return
+		}
+		key := cityKey{Country: info.Country, State: info.State, City: info.City}
+		s, ok := sums[key]
+		if !ok {
+			s = &sum{}
+			sums[key] = s
+		}
+		s.latitude += info.Latitude
+		s.longitude += info.Longitude
+		s.count++
+	})
+	cities := make(map[cityKey]coordinates, len(sums))
+	for key, s := range sums {
+		cities[key] = coordinates{
+			Latitude:  math.Round(s.latitude/float64(s.count)*1e5) / 1e5,
+			Longitude: math.Round(s.longitude/float64(s.count)*1e5) / 1e5,
+		}
+	}
+	c.cities.Store(&cities)
+	c.r.Debug().Int("cities", len(cities)).Msg("city coordinates updated")
+}
+
+// cityCoordinates returns the coordinates of a city or nil if it is unknown.
+func (c *Component) cityCoordinates(key cityKey) *coordinates {
+	cities := c.cities.Load()
+	if cities == nil {
+		return nil
+	}
+	if coords, ok := (*cities)[key]; ok {
+		return &coords
+	}
+	return nil
+}
@@ -4,27 +4,40 @@
 package console
 
 import (
+	"errors"
+	"fmt"
+	"slices"
 	"time"
 
 	"akvorado/common/schema"
 	sb "akvorado/common/sqlbuilder"
+	"akvorado/console/filter"
 	"akvorado/console/query"
 )
 
 // graphCommonHandlerInput is for bits common to graphLineHandlerInput and
 // graphSankeyHandlerInput.
 type graphCommonHandlerInput struct {
-	schema         *schema.Component
-	database       string
-	Start          time.Time      `json:"start" validate:"required"`
-	End            time.Time      `json:"end" validate:"required,gtfield=Start"`
-	Dimensions     []query.Column `json:"dimensions"`             // group by ...
-	Limit          int            `json:"limit" validate:"min=1"` // limit product of dimensions
-	LimitType      string         `json:"limitType" validate:"omitempty,oneof=avg max last"`
-	Filter         query.Filter   `json:"filter"`                               // where ...
-	TruncateAddrV4 int            `json:"truncate-v4" validate:"min=0,max=32"`  // 0 or 32 = no truncation
-	TruncateAddrV6 int            `json:"truncate-v6" validate:"min=0,max=128"` // 0 or 128 = no truncation
-	Units          string         `json:"units" validate:"required,oneof=fps pps l3bps l2bps inl2% outl2%"`
+	schema             *schema.Component
+	database           string
+	definitions        filter.Definitions
+	Start              time.Time                 `json:"start" validate:"required"`
+	End                time.Time                 `json:"end" validate:"required,gtfield=Start"`
+	Dimensions         []query.Column            `json:"dimensions"`             // group by ...
+	Limit              int                       `json:"limit" validate:"min=1"` // limit product of dimensions
+	LimitType          string                    `json:"limitType" validate:"omitempty,oneof=avg max last"`
+	Filter             query.Filter              `json:"filter"`                               // where ...
+	TruncateAddrV4     int                       `json:"truncate-v4" validate:"min=0,max=32"`  // 0 or 32 = no truncation
+	TruncateAddrV6     int                       `json:"truncate-v6" validate:"min=0,max=128"` // 0 or 128 = no truncation
+	TruncateDimensions map[string]addrTruncation `json:"truncate-dimensions" validate:"dive"`  // per dimension, overrides the above
+	Units              string                    `json:"units" validate:"required,oneof=fps pps l3bps l2bps inl2% outl2%"`
+	Deduplicated       bool                      `json:"deduplicated"` // exclude flows marked as duplicates
+}
+
+// addrTruncation is the prefix lengths used to truncate an address dimension.
+type addrTruncation struct {
+	V4 int `json:"v4" validate:"min=0,max=32"`  // 0 or 32 = no truncation
+	V6 int `json:"v6" validate:"min=0,max=128"` // 0 or 128 = no truncation
 }
 
 // reverseUnits returns the unit name for the opposite traffic direction. Most
@@ -47,42 +60,120 @@ func truncateIP(addr, bits sb.Expr) sb.Expr {
 		sb.Uint(1))
 }
 
-// sourceSelect builds a SELECT query to use as a source for data. Notably, it
-// will do IP truncation.
-func (input graphCommonHandlerInput) sourceSelect(table string) *sb.Query {
-	if input.TruncateAddrV4 == 0 {
-		input.TruncateAddrV4 = 32
+// truncateAddr truncates an address column to the provided prefix lengths. It
+// returns false when there is nothing to truncate.
+func truncateAddr(name string, v4, v6 int) (sb.Expr, bool) {
+	if v4 == 0 {
+		v4 = 32
+	}
+	if v6 == 0 {
+		v6 = 128
+	}
+	if v4 == 32 && v6 == 128 {
+		return sb.Expr{}, false
+	}
+	addr := sb.Column(name)
+	bits := sb.Uint(uint64(v6))
+	if v6 != v4+96 {
+		// Addresses are all stored as IPv6 ones, so the prefix length to use
+		// depends on the family of each address.
+		bits = sb.Function("if",
+			sb.Op(truncateIP(addr, sb.Uint(96)), "=",
+				sb.Function("toIPv6", sb.String("0.0.0.0"))),
+			sb.Uint(uint64(v4+96)),
+			sb.Uint(uint64(v6)))
+	}
+	return sb.Alias(truncateIP(addr, bits), name), true
+}
+
+// matchingNetwork returns the most specific network matching an address, as a
+// string. When no network matches, the string is empty.
+func (input graphCommonHandlerInput) matchingNetwork(addr sb.Expr) sb.Expr {
+	return sb.Function("dictGetOrDefault",
+		sb.String(sb.Table(schema.DictionaryNetworks).In(input.database).String()),
+		sb.String("prefix"), addr, sb.String(""))
+}
+
+// validateDimensions validates the dimensions and checks the per-dimension
+// truncation only applies to selected address dimensions.
+func (input *graphCommonHandlerInput) validateDimensions() error {
+	if err := query.Columns(input.Dimensions).Validate(input.schema); err != nil {
+		return err
 	}
-	if input.TruncateAddrV6 == 0 {
-		input.TruncateAddrV6 = 128
+	for name := range input.TruncateDimensions {
+		idx := slices.IndexFunc(input.Dimensions, func(qc query.Column) bool {
+			return qc.String() == name
+		})
+		if idx == -1 {
+			return fmt.Errorf("cannot truncate %s, not a dimension", name)
+		}
+		column, _ := input.schema.LookupColumnByKey(input.Dimensions[idx].Key())
+		if !column.ConsoleTruncateIP || input.Dimensions[idx].Network() {
+			return fmt.Errorf("cannot truncate %s, not an address", name)
+		}
 	}
+	return nil
+}
+
+// applyDeduplication restricts the filter to flows not marked as duplicates
+// when deduplicated results are requested. This needs the Duplicate column.
+func (input *graphCommonHandlerInput) applyDeduplication() error {
+	if !input.Deduplicated {
+		return nil
+	}
+	if column, ok := input.schema.LookupColumnByKey(schema.ColumnDuplicate); !ok || column.Disabled {
+		return errors.New("deduplicated results require the Duplicate column")
+	}
+	dedup := query.NewFilter("Duplicate = 0")
+	if err := dedup.Validate(input.schema, input.database); err != nil {
+		return err
+	}
+	input.Filter = input.Filter.And(dedup)
+	return nil
+}
+
+// sourceSelect builds a SELECT query to use as a source for data. Notably, it
+// will do IP truncation and compute the matching networks.
+func (input graphCommonHandlerInput) sourceSelect(table string) *sb.Query {
 	truncated := []sb.Expr{}
+	matched := []string{}
 	for _, qc := range input.Dimensions {
-		if column, _ := input.schema.LookupColumnByKey(qc.Key()); column.ConsoleTruncateIP {
-			if input.TruncateAddrV4 == 32 && input.TruncateAddrV6 == 128 {
-				continue
-			}
-			addr := sb.Column(qc.String())
-			bits := sb.Uint(uint64(input.TruncateAddrV6))
-			if input.TruncateAddrV6 != input.TruncateAddrV4+96 {
-				// Addresses are all stored as IPv6 ones, so the prefix length
-				// to use depends on the family of each address.
-				bits = sb.Function("if",
-					sb.Op(truncateIP(addr, sb.Uint(96)), "=",
-						sb.Function("toIPv6", sb.String("0.0.0.0"))),
-					sb.Uint(uint64(input.TruncateAddrV4+96)),
-					sb.Uint(uint64(input.TruncateAddrV6)))
+		if qc.Network() {
+			// The reverse direction reuses this source, so the matching
+			// network of the reverse column is needed too.
+			for _, key := range []schema.ColumnKey{qc.Key(), input.schema.ReverseColumnDirection(qc.Key())} {
+				if !slices.Contains(matched, key.String()) {
+					matched = append(matched, key.String())
+				}
 			}
-			truncated = append(truncated,
-				sb.Alias(truncateIP(addr, bits), qc.String()))
+			continue
+		}
+		if column, _ := input.schema.LookupColumnByKey(qc.Key()); !column.ConsoleTruncateIP {
+			continue
+		}
+		v4, v6 := input.TruncateAddrV4, input.TruncateAddrV6
+		if truncation, ok := input.TruncateDimensions[qc.String()]; ok {
+			v4, v6 = truncation.V4, truncation.V6
+		}
+		if expr, ok := truncateAddr(qc.String(), v4, v6); ok {
+			truncated = append(truncated, expr)
 		}
 	}
-	source := sb.Select()
-	if len(truncated) == 0 {
-		source.Item(sb.Star())
-	} else {
-		source.Item(sb.Star(), sb.Replace(truncated...))
+	source := sb.Select(sb.Star())
+	for _, name := range matched {
+		source.Item(sb.Alias(input.matchingNetwork(sb.Column(name)), name+"Network"))
 	}
-	return source.From(sb.Table(table)).
+	source.From(sb.Table(table)).
 		Setting("asterisk_include_alias_columns", sb.Uint(1))
+	if len(truncated) == 0 {
+		return source
+	}
+	if len(matched) == 0 {
+		return sb.Select().Item(sb.Star(), sb.Replace(truncated...)).
+			From(sb.Table(table)).
+			Setting("asterisk_include_alias_columns", sb.Uint(1))
+	}
+	// Networks are matched on the addresses before truncation.
+	return sb.Select().Item(sb.Star(), sb.Replace(truncated...)).
+		FromSelect(source)
 }
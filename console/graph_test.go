@@ -4,6 +4,7 @@
 package console
 
 import (
+	"fmt"
 	"testing"
 
 	"akvorado/common/helpers"
@@ -55,6 +56,51 @@ func TestSourceSelect(t *testing.T) {
 				TruncateAddrV6: 40,
 			},
 			Expected: "SELECT * REPLACE (tupleElement(IPv6CIDRToRange(SrcAddr, if(tupleElement(IPv6CIDRToRange(SrcAddr, 96), 1) = toIPv6('0.0.0.0'), 120, 40)), 1) AS SrcAddr) FROM flows_1m0s SETTINGS asterisk_include_alias_columns = 1",
+		}, {
+			Description: "per-dimension truncation",
+			Input: graphCommonHandlerInput{
+				Dimensions:     []query.Column{query.NewColumn("SrcAddr"), query.NewColumn("DstAddr")},
+				TruncateAddrV4: 16,
+				TruncateAddrV6: 112,
+				TruncateDimensions: map[string]addrTruncation{
+					"DstAddr": {V4: 24, V6: 48},
+				},
+			},
+			Expected: "SELECT * REPLACE (tupleElement(IPv6CIDRToRange(SrcAddr, 112), 1) AS SrcAddr, tupleElement(IPv6CIDRToRange(DstAddr, if(tupleElement(IPv6CIDRToRange(DstAddr, 96), 1) = toIPv6('0.0.0.0'), 120, 48)), 1) AS DstAddr) FROM flows_1m0s SETTINGS asterisk_include_alias_columns = 1",
+		}, {
+			Description: "per-dimension truncation disabling the global one",
+			Input: graphCommonHandlerInput{
+				Dimensions:     []query.Column{query.NewColumn("SrcAddr")},
+				TruncateAddrV4: 16,
+				TruncateAddrV6: 112,
+				TruncateDimensions: map[string]addrTruncation{
+					"SrcAddr": {V4: 32, V6: 128},
+				},
+			},
+			Expected: "SELECT * FROM flows_1m0s SETTINGS asterisk_include_alias_columns = 1",
+		}, {
+			Description: "matching network",
+			Input: graphCommonHandlerInput{
+				database:   "default",
+				Dimensions: []query.Column{query.NewColumn("DstAddrNetwork"), query.NewColumn("SrcAddrNetwork")},
+			},
+			Expected: "SELECT *, " +
+				"dictGetOrDefault('default.networks', 'prefix', DstAddr, '') AS DstAddrNetwork, " +
+				"dictGetOrDefault('default.networks', 'prefix', SrcAddr, '') AS SrcAddrNetwork " +
+				"FROM flows_1m0s SETTINGS asterisk_include_alias_columns = 1",
+		}, {
+			Description: "matching network and truncation",
+			Input: graphCommonHandlerInput{
+				database:       "default",
+				Dimensions:     []query.Column{query.NewColumn("SrcAddrNetwork"), query.NewColumn("SrcAddr")},
+				TruncateAddrV4: 16,
+				TruncateAddrV6: 112,
+			},
+			Expected: "SELECT * REPLACE (tupleElement(IPv6CIDRToRange(SrcAddr, 112), 1) AS SrcAddr) FROM (" +
+				"SELECT *, " +
+				"dictGetOrDefault('default.networks', 'prefix', SrcAddr, '') AS SrcAddrNetwork, " +
+				"dictGetOrDefault('default.networks', 'prefix', DstAddr, '') AS DstAddrNetwork " +
+				"FROM flows_1m0s SETTINGS asterisk_include_alias_columns = 1)",
 		},
 	}
 	for _, tc := range cases {
@@ -68,3 +114,103 @@ func TestSourceSelect(t *testing.T) {
 		}
 	}
 }
+
+func TestValidateDimensions(t *testing.T) {
+	cases := []struct {
+		Description string
+		Input       graphCommonHandlerInput
+		Expected    string
+	}{
+		{
+			Description: "valid",
+			Input: graphCommonHandlerInput{
+				Dimensions: []query.Column{query.NewColumn("SrcAddr"), query.NewColumn("DstAddrNetwork")},
+				TruncateDimensions: map[string]addrTruncation{
+					"SrcAddr": {V4: 24, V6: 48},
+				},
+			},
+		}, {
+			Description: "unknown dimension",
+			Input: graphCommonHandlerInput{
+				Dimensions: []query.Column{query.NewColumn("ExporterNameNetwork")},
+			},
+			Expected: "unknown column name ExporterNameNetwork",
+		}, {
+			Description: "truncation of a missing dimension",
+			Input: graphCommonHandlerInput{
+				Dimensions: []query.Column{query.NewColumn("SrcAddr")},
+				TruncateDimensions: map[string]addrTruncation{
+					"DstAddr": {V4: 24, V6: 48},
+				},
+			},
+			Expected: "cannot truncate DstAddr, not a dimension",
+		}, {
+			Description: "truncation of a non-address dimension",
+			Input: graphCommonHandlerInput{
+				Dimensions: []query.Column{query.NewColumn("ExporterName")},
+				TruncateDimensions: map[string]addrTruncation{
+					"ExporterName": {V4: 24, V6: 48},
+				},
+			},
+			Expected: "cannot truncate ExporterName, not an address",
+		}, {
+			Description: "truncation of a matching network",
+			Input: graphCommonHandlerInput{
+				Dimensions: []query.Column{query.NewColumn("SrcAddrNetwork")},
+				TruncateDimensions: map[string]addrTruncation{
+					"SrcAddrNetwork": {V4: 24, V6: 48},
+				},
+			},
+			Expected: "cannot truncate SrcAddrNetwork, not an address",
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.Description, func(t *testing.T) {
+			tc.Input.schema = schema.NewMock(t)
+			err := tc.Input.validateDimensions()
+			got := ""
+			if err != nil {
+				got = err.Error()
+			}
+			if diff := helpers.Diff(got, tc.Expected); diff != "" {
+				t.Errorf("validateDimensions() (-got, +want):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestApplyDeduplication(t *testing.T) {
+	qf := query.NewFilter("InIfBoundary = external")
+	input := graphCommonHandlerInput{
+		schema:       schema.NewMock(t),
+		Filter:       qf,
+		Deduplicated: true,
+	}
+	if err := input.Filter.Validate(input.schema, ""); err != nil {
+		t.Fatalf("Validate() error:\n%+v", err)
+	}
+	err := input.applyDeduplication()
+	if diff := helpers.Diff(fmt.Sprint(err), "deduplicated results require the Duplicate column"); diff != "" {
+		t.Errorf("applyDeduplication() without Duplicate (-got, +want):\n%s", diff)
+	}
+
+	input.schema = schema.NewMock(t).EnableAllColumns()
+	if err := input.applyDeduplication(); err != nil {
+		t.Fatalf("applyDeduplication() error:\n%+v", err)
+	}
+	if diff := helpers.Diff(input.Filter.String(), "(InIfBoundary = external) AND (Duplicate = 0)"); diff != "" {
+		t.Errorf("applyDeduplication() (-got, +want):\n%s", diff)
+	}
+
+	input.Filter = qf
+	input.Deduplicated = false
+	if err := input.Filter.Validate(input.schema, ""); err != nil {
+		t.Fatalf("Validate() error:\n%+v", err)
+	}
+	if err := input.applyDeduplication(); err != nil {
+		t.Fatalf("applyDeduplication() error:\n%+v", err)
+	}
+	if diff := helpers.Diff(input.Filter.String(), "InIfBoundary = external"); diff != "" {
+		t.Errorf("applyDeduplication() when disabled (-got, +want):\n%s", diff)
+	}
+}
@@ -4,6 +4,7 @@
 package console
 
 import (
+	"context"
 	"fmt"
 	"math"
 	"net/http"
@@ -24,6 +25,9 @@ type graphLineHandlerInput struct {
 	Points         uint `json:"points" validate:"required,min=5,max=2000"` // minimum number of points
 	Bidirectional  bool `json:"bidirectional"`
 	PreviousPeriod bool `json:"previous-period"`
+	// rawDimensions adds the raw value of the dimensions of each selected row,
+	// as recorded by the anomaly detection, with the axis 0.
+	rawDimensions bool
 }
 
 // graphLineHandlerOutput describes the output for the /graph/line endpoint. A
@@ -42,6 +46,8 @@ type graphLineHandlerOutput struct {
 	Max                  []int          `json:"max"`             // row → max xps
 	Last                 []int          `json:"last"`            // row → last xps
 	NinetyFivePercentile []int          `json:"95th"`            // row → 95th xps
+	Annotations          []anomaly      `json:"annotations,omitempty"`
+	rawRows              [][]string     // row → raw value of each dimension
 }
 
 // reverseDirection reverts the direction of a provided input. It does not
@@ -175,6 +181,21 @@ func (input graphLineHandlerInput) toSQL1(axis int, r resolved, options toSQL1Op
 	return query
 }
 
+// rawDimensionsSQL returns the query selecting the raw value of the dimensions
+// of each selected row. It reuses the "rows" subquery of the first axis.
+func (input graphLineHandlerInput) rawDimensionsSQL(r resolved) *sb.Query {
+	values := make([]sb.Expr, len(input.Dimensions))
+	for idx, column := range input.Dimensions {
+		values[idx] = sb.Function("toString", sb.Column(column.String()))
+	}
+	return sb.Select(
+		sb.Alias(sb.Int(0), "axis"),
+		sb.Alias(r.timefilterStart(), "time"),
+		sb.Alias(sb.Uint(0), "xps"),
+		sb.Alias(sb.Array(values...), "dimensions"),
+	).From(sb.Table("rows"))
+}
+
 // resolveContext returns what is needed to select the table for this query.
 func (input graphLineHandlerInput) resolveContext() inputContext {
 	return inputContext{
@@ -212,34 +233,66 @@ func (input graphLineHandlerInput) toSQL(res resolution) []*sb.Query {
 			shiftedBy:        period,
 		}))
 	}
+	if input.rawDimensions && len(input.Dimensions) > 0 {
+		queries = append(queries, input.rawDimensionsSQL(main))
+	}
 	return queries
 }
 
 func (c *Component) graphLineHandlerFunc(w http.ResponseWriter, req *http.Request) {
 	ctx := c.t.Context(req.Context())
+	input, ok := c.bindGraphLineInput(w, req)
+	if !ok {
+		return
+	}
+	input.rawDimensions = c.annotated(ctx, input)
+	output, ok := c.graphLineOutput(ctx, w, input)
+	if !ok {
+		return
+	}
+	if input.rawDimensions {
+		output.Annotations = c.anomalyAnnotations(ctx, input, output.rawRows)
+	}
+	httpserver.WriteJSON(w, http.StatusOK, output)
+}
+
+// bindGraphLineInput decodes and validates the input of the /graph/line
+// endpoint. On error, it writes the response and returns false.
+func (c *Component) bindGraphLineInput(w http.ResponseWriter, req *http.Request) (graphLineHandlerInput, bool) {
 	input := graphLineHandlerInput{graphCommonHandlerInput: graphCommonHandlerInput{
-		schema:   c.d.Schema,
-		database: c.d.ClickHouseDB.DatabaseName(),
+		schema:      c.d.Schema,
+		database:    c.d.ClickHouseDB.DatabaseName(),
+		definitions: c.filterDefinitions(req.Context()),
 	}}
 	if err := httpserver.BindJSON(req, &input); err != nil {
 		httpserver.WriteJSON(w, http.StatusBadRequest, helpers.M{"message": helpers.Capitalize(err.Error())})
-		return
+		return input, false
 	}
-	if err := query.Columns(input.Dimensions).Validate(input.schema); err != nil {
+	if err := input.validateDimensions(); err != nil {
 		httpserver.WriteJSON(w, http.StatusBadRequest, helpers.M{"message": helpers.Capitalize(err.Error())})
-		return
+		return input, false
 	}
-	if err := input.Filter.Validate(input.schema, input.database); err != nil {
+	if err := input.Filter.ValidateWithDefinitions(input.schema, input.database, input.definitions); err != nil {
 		httpserver.WriteJSON(w, http.StatusBadRequest, helpers.M{"message": helpers.Capitalize(err.Error())})
-		return
+		return input, false
+	}
+	if err := input.applyDeduplication(); err != nil {
+		httpserver.WriteJSON(w, http.StatusBadRequest, helpers.M{"message": helpers.Capitalize(err.Error())})
+		return input, false
 	}
+	input.Filter = input.Filter.And(c.accessFilter(req.Context()))
 	if input.Limit > c.config.DimensionsLimit {
 		httpserver.WriteJSON(w, http.StatusBadRequest,
 			helpers.M{"message": fmt.Sprintf("Limit is set beyond maximum value (%d)",
 				c.config.DimensionsLimit)})
-		return
+		return input, false
 	}
+	return input, true
+}
 
+// graphLineOutput queries the database and computes the output of the
+// /graph/line endpoint. On error, it writes the response and returns false.
+func (c *Component) graphLineOutput(ctx context.Context, w http.ResponseWriter, input graphLineHandlerInput) (graphLineHandlerOutput, bool) {
 	r := c.resolve(input.resolveContext())
 	sqlQuery := unionAll(input.toSQL(r))
 	w.Header().Set("X-SQL-Query", strings.ReplaceAll(sqlQuery, "\n", "  "))
@@ -250,12 +303,28 @@ func (c *Component) graphLineHandlerFunc(w http.ResponseWriter, req *http.Reques
 		Xps        float64   `ch:"xps"`
 		Dimensions []string  `ch:"dimensions"`
 	}{}
-	c.metrics.clickhouseQueries.WithLabelValues(r.Table).Inc()
+	ctx, done, ok := c.startQuery(ctx, w, r.Table, sqlQuery)
+	if !ok {
+		return graphLineHandlerOutput{}, false
+	}
+	defer done()
 	if err := c.d.ClickHouseDB.Conn.Select(ctx, &results, sqlQuery); err != nil {
-		c.r.Err(err).Str("query", sqlQuery).Msg("unable to query database")
-		httpserver.WriteJSON(w, http.StatusInternalServerError, helpers.M{"message": "Unable to query database."})
-		return
+		c.writeQueryError(ctx, w, err, sqlQuery)
+		return graphLineHandlerOutput{}, false
+	}
+	auditRows(ctx, len(results))
+
+	// The raw values of the dimensions use the axis 0. They are not drawn.
+	rawRows := [][]string{}
+	drawn := results[:0]
+	for _, result := range results {
+		if result.Axis == 0 {
+			rawRows = append(rawRows, result.Dimensions)
+			continue
+		}
+		drawn = append(drawn, result)
 	}
+	results = drawn
 
 	// When requesting the previous period, we get an empty dimension in
 	// results. Put it back.
@@ -273,7 +342,8 @@ func (c *Component) graphLineHandlerFunc(w http.ResponseWriter, req *http.Reques
 
 	// Set time axis. We assume the first returned axis has the complete view.
 	output := graphLineHandlerOutput{
-		Time: []time.Time{},
+		Time:    []time.Time{},
+		rawRows: rawRows,
 	}
 	lastTime := time.Time{}
 	for _, result := range results {
@@ -458,7 +528,7 @@ func (c *Component) graphLineHandlerFunc(w http.ResponseWriter, req *http.Reques
 			output.AxisNames[axis] = fmt.Sprintf("Previous %s", name)
 		}
 	}
-	httpserver.WriteJSON(w, http.StatusOK, output)
+	return output, true
 }
 
 type tableIntervalInput struct {
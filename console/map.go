@@ -0,0 +1,261 @@
+// SPDX-FileCopyrightText: 2026 Free Mobile
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package console
+
+import (
+	"cmp"
+	"fmt"
+	"net/http"
+	"slices"
+	"time"
+
+	"akvorado/common/helpers"
+	"akvorado/common/httpserver"
+	"akvorado/common/schema"
+	sb "akvorado/common/sqlbuilder"
+	"akvorado/console/filter"
+	"akvorado/console/query"
+)
+
+// mapHandlerInput describes the input for the /map endpoint.
+type mapHandlerInput struct {
+	schema      *schema.Component
+	database    string
+	definitions filter.Definitions
+	Start       time.Time    `json:"start" validate:"required"`
+	End         time.Time    `json:"end" validate:"required,gtfield=Start"`
+	Filter      query.Filter `json:"filter"`
+	Units       string       `json:"units" validate:"required,oneof=fps pps l3bps l2bps"`
+	Level       string       `json:"level" validate:"omitempty,oneof=country city"`
+	Limit       int          `json:"limit" validate:"min=1"`
+}
+
+// mapHandlerOutput describes the output for the /map endpoint. The matrix
+// only contains the largest source and destination country pairs. Cities are
+// only present when requested.
+type mapHandlerOutput struct {
+	Countries []mapLocation `json:"countries"`
+	Cities    []mapLocation `json:"cities,omitempty"`
+	Matrix    []mapFlow     `json:"matrix"`
+}
+
+// mapLocation is the traffic from and to a country or a city, averaged over
+// the time range, in the requested units. Coordinates are missing when the
+// location is unknown.
+type mapLocation struct {
+	Country     string       `json:"country"`
+	State       string       `json:"state,omitempty"`
+	City        string       `json:"city,omitempty"`
+	Coordinates *coordinates `json:"coordinates"`
+	Source      int          `json:"source"`
+	Destination int          `json:"destination"`
+}
+
+// mapFlow is the traffic from a source country to a destination country.
+type mapFlow struct {
+	Source      string `json:"source"`
+	Destination string `json:"destination"`
+	Value       int    `json:"value"`
+}
+
+// average returns the expression for the average traffic over the time range
+// for the rows matching the provided condition.
+func (input mapHandlerInput) average(r resolved, condition sb.Expr) sb.Expr {
+	sum := sb.Function("sum", unitsWeightExpr(input.Units))
+	if !condition.IsZero() {
+		sum = sb.Function("sumIf", unitsWeightExpr(input.Units), condition)
+	}
+	return sb.Op(sum, "/", sb.Uint(uint64(r.End.Sub(r.Start).Seconds())))
+}
+
+// where returns the condition on the time range and the filter. The end of
+// the time range is excluded.
+func (input mapHandlerInput) where(r resolved, conditions ...sb.Expr) sb.Expr {
+	return sb.And(append([]sb.Expr{
+		sb.Op(sb.Column("TimeReceived"), ">=", r.timefilterStart()),
+		sb.Op(sb.Column("TimeReceived"), "<", r.timefilterEnd()),
+		input.Filter.Direct(),
+	}, conditions...)...)
+}
+
+// countriesSQL returns the query for the traffic between each pair of
+// countries. There are not enough countries to need a limit.
+func (input mapHandlerInput) countriesSQL(r resolved) string {
+	return sb.Select(
+		sb.Alias(sb.Column("SrcCountry"), "source"),
+		sb.Alias(sb.Column("DstCountry"), "destination"),
+		sb.Alias(input.average(r, sb.Expr{}), "xps"),
+	).
+		From(sb.Table(r.Table)).
+		Where(input.where(r)).
+		GroupBy(sb.Column("source"), sb.Column("destination")).
+		Having(sb.Op(sb.Column("xps"), ">", sb.Uint(0))).
+		OrderBy(sb.Order(sb.Column("xps")).Desc()).
+		String()
+}
+
+// citiesSQL returns the query for the traffic from and to the busiest
+// cities. Each flow is counted once for its source city and once for its
+// destination city.
+func (input mapHandlerInput) citiesSQL(r resolved) string {
+	element := func(index uint64) sb.Expr {
+		return sb.Function("tupleElement", sb.Column("location"), sb.Uint(index))
+	}
+	location := func(prefix string, side uint64) sb.Expr {
+		return sb.Function("tuple",
+			sb.Column(prefix+"Country"),
+			sb.Column(prefix+"GeoState"),
+			sb.Column(prefix+"GeoCity"),
+			sb.Uint(side))
+	}
+	return sb.Select(
+		sb.Alias(element(1), "country"),
+		sb.Alias(element(2), "state"),
+		sb.Alias(element(3), "city"),
+		sb.Alias(input.average(r, sb.Op(element(4), "=", sb.Uint(1))), "source"),
+		sb.Alias(input.average(r, sb.Op(element(4), "=", sb.Uint(2))), "destination"),
+	).
+		From(sb.Table(r.Table)).
+		ArrayJoin(sb.Alias(sb.Array(location("Src", 1), location("Dst", 2)), "location")).
+		Where(input.where(r, sb.Op(sb.Column("city"), "!=", sb.String("")))).
+		GroupBy(sb.Column("country"), sb.Column("state"), sb.Column("city")).
+		OrderBy(sb.Order(sb.Op(sb.Column("source"), "+", sb.Column("destination"))).Desc()).
+		Limit(input.Limit).
+		String()
+}
+
+func (c *Component) mapHandlerFunc(w http.ResponseWriter, req *http.Request) {
+	ctx := c.t.Context(req.Context())
+	input := mapHandlerInput{
+		schema:      c.d.Schema,
+		database:    c.d.ClickHouseDB.DatabaseName(),
+		definitions: c.filterDefinitions(req.Context()),
+	}
+	if err := httpserver.BindJSON(req, &input); err != nil {
+		httpserver.WriteJSON(w, http.StatusBadRequest, helpers.M{"message": helpers.Capitalize(err.Error())})
+		return
+	}
+	if err := input.Filter.ValidateWithDefinitions(input.schema, input.database, input.definitions); err != nil {
+		httpserver.WriteJSON(w, http.StatusBadRequest, helpers.M{"message": helpers.Capitalize(err.Error())})
+		return
+	}
+	input.Filter = input.Filter.And(c.accessFilter(req.Context()))
+	if input.Limit > c.config.DimensionsLimit {
+		httpserver.WriteJSON(w, http.StatusBadRequest,
+			helpers.M{"message": fmt.Sprintf("Limit is set beyond maximum value (%d)",
+				c.config.DimensionsLimit)})
+		return
+	}
+
+	// There is no time axis, so the number of points only steers the table
+	// selection, like for sankey graphs.
+	r := c.resolve(inputContext{
+		Start:             input.Start,
+		End:               input.End,
+		MainTableRequired: input.Filter.MainTableRequired(),
+		Points:            20,
+	}).forRange(input.Start, input.End)
+	if r.points() == 0 {
+		httpserver.WriteJSON(w, http.StatusBadRequest, helpers.M{"message": "Time range is too short."})
+		return
+	}
+
+	// Each query is estimated separately.
+	countriesQuery := input.countriesSQL(r)
+	sqlQueries := []string{countriesQuery}
+	citiesQuery := ""
+	w.Header().Set("X-SQL-Query", countriesQuery)
+	if input.Level == "city" {
+		citiesQuery = input.citiesSQL(r)
+		sqlQueries = append(sqlQueries, citiesQuery)
+		w.Header().Set("X-SQL-Query-Cities", citiesQuery)
+	}
+	ctx, done, ok := c.startQuery(ctx, w, r.Table, sqlQueries...)
+	if !ok {
+		return
+	}
+	defer done()
+	pairs := []struct {
+		Source      string  `ch:"source"`
+		Destination string  `ch:"destination"`
+		Xps         float64 `ch:"xps"`
+	}{}
+	if err := c.d.ClickHouseDB.Conn.Select(ctx, &pairs, countriesQuery); err != nil {
+		c.writeQueryError(ctx, w, err, countriesQuery)
+		return
+	}
+	cities := []struct {
+		Country     string  `ch:"country"`
+		State       string  `ch:"state"`
+		City        string  `ch:"city"`
+		Source      float64 `ch:"source"`
+		Destination float64 `ch:"destination"`
+	}{}
+	if citiesQuery != "" {
+		if err := c.d.ClickHouseDB.Conn.Select(ctx, &cities, citiesQuery); err != nil {
+			c.writeQueryError(ctx, w, err, citiesQuery)
+			return
+		}
+	}
+	auditRows(ctx, len(pairs)+len(cities))
+
+	// Traffic from or to an unknown country is only accounted for the known
+	// side.
+	output := mapHandlerOutput{
+		Countries: []mapLocation{},
+		Matrix:    []mapFlow{},
+	}
+	type total struct{ source, destination float64 }
+	totals := map[string]*total{}
+	get := func(country string) *total {
+		t, ok := totals[country]
+		if !ok {
+			t = &total{}
+			totals[country] = t
+		}
+		return t
+	}
+	for _, pair := range pairs {
+		if pair.Source != "" {
+			get(pair.Source).source += pair.Xps
+		}
+		if pair.Destination != "" {
+			get(pair.Destination).destination += pair.Xps
+		}
+		if pair.Source != "" && pair.Destination != "" && len(output.Matrix) < input.Limit {
+			output.Matrix = append(output.Matrix, mapFlow{
+				Source:      pair.Source,
+				Destination: pair.Destination,
+				Value:       int(pair.Xps),
+			})
+		}
+	}
+	for country, t := range totals {
+		output.Countries = append(output.Countries, mapLocation{
+			Country:     country,
+			Coordinates: c.countryCoordinates(country),
+			Source:      int(t.source),
+			Destination: int(t.destination),
+		})
+	}
+	slices.SortFunc(output.Countries, func(a, b mapLocation) int {
+		return cmp.Or(
+			cmp.Compare(b.Source+b.Destination, a.Source+a.Destination),
+			cmp.Compare(a.Country, b.Country))
+	})
+	if input.Level == "city" {
+		output.Cities = make([]mapLocation, 0, len(cities))
+		for _, city := range cities {
+			output.Cities = append(output.Cities, mapLocation{
+				Country:     city.Country,
+				State:       city.State,
+				City:        city.City,
+				Coordinates: c.cityCoordinates(cityKey{city.Country, city.State, city.City}),
+				Source:      int(city.Source),
+				Destination: int(city.Destination),
+			})
+		}
+	}
+	httpserver.WriteJSON(w, http.StatusOK, output)
+}
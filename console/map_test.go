@@ -0,0 +1,271 @@
+// SPDX-FileCopyrightText: 2026 Free Mobile
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package console
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"go.uber.org/mock/gomock"
+
+	"akvorado/common/helpers"
+	"akvorado/common/reporter"
+	"akvorado/common/schema"
+	sb "akvorado/common/sqlbuilder"
+	"akvorado/console/query"
+	"akvorado/outlet/geoip"
+)
+
+func TestMapQuerySQL(t *testing.T) {
+	start := time.Date(2026, 10, 19, 10, 0, 0, 0, time.UTC)
+	end := time.Date(2026, 10, 19, 11, 0, 0, 0, time.UTC)
+	input := mapHandlerInput{
+		schema:   schema.NewMock(t),
+		database: "default",
+		Start:    start,
+		End:      end,
+		Filter:   query.NewFilter("InIfBoundary = external"),
+		Units:    "l3bps",
+		Level:    "city",
+		Limit:    10,
+	}
+	if err := input.Filter.Validate(input.schema, input.database); err != nil {
+		t.Fatalf("Validate() error:\n%+v", err)
+	}
+	r := resolution{Table: "flows_1m0s", Interval: 60, TableInterval: time.Minute}.forRange(start, end)
+
+	t.Run("countries", func(t *testing.T) {
+		expected := `
+SELECT SrcCountry AS source, DstCountry AS destination, sum(Bytes*SamplingRate*8) / 3600 AS xps
+FROM flows_1m0s
+WHERE TimeReceived >= toDateTime('2026-10-19 10:00:00', 'UTC')
+ AND TimeReceived < toDateTime('2026-10-19 11:00:00', 'UTC')
+ AND InIfBoundary = 'external'
+GROUP BY source, destination
+HAVING xps > 0
+ORDER BY xps DESC`
+		got := sb.Normalize(t, input.countriesSQL(r))
+		if diff := helpers.Diff(got, sb.Normalize(t, expected)); diff != "" {
+			t.Errorf("countriesSQL (-got, +want):\n%s", diff)
+		}
+	})
+	t.Run("cities", func(t *testing.T) {
+		expected := `
+SELECT
+ tupleElement(location, 1) AS country,
+ tupleElement(location, 2) AS state,
+ tupleElement(location, 3) AS city,
+ sumIf(Bytes*SamplingRate*8, tupleElement(location, 4) = 1) / 3600 AS source,
+ sumIf(Bytes*SamplingRate*8, tupleElement(location, 4) = 2) / 3600 AS destination
+FROM flows_1m0s
+ARRAY JOIN [tuple(SrcCountry, SrcGeoState, SrcGeoCity, 1), tuple(DstCountry, DstGeoState, DstGeoCity, 2)] AS location
+WHERE TimeReceived >= toDateTime('2026-10-19 10:00:00', 'UTC')
+ AND TimeReceived < toDateTime('2026-10-19 11:00:00', 'UTC')
+ AND InIfBoundary = 'external'
+ AND city != ''
+GROUP BY country, state, city
+ORDER BY source + destination DESC
+LIMIT 10`
+		got := sb.Normalize(t, input.citiesSQL(r))
+		if diff := helpers.Diff(got, sb.Normalize(t, expected)); diff != "" {
+			t.Errorf("citiesSQL (-got, +want):\n%s", diff)
+		}
+	})
+}
+
+func TestMapHandler(t *testing.T) {
+	c, h, mockConn, _ := NewMock(t, DefaultConfiguration())
+	c.cities.Store(&map[cityKey]coordinates{
+		{"FR", "IDF", "Paris"}: {Latitude: 48.86, Longitude: 2.35},
+	})
+	pairs := []struct {
+		Source      string  `ch:"source"`
+		Destination string  `ch:"destination"`
+		Xps         float64 `ch:"xps"`
+	}{
+		{"US", "FR", 3000},
+		{"FR", "US", 1000},
+		{"", "FR", 500},
+		{"DE", "FR", 200},
+	}
+	cities := []struct {
+		Country     string  `ch:"country"`
+		State       string  `ch:"state"`
+		City        string  `ch:"city"`
+		Source      float64 `ch:"source"`
+		Destination float64 `ch:"destination"`
+	}{
+		{"FR", "IDF", "Paris", 800, 3000},
+		{"US", "CA", "Nowhere", 2000, 600},
+	}
+	gomock.InOrder(
+		mockConn.EXPECT().
+			Select(gomock.Any(), gomock.Any(), gomock.Any()).
+			SetArg(1, pairs).
+			Return(nil),
+		mockConn.EXPECT().
+			Select(gomock.Any(), gomock.Any(), gomock.Any()).
+			SetArg(1, pairs).
+			Return(nil),
+		mockConn.EXPECT().
+			Select(gomock.Any(), gomock.Any(), gomock.Any()).
+			SetArg(1, cities).
+			Return(nil),
+	)
+	countries := []helpers.M{
+		{
+			"country":     "FR",
+			"coordinates": helpers.M{"latitude": 46.227638, "longitude": 2.213749},
+			"source":      1000,
+			"destination": 3700,
+		}, {
+			"country":     "US",
+			"coordinates": helpers.M{"latitude": 37.09024, "longitude": -95.712891},
+			"source":      3000,
+			"destination": 1000,
+		}, {
+			"country":     "DE",
+			"coordinates": helpers.M{"latitude": 51.165691, "longitude": 10.451526},
+			"source":      200,
+			"destination": 0,
+		},
+	}
+	matrix := []helpers.M{
+		{"source": "US", "destination": "FR", "value": 3000},
+		{"source": "FR", "destination": "US", "value": 1000},
+	}
+
+	helpers.TestHTTPEndpoints(t, h.LocalAddr(), helpers.HTTPEndpointCases{
+		{
+			Description: "countries",
+			URL:         "/api/v0/console/map",
+			JSONInput: helpers.M{
+				"start": time.Date(2026, 10, 19, 10, 0, 0, 0, time.UTC),
+				"end":   time.Date(2026, 10, 19, 11, 0, 0, 0, time.UTC),
+				"units": "l3bps",
+				"limit": 2,
+			},
+			JSONOutput: helpers.M{
+				"countries": countries,
+				"matrix":    matrix,
+			},
+		}, {
+			Description: "cities",
+			URL:         "/api/v0/console/map",
+			JSONInput: helpers.M{
+				"start": time.Date(2026, 10, 19, 10, 0, 0, 0, time.UTC),
+				"end":   time.Date(2026, 10, 19, 11, 0, 0, 0, time.UTC),
+				"units": "l3bps",
+				"level": "city",
+				"limit": 2,
+			},
+			JSONOutput: helpers.M{
+				"countries": countries,
+				"cities": []helpers.M{
+					{
+						"country":     "FR",
+						"state":       "IDF",
+						"city":        "Paris",
+						"coordinates": helpers.M{"latitude": 48.86, "longitude": 2.35},
+						"source":      800,
+						"destination": 3000,
+					}, {
+						"country":     "US",
+						"state":       "CA",
+						"city":        "Nowhere",
+						"coordinates": nil,
+						"source":      2000,
+						"destination": 600,
+					},
+				},
+				"matrix": matrix,
+			},
+		}, {
+			Description: "percentage units",
+			URL:         "/api/v0/console/map",
+			JSONInput: helpers.M{
+				"start": time.Date(2026, 10, 19, 10, 0, 0, 0, time.UTC),
+				"end":   time.Date(2026, 10, 19, 11, 0, 0, 0, time.UTC),
+				"units": "inl2%",
+				"limit": 2,
+			},
+			StatusCode: 400,
+			JSONOutput: helpers.M{"message": "Key: 'mapHandlerInput.Units' Error:Field validation for 'Units' failed on the 'oneof' tag"},
+		}, {
+			Description: "limit too high",
+			URL:         "/api/v0/console/map",
+			JSONInput: helpers.M{
+				"start": time.Date(2026, 10, 19, 10, 0, 0, 0, time.UTC),
+				"end":   time.Date(2026, 10, 19, 11, 0, 0, 0, time.UTC),
+				"units": "l3bps",
+				"limit": 100,
+			},
+			StatusCode: 400,
+			JSONOutput: helpers.M{"message": "Limit is set beyond maximum value (50)"},
+		},
+	})
+}
+
+func TestRebuildCities(t *testing.T) {
+	c, _, _, _ := NewMock(t, DefaultConfiguration())
+	c.d.GeoIP = geoip.NewMock(t, reporter.NewMock(t), true)
+	c.rebuildCities()
+
+	cases := []struct {
+		Key      cityKey
+		Expected *coordinates
+	}{
+		{cityKey{"GB", "ENG", "Boxford"}, &coordinates{Latitude: 51.75, Longitude: -1.25}},
+		{cityKey{"JP", "Shimane", "Matsue"}, &coordinates{Latitude: 35.48333, Longitude: 133.05}},
+		{cityKey{"FR", "", "Boxford"}, nil},
+	}
+	for _, tc := range cases {
+		if diff := helpers.Diff(c.cityCoordinates(tc.Key), tc.Expected); diff != "" {
+			t.Errorf("cityCoordinates(%v) (-got, +want):\n%s", tc.Key, diff)
+		}
+	}
+}
+
+func TestMapHandlerEstimate(t *testing.T) {
+	config := DefaultConfiguration()
+	config.QueryLimits.MaxEstimatedRows = 1_000_000
+	_, h, mockConn, _ := NewMock(t, config)
+
+	// Each query is estimated on its own.
+	estimate := func(fragment string, rows uint64) *gomock.Call {
+		return mockConn.EXPECT().
+			Select(gomock.Any(), gomock.Any(), gomock.Cond(func(sql string) bool {
+				return strings.HasPrefix(sql, "EXPLAIN ESTIMATE SELECT") &&
+					strings.Contains(sql, fragment) &&
+					!strings.Contains(sql, ";")
+			})).
+			SetArg(1, []struct {
+				Rows uint64 `ch:"rows"`
+			}{{rows}}).
+			Return(nil)
+	}
+	gomock.InOrder(
+		estimate("SrcCountry AS source", 600_000),
+		estimate("ARRAY JOIN", 600_000),
+	)
+
+	helpers.TestHTTPEndpoints(t, h.LocalAddr(), helpers.HTTPEndpointCases{
+		{
+			Description: "cities too expensive",
+			URL:         "/api/v0/console/map",
+			JSONInput: helpers.M{
+				"start": time.Date(2026, 10, 19, 10, 0, 0, 0, time.UTC),
+				"end":   time.Date(2026, 10, 19, 11, 0, 0, 0, time.UTC),
+				"units": "l3bps",
+				"level": "city",
+				"limit": 2,
+			},
+			StatusCode: 400,
+			JSONOutput: helpers.M{
+				"message": "Query is too expensive (about 1200000 rows to read, maximum is 1000000), reduce the time range or add filters.",
+			},
+		},
+	})
+}
@@ -0,0 +1,266 @@
+// SPDX-FileCopyrightText: 2026 Free Mobile
+// SPDX-License-Identifier: AGPL-3.0-only
+
+// Package parquet implements a minimal Parquet writer. Columns are required,
+// uncompressed and use the plain encoding. Rows are written as row groups of
+// 10,000 rows, so only one row group is kept in memory.
+package parquet
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"time"
+)
+
+// ColumnType is the type of a column.
+type ColumnType int
+
+const (
+	// String is an UTF-8 string.
+	String ColumnType = iota
+	// Int64 is a signed 64-bit integer.
+	Int64
+	// Timestamp is a timestamp with a millisecond precision.
+	Timestamp
+)
+
+// Column describes a column.
+type Column struct {
+	Name string
+	Type ColumnType
+}
+
+// Physical and converted types, encodings and page types from the Parquet
+// specification.
+const (
+	physicalInt64     = 2
+	physicalByteArray = 6
+
+	convertedUTF8            = 0
+	convertedTimestampMillis = 9
+
+	repetitionRequired = 0
+	encodingPlain      = 0
+	encodingRLE        = 3
+	pageTypeData       = 0
+	codecUncompressed  = 0
+)
+
+var magic = []byte("PAR1")
+
+// defaultRowGroupRows is the number of rows in each row group.
+const defaultRowGroupRows = 10_000
+
+// Writer writes rows to a Parquet file.
+type Writer struct {
+	out          io.Writer
+	columns      []Column
+	values       []bytes.Buffer // values of the current row group
+	rows         int            // rows in the current row group
+	rowGroupRows int
+	rowGroups    []rowGroup
+	offset       int64 // bytes written so far
+	err          error
+}
+
+// rowGroup describes a written row group.
+type rowGroup struct {
+	chunks []columnChunk
+	rows   int
+}
+
+// columnChunk describes a written column chunk.
+type columnChunk struct {
+	offset int64
+	size   int64
+}
+
+// NewWriter creates a new writer with the provided columns. Rows are written
+// to out each time a row group is complete. The footer is written on Close.
+func NewWriter(out io.Writer, columns []Column) *Writer {
+	return &Writer{
+		out:          out,
+		columns:      columns,
+		values:       make([]bytes.Buffer, len(columns)),
+		rowGroupRows: defaultRowGroupRows,
+	}
+}
+
+// Write adds a row. Values should be string for String columns, int64 (or
+// int) for Int64 columns and time.Time for Timestamp columns.
+func (w *Writer) Write(row []any) error {
+	if len(row) != len(w.columns) {
+		return fmt.Errorf("got %d values for %d columns", len(row), len(w.columns))
+	}
+	for idx, column := range w.columns {
+		var err error
+		switch v := row[idx].(type) {
+		case string:
+			err = column.check(String)
+		case int, int64:
+			err = column.check(Int64)
+		case time.Time:
+			err = column.check(Timestamp)
+		default:
+			err = fmt.Errorf("unsupported value %T for column %q", v, column.Name)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	for idx := range w.columns {
+		buf := &w.values[idx]
+		switch v := row[idx].(type) {
+		case string:
+			buf.Write(binary.LittleEndian.AppendUint32(nil, uint32(len(v))))
+			buf.WriteString(v)
+		case int:
+			buf.Write(binary.LittleEndian.AppendUint64(nil, uint64(v)))
+		case int64:
+			buf.Write(binary.LittleEndian.AppendUint64(nil, uint64(v)))
+		case time.Time:
+			buf.Write(binary.LittleEndian.AppendUint64(nil, uint64(v.UnixMilli())))
+		}
+	}
+	w.rows++
+	if w.rows >= w.rowGroupRows {
+		w.flush()
+	}
+	return w.err
+}
+
+// check returns an error if the column is not of the provided type.
+func (column Column) check(t ColumnType) error {
+	if column.Type != t {
+		return fmt.Errorf("unexpected value type for column %q", column.Name)
+	}
+	return nil
+}
+
+// write writes data to the output and keeps track of the offset. Once an
+// error happens, nothing is written anymore.
+func (w *Writer) write(data []byte) {
+	if w.err != nil {
+		return
+	}
+	if w.offset == 0 {
+		n, err := w.out.Write(magic)
+		w.offset += int64(n)
+		if err != nil {
+			w.err = err
+			return
+		}
+	}
+	n, err := w.out.Write(data)
+	w.offset += int64(n)
+	w.err = err
+}
+
+// flush writes the current row group, one data page for each column.
+func (w *Writer) flush() {
+	if w.rows == 0 {
+		return
+	}
+	group := rowGroup{chunks: make([]columnChunk, len(w.columns)), rows: w.rows}
+	for idx := range w.columns {
+		data := w.values[idx].Bytes()
+		header := newThriftWriter()
+		header.i32(1, pageTypeData)
+		header.i32(2, int32(len(data)))
+		header.i32(3, int32(len(data)))
+		header.structBegin(5)
+		header.i32(1, int32(w.rows))
+		header.i32(2, encodingPlain)
+		header.i32(3, encodingRLE)
+		header.i32(4, encodingRLE)
+		header.structEnd()
+		header.structEnd()
+
+		// The magic header is written with the first chunk.
+		w.write(nil)
+		group.chunks[idx].offset = w.offset
+		w.write(header.buf.Bytes())
+		w.write(data)
+		group.chunks[idx].size = w.offset - group.chunks[idx].offset
+		w.values[idx].Reset()
+	}
+	w.rowGroups = append(w.rowGroups, group)
+	w.rows = 0
+}
+
+// Close writes the remaining rows and the footer. It does not close the
+// underlying writer.
+func (w *Writer) Close() error {
+	w.flush()
+
+	// File metadata
+	var rows int64
+	for _, group := range w.rowGroups {
+		rows += int64(group.rows)
+	}
+	meta := newThriftWriter()
+	meta.i32(1, 1)
+	meta.listBegin(2, thriftStruct, len(w.columns)+1)
+	meta.listStruct()
+	meta.binary(4, []byte("schema"))
+	meta.i32(5, int32(len(w.columns)))
+	meta.structEnd()
+	for _, column := range w.columns {
+		meta.listStruct()
+		meta.i32(1, column.physicalType())
+		meta.i32(3, repetitionRequired)
+		meta.binary(4, []byte(column.Name))
+		switch column.Type {
+		case String:
+			meta.i32(6, convertedUTF8)
+		case Timestamp:
+			meta.i32(6, convertedTimestampMillis)
+		}
+		meta.structEnd()
+	}
+	meta.i64(3, rows)
+	meta.listBegin(4, thriftStruct, len(w.rowGroups))
+	for _, group := range w.rowGroups {
+		meta.listStruct()
+		meta.listBegin(1, thriftStruct, len(w.columns))
+		var total int64
+		for idx, column := range w.columns {
+			chunk := group.chunks[idx]
+			meta.listStruct()
+			meta.i64(2, chunk.offset)
+			meta.structBegin(3)
+			meta.i32(1, column.physicalType())
+			meta.listBegin(2, thriftI32, 1)
+			meta.listI32(encodingPlain)
+			meta.listBegin(3, thriftBinary, 1)
+			meta.listBinary([]byte(column.Name))
+			meta.i32(4, codecUncompressed)
+			meta.i64(5, int64(group.rows))
+			meta.i64(6, chunk.size)
+			meta.i64(7, chunk.size)
+			meta.i64(9, chunk.offset)
+			meta.structEnd()
+			meta.structEnd()
+			total += chunk.size
+		}
+		meta.i64(2, total)
+		meta.i64(3, int64(group.rows))
+		meta.structEnd()
+	}
+	meta.binary(6, []byte("akvorado"))
+	meta.structEnd()
+
+	w.write(meta.buf.Bytes())
+	w.write(binary.LittleEndian.AppendUint32(nil, uint32(meta.buf.Len())))
+	w.write(magic)
+	return w.err
+}
+
+func (column Column) physicalType() int32 {
+	if column.Type == String {
+		return physicalByteArray
+	}
+	return physicalInt64
+}
@@ -0,0 +1,288 @@
+// SPDX-FileCopyrightText: 2026 Free Mobile
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package parquet
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"akvorado/common/clickhousedb"
+	"akvorado/common/helpers"
+	"akvorado/common/reporter"
+)
+
+// thriftReader decodes a Thrift compact struct into a map from field IDs to
+// values. This is only used to check the output of the writer.
+type thriftReader struct {
+	t   *testing.T
+	buf *bytes.Reader
+}
+
+func (r *thriftReader) uvarint() uint64 {
+	v, err := binary.ReadUvarint(r.buf)
+	if err != nil {
+		r.t.Fatalf("ReadUvarint() error:\n%+v", err)
+	}
+	return v
+}
+
+func (r *thriftReader) zigzag() int64 {
+	v := r.uvarint()
+	return int64(v>>1) ^ -int64(v&1)
+}
+
+func (r *thriftReader) byte() byte {
+	b, err := r.buf.ReadByte()
+	if err != nil {
+		r.t.Fatalf("ReadByte() error:\n%+v", err)
+	}
+	return b
+}
+
+func (r *thriftReader) value(typ byte) any {
+	switch typ {
+	case thriftI32, thriftI64:
+		return r.zigzag()
+	case thriftBinary:
+		b := make([]byte, r.uvarint())
+		r.buf.Read(b)
+		return string(b)
+	case thriftList:
+		header := r.byte()
+		size := int(header >> 4)
+		if size == 15 {
+			size = int(r.uvarint())
+		}
+		list := []any{}
+		for range size {
+			list = append(list, r.value(header&0xf))
+		}
+		return list
+	case thriftStruct:
+		return r.structure()
+	}
+	r.t.Fatalf("unknown type %d", typ)
+	return nil
+}
+
+func (r *thriftReader) structure() map[int]any {
+	result := map[int]any{}
+	var last int
+	for {
+		header := r.byte()
+		if header == 0 {
+			return result
+		}
+		if delta := int(header >> 4); delta != 0 {
+			last += delta
+		} else {
+			last = int(r.zigzag())
+		}
+		result[last] = r.value(header & 0xf)
+	}
+}
+
+func TestWriter(t *testing.T) {
+	var out bytes.Buffer
+	w := NewWriter(&out, []Column{
+		{Name: "time", Type: Timestamp},
+		{Name: "name", Type: String},
+		{Name: "value", Type: Int64},
+	})
+	t1 := time.Date(2026, 10, 19, 10, 0, 0, 0, time.UTC)
+	for _, row := range [][]any{
+		{t1, "alpha", int64(1000)},
+		{t1.Add(time.Minute), "beta", -4},
+	} {
+		if err := w.Write(row); err != nil {
+			t.Fatalf("Write() error:\n%+v", err)
+		}
+	}
+	if err := w.Write([]any{"oops", "beta", 1}); err == nil {
+		t.Fatal("Write() with invalid type did not error")
+	}
+	if err := w.Write([]any{t1}); err == nil {
+		t.Fatal("Write() with missing values did not error")
+	}
+	if out.Len() != 0 {
+		t.Fatal("Write() wrote an incomplete row group")
+	}
+
+	if err := w.Close(); err != nil {
+		t.Fatalf("Close() error:\n%+v", err)
+	}
+	file := out.Bytes()
+	if !bytes.HasPrefix(file, magic) || !bytes.HasSuffix(file, magic) {
+		t.Fatal("Close() did not produce the magic header and footer")
+	}
+	footerLength := int(binary.LittleEndian.Uint32(file[len(file)-8:]))
+	footer := file[len(file)-8-footerLength : len(file)-8]
+	r := &thriftReader{t: t, buf: bytes.NewReader(footer)}
+	meta := r.structure()
+
+	// Check the column chunks point to the expected values.
+	rowGroup := meta[4].([]any)[0].(map[int]any)
+	for idx, chunk := range rowGroup[1].([]any) {
+		chunkMeta := chunk.(map[int]any)[3].(map[int]any)
+		offset := chunkMeta[9].(int64)
+		r := &thriftReader{t: t, buf: bytes.NewReader(file[offset:])}
+		page := r.structure()
+		data := make([]byte, page[2].(int64))
+		r.buf.Read(data)
+		var expected []byte
+		switch idx {
+		case 0:
+			expected = binary.LittleEndian.AppendUint64(expected, uint64(t1.UnixMilli()))
+			expected = binary.LittleEndian.AppendUint64(expected, uint64(t1.Add(time.Minute).UnixMilli()))
+		case 1:
+			expected = append(expected, "\x05\x00\x00\x00alpha\x04\x00\x00\x00beta"...)
+		case 2:
+			expected = binary.LittleEndian.AppendUint64(expected, 1000)
+			expected = binary.LittleEndian.AppendUint64(expected, ^uint64(3))
+		}
+		if diff := helpers.Diff(data, expected); diff != "" {
+			t.Errorf("Close() column %d (-got, +want):\n%s", idx, diff)
+		}
+		rowGroup[1].([]any)[idx].(map[int]any)[2] = "<offset>"
+		chunkMeta[6], chunkMeta[7], chunkMeta[9] = "<size>", "<size>", "<offset>"
+	}
+	rowGroup[2] = "<size>"
+
+	expected := map[int]any{
+		1: int64(1),
+		2: []any{
+			map[int]any{4: "schema", 5: int64(3)},
+			map[int]any{1: int64(physicalInt64), 3: int64(0), 4: "time", 6: int64(convertedTimestampMillis)},
+			map[int]any{1: int64(physicalByteArray), 3: int64(0), 4: "name", 6: int64(convertedUTF8)},
+			map[int]any{1: int64(physicalInt64), 3: int64(0), 4: "value"},
+		},
+		3: int64(2),
+		4: []any{
+			map[int]any{
+				1: []any{
+					map[int]any{2: "<offset>", 3: map[int]any{
+						1: int64(physicalInt64), 2: []any{int64(0)}, 3: []any{"time"},
+						4: int64(0), 5: int64(2), 6: "<size>", 7: "<size>", 9: "<offset>",
+					}},
+					map[int]any{2: "<offset>", 3: map[int]any{
+						1: int64(physicalByteArray), 2: []any{int64(0)}, 3: []any{"name"},
+						4: int64(0), 5: int64(2), 6: "<size>", 7: "<size>", 9: "<offset>",
+					}},
+					map[int]any{2: "<offset>", 3: map[int]any{
+						1: int64(physicalInt64), 2: []any{int64(0)}, 3: []any{"value"},
+						4: int64(0), 5: int64(2), 6: "<size>", 7: "<size>", 9: "<offset>",
+					}},
+				},
+				2: "<size>",
+				3: int64(2),
+			},
+		},
+		6: "akvorado",
+	}
+	if diff := helpers.Diff(meta, expected); diff != "" {
+		t.Fatalf("Close() metadata (-got, +want):\n%s", diff)
+	}
+}
+
+func TestWriterEmpty(t *testing.T) {
+	var out bytes.Buffer
+	w := NewWriter(&out, []Column{{Name: "name", Type: String}})
+	if err := w.Close(); err != nil {
+		t.Fatalf("Close() error:\n%+v", err)
+	}
+	file := out.Bytes()
+	footerLength := int(binary.LittleEndian.Uint32(file[len(file)-8:]))
+	r := &thriftReader{t: t, buf: bytes.NewReader(file[len(file)-8-footerLength : len(file)-8])}
+	meta := r.structure()
+	if diff := helpers.Diff(meta[3], int64(0)); diff != "" {
+		t.Fatalf("Close() num_rows (-got, +want):\n%s", diff)
+	}
+	if diff := helpers.Diff(meta[4], []any{}); diff != "" {
+		t.Fatalf("Close() row groups (-got, +want):\n%s", diff)
+	}
+}
+
+func TestWriterRowGroups(t *testing.T) {
+	var out bytes.Buffer
+	w := NewWriter(&out, []Column{{Name: "value", Type: Int64}})
+	w.rowGroupRows = 2
+	for i := range 5 {
+		if err := w.Write([]any{i}); err != nil {
+			t.Fatalf("Write() error:\n%+v", err)
+		}
+		if i == 1 && out.Len() == 0 {
+			t.Fatal("Write() did not write the complete row group")
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("Close() error:\n%+v", err)
+	}
+	file := out.Bytes()
+	footerLength := int(binary.LittleEndian.Uint32(file[len(file)-8:]))
+	r := &thriftReader{t: t, buf: bytes.NewReader(file[len(file)-8-footerLength : len(file)-8])}
+	meta := r.structure()
+	if diff := helpers.Diff(meta[3], int64(5)); diff != "" {
+		t.Fatalf("Close() num_rows (-got, +want):\n%s", diff)
+	}
+	rows := []int64{}
+	for _, group := range meta[4].([]any) {
+		rows = append(rows, group.(map[int]any)[3].(int64))
+	}
+	if diff := helpers.Diff(rows, []int64{2, 2, 1}); diff != "" {
+		t.Fatalf("Close() rows per row group (-got, +want):\n%s", diff)
+	}
+}
+
+func TestWriterWithClickHouse(t *testing.T) {
+	r := reporter.NewMock(t)
+	chComponent := clickhousedb.SetupClickHouse(t, r, false)
+
+	// Several row groups are decoded by a real Parquet reader.
+	var out bytes.Buffer
+	w := NewWriter(&out, []Column{
+		{Name: "time", Type: Timestamp},
+		{Name: "name", Type: String},
+		{Name: "value", Type: Int64},
+	})
+	w.rowGroupRows = 2
+	t1 := time.Date(2026, 10, 19, 10, 0, 0, 0, time.UTC)
+	type row struct {
+		Time  time.Time `ch:"time"`
+		Name  string    `ch:"name"`
+		Value int64     `ch:"value"`
+	}
+	expected := []row{
+		{t1, "alpha", 1000},
+		{t1.Add(time.Minute), "beta", -4},
+		{t1.Add(2 * time.Minute), "", 0},
+		{t1.Add(3*time.Minute + 500*time.Millisecond), "été", 1 << 40},
+		{t1.Add(4 * time.Minute), "omega", 7},
+	}
+	for _, row := range expected {
+		if err := w.Write([]any{row.Time, row.Name, row.Value}); err != nil {
+			t.Fatalf("Write() error:\n%+v", err)
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("Close() error:\n%+v", err)
+	}
+
+	var data strings.Builder
+	for _, b := range out.Bytes() {
+		fmt.Fprintf(&data, `\x%02x`, b)
+	}
+	var got []row
+	if err := chComponent.Select(t.Context(), &got, fmt.Sprintf(
+		`SELECT time, name, value FROM format(Parquet, 'time DateTime64(3, \'UTC\'), name String, value Int64', '%s')`,
+		data.String())); err != nil {
+		t.Fatalf("Select() error:\n%+v", err)
+	}
+	if diff := helpers.Diff(got, expected); diff != "" {
+		t.Fatalf("Select() (-got, +want):\n%s", diff)
+	}
+}
@@ -0,0 +1,199 @@
+// SPDX-FileCopyrightText: 2026 Free Mobile
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package console
+
+import (
+	"fmt"
+	"net/http"
+	"slices"
+	"time"
+
+	"akvorado/common/helpers"
+	"akvorado/common/httpserver"
+	"akvorado/common/schema"
+	sb "akvorado/common/sqlbuilder"
+	"akvorado/console/filter"
+	"akvorado/console/query"
+)
+
+// peeringHandlerInput describes the input for the /peering endpoint.
+type peeringHandlerInput struct {
+	schema      *schema.Component
+	database    string
+	definitions filter.Definitions
+	Start       time.Time    `json:"start" validate:"required"`
+	End         time.Time    `json:"end" validate:"required,gtfield=Start"`
+	Direction   string       `json:"direction" validate:"omitempty,oneof=out in"`
+	Filter      query.Filter `json:"filter"`
+	Limit       int          `json:"limit" validate:"min=1"`
+}
+
+// peeringHandlerOutput describes the output for the /peering endpoint.
+type peeringHandlerOutput struct {
+	Samples int          `json:"samples"`
+	Rows    []peeringRow `json:"rows"`
+}
+
+// peeringRow is an AS reached through transit. Rates are in bits per second
+// and volumes in bytes.
+type peeringRow struct {
+	ASN                  uint32   `json:"asn"`
+	Name                 string   `json:"name"`
+	Volume               int      `json:"volume"`
+	NinetyFivePercentile int      `json:"95th"`
+	TransitSites         []string `json:"transit-sites"`
+	PeeringVolume        int      `json:"peering-volume"`
+	PeeringSites         []string `json:"peering-sites"`
+	PeeringNeighbors     []uint32 `json:"peering-neighbors,omitempty"`
+}
+
+// toSQL converts the input to an SQL query. Outbound traffic is ranked by
+// destination AS and inbound traffic by source AS. Only outbound traffic tells
+// the neighbor AS of the peering interfaces. The end of the time range is
+// excluded to get exactly one sample per interval.
+func (input peeringHandlerInput) toSQL(r resolved, transit, peering []string) string {
+	asn, connectivity := "DstAS", "OutIfConnectivity"
+	if input.Direction == "in" {
+		asn, connectivity = "SrcAS", "InIfConnectivity"
+	}
+	in := func(values []string) sb.Expr {
+		items := make([]sb.Expr, len(values))
+		for idx, value := range values {
+			items[idx] = sb.String(value)
+		}
+		return sb.Op(sb.Column(connectivity), "IN", sb.Tuple(items...))
+	}
+	bytes := sb.Op(sb.Column("Bytes"), "*", sb.Column("SamplingRate"))
+	source := sb.Select(
+		sb.Alias(r.toStartOfInterval(), "time"),
+		sb.Alias(sb.Column(asn), "asn"),
+		sb.Alias(sb.Function("sumIf", bytes, in(transit)), "transit"),
+		sb.Alias(sb.Function("sumIf", bytes, in(peering)), "peering"),
+		sb.Alias(sb.Function("groupUniqArrayIf", sb.Column("ExporterSite"), in(transit)), "transitSites"),
+		sb.Alias(sb.Function("groupUniqArrayIf", sb.Column("ExporterSite"), in(peering)), "peeringSites"),
+	)
+	if input.Direction != "in" {
+		source.Item(sb.Alias(sb.Function("groupUniqArrayIf", sb.Column("Dst1stAS"), in(peering)), "peeringNeighbors"))
+	} else {
+		source.Item(sb.Alias(sb.Function("emptyArrayUInt32"), "peeringNeighbors"))
+	}
+	source.From(sb.Table(r.Table)).
+		Where(sb.And(
+			sb.Op(sb.Column("TimeReceived"), ">=", r.timefilterStart()),
+			sb.Op(sb.Column("TimeReceived"), "<", r.timefilterEnd()),
+			input.Filter.Direct(),
+			in(append(slices.Clone(transit), peering...)),
+			sb.Op(sb.Column(asn), "!=", sb.Uint(0)),
+		)).
+		GroupBy(sb.Column("time"), sb.Column("asn"))
+
+	merge := func(column string) sb.Expr {
+		return sb.Function("arraySort", sb.Function("arrayDistinct",
+			sb.Function("arrayFlatten", sb.Function("groupArray", sb.Column(column)))))
+	}
+	return sb.Select(
+		sb.Column("asn"),
+		sb.Alias(query.DictionaryLookup(input.database, schema.DictionaryASNs, sb.Column("asn"), ""), "name"),
+		sb.Alias(sb.Function("sum", sb.Column("transit")), "volume"),
+		sb.Alias(nearestRankPercentile(
+			sb.Op(sb.Op(sb.Column("transit"), "*", sb.Uint(8)), "/", sb.Uint(r.Interval)),
+			r.points(), 0.95), "p95"),
+		sb.Alias(merge("transitSites"), "transitSites"),
+		sb.Alias(sb.Function("sum", sb.Column("peering")), "peeringVolume"),
+		sb.Alias(merge("peeringSites"), "peeringSites"),
+		sb.Alias(merge("peeringNeighbors"), "peeringNeighbors"),
+	).
+		FromSelect(source).
+		GroupBy(sb.Column("asn")).
+		Having(sb.Op(sb.Column("volume"), ">", sb.Uint(0))).
+		OrderBy(sb.Order(sb.Column("volume")).Desc()).
+		Limit(input.Limit).
+		String()
+}
+
+func (c *Component) peeringHandlerFunc(w http.ResponseWriter, req *http.Request) {
+	ctx := c.t.Context(req.Context())
+	input := peeringHandlerInput{
+		schema:      c.d.Schema,
+		database:    c.d.ClickHouseDB.DatabaseName(),
+		definitions: c.filterDefinitions(req.Context()),
+	}
+	if err := httpserver.BindJSON(req, &input); err != nil {
+		httpserver.WriteJSON(w, http.StatusBadRequest, helpers.M{"message": helpers.Capitalize(err.Error())})
+		return
+	}
+	if err := input.Filter.ValidateWithDefinitions(input.schema, input.database, input.definitions); err != nil {
+		httpserver.WriteJSON(w, http.StatusBadRequest, helpers.M{"message": helpers.Capitalize(err.Error())})
+		return
+	}
+	input.Filter = input.Filter.And(c.accessFilter(req.Context()))
+	if input.Limit > c.config.DimensionsLimit {
+		httpserver.WriteJSON(w, http.StatusBadRequest,
+			helpers.M{"message": fmt.Sprintf("Limit is set beyond maximum value (%d)",
+				c.config.DimensionsLimit)})
+		return
+	}
+
+	if input.End.Sub(input.Start) < billingInterval {
+		httpserver.WriteJSON(w, http.StatusBadRequest, helpers.M{"message": "Time range is too short."})
+		return
+	}
+
+	// Samples are taken every 5 minutes, unless only a table with a coarser
+	// resolution covers the time range.
+	r := c.resolve(inputContext{
+		Start:             input.Start,
+		End:               input.End,
+		MainTableRequired: input.Filter.MainTableRequired(),
+		Points:            uint(input.End.Sub(input.Start) / billingInterval),
+	}).forRange(input.Start, input.End)
+	if r.points() == 0 {
+		httpserver.WriteJSON(w, http.StatusBadRequest, helpers.M{"message": "Time range is too short."})
+		return
+	}
+	sqlQuery := input.toSQL(r, c.config.PeeringReport.Transit, c.config.PeeringReport.Peering)
+	w.Header().Set("X-SQL-Query", sqlQuery)
+	results := []struct {
+		ASN              uint32   `ch:"asn"`
+		Name             string   `ch:"name"`
+		Volume           uint64   `ch:"volume"`
+		P95              float64  `ch:"p95"`
+		TransitSites     []string `ch:"transitSites"`
+		PeeringVolume    uint64   `ch:"peeringVolume"`
+		PeeringSites     []string `ch:"peeringSites"`
+		PeeringNeighbors []uint32 `ch:"peeringNeighbors"`
+	}{}
+	ctx, done, ok := c.startQuery(ctx, w, r.Table, sqlQuery)
+	if !ok {
+		return
+	}
+	defer done()
+	if err := c.d.ClickHouseDB.Conn.Select(ctx, &results, sqlQuery); err != nil {
+		c.writeQueryError(ctx, w, err, sqlQuery)
+		return
+	}
+	auditRows(ctx, len(results))
+
+	// Exporters without a site are not interesting.
+	notEmpty := func(sites []string) []string {
+		return slices.DeleteFunc(append([]string{}, sites...), func(site string) bool { return site == "" })
+	}
+	output := peeringHandlerOutput{
+		Samples: r.points(),
+		Rows:    make([]peeringRow, 0, len(results)),
+	}
+	for _, result := range results {
+		output.Rows = append(output.Rows, peeringRow{
+			ASN:                  result.ASN,
+			Name:                 result.Name,
+			Volume:               int(result.Volume),
+			NinetyFivePercentile: int(result.P95),
+			TransitSites:         notEmpty(result.TransitSites),
+			PeeringVolume:        int(result.PeeringVolume),
+			PeeringSites:         notEmpty(result.PeeringSites),
+			PeeringNeighbors:     result.PeeringNeighbors,
+		})
+	}
+	httpserver.WriteJSON(w, http.StatusOK, output)
+}
@@ -0,0 +1,196 @@
+// SPDX-FileCopyrightText: 2026 Free Mobile
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package console
+
+import (
+	"testing"
+	"time"
+
+	"go.uber.org/mock/gomock"
+
+	"akvorado/common/helpers"
+	"akvorado/common/schema"
+	sb "akvorado/common/sqlbuilder"
+	"akvorado/console/query"
+)
+
+func TestPeeringQuerySQL(t *testing.T) {
+	start := time.Date(2026, 9, 1, 0, 0, 0, 0, time.UTC)
+	end := time.Date(2026, 10, 1, 0, 0, 0, 0, time.UTC)
+	cases := []struct {
+		Description string
+		Pos         helpers.Pos
+		Input       peeringHandlerInput
+		Expected    string
+	}{
+		{
+			Description: "outbound",
+			Pos:         helpers.Mark(),
+			Input: peeringHandlerInput{
+				Start:  start,
+				End:    end,
+				Filter: query.NewFilter(""),
+				Limit:  20,
+			},
+			Expected: `
+SELECT asn, dictGetOrDefault('default.asns', 'name', asn, '') AS name,
+ sum(transit) AS volume,
+ arraySort(arrayConcat(groupArray(transit*8/300), arrayWithConstant(8640 - count(), 0)))[8208] AS p95,
+ arraySort(arrayDistinct(arrayFlatten(groupArray(transitSites)))) AS transitSites,
+ sum(peering) AS peeringVolume,
+ arraySort(arrayDistinct(arrayFlatten(groupArray(peeringSites)))) AS peeringSites,
+ arraySort(arrayDistinct(arrayFlatten(groupArray(peeringNeighbors)))) AS peeringNeighbors
+FROM (
+ SELECT
+  toStartOfInterval(TimeReceived + INTERVAL 300 second, INTERVAL 300 second) - INTERVAL 300 second AS time,
+  DstAS AS asn,
+  sumIf(Bytes*SamplingRate, OutIfConnectivity IN ('transit')) AS transit,
+  sumIf(Bytes*SamplingRate, OutIfConnectivity IN ('pni', 'ix')) AS peering,
+  groupUniqArrayIf(ExporterSite, OutIfConnectivity IN ('transit')) AS transitSites,
+  groupUniqArrayIf(ExporterSite, OutIfConnectivity IN ('pni', 'ix')) AS peeringSites,
+  groupUniqArrayIf(Dst1stAS, OutIfConnectivity IN ('pni', 'ix')) AS peeringNeighbors
+ FROM flows_5m0s
+ WHERE TimeReceived >= toDateTime('2026-09-01 00:00:00', 'UTC')
+ AND TimeReceived < toDateTime('2026-10-01 00:00:00', 'UTC')
+ AND OutIfConnectivity IN ('transit', 'pni', 'ix')
+ AND DstAS != 0
+ GROUP BY time, asn
+)
+GROUP BY asn
+HAVING volume > 0
+ORDER BY volume DESC
+LIMIT 20`,
+		}, {
+			Description: "inbound, with filter",
+			Pos:         helpers.Mark(),
+			Input: peeringHandlerInput{
+				Start:     start,
+				End:       end,
+				Direction: "in",
+				Filter:    query.NewFilter("EType = IPv6"),
+				Limit:     20,
+			},
+			Expected: `
+SELECT asn, dictGetOrDefault('default.asns', 'name', asn, '') AS name,
+ sum(transit) AS volume,
+ arraySort(arrayConcat(groupArray(transit*8/300), arrayWithConstant(8640 - count(), 0)))[8208] AS p95,
+ arraySort(arrayDistinct(arrayFlatten(groupArray(transitSites)))) AS transitSites,
+ sum(peering) AS peeringVolume,
+ arraySort(arrayDistinct(arrayFlatten(groupArray(peeringSites)))) AS peeringSites,
+ arraySort(arrayDistinct(arrayFlatten(groupArray(peeringNeighbors)))) AS peeringNeighbors
+FROM (
+ SELECT
+  toStartOfInterval(TimeReceived + INTERVAL 300 second, INTERVAL 300 second) - INTERVAL 300 second AS time,
+  SrcAS AS asn,
+  sumIf(Bytes*SamplingRate, InIfConnectivity IN ('transit')) AS transit,
+  sumIf(Bytes*SamplingRate, InIfConnectivity IN ('pni', 'ix')) AS peering,
+  groupUniqArrayIf(ExporterSite, InIfConnectivity IN ('transit')) AS transitSites,
+  groupUniqArrayIf(ExporterSite, InIfConnectivity IN ('pni', 'ix')) AS peeringSites,
+  emptyArrayUInt32() AS peeringNeighbors
+ FROM flows_5m0s
+ WHERE TimeReceived >= toDateTime('2026-09-01 00:00:00', 'UTC')
+ AND TimeReceived < toDateTime('2026-10-01 00:00:00', 'UTC')
+ AND EType = 34525
+ AND InIfConnectivity IN ('transit', 'pni', 'ix')
+ AND SrcAS != 0
+ GROUP BY time, asn
+)
+GROUP BY asn
+HAVING volume > 0
+ORDER BY volume DESC
+LIMIT 20`,
+		},
+	}
+	sch := schema.NewMock(t)
+	r := resolution{Table: "flows_5m0s", Interval: 300, TableInterval: 5 * time.Minute}.forRange(start, end)
+	for _, tc := range cases {
+		tc.Input.schema = sch
+		tc.Input.database = "default"
+		if err := tc.Input.Filter.Validate(tc.Input.schema, tc.Input.database); err != nil {
+			t.Fatalf("%sValidate() error:\n%+v", tc.Pos, err)
+		}
+		t.Run(tc.Description, func(t *testing.T) {
+			got := sb.Normalize(t, tc.Input.toSQL(r, []string{"transit"}, []string{"pni", "ix"}))
+			if diff := helpers.Diff(got, sb.Normalize(t, tc.Expected)); diff != "" {
+				t.Errorf("%stoSQL (-got, +want):\n%s", tc.Pos, diff)
+			}
+		})
+	}
+}
+
+func TestPeeringHandler(t *testing.T) {
+	_, h, mockConn, _ := NewMock(t, DefaultConfiguration())
+	results := []struct {
+		ASN              uint32   `ch:"asn"`
+		Name             string   `ch:"name"`
+		Volume           uint64   `ch:"volume"`
+		P95              float64  `ch:"p95"`
+		TransitSites     []string `ch:"transitSites"`
+		PeeringVolume    uint64   `ch:"peeringVolume"`
+		PeeringSites     []string `ch:"peeringSites"`
+		PeeringNeighbors []uint32 `ch:"peeringNeighbors"`
+	}{
+		{2906, "Netflix", 5_000_000_000_000, 2_000_000_000, []string{"paris", "lyon"}, 1_000_000_000_000, []string{"", "marseille"}, []uint32{2906}},
+		{15169, "Google", 3_000_000_000_000, 1_000_000_000, []string{"paris"}, 0, []string{}, []uint32{}},
+	}
+	mockConn.EXPECT().
+		Select(gomock.Any(), gomock.Any(), gomock.Any()).
+		SetArg(1, results).
+		Return(nil)
+
+	helpers.TestHTTPEndpoints(t, h.LocalAddr(), helpers.HTTPEndpointCases{
+		{
+			Description: "outbound",
+			URL:         "/api/v0/console/peering",
+			JSONInput: helpers.M{
+				"start": time.Date(2026, 9, 1, 0, 0, 0, 0, time.UTC),
+				"end":   time.Date(2026, 9, 2, 0, 0, 0, 0, time.UTC),
+				"limit": 10,
+			},
+			JSONOutput: helpers.M{
+				"samples": 288,
+				"rows": []helpers.M{
+					{
+						"asn":               2906,
+						"name":              "Netflix",
+						"volume":            5_000_000_000_000,
+						"95th":              2_000_000_000,
+						"transit-sites":     []string{"paris", "lyon"},
+						"peering-volume":    1_000_000_000_000,
+						"peering-sites":     []string{"marseille"},
+						"peering-neighbors": []uint32{2906},
+					}, {
+						"asn":            15169,
+						"name":           "Google",
+						"volume":         3_000_000_000_000,
+						"95th":           1_000_000_000,
+						"transit-sites":  []string{"paris"},
+						"peering-volume": 0,
+						"peering-sites":  []string{},
+					},
+				},
+			},
+		}, {
+			Description: "time range too short",
+			URL:         "/api/v0/console/peering",
+			JSONInput: helpers.M{
+				"start": time.Date(2026, 9, 1, 0, 0, 0, 0, time.UTC),
+				"end":   time.Date(2026, 9, 1, 0, 2, 0, 0, time.UTC),
+				"limit": 10,
+			},
+			StatusCode: 400,
+			JSONOutput: helpers.M{"message": "Time range is too short."},
+		}, {
+			Description: "limit too high",
+			URL:         "/api/v0/console/peering",
+			JSONInput: helpers.M{
+				"start": time.Date(2026, 9, 1, 0, 0, 0, 0, time.UTC),
+				"end":   time.Date(2026, 9, 2, 0, 0, 0, 0, time.UTC),
+				"limit": 100,
+			},
+			StatusCode: 400,
+			JSONOutput: helpers.M{"message": "Limit is set beyond maximum value (50)"},
+		},
+	})
+}
@@ -20,8 +20,13 @@ type Column struct {
 	validated bool
 	name      string
 	key       schema.ColumnKey
+	network   bool
 }
 
+// networkSuffix is appended to the name of an address column to group
+// addresses by their most specific matching network.
+const networkSuffix = "Network"
+
 // Columns is a set of query columns.
 type Columns []Column
 
@@ -63,20 +68,40 @@ func (qc *Column) Key() schema.ColumnKey {
 	return qc.key
 }
 
+// Network returns true if the column groups the addresses of the underlying
+// column by their most specific matching network. In this case, Key() returns
+// the key of the address column.
+func (qc *Column) Network() bool {
+	qc.check()
+	return qc.network
+}
+
 // Validate should be called before using the column. We need a schema component
 // for that.
 func (qc *Column) Validate(schema *schema.Component) error {
 	if column, ok := schema.LookupColumnByName(qc.name); ok && !column.ConsoleNotDimension && !column.Disabled {
 		qc.key = column.Key
+		qc.network = false
 		qc.validated = true
 		return nil
 	}
+	if name, ok := strings.CutSuffix(qc.name, networkSuffix); ok {
+		if column, ok := schema.LookupColumnByName(name); ok && column.ConsoleTruncateIP && !column.Disabled {
+			qc.key = column.Key
+			qc.network = true
+			qc.validated = true
+			return nil
+		}
+	}
 	return fmt.Errorf("unknown column name %s", qc.name)
 }
 
 // Reverse reverses the column direction
 func (qc *Column) Reverse(schema *schema.Component) {
 	name := schema.ReverseColumnDirection(qc.Key()).String()
+	if qc.network {
+		name += networkSuffix
+	}
 	reverted := Column{name: name}
 	if reverted.Validate(schema) == nil {
 		*qc = reverted
@@ -106,6 +131,10 @@ func (qcs Columns) Validate(schema *schema.Component) error {
 func (qc Column) ToSQLSelect(sch *schema.Component, database string) sb.Expr {
 	key := qc.Key()
 	self := sb.Column(qc.String())
+	if qc.network {
+		// The matching network is computed as a string by the caller.
+		return self
+	}
 	switch key {
 	// Special cases
 	case schema.ColumnSrcAS, schema.ColumnDstAS, schema.ColumnDst1stAS, schema.ColumnDst2ndAS, schema.ColumnDst3rdAS:
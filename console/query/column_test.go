@@ -4,6 +4,7 @@
 package query_test
 
 import (
+	"strings"
 	"testing"
 
 	"akvorado/common/helpers"
@@ -18,6 +19,8 @@ func TestUnmarshalQueryColumn(t *testing.T) {
 		Error    bool
 	}{
 		{"DstAddr", schema.ColumnDstAddr, false},
+		{"DstAddrNetwork", schema.ColumnDstAddr, false},
+		{"ExporterNameNetwork", 0, true},
 		{"TimeReceived", 0, true},
 		{"Nothing", 0, true},
 	}
@@ -39,6 +42,9 @@ func TestUnmarshalQueryColumn(t *testing.T) {
 		if diff := helpers.Diff(qc.Key(), tc.Expected); diff != "" {
 			t.Fatalf("UnmarshalText(%q) (-got, +want):\n%s", tc.Input, diff)
 		}
+		if got, expected := qc.Network(), strings.HasSuffix(tc.Input, "Network"); got != expected {
+			t.Fatalf("Network(%q) == %v, expected %v", tc.Input, got, expected)
+		}
 	}
 }
 
@@ -144,6 +150,7 @@ func TestReverseDirection(t *testing.T) {
 		query.NewColumn("DstAS"),
 		query.NewColumn("ExporterName"),
 		query.NewColumn("InIfProvider"),
+		query.NewColumn("SrcAddrNetwork"),
 	}
 	sch := schema.NewMock(t)
 	if err := columns.Validate(sch); err != nil {
@@ -155,6 +162,7 @@ func TestReverseDirection(t *testing.T) {
 		query.NewColumn("SrcAS"),
 		query.NewColumn("ExporterName"),
 		query.NewColumn("OutIfProvider"),
+		query.NewColumn("DstAddrNetwork"),
 	}
 	if diff := helpers.Diff(columns, expected); diff != "" {
 		t.Fatalf("Reverse() (-got, +want):\n%s", diff)
@@ -63,17 +63,28 @@ func (qf *Filter) UnmarshalText(input []byte) error {
 // Validate validates a query filter with the provided schema. The database is
 // the one holding the dictionaries a filter may need.
 func (qf *Filter) Validate(sch *schema.Component, database string) error {
+	return qf.ValidateWithDefinitions(sch, database, filter.Definitions{})
+}
+
+// ValidateWithDefinitions validates a query filter like Validate. The filter
+// can reference the provided named sets and macros.
+func (qf *Filter) ValidateWithDefinitions(sch *schema.Component, database string, definitions filter.Definitions) error {
 	if qf.filter == "" {
 		qf.validated = true
 		return nil
 	}
 	input := []byte(qf.filter)
-	directMeta := &filter.Meta{Schema: sch, Database: database}
+	directMeta := &filter.Meta{Schema: sch, Database: database, Definitions: definitions}
 	direct, err := filter.Parse("", input, filter.GlobalStore("meta", directMeta))
 	if err != nil {
 		return fmt.Errorf("cannot parse filter: %s", filter.HumanError(err))
 	}
-	reverseMeta := &filter.Meta{Schema: sch, Database: database, ReverseDirection: true}
+	reverseMeta := &filter.Meta{
+		Schema:           sch,
+		Database:         database,
+		Definitions:      definitions,
+		ReverseDirection: true,
+	}
 	reverse, err := filter.Parse("", input, filter.GlobalStore("meta", reverseMeta))
 	if err != nil {
 		return fmt.Errorf("cannot parse reverse filter: %s", filter.HumanError(err))
@@ -107,3 +118,23 @@ func (qf Filter) Direct() sb.Expr {
 func (qf *Filter) Swap() {
 	qf.direct, qf.reverse = qf.reverse, qf.direct
 }
+
+// And combines two validated filters. The result matches the flows matched by
+// both filters.
+func (qf Filter) And(oqf Filter) Filter {
+	qf.check()
+	oqf.check()
+	switch {
+	case oqf.filter == "":
+		return qf
+	case qf.filter == "":
+		return oqf
+	}
+	return Filter{
+		validated:         true,
+		filter:            fmt.Sprintf("(%s) AND (%s)", qf.filter, oqf.filter),
+		direct:            sb.And(qf.direct, oqf.direct),
+		reverse:           sb.And(qf.reverse, oqf.reverse),
+		mainTableRequired: qf.mainTableRequired || oqf.mainTableRequired,
+	}
+}
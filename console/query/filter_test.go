@@ -8,6 +8,7 @@ import (
 
 	"akvorado/common/helpers"
 	"akvorado/common/schema"
+	"akvorado/console/filter"
 	"akvorado/console/query"
 )
 
@@ -90,3 +91,84 @@ func TestFilterSwap(t *testing.T) {
 		t.Fatalf("Swap() (-got, +want):\n%s", diff)
 	}
 }
+
+func TestFilterDefinitions(t *testing.T) {
+	definitions := filter.Definitions{
+		Sets:   map[string]string{"transit": "AS174, AS1299"},
+		Macros: map[string]string{"from_transit": "SrcAS IN $transit"},
+	}
+	qf := query.NewFilter("@from_transit AND DstPort = 443")
+	if err := qf.ValidateWithDefinitions(schema.NewMock(t), "", definitions); err != nil {
+		t.Fatalf("ValidateWithDefinitions() error:\n%+v", err)
+	}
+	if diff := helpers.Diff(qf.Direct().String(), "(SrcAS IN (174, 1299)) AND DstPort = 443"); diff != "" {
+		t.Fatalf("Direct() (-got, +want):\n%s", diff)
+	}
+	if diff := helpers.Diff(qf.Reverse().String(), "(DstAS IN (174, 1299)) AND SrcPort = 443"); diff != "" {
+		t.Fatalf("Reverse() (-got, +want):\n%s", diff)
+	}
+
+	qf = query.NewFilter("@from_transit")
+	if err := qf.Validate(schema.NewMock(t), ""); err == nil {
+		t.Fatal("Validate() did not error")
+	}
+}
+
+func TestFilterAnd(t *testing.T) {
+	sch := schema.NewMock(t)
+	cases := []struct {
+		Pos             helpers.Pos
+		Filter1         string
+		Filter2         string
+		ExpectedString  string
+		ExpectedDirect  string
+		ExpectedReverse string
+	}{
+		{
+			Pos:     helpers.Mark(),
+			Filter1: "",
+			Filter2: "",
+		}, {
+			Pos:             helpers.Mark(),
+			Filter1:         "SrcAS = 12322",
+			Filter2:         "",
+			ExpectedString:  "SrcAS = 12322",
+			ExpectedDirect:  "SrcAS = 12322",
+			ExpectedReverse: "DstAS = 12322",
+		}, {
+			Pos:             helpers.Mark(),
+			Filter1:         "",
+			Filter2:         "ExporterName = 'th2-edge1'",
+			ExpectedString:  "ExporterName = 'th2-edge1'",
+			ExpectedDirect:  "ExporterName = 'th2-edge1'",
+			ExpectedReverse: "ExporterName = 'th2-edge1'",
+		}, {
+			Pos:             helpers.Mark(),
+			Filter1:         "SrcAS = 12322 OR SrcAS = 29447",
+			Filter2:         "DstNetTenant = 'acme'",
+			ExpectedString:  "(SrcAS = 12322 OR SrcAS = 29447) AND (DstNetTenant = 'acme')",
+			ExpectedDirect:  "(SrcAS = 12322 OR SrcAS = 29447) AND DstNetTenant = 'acme'",
+			ExpectedReverse: "(DstAS = 12322 OR DstAS = 29447) AND SrcNetTenant = 'acme'",
+		},
+	}
+	for _, tc := range cases {
+		qf1 := query.NewFilter(tc.Filter1)
+		qf2 := query.NewFilter(tc.Filter2)
+		if err := qf1.Validate(sch, ""); err != nil {
+			t.Fatalf("%sValidate() error:\n%+v", tc.Pos, err)
+		}
+		if err := qf2.Validate(sch, ""); err != nil {
+			t.Fatalf("%sValidate() error:\n%+v", tc.Pos, err)
+		}
+		got := qf1.And(qf2)
+		var direct, reverse string
+		if !got.Direct().IsZero() {
+			direct = got.Direct().String()
+			reverse = got.Reverse().String()
+		}
+		if diff := helpers.Diff([]string{got.String(), direct, reverse},
+			[]string{tc.ExpectedString, tc.ExpectedDirect, tc.ExpectedReverse}); diff != "" {
+			t.Errorf("%sAnd() (-got, +want):\n%s", tc.Pos, diff)
+		}
+	}
+}
@@ -13,6 +13,7 @@ import (
 	"path/filepath"
 	"runtime"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/benbjohnson/clock"
@@ -28,6 +29,7 @@ import (
 	"akvorado/console/authentication"
 	"akvorado/console/database"
 	"akvorado/console/query"
+	"akvorado/outlet/geoip"
 )
 
 // Component represents the console component.
@@ -40,9 +42,23 @@ type Component struct {
 	homepageGraphFilter sb.Expr
 	flowsTables         []flowsTable
 	flowsTablesLock     sync.RWMutex
+	accessPolicies      []accessPolicy
+	billingEntities     []billingEntity
+	unrestricted        query.Filter
+	queryUsers          map[string]*queryUser
+	queryUsersLock      sync.Mutex
+
+	// countries are the coordinates of the centroid of each country.
+	countries map[string]coordinates
+	// cities is replaced each time the GeoIP databases are updated.
+	cities atomic.Pointer[map[cityKey]coordinates]
+	// geoipUpdate receives a notification when a GeoIP database changes.
+	geoipUpdate <-chan struct{}
 
 	metrics struct {
 		clickhouseQueries *reporter.CounterVec
+		userQueries       *reporter.CounterVec
+		rejectedQueries   *reporter.CounterVec
 	}
 }
 
@@ -55,6 +71,7 @@ type Dependencies struct {
 	Auth         *authentication.Component
 	Database     *database.Component
 	Schema       *schema.Component
+	GeoIP        *geoip.Component
 }
 
 // New creates a new console component.
@@ -79,6 +96,25 @@ func New(r *reporter.Reporter, config Configuration, dependencies Dependencies)
 		config:              config,
 		homepageGraphFilter: homepageGraphFilter,
 		flowsTables:         []flowsTable{{"flows", 0, time.Time{}}},
+		queryUsers:          map[string]*queryUser{},
+	}
+	if err := c.newFilterDefinitions(); err != nil {
+		return nil, err
+	}
+	if err := c.newAccessPolicies(); err != nil {
+		return nil, err
+	}
+	if err := c.newBillingEntities(); err != nil {
+		return nil, err
+	}
+	if err := c.validateBuiltinDashboards(); err != nil {
+		return nil, err
+	}
+	if err := c.loadCountries(); err != nil {
+		return nil, err
+	}
+	if c.d.GeoIP != nil {
+		c.geoipUpdate = c.d.GeoIP.Notify()
 	}
 
 	c.d.Daemon.Track(&c.t, "console")
@@ -89,6 +125,18 @@ func New(r *reporter.Reporter, config Configuration, dependencies Dependencies)
 			Help: "Number of requests to ClickHouse.",
 		}, []string{"table"},
 	)
+	c.metrics.userQueries = c.r.CounterVec(
+		reporter.CounterOpts{
+			Name: "user_queries_total",
+			Help: "Number of requests to ClickHouse for each user.",
+		}, []string{"user"},
+	)
+	c.metrics.rejectedQueries = c.r.CounterVec(
+		reporter.CounterOpts{
+			Name: "user_rejected_queries_total",
+			Help: "Number of queries rejected for each user.",
+		}, []string{"user", "reason"},
+	)
 	return &c, nil
 }
 
@@ -102,24 +150,51 @@ func (c *Component) Start() error {
 	c.d.HTTP.AddHandler("/assets/", http.StripPrefix("/assets/", http.HandlerFunc(c.staticAssetsHandlerFunc)))
 	c.d.HTTP.AddHandler("/assets/docs/", http.StripPrefix("/assets/docs/", http.HandlerFunc(c.docAssetsHandlerFunc)))
 	// Dynamic assets
-	endpoint := c.d.HTTP.APIRouter.Group("/api/v0/console", c.d.Auth.UserAuthentication())
+	auth := c.d.HTTP.APIRouter.Group("/api/v0/console/auth")
+	auth.GET("/login", c.d.Auth.OIDCLoginHandlerFunc)
+	auth.GET("/callback", c.d.Auth.OIDCCallbackHandlerFunc)
+	auth.GET("/logout", c.d.Auth.OIDCLogoutHandlerFunc)
+	endpoint := c.d.HTTP.APIRouter.Group("/api/v0/console",
+		c.d.Auth.UserAuthentication(), c.accessControl())
+	definitions := c.filterDefinitionsMiddleware()
 	endpoint.GET("/configuration", c.configHandlerFunc)
 	endpoint.GET("/docs/{name}", c.docsHandlerFunc)
-	endpoint.GET("/widget/flow-last", c.widgetFlowLastHandlerFunc, c.d.HTTP.CacheByRequestPath(5*time.Second))
-	endpoint.GET("/widget/flow-rate", c.widgetFlowRateHandlerFunc, c.d.HTTP.CacheByRequestPath(5*time.Second))
-	endpoint.GET("/widget/exporters", c.widgetExportersHandlerFunc, c.d.HTTP.CacheByRequestPath(30*time.Second))
-	endpoint.GET("/widget/top/{name}", c.widgetTopHandlerFunc, c.d.HTTP.CacheByRequestPath(30*time.Second))
-	endpoint.GET("/widget/graph", c.widgetGraphHandlerFunc, c.d.HTTP.CacheByRequestPath(5*time.Minute))
-	endpoint.POST("/graph/line", c.graphLineHandlerFunc, c.d.HTTP.CacheByRequestBody(c.config.CacheTTL))
-	endpoint.POST("/graph/sankey", c.graphSankeyHandlerFunc, c.d.HTTP.CacheByRequestBody(c.config.CacheTTL))
+	endpoint.GET("/widget/flow-last", c.widgetFlowLastHandlerFunc, c.auditLog(), c.d.HTTP.CacheByRequestPath(5*time.Second))
+	endpoint.GET("/widget/flow-rate", c.widgetFlowRateHandlerFunc, c.auditLog(), c.d.HTTP.CacheByRequestPath(5*time.Second))
+	endpoint.GET("/widget/exporters", c.widgetExportersHandlerFunc, c.auditLog(), c.d.HTTP.CacheByRequestPath(30*time.Second))
+	endpoint.GET("/widget/top/{name}", c.widgetTopHandlerFunc, c.auditLog(), c.d.HTTP.CacheByRequestPath(30*time.Second))
+	endpoint.GET("/widget/graph", c.widgetGraphHandlerFunc, c.auditLog(), c.d.HTTP.CacheByRequestPath(5*time.Minute))
+	endpoint.POST("/graph/line", c.graphLineHandlerFunc, c.auditLog(), definitions, c.d.HTTP.CacheByRequestBody(c.config.CacheTTL))
+	endpoint.POST("/graph/sankey", c.graphSankeyHandlerFunc, c.auditLog(), definitions, c.d.HTTP.CacheByRequestBody(c.config.CacheTTL))
+	endpoint.POST("/graph/line/export", c.graphLineExportHandlerFunc, c.auditLog(), definitions)
+	endpoint.POST("/graph/sankey/export", c.graphSankeyExportHandlerFunc, c.auditLog(), definitions)
 	endpoint.POST("/graph/table-interval", c.getTableAndIntervalHandlerFunc)
-	endpoint.POST("/filter/validate", c.filterValidateHandlerFunc)
-	endpoint.POST("/filter/complete", c.filterCompleteHandlerFunc, c.d.HTTP.CacheByRequestBody(time.Minute))
+	endpoint.POST("/flows", c.flowsHandlerFunc, c.auditLog(), definitions)
+	endpoint.GET("/billing/{month}", c.billingHandlerFunc, c.auditLog(), c.d.HTTP.CacheByRequestPath(c.config.CacheTTL))
+	endpoint.GET("/billing/{month}/export", c.billingExportHandlerFunc, c.auditLog())
+	endpoint.POST("/peering", c.peeringHandlerFunc, c.auditLog(), definitions, c.d.HTTP.CacheByRequestBody(c.config.CacheTTL))
+	endpoint.POST("/diff", c.diffHandlerFunc, c.auditLog(), definitions, c.d.HTTP.CacheByRequestBody(c.config.CacheTTL))
+	endpoint.POST("/anomalies", c.anomaliesHandlerFunc, c.auditLog())
+	endpoint.POST("/map", c.mapHandlerFunc, c.auditLog(), definitions, c.d.HTTP.CacheByRequestBody(c.config.CacheTTL))
+	endpoint.POST("/filter/validate", c.filterValidateHandlerFunc, definitions)
+	endpoint.POST("/filter/complete", c.filterCompleteHandlerFunc, c.auditLog(), definitions, c.d.HTTP.CacheByRequestBody(time.Minute))
 	endpoint.GET("/filter/saved", c.filterSavedListHandlerFunc)
-	endpoint.DELETE("/filter/saved/{id}", c.filterSavedDeleteHandlerFunc)
-	endpoint.POST("/filter/saved", c.filterSavedAddHandlerFunc)
+	endpoint.DELETE("/filter/saved/{id}", c.filterSavedDeleteHandlerFunc, c.d.Auth.RequireWriteAccess())
+	endpoint.POST("/filter/saved", c.filterSavedAddHandlerFunc, c.d.Auth.RequireWriteAccess())
+	endpoint.GET("/filter/definitions", c.filterDefinitionsListHandlerFunc)
+	endpoint.POST("/filter/definitions", c.filterDefinitionsSetHandlerFunc, c.d.Auth.RequireWriteAccess(), definitions)
+	endpoint.DELETE("/filter/definitions/{id}", c.filterDefinitionsDeleteHandlerFunc, c.d.Auth.RequireWriteAccess())
+	endpoint.GET("/dashboards", c.dashboardsListHandlerFunc)
+	endpoint.GET("/dashboards/{id}", c.dashboardGetHandlerFunc)
+	endpoint.POST("/dashboards", c.dashboardAddHandlerFunc, c.d.Auth.RequireWriteAccess(), definitions)
+	endpoint.PUT("/dashboards/{id}", c.dashboardUpdateHandlerFunc, c.d.Auth.RequireWriteAccess(), definitions)
+	endpoint.DELETE("/dashboards/{id}", c.dashboardDeleteHandlerFunc, c.d.Auth.RequireWriteAccess())
+	endpoint.GET("/audit", c.auditLogHandlerFunc, c.d.Auth.RequireAdmin())
 	endpoint.GET("/user/info", c.d.Auth.UserInfoHandlerFunc)
 	endpoint.GET("/user/avatar", c.d.Auth.UserAvatarHandlerFunc)
+	endpoint.GET("/user/tokens", c.d.Auth.APITokensListHandlerFunc)
+	endpoint.POST("/user/tokens", c.d.Auth.APITokenCreateHandlerFunc, c.d.Auth.RequireWriteAccess())
+	endpoint.DELETE("/user/tokens/{id}", c.d.Auth.APITokenDeleteHandlerFunc, c.d.Auth.RequireWriteAccess())
 
 	c.t.Go(func() error {
 		ticker := time.NewTicker(10 * time.Second)
@@ -138,6 +213,37 @@ func (c *Component) Start() error {
 			}
 		}
 	})
+	c.t.Go(func() error {
+		ticker := c.d.Clock.Ticker(time.Hour)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				c.pruneAuditLog()
+			case <-c.t.Dying():
+				return nil
+			}
+		}
+	})
+	if c.geoipUpdate != nil {
+		c.t.Go(func() error {
+			// Databases opened on start have already sent a notification,
+			// drop it as we are reading them right now.
+			select {
+			case <-c.geoipUpdate:
+			default:
+			}
+			c.rebuildCities()
+			for {
+				select {
+				case <-c.t.Dying():
+					return nil
+				case <-c.geoipUpdate:
+					c.rebuildCities()
+				}
+			}
+		})
+	}
 	return nil
 }
 
@@ -4,6 +4,7 @@
 package console
 
 import (
+	"context"
 	"fmt"
 	"net/http"
 	"slices"
@@ -148,37 +149,61 @@ func (input graphSankeyHandlerInput) toSQL(res resolution) []*sb.Query {
 
 func (c *Component) graphSankeyHandlerFunc(w http.ResponseWriter, req *http.Request) {
 	ctx := c.t.Context(req.Context())
+	input, ok := c.bindGraphSankeyInput(w, req)
+	if !ok {
+		return
+	}
+	output, ok := c.graphSankeyOutput(ctx, w, input)
+	if !ok {
+		return
+	}
+	httpserver.WriteJSON(w, http.StatusOK, output)
+}
+
+// bindGraphSankeyInput decodes and validates the input of the /graph/sankey
+// endpoint. On error, it writes the response and returns false.
+func (c *Component) bindGraphSankeyInput(w http.ResponseWriter, req *http.Request) (graphSankeyHandlerInput, bool) {
 	input := graphSankeyHandlerInput{graphCommonHandlerInput: graphCommonHandlerInput{
-		schema:   c.d.Schema,
-		database: c.d.ClickHouseDB.DatabaseName(),
+		schema:      c.d.Schema,
+		database:    c.d.ClickHouseDB.DatabaseName(),
+		definitions: c.filterDefinitions(req.Context()),
 	}}
 	if err := httpserver.BindJSON(req, &input); err != nil {
 		httpserver.WriteJSON(w, http.StatusBadRequest, helpers.M{"message": helpers.Capitalize(err.Error())})
-		return
+		return input, false
 	}
-	if err := query.Columns(input.Dimensions).Validate(input.schema); err != nil {
+	if err := input.validateDimensions(); err != nil {
 		httpserver.WriteJSON(w, http.StatusBadRequest, helpers.M{"message": helpers.Capitalize(err.Error())})
-		return
+		return input, false
 	}
-	if err := input.Filter.Validate(input.schema, input.database); err != nil {
+	if err := input.Filter.ValidateWithDefinitions(input.schema, input.database, input.definitions); err != nil {
 		httpserver.WriteJSON(w, http.StatusBadRequest, helpers.M{"message": helpers.Capitalize(err.Error())})
-		return
+		return input, false
 	}
+	if err := input.applyDeduplication(); err != nil {
+		httpserver.WriteJSON(w, http.StatusBadRequest, helpers.M{"message": helpers.Capitalize(err.Error())})
+		return input, false
+	}
+	input.Filter = input.Filter.And(c.accessFilter(req.Context()))
 	if input.Limit > c.config.DimensionsLimit {
 		httpserver.WriteJSON(w, http.StatusBadRequest,
 			helpers.M{"message": fmt.Sprintf("Limit is set beyond maximum value (%d)",
 				c.config.DimensionsLimit)})
-		return
+		return input, false
 	}
 	if len(input.Dimensions) == 0 {
 		// A sankey diagram links dimension values together, there is nothing to
 		// draw without a dimension.
 		httpserver.WriteJSON(w, http.StatusBadRequest,
 			helpers.M{"message": "At least one dimension is required."})
-		return
+		return input, false
 	}
+	return input, true
+}
 
-	// Prepare and execute query
+// graphSankeyOutput queries the database and computes the output of the
+// /graph/sankey endpoint. On error, it writes the response and returns false.
+func (c *Component) graphSankeyOutput(ctx context.Context, w http.ResponseWriter, input graphSankeyHandlerInput) (graphSankeyHandlerOutput, bool) {
 	r := c.resolve(input.resolveContext())
 	sqlQuery := unionAll(input.toSQL(r))
 	w.Header().Set("X-SQL-Query", strings.ReplaceAll(sqlQuery, "\n", "  "))
@@ -187,12 +212,16 @@ func (c *Component) graphSankeyHandlerFunc(w http.ResponseWriter, req *http.Requ
 		Xps        float64  `ch:"xps"`
 		Dimensions []string `ch:"dimensions"`
 	}{}
-	c.metrics.clickhouseQueries.WithLabelValues(r.Table).Inc()
+	ctx, done, ok := c.startQuery(ctx, w, r.Table, sqlQuery)
+	if !ok {
+		return graphSankeyHandlerOutput{}, false
+	}
+	defer done()
 	if err := c.d.ClickHouseDB.Conn.Select(ctx, &results, sqlQuery); err != nil {
-		c.r.Err(err).Str("query", sqlQuery).Msg("unable to query database")
-		httpserver.WriteJSON(w, http.StatusInternalServerError, helpers.M{"message": "Unable to query database."})
-		return
+		c.writeQueryError(ctx, w, err, sqlQuery)
+		return graphSankeyHandlerOutput{}, false
 	}
+	auditRows(ctx, len(results))
 
 	// Prepare output
 	output := graphSankeyHandlerOutput{
@@ -212,7 +241,8 @@ func (c *Component) graphSankeyHandlerFunc(w http.ResponseWriter, req *http.Requ
 	for i, col := range input.Dimensions {
 		dimensionLabels[1][i] = col.String()
 		if input.Bidirectional {
-			dimensionLabels[2][i] = input.schema.ReverseColumnDirection(col.Key()).String()
+			col.Reverse(input.schema)
+			dimensionLabels[2][i] = col.String()
 		}
 	}
 
@@ -269,6 +299,5 @@ func (c *Component) graphSankeyHandlerFunc(w http.ResponseWriter, req *http.Requ
 			output.AxisNames[axis] = "Reverse"
 		}
 	}
-
-	httpserver.WriteJSON(w, http.StatusOK, output)
+	return output, true
 }
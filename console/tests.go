@@ -30,13 +30,19 @@ func NewMock(t *testing.T, config Configuration) (*Component, *httpserver.Compon
 	h := httpserver.NewMock(t, r)
 	ch, mockConn := clickhousedb.NewMock(t, r)
 	mockClock := clock.NewMock()
+	db := database.NewMock(t, r, database.DefaultConfiguration())
+	auth, err := authentication.New(r, authentication.DefaultConfiguration(),
+		authentication.Dependencies{Database: db})
+	if err != nil {
+		t.Fatalf("authentication.New() error:\n%+v", err)
+	}
 	c, err := New(r, config, Dependencies{
 		Daemon:       daemon.NewMock(t),
 		HTTP:         h,
 		ClickHouseDB: ch,
 		Clock:        mockClock,
-		Auth:         authentication.NewMock(t, r),
-		Database:     database.NewMock(t, r, database.DefaultConfiguration()),
+		Auth:         auth,
+		Database:     db,
 		Schema:       schema.NewMock(t),
 	})
 	if err != nil {
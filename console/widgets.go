@@ -17,24 +17,27 @@ import (
 	"akvorado/console/query"
 )
 
+// readableFlowColumns are the columns of the flows table not readable as they
+// are stored, with an expression to get them in a friendlier form.
+var readableFlowColumns = []struct {
+	key         schema.ColumnKey
+	replaceWith sb.Expr
+}{
+	{schema.ColumnSrcCommunities, query.CommunitiesToStrings("SrcCommunities")},
+	{schema.ColumnSrcLargeCommunities, query.LargeCommunitiesToStrings("SrcLargeCommunities")},
+	{schema.ColumnDstCommunities, query.CommunitiesToStrings("DstCommunities")},
+	{schema.ColumnDstLargeCommunities, query.LargeCommunitiesToStrings("DstLargeCommunities")},
+	{schema.ColumnSrcMAC, sb.Function("MACNumToString", sb.Column("SrcMAC"))},
+	{schema.ColumnDstMAC, sb.Function("MACNumToString", sb.Column("DstMAC"))},
+}
+
 func (c *Component) widgetFlowLastHandlerFunc(w http.ResponseWriter, req *http.Request) {
 	ctx := c.t.Context(req.Context())
-	replace := []struct {
-		key         schema.ColumnKey
-		replaceWith sb.Expr
-	}{
-		{schema.ColumnSrcCommunities, query.CommunitiesToStrings("SrcCommunities")},
-		{schema.ColumnSrcLargeCommunities, query.LargeCommunitiesToStrings("SrcLargeCommunities")},
-		{schema.ColumnDstCommunities, query.CommunitiesToStrings("DstCommunities")},
-		{schema.ColumnDstLargeCommunities, query.LargeCommunitiesToStrings("DstLargeCommunities")},
-		{schema.ColumnSrcMAC, sb.Function("MACNumToString", sb.Column("SrcMAC"))},
-		{schema.ColumnDstMAC, sb.Function("MACNumToString", sb.Column("DstMAC"))},
-	}
-	// The columns below are not readable as they are stored, so they are
-	// dropped from the "*" and added back in a friendlier form.
+	// Unreadable columns are dropped from the "*" and added back in a
+	// friendlier form.
 	replaced := []sb.Expr{}
 	except := []string{}
-	for _, r := range replace {
+	for _, r := range readableFlowColumns {
 		if column, ok := c.d.Schema.LookupColumnByKey(r.key); ok && !column.Disabled {
 			except = append(except, r.key.String())
 			replaced = append(replaced, sb.Alias(r.replaceWith, r.key.String()))
@@ -51,17 +54,24 @@ func (c *Component) widgetFlowLastHandlerFunc(w http.ResponseWriter, req *http.R
 	}
 	sqlQuery := last.
 		From(sb.Table("flows")).
-		Where(sb.Op(sb.Column("TimeReceived"), "=",
-			sb.Select(sb.Function("MAX", sb.Column("TimeReceived"))).
-				From(sb.Table("flows")).Subquery())).
+		Where(sb.And(
+			sb.Op(sb.Column("TimeReceived"), "=",
+				sb.Select(sb.Function("MAX", sb.Column("TimeReceived"))).
+					From(sb.Table("flows")).
+					Where(c.accessWhere(req.Context())).
+					Subquery()),
+			c.accessWhere(req.Context()))).
 		Limit(1).
 		String()
 	w.Header().Set("X-SQL-Query", sqlQuery)
-	// Do not increase counter for this one.
+	ctx, done, ok := c.startQuery(ctx, w, "flows", sqlQuery)
+	if !ok {
+		return
+	}
+	defer done()
 	rows, err := c.d.ClickHouseDB.Conn.Query(ctx, sqlQuery)
 	if err != nil {
-		c.r.Err(err).Msg("unable to query database")
-		httpserver.WriteJSON(w, http.StatusInternalServerError, helpers.M{"message": "Unable to query database."})
+		c.writeQueryError(ctx, w, err, sqlQuery)
 		return
 	}
 	defer rows.Close()
@@ -84,6 +94,7 @@ func (c *Component) widgetFlowLastHandlerFunc(w http.ResponseWriter, req *http.R
 		httpserver.WriteJSON(w, http.StatusInternalServerError, helpers.M{"message": "Unable to parse flow."})
 		return
 	}
+	auditRows(ctx, 1)
 	for index, column := range rows.Columns() {
 		response[column] = vars[index]
 	}
@@ -93,8 +104,18 @@ func (c *Component) widgetFlowLastHandlerFunc(w http.ResponseWriter, req *http.R
 func (c *Component) widgetFlowRateHandlerFunc(w http.ResponseWriter, req *http.Request) {
 	ctx := c.t.Context(req.Context())
 	query := `SELECT COUNT(*)/300 AS rate FROM flows WHERE TimeReceived > date_sub(minute, 5, now())`
+	if c.accessRestricted(req.Context()) {
+		query = sb.Select(sb.Alias(sb.MustParseExpr("COUNT(*)/300"), "rate")).
+			From(sb.Table("flows")).
+			Where(sb.And(recentFlows(5), c.accessWhere(req.Context()))).
+			String()
+	}
 	w.Header().Set("X-SQL-Query", query)
-	// Do not increase counter for this one.
+	ctx, done, ok := c.startQuery(ctx, w, "flows", query)
+	if !ok {
+		return
+	}
+	defer done()
 	var result float64
 	row := c.d.ClickHouseDB.Conn.QueryRow(ctx, query)
 	if err := row.Scan(&result); err != nil {
@@ -102,6 +123,7 @@ func (c *Component) widgetFlowRateHandlerFunc(w http.ResponseWriter, req *http.R
 		httpserver.WriteJSON(w, http.StatusInternalServerError, helpers.M{"message": "Unable to parse result."})
 		return
 	}
+	auditRows(ctx, 1)
 	httpserver.WriteIndentedJSON(w, http.StatusOK, helpers.M{
 		"rate":   result,
 		"period": "second",
@@ -110,19 +132,34 @@ func (c *Component) widgetFlowRateHandlerFunc(w http.ResponseWriter, req *http.R
 
 func (c *Component) widgetExportersHandlerFunc(w http.ResponseWriter, req *http.Request) {
 	ctx := c.t.Context(req.Context())
+	table := "exporters"
 	query := `SELECT ExporterName FROM exporters GROUP BY ExporterName ORDER BY ExporterName`
+	if c.accessRestricted(req.Context()) {
+		// The exporters table is not restricted, use the recent flows instead.
+		exporterName := sb.Column("ExporterName")
+		table = "flows"
+		query = sb.Select(exporterName).
+			From(sb.Table("flows")).
+			Where(sb.And(recentFlows(60), c.accessWhere(req.Context()))).
+			GroupBy(exporterName).
+			OrderBy(sb.Order(exporterName)).
+			String()
+	}
 	w.Header().Set("X-SQL-Query", query)
-	// Do not increase counter for this one.
+	ctx, done, ok := c.startQuery(ctx, w, table, query)
+	if !ok {
+		return
+	}
+	defer done()
 
 	exporters := []struct {
 		ExporterName string
 	}{}
-	err := c.d.ClickHouseDB.Conn.Select(ctx, &exporters, query)
-	if err != nil {
-		c.r.Err(err).Msg("unable to query database")
-		httpserver.WriteJSON(w, http.StatusInternalServerError, helpers.M{"message": "Unable to query database."})
+	if err := c.d.ClickHouseDB.Conn.Select(ctx, &exporters, query); err != nil {
+		c.writeQueryError(ctx, w, err, query)
 		return
 	}
+	auditRows(ctx, len(exporters))
 	exporterList := make([]string, len(exporters))
 	for idx, exporter := range exporters {
 		exporterList[idx] = exporter.ExporterName
@@ -210,15 +247,16 @@ func (c *Component) widgetTopHandlerFunc(w http.ResponseWriter, req *http.Reques
 		groupby = []sb.Expr{selector}
 	}
 
+	access := c.accessFilter(req.Context())
 	now := c.d.Clock.Now()
 	start, end := now.Add(-5*time.Minute), now
 	r := c.resolve(inputContext{
 		Start:             start,
 		End:               end,
-		MainTableRequired: mainTableRequired,
+		MainTableRequired: mainTableRequired || access.MainTableRequired(),
 		Points:            5,
 	}).forRange(start, end)
-	where := sb.And(r.timefilter(), filter)
+	where := sb.And(r.timefilter(), filter, access.Direct())
 	bytes := sb.MustParseExpr("SUM(Bytes*SamplingRate)")
 	sqlQuery := sb.Select(
 		sb.Alias(sb.Function("if",
@@ -238,23 +276,28 @@ func (c *Component) widgetTopHandlerFunc(w http.ResponseWriter, req *http.Reques
 	w.Header().Set("X-SQL-Query", sqlQuery)
 
 	results := []topResult{}
-	c.metrics.clickhouseQueries.WithLabelValues(r.Table).Inc()
+	ctx, done, ok := c.startQuery(ctx, w, r.Table, sqlQuery)
+	if !ok {
+		return
+	}
+	defer done()
 	if err := c.d.ClickHouseDB.Conn.Select(ctx, &results, sqlQuery); err != nil {
-		c.r.Err(err).Msg("unable to query database")
-		httpserver.WriteJSON(w, http.StatusInternalServerError, helpers.M{"message": "Unable to query database."})
+		c.writeQueryError(ctx, w, err, sqlQuery)
 		return
 	}
+	auditRows(ctx, len(results))
 	httpserver.WriteJSON(w, http.StatusOK, helpers.M{"top": results})
 }
 
 func (c *Component) widgetGraphHandlerFunc(w http.ResponseWriter, req *http.Request) {
 	ctx := c.t.Context(req.Context())
+	access := c.accessFilter(req.Context())
 	now := c.d.Clock.Now()
 	start, end := now.Add(-c.config.HomepageGraphTimeRange), now
 	r := c.resolve(inputContext{
 		Start:             start,
 		End:               end,
-		MainTableRequired: false,
+		MainTableRequired: access.MainTableRequired(),
 		Points:            200,
 	}).forRange(start, end)
 	gbps := sb.Function("SUM",
@@ -267,7 +310,7 @@ func (c *Component) widgetGraphHandlerFunc(w http.ResponseWriter, req *http.Requ
 		sb.Alias(r.toStartOfInterval(), "Time"),
 		sb.Alias(gbps, "Gbps")).
 		From(sb.Table(r.Table)).
-		Where(sb.And(r.timefilter(), c.homepageGraphFilter)).
+		Where(sb.And(r.timefilter(), c.homepageGraphFilter, access.Direct())).
 		GroupBy(sb.Column("Time")).
 		OrderBy(sb.Order(sb.Column("Time")).Fill(
 			r.timefilterStart(),
@@ -280,13 +323,16 @@ func (c *Component) widgetGraphHandlerFunc(w http.ResponseWriter, req *http.Requ
 		Time time.Time `json:"t"`
 		Gbps float64   `json:"gbps"`
 	}{}
-	c.metrics.clickhouseQueries.WithLabelValues(r.Table).Inc()
-	err := c.d.ClickHouseDB.Conn.Select(ctx, &results, sqlQuery)
-	if err != nil {
-		c.r.Err(err).Msg("unable to query database")
-		httpserver.WriteJSON(w, http.StatusInternalServerError, helpers.M{"message": "Unable to query database."})
+	ctx, done, ok := c.startQuery(ctx, w, r.Table, sqlQuery)
+	if !ok {
+		return
+	}
+	defer done()
+	if err := c.d.ClickHouseDB.Conn.Select(ctx, &results, sqlQuery); err != nil {
+		c.writeQueryError(ctx, w, err, sqlQuery)
 		return
 	}
+	auditRows(ctx, len(results))
 
 	httpserver.WriteJSON(w, http.StatusOK, helpers.M{"data": results})
 }
@@ -374,3 +374,50 @@ ORDER BY Time WITH FILL
 		})
 	}
 }
+
+func TestWidgetsQueryLimits(t *testing.T) {
+	config := DefaultConfiguration()
+	config.QueryLimits.Rate = 0.1
+	config.QueryLimits.Burst = 1
+	_, h, mockConn, _ := NewMock(t, config)
+
+	// Only the first query is run, the clock does not move.
+	mockConn.EXPECT().
+		Select(gomock.Any(), gomock.Any(), gomock.Any()).
+		Return(nil).
+		Times(1)
+
+	helpers.TestHTTPEndpoints(t, h.LocalAddr(), helpers.HTTPEndpointCases{
+		{
+			Description: "first widget",
+			URL:         "/api/v0/console/widget/top/src-as",
+			JSONOutput:  helpers.M{"top": []helpers.M{}},
+		}, {
+			Description: "graph widget",
+			URL:         "/api/v0/console/widget/graph",
+			StatusCode:  429,
+			JSONOutput:  helpers.M{"message": "Too many queries, please retry later."},
+		}, {
+			Description: "flow rate widget",
+			URL:         "/api/v0/console/widget/flow-rate",
+			StatusCode:  429,
+			JSONOutput:  helpers.M{"message": "Too many queries, please retry later."},
+		}, {
+			Description: "last flow widget",
+			URL:         "/api/v0/console/widget/flow-last",
+			StatusCode:  429,
+			JSONOutput:  helpers.M{"message": "Too many queries, please retry later."},
+		}, {
+			Description: "exporters widget",
+			URL:         "/api/v0/console/widget/exporters",
+			StatusCode:  429,
+			JSONOutput:  helpers.M{"message": "Too many queries, please retry later."},
+		}, {
+			Description: "completion",
+			URL:         "/api/v0/console/filter/complete",
+			JSONInput:   helpers.M{"what": "value", "column": "srcMAC", "prefix": "11:"},
+			StatusCode:  429,
+			JSONOutput:  helpers.M{"message": "Too many queries, please retry later."},
+		},
+	})
+}
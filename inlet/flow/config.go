@@ -4,6 +4,8 @@
 package flow
 
 import (
+	"time"
+
 	"akvorado/common/helpers"
 	"akvorado/common/pb"
 	"akvorado/inlet/flow/input"
@@ -15,6 +17,9 @@ import (
 type Configuration struct {
 	// Inputs define a list of input modules to enable
 	Inputs []InputConfiguration `validate:"dive"`
+	// ExporterIdleTimeout is the duration after which an exporter not seen
+	// anymore is removed from the statistics. 0 means never.
+	ExporterIdleTimeout time.Duration
 }
 
 // DefaultConfiguration represents the default configuration for the flow component
@@ -29,6 +34,7 @@ func DefaultConfiguration() Configuration {
 			Decoder:         pb.RawFlow_DECODER_SFLOW,
 			Config:          udp.DefaultConfiguration(),
 		}},
+		ExporterIdleTimeout: time.Hour,
 	}
 }
 
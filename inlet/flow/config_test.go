@@ -346,6 +346,7 @@ func TestMarshalYAML(t *testing.T) {
 	expected := `inputs:
     - decapsulationprotocol: none
       decoder: netflow
+      forward: []
       listen: 192.0.2.11:2055
       ratelimit: 0
       receivebuffer: 0
@@ -355,6 +356,7 @@ func TestMarshalYAML(t *testing.T) {
       workers: 3
     - decapsulationprotocol: srv6
       decoder: sflow
+      forward: []
       listen: 192.0.2.11:6343
       ratelimit: 0
       receivebuffer: 0
@@ -362,6 +364,7 @@ func TestMarshalYAML(t *testing.T) {
       type: udp
       usesrcaddrforexporteraddr: true
       workers: 3
+exporteridletimeout: 0s
 `
 	if diff := helpers.Diff(strings.Split(string(got), "\n"), strings.Split(expected, "\n")); diff != "" {
 		t.Fatalf("Marshal() (-got, +want):\n%s", diff)
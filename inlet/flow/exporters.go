@@ -0,0 +1,126 @@
+// SPDX-FileCopyrightText: 2026 Free Mobile
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package flow
+
+import (
+	"cmp"
+	"net/http"
+	"net/url"
+	"reflect"
+	"slices"
+	"sync/atomic"
+	"time"
+
+	"akvorado/common/httpserver"
+	"akvorado/inlet/flow/input"
+	"akvorado/inlet/flow/input/udp"
+)
+
+// exporterKey identifies an exporter seen by an input.
+type exporterKey struct {
+	input    int
+	exporter string
+}
+
+// exporterStats contains the statistics for an exporter seen by an input. They
+// are updated from the input workers.
+type exporterStats struct {
+	lastSeen    atomic.Int64
+	packets     atomic.Uint64
+	bytes       atomic.Uint64
+	kafkaErrors atomic.Uint64
+}
+
+// exporterStatsFor returns the statistics for an exporter, creating them if
+// needed.
+func (c *Component) exporterStatsFor(input int, exporter string) *exporterStats {
+	key := exporterKey{input: input, exporter: exporter}
+	if stats, ok := c.exporters.Load(key); ok {
+		return stats.(*exporterStats)
+	}
+	stats, _ := c.exporters.LoadOrStore(key, &exporterStats{})
+	return stats.(*exporterStats)
+}
+
+// expireExporters removes the statistics of the exporters not seen since the
+// provided time.
+func (c *Component) expireExporters(before time.Time) {
+	c.exporters.Range(func(k, v any) bool {
+		if v.(*exporterStats).lastSeen.Load() < before.Unix() {
+			c.exporters.Delete(k)
+		}
+		return true
+	})
+}
+
+// inputType returns the type of the provided input configuration.
+func inputType(config input.Configuration) string {
+	t := reflect.TypeOf(config)
+	for name, fn := range inputs {
+		if reflect.TypeOf(fn()) == t {
+			return name
+		}
+	}
+	return "unknown"
+}
+
+// exporterInformation is the information returned about an exporter. Flows
+// are rate-limited by the outlet, so only the configured rate limit is known
+// here. When it is set, RateLimited points to the outlet endpoint counting the
+// dropped flows.
+type exporterInformation struct {
+	Exporter            string    `json:"exporter"`
+	Input               int       `json:"input"`
+	InputType           string    `json:"input-type"`
+	Listen              string    `json:"listen,omitempty"`
+	Decoder             string    `json:"decoder"`
+	ConfiguredRateLimit uint64    `json:"configured-rate-limit"`
+	RateLimited         string    `json:"rate-limited,omitempty"`
+	LastSeen            time.Time `json:"last-seen"`
+	Packets             uint64    `json:"packets"`
+	Bytes               uint64    `json:"bytes"`
+	KafkaErrors         uint64    `json:"kafka-errors"`
+}
+
+// exportersHTTPHandler lists the exporters seen by each input. The optional
+// "exporter" query parameter restricts the list to one exporter.
+func (c *Component) exportersHTTPHandler(w http.ResponseWriter, req *http.Request) {
+	filter := req.URL.Query().Get("exporter")
+	exporters := []exporterInformation{}
+	c.exporters.Range(func(k, v any) bool {
+		key := k.(exporterKey)
+		if filter != "" && key.exporter != filter {
+			return true
+		}
+		stats := v.(*exporterStats)
+		config := c.config.Inputs[key.input]
+		decoder, _ := config.Decoder.MarshalText()
+		info := exporterInformation{
+			Exporter:            key.exporter,
+			Input:               key.input,
+			InputType:           inputType(config.Config),
+			Decoder:             string(decoder),
+			ConfiguredRateLimit: config.RateLimit,
+			LastSeen:            time.Unix(stats.lastSeen.Load(), 0).UTC(),
+			Packets:             stats.packets.Load(),
+			Bytes:               stats.bytes.Load(),
+			KafkaErrors:         stats.kafkaErrors.Load(),
+		}
+		if config.RateLimit > 0 {
+			info.RateLimited = "/api/v0/outlet/exporters?" +
+				url.Values{"exporter": {key.exporter}}.Encode()
+		}
+		if udpConfig, ok := config.Config.(*udp.Configuration); ok {
+			info.Listen = udpConfig.Listen
+		}
+		exporters = append(exporters, info)
+		return true
+	})
+	slices.SortFunc(exporters, func(a, b exporterInformation) int {
+		return cmp.Or(
+			cmp.Compare(a.Input, b.Input),
+			cmp.Compare(a.Exporter, b.Exporter))
+	})
+	httpserver.WriteJSON(w, http.StatusOK, exporters)
+}
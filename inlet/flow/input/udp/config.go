@@ -4,6 +4,8 @@
 package udp
 
 import (
+	"net/netip"
+
 	"akvorado/common/helpers"
 	"akvorado/inlet/flow/input"
 )
@@ -21,6 +23,24 @@ type Configuration struct {
 	// The value cannot exceed the kernel max value
 	// (net.core.wmem_max).
 	ReceiveBuffer uint
+	// Forward is a list of destinations to forward the received packets to,
+	// unchanged.
+	Forward []ForwardConfiguration `validate:"dive"`
+}
+
+// ForwardConfiguration describes a destination to forward received packets
+// to.
+type ForwardConfiguration struct {
+	// Destination is the address to send packets to.
+	Destination string `validate:"required,hostname_port"`
+	// Exporters restricts forwarding to packets from the exporters in these
+	// subnets. When empty, all packets are forwarded.
+	Exporters []netip.Prefix
+	// PreserveSource tells to use the address of the exporter as the source
+	// address of the forwarded packets. This requires the CAP_NET_ADMIN
+	// capability and is only supported on Linux. Otherwise, the packets are
+	// sent with the address of the inlet.
+	PreserveSource bool
 }
 
 // DefaultConfiguration is the default configuration for this input
@@ -0,0 +1,135 @@
+// SPDX-FileCopyrightText: 2026 Free Mobile
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package udp
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/netip"
+	"runtime/pprof"
+	"time"
+
+	"akvorado/common/helpers"
+	"akvorado/common/reporter"
+)
+
+// forwardQueueSize is the number of packets waiting to be forwarded to a
+// destination. When the queue is full, packets are dropped to not slow down
+// the workers.
+const forwardQueueSize = 1024
+
+// forwarder forwards received packets to a destination.
+type forwarder struct {
+	config    ForwardConfiguration
+	exporters []netip.Prefix
+	conn      *net.UDPConn
+	ipv6      bool // destination is IPv6
+	preserve  bool // source address is preserved
+	queue     chan forwardedPacket
+}
+
+// forwardedPacket is a packet to be forwarded.
+type forwardedPacket struct {
+	source  netip.Addr
+	payload []byte
+}
+
+// newForwarder creates a forwarder for the provided configuration.
+func (in *Input) newForwarder(config ForwardConfiguration) (*forwarder, error) {
+	raddr, err := net.ResolveUDPAddr("udp", config.Destination)
+	if err != nil {
+		return nil, fmt.Errorf("unable to resolve %v: %w", config.Destination, err)
+	}
+	f := &forwarder{
+		config: config,
+		ipv6:   raddr.IP.To4() == nil,
+		queue:  make(chan forwardedPacket, forwardQueueSize),
+	}
+	for _, prefix := range config.Exporters {
+		f.exporters = append(f.exporters, helpers.UnmapPrefix(prefix.Masked()))
+	}
+	if config.PreserveSource {
+		f.conn, err = dialTransparent(in.t.Context(context.Background()), raddr)
+		if err != nil {
+			in.r.Warn().Err(err).
+				Str("destination", config.Destination).
+				Msg("cannot preserve source address when forwarding packets")
+		} else {
+			f.preserve = true
+		}
+	}
+	if f.conn == nil {
+		f.conn, err = net.DialUDP("udp", nil, raddr)
+		if err != nil {
+			return nil, fmt.Errorf("unable to connect to %v: %w", config.Destination, err)
+		}
+	}
+	return f, nil
+}
+
+// accept tells if a packet from the provided exporter should be forwarded.
+func (f *forwarder) accept(source netip.Addr) bool {
+	if len(f.exporters) == 0 {
+		return true
+	}
+	for _, prefix := range f.exporters {
+		if prefix.Contains(source) {
+			return true
+		}
+	}
+	return false
+}
+
+// forward queues packets for the forwarders accepting them. The payload is
+// copied once and shared between forwarders.
+func (in *Input) forward(source netip.Addr, payload []byte) {
+	var shared []byte
+	for _, f := range in.forwarders {
+		if !f.accept(source) {
+			continue
+		}
+		if shared == nil {
+			shared = make([]byte, len(payload))
+			copy(shared, payload)
+		}
+		select {
+		case f.queue <- forwardedPacket{source: source, payload: shared}:
+		default:
+			in.metrics.forwardedDrops.WithLabelValues(in.config.Listen, f.config.Destination).Inc()
+		}
+	}
+}
+
+// runForwarder sends the queued packets to the destination until the input is
+// stopped.
+func (in *Input) runForwarder(f *forwarder) error {
+	labels := pprof.Labels("goroutine", fmt.Sprintf("udp-forward-%s", f.config.Destination))
+	pprof.SetGoroutineLabels(pprof.WithLabels(context.Background(), labels))
+	defer f.conn.Close()
+	listen := in.config.Listen
+	destination := f.config.Destination
+	errLogger := in.r.Sample(reporter.BurstSampler(time.Minute, 1))
+	dying := in.t.Dying()
+	for {
+		select {
+		case <-dying:
+			return nil
+		case packet := <-f.queue:
+			var oob []byte
+			if f.preserve {
+				oob = sourceControlMessage(packet.source, f.ipv6)
+			}
+			if _, _, err := f.conn.WriteMsgUDP(packet.payload, oob, nil); err != nil {
+				errLogger.Err(err).
+					Str("listen", listen).
+					Str("destination", destination).
+					Msg("unable to forward UDP packet")
+				in.metrics.forwardedErrors.WithLabelValues(listen, destination).Inc()
+				continue
+			}
+			in.metrics.forwardedPackets.WithLabelValues(listen, destination).Inc()
+		}
+	}
+}
@@ -0,0 +1,70 @@
+// SPDX-FileCopyrightText: 2026 Free Mobile
+// SPDX-License-Identifier: AGPL-3.0-only
+
+//go:build linux
+
+package udp
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/netip"
+	"syscall"
+	"unsafe"
+
+	"golang.org/x/sys/unix"
+)
+
+// dialTransparent connects a UDP socket to the provided address. The socket
+// is allowed to send packets with a non-local source address.
+func dialTransparent(ctx context.Context, raddr *net.UDPAddr) (*net.UDPConn, error) {
+	level, option := unix.SOL_IP, unix.IP_TRANSPARENT
+	if raddr.IP.To4() == nil {
+		level, option = unix.SOL_IPV6, unix.IPV6_TRANSPARENT
+	}
+	dialer := net.Dialer{
+		Control: func(_, _ string, c syscall.RawConn) error {
+			var err error
+			c.Control(func(fd uintptr) {
+				err = unix.SetsockoptInt(int(fd), level, option, 1)
+			})
+			if err != nil {
+				return fmt.Errorf("cannot set transparent option: %w", err)
+			}
+			return nil
+		},
+	}
+	conn, err := dialer.DialContext(ctx, "udp", raddr.String())
+	if err != nil {
+		return nil, err
+	}
+	return conn.(*net.UDPConn), nil
+}
+
+// sourceControlMessage returns the control message to send a packet with the
+// provided source address. It returns nil if the source address does not match
+// the family of the destination.
+func sourceControlMessage(source netip.Addr, ipv6 bool) []byte {
+	switch {
+	case !ipv6 && source.Is4():
+		b := make([]byte, unix.CmsgSpace(unix.SizeofInet4Pktinfo))
+		h := (*unix.Cmsghdr)(unsafe.Pointer(&b[0]))
+		h.Level = unix.IPPROTO_IP
+		h.Type = unix.IP_PKTINFO
+		h.SetLen(unix.CmsgLen(unix.SizeofInet4Pktinfo))
+		info := (*unix.Inet4Pktinfo)(unsafe.Pointer(&b[unix.CmsgLen(0)]))
+		info.Spec_dst = source.As4()
+		return b
+	case ipv6 && source.Is6():
+		b := make([]byte, unix.CmsgSpace(unix.SizeofInet6Pktinfo))
+		h := (*unix.Cmsghdr)(unsafe.Pointer(&b[0]))
+		h.Level = unix.IPPROTO_IPV6
+		h.Type = unix.IPV6_PKTINFO
+		h.SetLen(unix.CmsgLen(unix.SizeofInet6Pktinfo))
+		info := (*unix.Inet6Pktinfo)(unsafe.Pointer(&b[unix.CmsgLen(0)]))
+		info.Addr = source.As16()
+		return b
+	}
+	return nil
+}
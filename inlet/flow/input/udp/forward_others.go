@@ -0,0 +1,23 @@
+// SPDX-FileCopyrightText: 2026 Free Mobile
+// SPDX-License-Identifier: AGPL-3.0-only
+
+//go:build !linux
+
+package udp
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/netip"
+)
+
+// dialTransparent is not supported on this platform.
+func dialTransparent(context.Context, *net.UDPAddr) (*net.UDPConn, error) {
+	return nil, errors.New("source address preservation not supported on this platform")
+}
+
+// sourceControlMessage is not supported on this platform.
+func sourceControlMessage(netip.Addr, bool) []byte {
+	return nil
+}
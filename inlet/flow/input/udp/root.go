@@ -9,6 +9,7 @@ import (
 	"errors"
 	"fmt"
 	"net"
+	"net/netip"
 	"runtime/pprof"
 	"strconv"
 	"syscall"
@@ -36,10 +37,15 @@ type Input struct {
 		errors        *reporter.CounterVec
 		inDrops       *reporter.GaugeVec
 		ebpf          reporter.Gauge
+
+		forwardedPackets *reporter.CounterVec
+		forwardedDrops   *reporter.CounterVec
+		forwardedErrors  *reporter.CounterVec
 	}
 
-	address net.Addr       // listening address, for testing purpoese
-	send    input.SendFunc // function to send to kafka
+	address    net.Addr       // listening address, for testing purpoese
+	send       input.SendFunc // function to send to kafka
+	forwarders []*forwarder   // destinations to forward packets to
 }
 
 var (
@@ -105,6 +111,27 @@ func (configuration Configuration) New(r *reporter.Reporter, daemon daemon.Compo
 		},
 	)
 	input.metrics.ebpf.Set(0)
+	input.metrics.forwardedPackets = r.CounterVec(
+		reporter.CounterOpts{
+			Name: "forwarded_packets_total",
+			Help: "Packets forwarded to another destination.",
+		},
+		[]string{"listener", "destination"},
+	)
+	input.metrics.forwardedDrops = r.CounterVec(
+		reporter.CounterOpts{
+			Name: "forwarded_dropped_packets_total",
+			Help: "Packets not forwarded because the destination queue was full.",
+		},
+		[]string{"listener", "destination"},
+	)
+	input.metrics.forwardedErrors = r.CounterVec(
+		reporter.CounterOpts{
+			Name: "forwarded_errors_total",
+			Help: "Errors while forwarding packets to another destination.",
+		},
+		[]string{"listener", "destination"},
+	)
 
 	daemon.Track(&input.t, "inlet/flow/input/udp")
 	return input, nil
@@ -114,6 +141,19 @@ func (configuration Configuration) New(r *reporter.Reporter, daemon daemon.Compo
 func (in *Input) Start() error {
 	in.r.Info().Str("listen", in.config.Listen).Msg("starting UDP input")
 
+	// Setup forwarders
+	in.forwarders = nil
+	for _, config := range in.config.Forward {
+		f, err := in.newForwarder(config)
+		if err != nil {
+			for _, f := range in.forwarders {
+				f.conn.Close()
+			}
+			return err
+		}
+		in.forwarders = append(in.forwarders, f)
+	}
+
 	// Listen to UDP port
 	conns := []*net.UDPConn{}
 	fds := []uintptr{}
@@ -182,6 +222,12 @@ func (in *Input) Start() error {
 		in.metrics.ebpf.Set(0)
 	}
 
+	for _, f := range in.forwarders {
+		in.t.Go(func() error {
+			return in.runForwarder(f)
+		})
+	}
+
 	for i := range in.config.Workers {
 		workerID := i
 		worker := strconv.Itoa(i)
@@ -228,6 +274,12 @@ func (in *Input) Start() error {
 				in.metrics.packetSizeSum.WithLabelValues(listen, worker, srcIP).
 					Observe(float64(n))
 
+				if len(in.forwarders) > 0 {
+					if addr, ok := netip.AddrFromSlice(source.IP); ok {
+						in.forward(addr.Unmap(), payload[:n])
+					}
+				}
+
 				flow.Reset()
 				flow.TimeReceived = uint64(oobMsg.Received.Unix())
 				flow.Payload = payload[:n]
@@ -6,6 +6,7 @@ package udp
 import (
 	"fmt"
 	"net"
+	"net/netip"
 	"os"
 	"regexp"
 	"strconv"
@@ -194,3 +195,81 @@ func TestUDPWorkerBalancing(t *testing.T) {
 		}
 	}
 }
+
+func TestUDPForward(t *testing.T) {
+	// Destinations
+	destinations := []*net.UDPConn{}
+	for range 2 {
+		conn, err := net.ListenUDP("udp", &net.UDPAddr{IP: net.ParseIP("127.0.0.1")})
+		if err != nil {
+			t.Fatalf("ListenUDP() error:\n%+v", err)
+		}
+		t.Cleanup(func() { conn.Close() })
+		destinations = append(destinations, conn)
+	}
+
+	r := reporter.NewMock(t)
+	configuration := DefaultConfiguration().(*Configuration)
+	configuration.Listen = "127.0.0.1:0"
+	configuration.Forward = []ForwardConfiguration{
+		{
+			Destination:    destinations[0].LocalAddr().String(),
+			PreserveSource: true,
+		}, {
+			Destination: destinations[1].LocalAddr().String(),
+			Exporters:   []netip.Prefix{netip.MustParsePrefix("192.0.2.0/24")},
+		},
+	}
+	received := make(chan bool, 1)
+	in, err := configuration.New(r, daemon.NewMock(t), func(string, *pb.RawFlow) {
+		received <- true
+	})
+	if err != nil {
+		t.Fatalf("New() error:\n%+v", err)
+	}
+	helpers.StartStop(t, in)
+
+	conn, err := net.Dial("udp", in.(*Input).address.String())
+	if err != nil {
+		t.Fatalf("Dial() error:\n%+v", err)
+	}
+	if _, err := conn.Write([]byte("hello world!")); err != nil {
+		t.Fatalf("Write() error:\n%+v", err)
+	}
+
+	// The packet is received by the first destination
+	payload := make([]byte, 100)
+	destinations[0].SetReadDeadline(time.Now().Add(time.Second))
+	n, source, err := destinations[0].ReadFromUDP(payload)
+	if err != nil {
+		t.Fatalf("ReadFromUDP() error:\n%+v", err)
+	}
+	if diff := helpers.Diff(string(payload[:n]), "hello world!"); diff != "" {
+		t.Fatalf("ReadFromUDP() (-got, +want):\n%s", diff)
+	}
+	if !source.IP.Equal(net.ParseIP("127.0.0.1")) {
+		t.Fatalf("ReadFromUDP() source = %s", source)
+	}
+	select {
+	case <-received:
+	case <-time.After(time.Second):
+		t.Fatal("no flow sent to Kafka")
+	}
+
+	// The counter is incremented after sending the packet
+	expectedMetrics := map[string]string{
+		fmt.Sprintf(`forwarded_packets_total{destination="%s",listener="127.0.0.1:0"}`,
+			destinations[0].LocalAddr()): "1",
+	}
+	var gotMetrics map[string]string
+	for range 10 {
+		gotMetrics = r.GetMetrics("akvorado_inlet_flow_input_udp_", "forwarded_")
+		if len(gotMetrics) > 0 {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if diff := helpers.Diff(gotMetrics, expectedMetrics); diff != "" {
+		t.Fatalf("Input metrics (-got, +want):\n%s", diff)
+	}
+}
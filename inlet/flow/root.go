@@ -7,6 +7,7 @@ package flow
 import (
 	"errors"
 	"sync"
+	"time"
 
 	"gopkg.in/tomb.v2"
 
@@ -28,6 +29,7 @@ type Component struct {
 
 	inputs      []input.Input
 	payloadPool sync.Pool
+	exporters   sync.Map // exporterKey → *exporterStats
 }
 
 // Dependencies are the dependencies of the flow component.
@@ -64,7 +66,7 @@ func New(r *reporter.Reporter, configuration Configuration, dependencies Depende
 	// Initialize inputs
 	for idx, input := range c.config.Inputs {
 		var err error
-		c.inputs[idx], err = input.Config.New(r, c.d.Daemon, c.Send(input))
+		c.inputs[idx], err = input.Config.New(r, c.d.Daemon, c.Send(idx, input))
 		if err != nil {
 			return nil, err
 		}
@@ -75,9 +77,15 @@ func New(r *reporter.Reporter, configuration Configuration, dependencies Depende
 	return &c, nil
 }
 
-// Send sends a raw flow to Kafka.
-func (c *Component) Send(config InputConfiguration) input.SendFunc {
+// Send sends a raw flow to Kafka. It also updates the statistics for the
+// exporter seen by the input with the provided index.
+func (c *Component) Send(idx int, config InputConfiguration) input.SendFunc {
 	return func(exporter string, flow *pb.RawFlow) {
+		stats := c.exporterStatsFor(idx, exporter)
+		stats.lastSeen.Store(int64(flow.TimeReceived))
+		stats.packets.Add(1)
+		stats.bytes.Add(uint64(len(flow.Payload)))
+
 		flow.TimestampSource = config.TimestampSource
 		flow.Decoder = config.Decoder
 		flow.UseSourceAddress = config.UseSrcAddrForExporterAddr
@@ -98,7 +106,10 @@ func (c *Component) Send(config InputConfiguration) input.SendFunc {
 
 		// Marshal to it, send it to Kafka and return it when done
 		if n, err := flow.MarshalToSizedBufferVT(bytes[:n]); err == nil {
-			c.d.Kafka.Send(exporter, bytes[:n], func() {
+			c.d.Kafka.Send(exporter, bytes[:n], func(err error) {
+				if err != nil {
+					stats.kafkaErrors.Add(1)
+				}
 				c.payloadPool.Put(ptr)
 			})
 		} else {
@@ -109,6 +120,19 @@ func (c *Component) Send(config InputConfiguration) input.SendFunc {
 
 // Start starts the flow component.
 func (c *Component) Start() error {
+	c.d.HTTP.APIRouter.GET("/api/v0/inlet/exporters", c.exportersHTTPHandler)
+	if c.config.ExporterIdleTimeout > 0 {
+		c.t.Go(func() error {
+			for {
+				select {
+				case <-c.t.Dying():
+					return nil
+				case <-time.After(c.config.ExporterIdleTimeout):
+					c.expireExporters(time.Now().Add(-c.config.ExporterIdleTimeout))
+				}
+			}
+		})
+	}
 	for _, input := range c.inputs {
 		err := input.Start()
 		stopper := input.Stop
@@ -5,7 +5,9 @@ package flow
 
 import (
 	"bytes"
+	"encoding/json"
 	"fmt"
+	"net/http"
 	"path"
 	"runtime"
 	"sync"
@@ -21,6 +23,7 @@ import (
 	"akvorado/inlet/flow/input/file"
 	"akvorado/inlet/kafka"
 
+	"github.com/google/go-cmp/cmp/cmpopts"
 	"github.com/twmb/franz-go/pkg/kgo"
 )
 
@@ -38,6 +41,7 @@ func TestFlow(t *testing.T) {
 				Paths:    paths,
 				MaxFlows: 100,
 			},
+			RateLimit: 10000,
 		},
 	}
 
@@ -104,4 +108,49 @@ func TestFlow(t *testing.T) {
 	case <-time.After(time.Second):
 		t.Fatalf("flows not received")
 	}
+
+	// Check exporter statistics
+	resp, err := http.Get(fmt.Sprintf("http://%s/api/v0/inlet/exporters", c.d.HTTP.LocalAddr()))
+	if err != nil {
+		t.Fatalf("GET /api/v0/inlet/exporters:\n%+v", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("GET /api/v0/inlet/exporters: got status code %d, not 200", resp.StatusCode)
+	}
+	var got []exporterInformation
+	if err := json.NewDecoder(resp.Body).Decode(&got); err != nil {
+		t.Fatalf("GET /api/v0/inlet/exporters: cannot decode JSON:\n%+v", err)
+	}
+	expected := []exporterInformation{{
+		Exporter:            "127.0.0.1",
+		Input:               0,
+		InputType:           "file",
+		ConfiguredRateLimit: 10000,
+		RateLimited:         "/api/v0/outlet/exporters?exporter=127.0.0.1",
+		Packets:             100,
+		Bytes:               50*uint64(len("hello world!\n")) + 50*uint64(len("bye bye\n")),
+	}}
+	if diff := helpers.Diff(got, expected,
+		cmpopts.IgnoreFields(exporterInformation{}, "LastSeen")); diff != "" {
+		t.Fatalf("GET /api/v0/inlet/exporters (-got, +want):\n%s", diff)
+	}
+
+	// Expire idle exporters
+	countExporters := func() int {
+		count := 0
+		c.exporters.Range(func(any, any) bool {
+			count++
+			return true
+		})
+		return count
+	}
+	c.expireExporters(time.Now().Add(-time.Hour))
+	if diff := helpers.Diff(countExporters(), 1); diff != "" {
+		t.Fatalf("expireExporters() with recent exporter (-got, +want):\n%s", diff)
+	}
+	c.expireExporters(time.Now().Add(time.Minute))
+	if diff := helpers.Diff(countExporters(), 0); diff != "" {
+		t.Fatalf("expireExporters() with idle exporter (-got, +want):\n%s", diff)
+	}
 }
@@ -4,6 +4,8 @@
 package kafka
 
 import (
+	"time"
+
 	"github.com/twmb/franz-go/pkg/kgo"
 
 	"akvorado/common/helpers"
@@ -19,6 +21,21 @@ type Configuration struct {
 	QueueSize int `validate:"min=1"`
 	// LoadBalance defines the load-balancing algorithm to use for Kafka partitions.
 	LoadBalance kafka.LoadBalanceAlgorithm
+	// DiskQueue defines a queue on disk for messages which cannot be sent to
+	// Kafka.
+	DiskQueue DiskQueueConfiguration
+}
+
+// DiskQueueConfiguration describes the configuration for the disk queue.
+type DiskQueueConfiguration struct {
+	// Directory is where to store the queued messages. The disk queue is
+	// disabled when empty.
+	Directory string
+	// MaxSize is the maximum size of the queue, in bytes.
+	MaxSize int64 `validate:"min=16777216"`
+	// MaxAge is the maximum time a message is kept in the queue. Older
+	// messages are dropped instead of being sent.
+	MaxAge time.Duration `validate:"min=1m"`
 }
 
 // DefaultConfiguration represents the default configuration for the Kafka exporter.
@@ -28,6 +45,10 @@ func DefaultConfiguration() Configuration {
 		CompressionCodec: kafka.CompressionCodec(kgo.Lz4Compression()),
 		QueueSize:        4096,
 		LoadBalance:      kafka.LoadBalanceRandom,
+		DiskQueue: DiskQueueConfiguration{
+			MaxSize: 1 << 30,
+			MaxAge:  24 * time.Hour,
+		},
 	}
 }
 
@@ -0,0 +1,269 @@
+// SPDX-FileCopyrightText: 2026 Free Mobile
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package kafka
+
+import (
+	"bufio"
+	"cmp"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// diskQueueSegmentSize is the size after which a new segment is started.
+const diskQueueSegmentSize = 16 << 20
+
+// diskQueueHeaderSize is the size of the header of each record: enqueue time
+// (8 bytes), exporter length (2 bytes) and payload length (4 bytes).
+const diskQueueHeaderSize = 8 + 2 + 4
+
+// diskQueueDrainTimeout is the maximum time to send a segment read from the
+// disk queue. Messages not sent in time are queued again.
+const diskQueueDrainTimeout = time.Minute
+
+var errDiskQueueFull = errors.New("disk queue is full")
+
+// diskQueue is a bounded FIFO queue of messages stored on disk. It is split
+// into segments: records are appended to the last one and whole segments are
+// read from the first one. A segment is only removed once all its records are
+// handled, therefore, records may be sent twice after a crash.
+type diskQueue struct {
+	dir     string
+	maxSize int64
+
+	mu       sync.Mutex
+	segments []*diskQueueSegment // oldest first
+	current  *os.File            // last segment, opened for writing
+	size     int64
+	records  int
+	nextID   uint64
+}
+
+// diskQueueSegment is a file holding queued records.
+type diskQueueSegment struct {
+	id      uint64
+	size    int64
+	records int
+	oldest  time.Time
+}
+
+// diskQueueRecord is a record stored in the disk queue.
+type diskQueueRecord struct {
+	enqueued time.Time
+	exporter string
+	payload  []byte
+}
+
+// newDiskQueue opens the disk queue in the provided directory. Existing
+// segments are loaded.
+func newDiskQueue(dir string, maxSize int64) (*diskQueue, error) {
+	if err := os.MkdirAll(dir, 0o750); err != nil {
+		return nil, fmt.Errorf("cannot create disk queue directory: %w", err)
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("cannot read disk queue directory: %w", err)
+	}
+	q := &diskQueue{dir: dir, maxSize: maxSize}
+	for _, entry := range entries {
+		name, ok := strings.CutSuffix(entry.Name(), ".seg")
+		if !ok || entry.IsDir() {
+			continue
+		}
+		id, err := strconv.ParseUint(name, 10, 64)
+		if err != nil {
+			continue
+		}
+		records, err := q.readSegment(id)
+		if err != nil {
+			return nil, err
+		}
+		segment := &diskQueueSegment{id: id, records: len(records)}
+		for _, record := range records {
+			segment.size += int64(diskQueueHeaderSize + len(record.exporter) + len(record.payload))
+		}
+		if len(records) > 0 {
+			segment.oldest = records[0].enqueued
+		}
+		q.segments = append(q.segments, segment)
+		q.size += segment.size
+		q.records += segment.records
+		q.nextID = max(q.nextID, id+1)
+	}
+	slices.SortFunc(q.segments, func(a, b *diskQueueSegment) int {
+		return cmp.Compare(a.id, b.id)
+	})
+	return q, nil
+}
+
+// segmentPath returns the path of the provided segment.
+func (q *diskQueue) segmentPath(id uint64) string {
+	return filepath.Join(q.dir, fmt.Sprintf("%020d.seg", id))
+}
+
+// Push appends a record to the queue.
+func (q *diskQueue) Push(exporter string, payload []byte, now time.Time) error {
+	recordSize := int64(diskQueueHeaderSize + len(exporter) + len(payload))
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if q.size+recordSize > q.maxSize {
+		return errDiskQueueFull
+	}
+	return q.push(exporter, payload, now)
+}
+
+// push appends a record to the queue without checking its size. The lock
+// should be held.
+func (q *diskQueue) push(exporter string, payload []byte, now time.Time) error {
+	recordSize := int64(diskQueueHeaderSize + len(exporter) + len(payload))
+	var segment *diskQueueSegment
+	if q.current != nil {
+		segment = q.segments[len(q.segments)-1]
+	}
+	if segment == nil || segment.size >= diskQueueSegmentSize {
+		if err := q.closeCurrent(); err != nil {
+			return err
+		}
+		segment = &diskQueueSegment{id: q.nextID, oldest: now}
+		f, err := os.OpenFile(q.segmentPath(segment.id), os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o640)
+		if err != nil {
+			return fmt.Errorf("cannot create disk queue segment: %w", err)
+		}
+		q.nextID++
+		q.current = f
+		q.segments = append(q.segments, segment)
+	}
+
+	buf := make([]byte, recordSize)
+	binary.BigEndian.PutUint64(buf[0:], uint64(now.UnixNano()))
+	binary.BigEndian.PutUint16(buf[8:], uint16(len(exporter)))
+	binary.BigEndian.PutUint32(buf[10:], uint32(len(payload)))
+	copy(buf[diskQueueHeaderSize:], exporter)
+	copy(buf[diskQueueHeaderSize+len(exporter):], payload)
+	if _, err := q.current.Write(buf); err != nil {
+		return fmt.Errorf("cannot write to disk queue: %w", err)
+	}
+	segment.size += recordSize
+	segment.records++
+	q.size += recordSize
+	q.records++
+	return nil
+}
+
+// closeCurrent closes the segment opened for writing.
+func (q *diskQueue) closeCurrent() error {
+	if q.current == nil {
+		return nil
+	}
+	err := q.current.Close()
+	q.current = nil
+	if err != nil {
+		return fmt.Errorf("cannot close disk queue segment: %w", err)
+	}
+	return nil
+}
+
+// Pop returns the records of the oldest segment. The returned function
+// should be called once they are handled, with the records which could not be
+// handled. They are queued again before removing the segment. As they were
+// already accounted in the size of the segment, they are always accepted. Only
+// one caller should use Pop at a time.
+func (q *diskQueue) Pop() ([]diskQueueRecord, func([]diskQueueRecord) error, error) {
+	q.mu.Lock()
+	if len(q.segments) == 0 {
+		q.mu.Unlock()
+		return nil, func([]diskQueueRecord) error { return nil }, nil
+	}
+	segment := q.segments[0]
+	if len(q.segments) == 1 {
+		// The oldest segment is also the one we write to.
+		if err := q.closeCurrent(); err != nil {
+			q.mu.Unlock()
+			return nil, nil, err
+		}
+	}
+	q.mu.Unlock()
+
+	records, err := q.readSegment(segment.id)
+	if err != nil {
+		return nil, nil, err
+	}
+	done := func(requeue []diskQueueRecord) error {
+		q.mu.Lock()
+		defer q.mu.Unlock()
+		for _, record := range requeue {
+			if err := q.push(record.exporter, record.payload, record.enqueued); err != nil {
+				return err
+			}
+		}
+		if err := os.Remove(q.segmentPath(segment.id)); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return fmt.Errorf("cannot remove disk queue segment: %w", err)
+		}
+		q.segments = slices.DeleteFunc(q.segments, func(s *diskQueueSegment) bool {
+			return s == segment
+		})
+		q.size -= segment.size
+		q.records -= segment.records
+		return nil
+	}
+	return records, done, nil
+}
+
+// readSegment reads all the records of a segment. A truncated last record is
+// ignored.
+func (q *diskQueue) readSegment(id uint64) ([]diskQueueRecord, error) {
+	f, err := os.Open(q.segmentPath(id))
+	if err != nil {
+		return nil, fmt.Errorf("cannot open disk queue segment: %w", err)
+	}
+	defer f.Close()
+	reader := bufio.NewReader(f)
+	records := []diskQueueRecord{}
+	header := make([]byte, diskQueueHeaderSize)
+	for {
+		if _, err := io.ReadFull(reader, header); err != nil {
+			break
+		}
+		enqueued := time.Unix(0, int64(binary.BigEndian.Uint64(header[0:])))
+		exporterLen := int(binary.BigEndian.Uint16(header[8:]))
+		payloadLen := int(binary.BigEndian.Uint32(header[10:]))
+		data := make([]byte, exporterLen+payloadLen)
+		if _, err := io.ReadFull(reader, data); err != nil {
+			break
+		}
+		records = append(records, diskQueueRecord{
+			enqueued: enqueued,
+			exporter: string(data[:exporterLen]),
+			payload:  data[exporterLen:],
+		})
+	}
+	return records, nil
+}
+
+// Stats returns the number of records, the size and the time of the oldest
+// record in the queue.
+func (q *diskQueue) Stats() (int, int64, time.Time) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	var oldest time.Time
+	if len(q.segments) > 0 {
+		oldest = q.segments[0].oldest
+	}
+	return q.records, q.size, oldest
+}
+
+// Close closes the disk queue. Queued records are kept on disk.
+func (q *diskQueue) Close() error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return q.closeCurrent()
+}
@@ -0,0 +1,171 @@
+// SPDX-FileCopyrightText: 2026 Free Mobile
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package kafka
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+
+	"akvorado/common/helpers"
+)
+
+func TestDiskQueue(t *testing.T) {
+	dir := t.TempDir()
+	q, err := newDiskQueue(dir, 128)
+	if err != nil {
+		t.Fatalf("newDiskQueue() error:\n%+v", err)
+	}
+	now := time.Date(2026, 10, 19, 10, 0, 0, 0, time.UTC)
+
+	// Empty queue
+	records, done, err := q.Pop()
+	if err != nil {
+		t.Fatalf("Pop() error:\n%+v", err)
+	}
+	if len(records) != 0 {
+		t.Fatalf("Pop() returned %d records on an empty queue", len(records))
+	}
+	if err := done(nil); err != nil {
+		t.Fatalf("done() error:\n%+v", err)
+	}
+
+	// Fill the queue
+	for i := range 4 {
+		if err := q.Push("192.0.2.1", fmt.Appendf(nil, "message %d", i), now.Add(time.Duration(i)*time.Second)); err != nil {
+			t.Fatalf("Push() error:\n%+v", err)
+		}
+	}
+	if err := q.Push("192.0.2.1", []byte("message 4"), now); !errors.Is(err, errDiskQueueFull) {
+		t.Fatalf("Push() error:\n%+v", err)
+	}
+	records2, size, oldest := q.Stats()
+	if records2 != 4 || size != 4*(diskQueueHeaderSize+9+9) || !oldest.Equal(now) {
+		t.Fatalf("Stats() = %d, %d, %s", records2, size, oldest)
+	}
+
+	// Reopen the queue
+	if err := q.Close(); err != nil {
+		t.Fatalf("Close() error:\n%+v", err)
+	}
+	q, err = newDiskQueue(dir, 128)
+	if err != nil {
+		t.Fatalf("newDiskQueue() error:\n%+v", err)
+	}
+	if got, _, _ := q.Stats(); got != 4 {
+		t.Fatalf("Stats() after reopening = %d records, not 4", got)
+	}
+
+	// Pop the records
+	records, done, err = q.Pop()
+	if err != nil {
+		t.Fatalf("Pop() error:\n%+v", err)
+	}
+	got := []string{}
+	for _, record := range records {
+		got = append(got, fmt.Sprintf("%s %s %s", record.enqueued.UTC().Format(time.TimeOnly), record.exporter, record.payload))
+	}
+	expected := []string{
+		"10:00:00 192.0.2.1 message 0",
+		"10:00:01 192.0.2.1 message 1",
+		"10:00:02 192.0.2.1 message 2",
+		"10:00:03 192.0.2.1 message 3",
+	}
+	if diff := helpers.Diff(got, expected); diff != "" {
+		t.Fatalf("Pop() (-got, +want):\n%s", diff)
+	}
+	if err := done(nil); err != nil {
+		t.Fatalf("done() error:\n%+v", err)
+	}
+	if records, size, oldest := q.Stats(); records != 0 || size != 0 || !oldest.IsZero() {
+		t.Fatalf("Stats() = %d, %d, %s", records, size, oldest)
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatalf("ReadDir() error:\n%+v", err)
+	}
+	if len(entries) != 0 {
+		t.Fatalf("ReadDir() returned %d entries, expected none", len(entries))
+	}
+}
+
+func TestDiskQueueTruncated(t *testing.T) {
+	dir := t.TempDir()
+	q, err := newDiskQueue(dir, 1000)
+	if err != nil {
+		t.Fatalf("newDiskQueue() error:\n%+v", err)
+	}
+	for _, payload := range []string{"hello", "world"} {
+		if err := q.Push("192.0.2.1", []byte(payload), time.Now()); err != nil {
+			t.Fatalf("Push() error:\n%+v", err)
+		}
+	}
+	q.Close()
+
+	// Truncate the last record
+	path := filepath.Join(dir, fmt.Sprintf("%020d.seg", 0))
+	if err := os.Truncate(path, (diskQueueHeaderSize+9+5)+diskQueueHeaderSize+3); err != nil {
+		t.Fatalf("Truncate() error:\n%+v", err)
+	}
+	q, err = newDiskQueue(dir, 1000)
+	if err != nil {
+		t.Fatalf("newDiskQueue() error:\n%+v", err)
+	}
+	records, _, err := q.Pop()
+	if err != nil {
+		t.Fatalf("Pop() error:\n%+v", err)
+	}
+	if len(records) != 1 || string(records[0].payload) != "hello" {
+		t.Fatalf("Pop() returned %+v", records)
+	}
+}
+
+func TestDiskQueueRequeueFull(t *testing.T) {
+	dir := t.TempDir()
+	q, err := newDiskQueue(dir, 128)
+	if err != nil {
+		t.Fatalf("newDiskQueue() error:\n%+v", err)
+	}
+	now := time.Date(2026, 10, 19, 10, 0, 0, 0, time.UTC)
+	for i := range 4 {
+		if err := q.Push("192.0.2.1", fmt.Appendf(nil, "message %d", i), now.Add(time.Duration(i)*time.Second)); err != nil {
+			t.Fatalf("Push() error:\n%+v", err)
+		}
+	}
+
+	// Requeue all the records of a full queue
+	records, done, err := q.Pop()
+	if err != nil {
+		t.Fatalf("Pop() error:\n%+v", err)
+	}
+	if err := done(records); err != nil {
+		t.Fatalf("done() error:\n%+v", err)
+	}
+	if got, size, oldest := q.Stats(); got != 4 || size != 4*(diskQueueHeaderSize+9+9) || !oldest.Equal(now) {
+		t.Fatalf("Stats() = %d, %d, %s", got, size, oldest)
+	}
+	if err := q.Push("192.0.2.1", []byte("message 4"), now); !errors.Is(err, errDiskQueueFull) {
+		t.Fatalf("Push() error:\n%+v", err)
+	}
+
+	// The records are still in the same order
+	requeued, done, err := q.Pop()
+	if err != nil {
+		t.Fatalf("Pop() error:\n%+v", err)
+	}
+	if diff := helpers.Diff(requeued, records, cmp.AllowUnexported(diskQueueRecord{})); diff != "" {
+		t.Fatalf("Pop() (-got, +want):\n%s", diff)
+	}
+	if err := done(nil); err != nil {
+		t.Fatalf("done() error:\n%+v", err)
+	}
+	if got, size, _ := q.Stats(); got != 0 || size != 0 {
+		t.Fatalf("Stats() = %d, %d", got, size)
+	}
+}
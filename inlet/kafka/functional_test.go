@@ -56,8 +56,8 @@ func TestFakeKafka(t *testing.T) {
 	}
 	var wg sync.WaitGroup
 	wg.Add(2)
-	c.Send("127.0.0.1", msg1, func() { wg.Done() })
-	c.Send("127.0.0.1", msg2, func() { wg.Done() })
+	c.Send("127.0.0.1", msg1, func(error) { wg.Done() })
+	c.Send("127.0.0.1", msg2, func(error) { wg.Done() })
 	c.Flush(t)
 	done := make(chan struct{})
 	go func() {
@@ -4,6 +4,8 @@
 package kafka
 
 import (
+	"time"
+
 	"akvorado/common/reporter"
 )
 
@@ -11,6 +13,14 @@ type metrics struct {
 	messagesSent *reporter.CounterVec
 	bytesSent    *reporter.CounterVec
 	errors       *reporter.CounterVec
+
+	diskQueueRecords   reporter.GaugeFunc
+	diskQueueBytes     reporter.GaugeFunc
+	diskQueueOldestAge reporter.GaugeFunc
+	diskQueueQueued    reporter.Counter
+	diskQueueDrained   reporter.Counter
+	diskQueueDropped   reporter.Counter
+	diskQueueExpired   reporter.Counter
 }
 
 func (c *Component) initMetrics() {
@@ -35,4 +45,65 @@ func (c *Component) initMetrics() {
 		},
 		[]string{"error"},
 	)
+
+	if c.diskQueue == nil {
+		return
+	}
+	c.metrics.diskQueueRecords = c.r.GaugeFunc(
+		reporter.GaugeOpts{
+			Name: "disk_queue_messages",
+			Help: "Number of messages in the disk queue.",
+		},
+		func() float64 {
+			records, _, _ := c.diskQueue.Stats()
+			return float64(records)
+		},
+	)
+	c.metrics.diskQueueBytes = c.r.GaugeFunc(
+		reporter.GaugeOpts{
+			Name: "disk_queue_bytes",
+			Help: "Size of the disk queue.",
+		},
+		func() float64 {
+			_, size, _ := c.diskQueue.Stats()
+			return float64(size)
+		},
+	)
+	c.metrics.diskQueueOldestAge = c.r.GaugeFunc(
+		reporter.GaugeOpts{
+			Name: "disk_queue_oldest_age_seconds",
+			Help: "Age of the oldest message in the disk queue.",
+		},
+		func() float64 {
+			_, _, oldest := c.diskQueue.Stats()
+			if oldest.IsZero() {
+				return 0
+			}
+			return time.Since(oldest).Seconds()
+		},
+	)
+	c.metrics.diskQueueQueued = c.r.Counter(
+		reporter.CounterOpts{
+			Name: "disk_queue_queued_messages_total",
+			Help: "Number of messages written to the disk queue.",
+		},
+	)
+	c.metrics.diskQueueDrained = c.r.Counter(
+		reporter.CounterOpts{
+			Name: "disk_queue_drained_messages_total",
+			Help: "Number of messages from the disk queue sent to Kafka.",
+		},
+	)
+	c.metrics.diskQueueDropped = c.r.Counter(
+		reporter.CounterOpts{
+			Name: "disk_queue_dropped_messages_total",
+			Help: "Number of messages dropped because the disk queue is full.",
+		},
+	)
+	c.metrics.diskQueueExpired = c.r.Counter(
+		reporter.CounterOpts{
+			Name: "disk_queue_expired_messages_total",
+			Help: "Number of messages dropped because they were queued for too long.",
+		},
+	)
 }
@@ -6,8 +6,10 @@ package kafka
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/twmb/franz-go/pkg/kerr"
@@ -33,6 +35,7 @@ type Component struct {
 	kafkaClient *kgo.Client
 	errLogger   reporter.Logger
 	metrics     metrics
+	diskQueue   *diskQueue
 }
 
 // Dependencies define the dependencies of the Kafka exporter.
@@ -55,6 +58,12 @@ func New(r *reporter.Reporter, configuration Configuration, dependencies Depende
 		kafkaTopic: fmt.Sprintf("%s-v%d", configuration.Topic, pb.Version),
 		errLogger:  r.Sample(reporter.BurstSampler(10*time.Second, 3)),
 	}
+	if configuration.DiskQueue.Directory != "" {
+		c.diskQueue, err = newDiskQueue(configuration.DiskQueue.Directory, configuration.DiskQueue.MaxSize)
+		if err != nil {
+			return nil, err
+		}
+	}
 	c.initMetrics()
 
 	// Initialize options error to be able to validate them.
@@ -92,8 +101,16 @@ func (c *Component) Start() error {
 	c.t.Go(func() error {
 		<-c.t.Dying()
 		kafkaClient.Close()
+		if c.diskQueue != nil {
+			if err := c.diskQueue.Close(); err != nil {
+				c.r.Err(err).Msg("unable to close disk queue")
+			}
+		}
 		return nil
 	})
+	if c.diskQueue != nil {
+		c.t.Go(c.drainDiskQueue)
+	}
 	return nil
 }
 
@@ -105,29 +122,143 @@ func (c *Component) Stop() error {
 	return c.t.Wait()
 }
 
-// Send a message to Kafka.
-func (c *Component) Send(exporter string, payload []byte, finalizer func()) {
+// Send a message to Kafka. The finalizer is called with the result once the
+// message is sent. When the disk queue is enabled, messages which cannot be
+// sent because of a transient error are queued on disk instead.
+func (c *Component) Send(exporter string, payload []byte, finalizer func(error)) {
 	record := &kgo.Record{
 		Topic: c.kafkaTopic,
 		Key:   c.config.LoadBalance.RecordKey(exporter),
 		Value: payload,
 	}
-	c.kafkaClient.Produce(context.Background(), record, func(r *kgo.Record, err error) {
+	produce := c.kafkaClient.Produce
+	if c.diskQueue != nil {
+		// Do not block when the producer buffer is full: queue on disk.
+		produce = c.kafkaClient.TryProduce
+	}
+	produce(context.Background(), record, func(r *kgo.Record, err error) {
 		if err == nil {
 			c.metrics.bytesSent.WithLabelValues(exporter).Add(float64(len(payload)))
 			c.metrics.messagesSent.WithLabelValues(exporter).Inc()
+		} else if retriable(err) && c.queueOnDisk(exporter, payload, time.Now()) {
+			err = nil
 		} else {
-			if ke, ok := err.(*kerr.Error); ok {
-				c.metrics.errors.WithLabelValues(ke.Message).Inc()
-			} else {
-				c.metrics.errors.WithLabelValues("unknown").Inc()
-			}
+			c.countError(err)
 			c.errLogger.Err(err).
 				Str("topic", c.kafkaTopic).
 				Int64("offset", r.Offset).
 				Int32("partition", r.Partition).
 				Msg("Kafka producer error")
 		}
-		finalizer()
+		finalizer(err)
 	})
 }
+
+// countError increments the error counter for the provided error.
+func (c *Component) countError(err error) {
+	var ke *kerr.Error
+	if errors.As(err, &ke) {
+		c.metrics.errors.WithLabelValues(ke.Message).Inc()
+	} else {
+		c.metrics.errors.WithLabelValues("unknown").Inc()
+	}
+}
+
+// retriable tells if a message which failed with the provided error may be
+// sent later. Other errors, like a message too large, would fail again.
+func retriable(err error) bool {
+	return errors.Is(err, kgo.ErrMaxBuffered) ||
+		errors.Is(err, kgo.ErrClientClosed) ||
+		errors.Is(err, context.Canceled) ||
+		errors.Is(err, context.DeadlineExceeded) ||
+		kerr.IsRetriable(err)
+}
+
+// queueOnDisk queues a message on disk, if the disk queue is enabled. It
+// returns true if the message was queued.
+func (c *Component) queueOnDisk(exporter string, payload []byte, enqueued time.Time) bool {
+	if c.diskQueue == nil {
+		return false
+	}
+	if err := c.diskQueue.Push(exporter, payload, enqueued); err != nil {
+		if errors.Is(err, errDiskQueueFull) {
+			c.metrics.diskQueueDropped.Inc()
+		} else {
+			c.errLogger.Err(err).Msg("unable to queue message on disk")
+		}
+		return false
+	}
+	c.metrics.diskQueueQueued.Inc()
+	return true
+}
+
+// drainDiskQueue sends the messages from the disk queue to Kafka when the
+// producer buffer is not busy. Messages older than the maximum age are
+// dropped. Messages failing with a transient error are queued again.
+func (c *Component) drainDiskQueue() error {
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+	ctx := c.t.Context(context.Background())
+	for {
+		select {
+		case <-c.t.Dying():
+			return nil
+		case <-ticker.C:
+		}
+		if records, _, _ := c.diskQueue.Stats(); records == 0 {
+			continue
+		}
+		if c.kafkaClient.BufferedProduceRecords() > int64(c.config.QueueSize/2) {
+			continue
+		}
+		records, done, err := c.diskQueue.Pop()
+		if err != nil {
+			c.errLogger.Err(err).Msg("unable to read disk queue")
+			continue
+		}
+		c.r.Debug().Int("messages", len(records)).Msg("drain disk queue")
+		// Do not wait forever for a batch: unsent messages are queued again.
+		batchCtx, cancel := context.WithTimeout(ctx, diskQueueDrainTimeout)
+		now := time.Now()
+		var wg sync.WaitGroup
+		var requeueMu sync.Mutex
+		requeue := []diskQueueRecord{}
+		for _, record := range records {
+			if now.Sub(record.enqueued) > c.config.DiskQueue.MaxAge {
+				c.metrics.diskQueueExpired.Inc()
+				continue
+			}
+			kafkaRecord := &kgo.Record{
+				Topic: c.kafkaTopic,
+				Key:   c.config.LoadBalance.RecordKey(record.exporter),
+				Value: record.payload,
+			}
+			wg.Add(1)
+			c.kafkaClient.Produce(batchCtx, kafkaRecord, func(_ *kgo.Record, err error) {
+				defer wg.Done()
+				if err == nil {
+					c.metrics.bytesSent.WithLabelValues(record.exporter).Add(float64(len(record.payload)))
+					c.metrics.messagesSent.WithLabelValues(record.exporter).Inc()
+					c.metrics.diskQueueDrained.Inc()
+					return
+				}
+				if !retriable(err) {
+					c.countError(err)
+					c.errLogger.Err(err).
+						Str("topic", c.kafkaTopic).
+						Msg("Kafka producer error for message from disk queue")
+					return
+				}
+				// Queue it again with its original time.
+				requeueMu.Lock()
+				requeue = append(requeue, record)
+				requeueMu.Unlock()
+			})
+		}
+		wg.Wait()
+		cancel()
+		if err := done(requeue); err != nil {
+			c.errLogger.Err(err).Msg("unable to remove drained messages from disk queue")
+		}
+	}
+}
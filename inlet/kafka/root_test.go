@@ -5,7 +5,9 @@ package kafka
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"os"
 	"slices"
 	"sync"
 	"sync/atomic"
@@ -60,10 +62,10 @@ func TestKafka(t *testing.T) {
 	// Send messages
 	var wg sync.WaitGroup
 	wg.Add(4)
-	c.Send("127.0.0.1", []byte("hello world!"), func() { wg.Done() })
-	c.Send("127.0.0.1", []byte("goodbye world!"), func() { wg.Done() })
-	c.Send("127.0.0.1", []byte("nooooo!"), func() { wg.Done() })
-	c.Send("127.0.0.1", []byte("all good"), func() { wg.Done() })
+	c.Send("127.0.0.1", []byte("hello world!"), func(error) { wg.Done() })
+	c.Send("127.0.0.1", []byte("goodbye world!"), func(error) { wg.Done() })
+	c.Send("127.0.0.1", []byte("nooooo!"), func(error) { wg.Done() })
+	c.Send("127.0.0.1", []byte("all good"), func(error) { wg.Done() })
 	done := make(chan struct{})
 	go func() {
 		wg.Wait()
@@ -158,7 +160,7 @@ func TestLoadBalancingAlgorithm(t *testing.T) {
 
 			// Send messages
 			for i := range total {
-				c.Send("127.0.0.1", fmt.Appendf(nil, "hello %d", i), func() {})
+				c.Send("127.0.0.1", fmt.Appendf(nil, "hello %d", i), func(error) {})
 			}
 			wg.Wait()
 
@@ -186,3 +188,251 @@ func TestLoadBalancingAlgorithm(t *testing.T) {
 		})
 	}
 }
+
+func TestKafkaDiskQueue(t *testing.T) {
+	r := reporter.NewMock(t)
+	config := DefaultConfiguration()
+	config.QueueSize = 1
+	config.Topic = "disk-queue"
+	config.DiskQueue.Directory = t.TempDir()
+	c, mock := NewMock(t, r, config)
+	defer mock.Close()
+
+	// Reject the first produce request.
+	var count atomic.Uint32
+	mock.ControlKey(0, func(kreq kmsg.Request) (kmsg.Response, error, bool) {
+		mock.KeepControl()
+		if count.Add(1) != 1 {
+			return nil, nil, false
+		}
+		req := kreq.(*kmsg.ProduceRequest)
+		resp := kreq.ResponseKind().(*kmsg.ProduceResponse)
+		for _, rt := range req.Topics {
+			st := kmsg.NewProduceResponseTopic()
+			st.Topic = rt.Topic
+			st.TopicID = rt.TopicID
+			for _, rp := range rt.Partitions {
+				sp := kmsg.NewProduceResponseTopicPartition()
+				sp.Partition = rp.Partition
+				sp.ErrorCode = kerr.CorruptMessage.Code
+				st.Partitions = append(st.Partitions, sp)
+			}
+			resp.Topics = append(resp.Topics, st)
+		}
+		return resp, nil, true
+	})
+
+	var mu sync.Mutex
+	messages := []string{}
+	received := make(chan struct{}, 10)
+	kafka.InterceptMessages(t, mock, func(r *kgo.Record) {
+		mu.Lock()
+		defer mu.Unlock()
+		messages = append(messages, string(r.Value))
+		received <- struct{}{}
+	})
+
+	// The first message is queued on disk, then drained.
+	var errs []error
+	var errsMu sync.Mutex
+	for _, payload := range []string{"hello world!", "goodbye world!"} {
+		c.Send("127.0.0.1", []byte(payload), func(err error) {
+			errsMu.Lock()
+			defer errsMu.Unlock()
+			errs = append(errs, err)
+		})
+		c.Flush(t)
+	}
+	for range 2 {
+		select {
+		case <-received:
+		case <-time.After(5 * time.Second):
+			t.Fatal("messages not received")
+		}
+	}
+	mu.Lock()
+	slices.Sort(messages)
+	if diff := helpers.Diff(messages, []string{"goodbye world!", "hello world!"}); diff != "" {
+		t.Fatalf("Send() (-got, +want):\n%s", diff)
+	}
+	mu.Unlock()
+	errsMu.Lock()
+	if diff := helpers.Diff(errs, []error{nil, nil}); diff != "" {
+		t.Fatalf("Send() errors (-got, +want):\n%s", diff)
+	}
+	errsMu.Unlock()
+
+	// Wait for the segment to be removed from the queue.
+	for i := 0; ; i++ {
+		if records, _, _ := c.diskQueue.Stats(); records == 0 {
+			break
+		}
+		if i == 50 {
+			t.Fatal("disk queue not drained")
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+	gotMetrics := r.GetMetrics("akvorado_inlet_kafka_", "disk_queue_", "sent_messages")
+	expectedMetrics := map[string]string{
+		`disk_queue_bytes`:                          "0",
+		`disk_queue_messages`:                       "0",
+		`disk_queue_oldest_age_seconds`:             "0",
+		`disk_queue_queued_messages_total`:          "1",
+		`disk_queue_drained_messages_total`:         "1",
+		`disk_queue_dropped_messages_total`:         "0",
+		`disk_queue_expired_messages_total`:         "0",
+		`sent_messages_total{exporter="127.0.0.1"}`: "2",
+	}
+	if diff := helpers.Diff(gotMetrics, expectedMetrics); diff != "" {
+		t.Fatalf("Metrics (-got, +want):\n%s", diff)
+	}
+}
+
+func TestKafkaDiskQueueDrops(t *testing.T) {
+	r := reporter.NewMock(t)
+	config := DefaultConfiguration()
+	config.Topic = "disk-queue-drops"
+	config.DiskQueue.Directory = t.TempDir()
+
+	// Queue a message which is too old to be sent.
+	q, err := newDiskQueue(config.DiskQueue.Directory, config.DiskQueue.MaxSize)
+	if err != nil {
+		t.Fatalf("newDiskQueue() error:\n%+v", err)
+	}
+	if err := q.Push("127.0.0.1", []byte("too old"), time.Now().Add(-25*time.Hour)); err != nil {
+		t.Fatalf("Push() error:\n%+v", err)
+	}
+	if err := q.Close(); err != nil {
+		t.Fatalf("Close() error:\n%+v", err)
+	}
+
+	c, mock := NewMock(t, r, config)
+	defer mock.Close()
+
+	// Reject all produce requests with a non-retriable error.
+	mock.ControlKey(0, func(kreq kmsg.Request) (kmsg.Response, error, bool) {
+		mock.KeepControl()
+		req := kreq.(*kmsg.ProduceRequest)
+		resp := kreq.ResponseKind().(*kmsg.ProduceResponse)
+		for _, rt := range req.Topics {
+			st := kmsg.NewProduceResponseTopic()
+			st.Topic = rt.Topic
+			st.TopicID = rt.TopicID
+			for _, rp := range rt.Partitions {
+				sp := kmsg.NewProduceResponseTopicPartition()
+				sp.Partition = rp.Partition
+				sp.ErrorCode = kerr.MessageTooLarge.Code
+				st.Partitions = append(st.Partitions, sp)
+			}
+			resp.Topics = append(resp.Topics, st)
+		}
+		return resp, nil, true
+	})
+
+	// The message is not queued on disk.
+	errs := make(chan error, 1)
+	c.Send("127.0.0.1", []byte("hello world!"), func(err error) {
+		errs <- err
+	})
+	select {
+	case err := <-errs:
+		if !errors.Is(err, kerr.MessageTooLarge) {
+			t.Fatalf("Send() error:\n%+v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Send() did not complete")
+	}
+
+	// The old message is dropped from the disk queue.
+	for i := 0; ; i++ {
+		if records, _, _ := c.diskQueue.Stats(); records == 0 {
+			break
+		}
+		if i == 50 {
+			t.Fatal("disk queue not drained")
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+	gotMetrics := r.GetMetrics("akvorado_inlet_kafka_", "disk_queue_", "errors_total")
+	expectedMetrics := map[string]string{
+		`disk_queue_bytes`:                        "0",
+		`disk_queue_messages`:                     "0",
+		`disk_queue_oldest_age_seconds`:           "0",
+		`disk_queue_queued_messages_total`:        "0",
+		`disk_queue_drained_messages_total`:       "0",
+		`disk_queue_dropped_messages_total`:       "0",
+		`disk_queue_expired_messages_total`:       "1",
+		`errors_total{error="MESSAGE_TOO_LARGE"}`: "1",
+	}
+	if diff := helpers.Diff(gotMetrics, expectedMetrics); diff != "" {
+		t.Fatalf("Metrics (-got, +want):\n%s", diff)
+	}
+}
+
+func TestKafkaDiskQueueFullRequeue(t *testing.T) {
+	r := reporter.NewMock(t)
+	config := DefaultConfiguration()
+	config.Topic = "disk-queue-full"
+	config.DiskQueue.Directory = t.TempDir()
+	config.DiskQueue.MaxSize = int64(4 * (diskQueueHeaderSize + len("127.0.0.1") + len("message 0")))
+
+	// Fill the queue.
+	q, err := newDiskQueue(config.DiskQueue.Directory, config.DiskQueue.MaxSize)
+	if err != nil {
+		t.Fatalf("newDiskQueue() error:\n%+v", err)
+	}
+	for i := range 4 {
+		if err := q.Push("127.0.0.1", fmt.Appendf(nil, "message %d", i), time.Now()); err != nil {
+			t.Fatalf("Push() error:\n%+v", err)
+		}
+	}
+	if err := q.Close(); err != nil {
+		t.Fatalf("Close() error:\n%+v", err)
+	}
+	first := q.segmentPath(0)
+
+	c, mock := NewMock(t, r, config)
+	defer mock.Close()
+
+	// Reject all produce requests with a retriable error.
+	mock.ControlKey(0, func(kreq kmsg.Request) (kmsg.Response, error, bool) {
+		mock.KeepControl()
+		req := kreq.(*kmsg.ProduceRequest)
+		resp := kreq.ResponseKind().(*kmsg.ProduceResponse)
+		for _, rt := range req.Topics {
+			st := kmsg.NewProduceResponseTopic()
+			st.Topic = rt.Topic
+			st.TopicID = rt.TopicID
+			for _, rp := range rt.Partitions {
+				sp := kmsg.NewProduceResponseTopicPartition()
+				sp.Partition = rp.Partition
+				sp.ErrorCode = kerr.CorruptMessage.Code
+				st.Partitions = append(st.Partitions, sp)
+			}
+			resp.Topics = append(resp.Topics, st)
+		}
+		return resp, nil, true
+	})
+
+	// Wait for the first segment to be drained. Its records are queued again.
+	for i := 0; ; i++ {
+		if _, err := os.Stat(first); errors.Is(err, os.ErrNotExist) {
+			break
+		}
+		if i == 50 {
+			t.Fatal("disk queue not drained")
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+	if records, size, _ := c.diskQueue.Stats(); records != 4 || size != config.DiskQueue.MaxSize {
+		t.Fatalf("Stats() = %d, %d", records, size)
+	}
+	gotMetrics := r.GetMetrics("akvorado_inlet_kafka_", "disk_queue_dropped", "disk_queue_drained")
+	expectedMetrics := map[string]string{
+		`disk_queue_drained_messages_total`: "0",
+		`disk_queue_dropped_messages_total`: "0",
+	}
+	if diff := helpers.Diff(gotMetrics, expectedMetrics); diff != "" {
+		t.Fatalf("Metrics (-got, +want):\n%s", diff)
+	}
+}
@@ -0,0 +1,238 @@
+// SPDX-FileCopyrightText: 2026 Free Mobile
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package clickhouse
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	sb "akvorado/common/sqlbuilder"
+)
+
+// anomaliesTable is the name of the table recording the detected anomalies.
+const anomaliesTable = "anomalies"
+
+// anomaliesResolution is the interval of the flows table used to detect
+// anomalies.
+const anomaliesResolution = 5 * time.Minute
+
+// madScale turns a median absolute deviation into an estimation of the
+// standard deviation for normally distributed values.
+const madScale = 1.4826
+
+// checkAnomaliesConfiguration checks the anomaly detection configuration
+// against the resolutions and the schema.
+func (c *Component) checkAnomaliesConfiguration() error {
+	if !c.config.Anomalies.Enabled() {
+		return nil
+	}
+	var resolution *ResolutionConfiguration
+	for idx := range c.config.Resolutions {
+		if c.config.Resolutions[idx].Interval == anomaliesResolution {
+			resolution = &c.config.Resolutions[idx]
+		}
+	}
+	if resolution == nil {
+		return fmt.Errorf("anomalies: a resolution with an interval of %s is required",
+			anomaliesResolution)
+	}
+	history := time.Duration(c.config.Anomalies.Weeks)*7*24*time.Hour + time.Hour
+	if resolution.TTL != 0 && history > resolution.TTL {
+		return fmt.Errorf("anomalies: %d weeks of history do not fit in the TTL of the %s table (%s)",
+			c.config.Anomalies.Weeks, c.anomaliesSourceTable(), resolution.TTL)
+	}
+	names := map[string]bool{}
+	for _, detector := range c.config.Anomalies.Detectors {
+		if names[detector.Name] {
+			return fmt.Errorf("anomalies: duplicate detector %q", detector.Name)
+		}
+		names[detector.Name] = true
+		for _, dimension := range detector.Dimensions {
+			column, ok := c.d.Schema.LookupColumnByName(dimension)
+			if !ok || column.Disabled {
+				return fmt.Errorf("anomalies: detector %q: unknown dimension %q",
+					detector.Name, dimension)
+			}
+			if column.ClickHouseMainOnly {
+				return fmt.Errorf("anomalies: detector %q: dimension %q is not in the %s table",
+					detector.Name, dimension, c.anomaliesSourceTable())
+			}
+		}
+	}
+	return nil
+}
+
+// anomaliesSourceTable returns the name of the flows table used to detect
+// anomalies.
+func (c *Component) anomaliesSourceTable() string {
+	return fmt.Sprintf("flows_%s", anomaliesResolution)
+}
+
+// createAnomaliesTable creates the table recording the detected anomalies. In
+// a cluster, this is the local table behind the distributed one.
+func (c *Component) createAnomaliesTable(ctx context.Context) error {
+	tableName := c.localTable(anomaliesTable)
+	createQuery := sb.CreateTable(c.table(tableName)).
+		Columns(
+			sb.NewColumnDef("TimeStart", "DateTime"),
+			sb.NewColumnDef("Detector", "LowCardinality(String)"),
+			sb.NewColumnDef("DimensionNames", "Array(LowCardinality(String))"),
+			sb.NewColumnDef("Dimensions", "Array(String)"),
+			sb.NewColumnDef("Value", "Float64"),
+			sb.NewColumnDef("Baseline", "Float64"),
+			sb.NewColumnDef("Deviation", "Float64"),
+			sb.NewColumnDef("Score", "Float64"),
+			sb.NewColumnDef("TimeDetected", "DateTime"),
+		).
+		Engine(c.mergeTreeEngine(tableName, "Replacing", sb.Column("TimeDetected"))).
+		OrderBy(sb.Column("TimeStart"), sb.Column("Detector"), sb.Column("Dimensions")).
+		TTL(sb.Op(sb.Column("TimeStart"), "+",
+			sb.Function("toIntervalSecond", sb.Uint(uint64(c.config.Anomalies.TTL.Seconds())))))
+
+	// Check if the table already exists
+	if ok, err := c.tableAlreadyExists(ctx, tableName, "create_table_query", createQuery); err != nil {
+		return err
+	} else if ok {
+		c.r.Info().Msgf("%s table already exists, skip migration", tableName)
+		return errSkipStep
+	}
+
+	c.r.Info().Msgf("create %s table", tableName)
+	if err := c.d.ClickHouse.ExecOnCluster(ctx, createQuery.OrReplace()); err != nil {
+		return fmt.Errorf("cannot create %s table: %w", tableName, err)
+	}
+	return nil
+}
+
+// anomaliesLoop periodically checks the last complete intervals for
+// anomalies. It starts once the migrations are done.
+func (c *Component) anomaliesLoop() error {
+	select {
+	case <-c.t.Dying():
+		return nil
+	case <-c.migrationsDone:
+	}
+	ticker := time.NewTicker(c.config.Anomalies.Interval)
+	defer ticker.Stop()
+	var last time.Time
+	for {
+		var err error
+		if last, err = c.detectAnomalies(c.t.Context(nil), time.Now(), last); err != nil {
+			c.r.Err(err).Msg("unable to detect anomalies")
+		}
+		select {
+		case <-c.t.Dying():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+// detectAnomalies checks the intervals completed since the last checked one
+// and returns the new last checked interval. Only the last hour is checked
+// after a long interruption.
+func (c *Component) detectAnomalies(ctx context.Context, now, last time.Time) (time.Time, error) {
+	target := now.Add(-c.config.Anomalies.Delay).Truncate(anomaliesResolution).Add(-anomaliesResolution)
+	start := last.Add(anomaliesResolution)
+	if last.IsZero() || start.Before(target.Add(-time.Hour)) {
+		start = target
+	}
+	for t := start; !t.After(target); t = t.Add(anomaliesResolution) {
+		for _, detector := range c.config.Anomalies.Detectors {
+			if err := c.d.ClickHouse.Exec(ctx, c.anomaliesQuery(detector, t)); err != nil {
+				c.metrics.anomaliesErrors.Inc()
+				return last, fmt.Errorf("cannot detect anomalies for %s: %w", detector.Name, err)
+			}
+		}
+		c.metrics.anomaliesChecks.Inc()
+		last = t
+	}
+	return last, nil
+}
+
+// anomaliesQuery returns the query recording the anomalies found by a
+// detector for the interval starting at the provided time. The baseline is
+// the median of the rates during the same hour of the week for the previous
+// weeks, missing rates being 0. The deviation is the median absolute
+// deviation from this baseline.
+func (c *Component) anomaliesQuery(detector AnomalyDetectorConfiguration, start time.Time) string {
+	dateTime := func(t time.Time) sb.Expr {
+		return sb.Function("toDateTime", sb.String(t.UTC().Format(time.DateTime)), sb.String("UTC"))
+	}
+	float := func(value float64) sb.Expr {
+		return sb.Number(strconv.FormatFloat(value, 'g', -1, 64))
+	}
+	received := sb.Column("TimeReceived")
+	current := dateTime(start)
+	ranges := []sb.Expr{sb.Op(received, "=", current)}
+	hour := start.Truncate(time.Hour)
+	for week := 1; week <= c.config.Anomalies.Weeks; week++ {
+		from := hour.Add(-time.Duration(week) * 7 * 24 * time.Hour)
+		ranges = append(ranges, sb.And(
+			sb.Op(received, ">=", dateTime(from)),
+			sb.Op(received, "<", dateTime(from.Add(time.Hour)))))
+	}
+	names := make([]sb.Expr, len(detector.Dimensions))
+	values := make([]sb.Expr, len(detector.Dimensions))
+	for idx, dimension := range detector.Dimensions {
+		names[idx] = sb.String(dimension)
+		values[idx] = sb.Function("toString", sb.Column(dimension))
+	}
+	samples := c.config.Anomalies.Weeks * int(time.Hour/anomaliesResolution)
+
+	// Rate for each interval and each combination of dimensions.
+	rates := sb.Select(
+		received,
+		sb.Alias(sb.Array(values...), "dimensions"),
+		sb.Alias(sb.Op(
+			sb.Function("sum", sb.Op(sb.Op(sb.Column("Bytes"), "*", sb.Column("SamplingRate")), "*", sb.Uint(8))),
+			"/", sb.Uint(uint64(anomaliesResolution.Seconds()))), "rate"),
+	).
+		From(c.table(c.distributedTable(c.anomaliesSourceTable()))).
+		Where(sb.Or(ranges...)).
+		GroupBy(received, sb.Column("dimensions"))
+	// Current rate, baseline and deviation for each combination of dimensions.
+	quantile := func(array sb.Expr) sb.Expr {
+		return sb.Function("arrayReduce", sb.String("quantileExact"), array)
+	}
+	baselines := sb.Select(
+		sb.Column("dimensions"),
+		sb.Alias(sb.Function("sumIf", sb.Column("rate"), sb.Op(received, "=", current)), "value"),
+		sb.Alias(sb.Function("arrayResize",
+			sb.Function("groupArrayIf", sb.Column("rate"), sb.Op(received, "!=", current)),
+			sb.Uint(uint64(samples)),
+			sb.Function("toFloat64", sb.Uint(0))), "samples"),
+		sb.Alias(quantile(sb.Column("samples")), "baseline"),
+		sb.Alias(quantile(sb.Function("arrayMap",
+			sb.Lambda("x", sb.Function("abs", sb.Op(sb.Column("x"), "-", sb.Column("baseline")))),
+			sb.Column("samples"))), "deviation"),
+	).
+		FromSelect(rates).
+		GroupBy(sb.Column("dimensions"))
+	anomalies := sb.Select(
+		sb.Alias(current, "TimeStart"),
+		sb.Alias(sb.String(detector.Name), "Detector"),
+		sb.Alias(sb.Array(names...), "DimensionNames"),
+		sb.Alias(sb.Column("dimensions"), "Dimensions"),
+		sb.Alias(sb.Column("value"), "Value"),
+		sb.Alias(sb.Column("baseline"), "Baseline"),
+		sb.Alias(sb.Column("deviation"), "Deviation"),
+		sb.Alias(sb.Op(
+			sb.Op(sb.Column("value"), "-", sb.Column("baseline")),
+			"/", sb.Function("greatest", sb.Op(float(madScale), "*", sb.Column("deviation")), sb.Uint(1))), "Score"),
+		sb.Alias(sb.Function("now"), "TimeDetected"),
+	).
+		FromSelect(baselines).
+		Where(sb.And(
+			sb.Op(sb.Function("greatest", sb.Column("value"), sb.Column("baseline")), ">=",
+				sb.Uint(c.config.Anomalies.MinimumRate)),
+			sb.Op(sb.Function("abs", sb.Column("Score")), ">=", float(c.config.Anomalies.Sensitivity))))
+
+	return fmt.Sprintf("INSERT INTO %s (%s) %s",
+		c.table(c.distributedTable(anomaliesTable)),
+		"TimeStart, Detector, DimensionNames, Dimensions, Value, Baseline, Deviation, Score, TimeDetected",
+		anomalies)
+}
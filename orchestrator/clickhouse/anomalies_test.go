@@ -0,0 +1,209 @@
+// SPDX-FileCopyrightText: 2026 Free Mobile
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package clickhouse
+
+import (
+	"testing"
+	"time"
+
+	"akvorado/common/clickhousedb"
+	"akvorado/common/daemon"
+	"akvorado/common/helpers"
+	"akvorado/common/httpserver"
+	"akvorado/common/reporter"
+	"akvorado/common/schema"
+	sb "akvorado/common/sqlbuilder"
+)
+
+func TestAnomaliesConfiguration(t *testing.T) {
+	cases := []struct {
+		Pos    helpers.Pos
+		Modify func(*Configuration)
+		Error  bool
+	}{
+		{
+			Pos:    helpers.Mark(),
+			Modify: func(*Configuration) {},
+		}, {
+			Pos: helpers.Mark(),
+			Modify: func(c *Configuration) {
+				c.Anomalies.Detectors = []AnomalyDetectorConfiguration{
+					{Name: "interfaces", Dimensions: []string{"ExporterName", "InIfName"}},
+					{Name: "as", Dimensions: []string{"SrcAS"}},
+				}
+			},
+		}, {
+			Pos: helpers.Mark(),
+			Modify: func(c *Configuration) {
+				c.Anomalies.Detectors = []AnomalyDetectorConfiguration{
+					{Name: "as", Dimensions: []string{"SrcAS"}},
+					{Name: "as", Dimensions: []string{"DstAS"}},
+				}
+			},
+			Error: true,
+		}, {
+			Pos: helpers.Mark(),
+			Modify: func(c *Configuration) {
+				c.Anomalies.Detectors = []AnomalyDetectorConfiguration{
+					{Name: "unknown", Dimensions: []string{"SrcUnknown"}},
+				}
+			},
+			Error: true,
+		}, {
+			Pos: helpers.Mark(),
+			Modify: func(c *Configuration) {
+				c.Anomalies.Detectors = []AnomalyDetectorConfiguration{
+					{Name: "addresses", Dimensions: []string{"SrcAddr"}},
+				}
+			},
+			Error: true,
+		}, {
+			Pos: helpers.Mark(),
+			Modify: func(c *Configuration) {
+				c.Anomalies.Detectors = []AnomalyDetectorConfiguration{
+					{Name: "as", Dimensions: []string{"SrcAS"}},
+				}
+				c.Anomalies.Weeks = 13
+			},
+			Error: true,
+		}, {
+			Pos: helpers.Mark(),
+			Modify: func(c *Configuration) {
+				c.Anomalies.Detectors = []AnomalyDetectorConfiguration{
+					{Name: "as", Dimensions: []string{"SrcAS"}},
+				}
+				c.Resolutions = c.Resolutions[:2]
+			},
+			Error: true,
+		},
+	}
+	for _, tc := range cases {
+		r := reporter.NewMock(t)
+		config := DefaultConfiguration()
+		tc.Modify(&config)
+		_, err := New(r, config, Dependencies{
+			Daemon: daemon.NewMock(t),
+			HTTP:   httpserver.NewMock(t, r),
+			Schema: schema.NewMock(t),
+		})
+		if err != nil && !tc.Error {
+			t.Errorf("%sNew() error:\n%+v", tc.Pos, err)
+		} else if err == nil && tc.Error {
+			t.Errorf("%sNew() did not error", tc.Pos)
+		}
+	}
+}
+
+func TestAnomaliesQuery(t *testing.T) {
+	config := DefaultConfiguration()
+	config.Anomalies.Weeks = 2
+	ch, _ := clickhousedb.NewMock(t, reporter.NewMock(t))
+	c := Component{config: config, d: &Dependencies{ClickHouse: ch}}
+	got := c.anomaliesQuery(AnomalyDetectorConfiguration{
+		Name:       "interfaces",
+		Dimensions: []string{"ExporterName", "InIfName"},
+	}, time.Date(2026, 10, 19, 10, 25, 0, 0, time.UTC))
+	expected := `
+INSERT INTO default.anomalies (TimeStart, Detector, DimensionNames, Dimensions, Value, Baseline, Deviation, Score, TimeDetected)
+SELECT
+ toDateTime('2026-10-19 10:25:00', 'UTC') AS TimeStart,
+ 'interfaces' AS Detector,
+ ['ExporterName', 'InIfName'] AS DimensionNames,
+ dimensions AS Dimensions,
+ value AS Value,
+ baseline AS Baseline,
+ deviation AS Deviation,
+ (value - baseline) / greatest(1.4826 * deviation, 1) AS Score,
+ now() AS TimeDetected
+FROM (
+ SELECT
+  dimensions,
+  sumIf(rate, TimeReceived = toDateTime('2026-10-19 10:25:00', 'UTC')) AS value,
+  arrayResize(groupArrayIf(rate, TimeReceived != toDateTime('2026-10-19 10:25:00', 'UTC')), 24, toFloat64(0)) AS samples,
+  arrayReduce('quantileExact', samples) AS baseline,
+  arrayReduce('quantileExact', arrayMap(x -> abs(x - baseline), samples)) AS deviation
+ FROM (
+  SELECT TimeReceived, [toString(ExporterName), toString(InIfName)] AS dimensions, sum(Bytes * SamplingRate * 8) / 300 AS rate
+  FROM default.flows_5m0s
+  WHERE TimeReceived = toDateTime('2026-10-19 10:25:00', 'UTC')
+  OR TimeReceived >= toDateTime('2026-10-12 10:00:00', 'UTC') AND TimeReceived < toDateTime('2026-10-12 11:00:00', 'UTC')
+  OR TimeReceived >= toDateTime('2026-10-05 10:00:00', 'UTC') AND TimeReceived < toDateTime('2026-10-05 11:00:00', 'UTC')
+  GROUP BY TimeReceived, dimensions
+ )
+ GROUP BY dimensions
+)
+WHERE greatest(value, baseline) >= 1000000
+AND abs(Score) >= 5
+`
+	if diff := helpers.Diff(sb.Normalize(t, got), sb.Normalize(t, expected)); diff != "" {
+		t.Fatalf("anomaliesQuery() (-got, +want):\n%s", diff)
+	}
+}
+
+func TestDetectAnomalies(t *testing.T) {
+	r := reporter.NewMock(t)
+	chComponent := clickhousedb.SetupClickHouse(t, r, false)
+	dropAllTables(t, chComponent)
+	ch := startTestComponentWithConfig(t, r, chComponent, nil, func(c *Configuration) {
+		c.Anomalies.Detectors = []AnomalyDetectorConfiguration{
+			{Name: "exporters", Dimensions: []string{"ExporterName"}},
+		}
+		c.Anomalies.MinimumRate = 0
+		c.Anomalies.Interval = time.Hour
+	})
+
+	// Each exporter sends 10 kbps during the same hour of the previous weeks.
+	// Then, router1 sends 100 kbps while router2 does not change.
+	current := time.Now().Truncate(time.Hour).Add(-2 * time.Hour).Add(25 * time.Minute)
+	insert := func(received time.Time, exporter string, bytes uint64) {
+		if err := chComponent.Exec(t.Context(),
+			"INSERT INTO flows (TimeReceived, ExporterName, Bytes, Packets, SamplingRate) VALUES ($1, $2, $3, 1, 1)",
+			received, exporter, bytes); err != nil {
+			t.Fatalf("Exec() error:\n%+v", err)
+		}
+	}
+	for week := 1; week <= ch.config.Anomalies.Weeks; week++ {
+		hour := current.Truncate(time.Hour).Add(-time.Duration(week) * 7 * 24 * time.Hour)
+		for received := hour; received.Before(hour.Add(time.Hour)); received = received.Add(anomaliesResolution) {
+			insert(received, "router1", 375_000)
+			insert(received, "router2", 375_000)
+		}
+	}
+	insert(current, "router1", 3_750_000)
+	insert(current, "router2", 375_000)
+
+	now := current.Add(anomaliesResolution + ch.config.Anomalies.Delay)
+	last, err := ch.detectAnomalies(t.Context(), now, time.Time{})
+	if err != nil {
+		t.Fatalf("detectAnomalies() error:\n%+v", err)
+	}
+	if !last.Equal(current) {
+		t.Fatalf("detectAnomalies() checked up to %s, not %s", last, current)
+	}
+	// Nothing new to check
+	if _, err := ch.detectAnomalies(t.Context(), now, last); err != nil {
+		t.Fatalf("detectAnomalies() error:\n%+v", err)
+	}
+
+	var got []struct {
+		Detector   string   `ch:"Detector"`
+		Dimensions []string `ch:"Dimensions"`
+		Value      float64  `ch:"Value"`
+		Baseline   float64  `ch:"Baseline"`
+	}
+	if err := chComponent.Select(t.Context(), &got,
+		"SELECT Detector, Dimensions, Value, Baseline FROM anomalies FINAL WHERE TimeStart = $1",
+		current); err != nil {
+		t.Fatalf("Select() error:\n%+v", err)
+	}
+	expected := []struct {
+		Detector   string   `ch:"Detector"`
+		Dimensions []string `ch:"Dimensions"`
+		Value      float64  `ch:"Value"`
+		Baseline   float64  `ch:"Baseline"`
+	}{{"exporters", []string{"router1"}, 100_000, 10_000}}
+	if diff := helpers.Diff(got, expected); diff != "" {
+		t.Fatalf("anomalies (-got, +want):\n%s", diff)
+	}
+}
@@ -0,0 +1,249 @@
+// SPDX-FileCopyrightText: 2026 Free Mobile
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package clickhouse
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/ClickHouse/clickhouse-go/v2"
+
+	"akvorado/common/schema"
+	sb "akvorado/common/sqlbuilder"
+)
+
+// archivesTable is the name of the table recording the archived hours.
+const archivesTable = "flows_archives"
+
+// archiveFormat is the format used for archived flows.
+const archiveFormat = "Parquet"
+
+// archivedHour is an hour of flows recorded in the archives table.
+type archivedHour struct {
+	TimeStart time.Time `ch:"TimeStart"`
+	Path      string    `ch:"Path"`
+}
+
+// checkArchiveConfiguration checks the archive configuration against the
+// resolutions. Resolutions should already be sorted.
+func (c *Component) checkArchiveConfiguration() error {
+	if !c.config.Archive.Enabled() {
+		return nil
+	}
+	if c.config.Archive.Directory != "" && c.config.Archive.S3.URL != "" {
+		return errors.New("archive: cannot use both a directory and a S3 URL")
+	}
+	ttl := c.config.Resolutions[0].TTL
+	if ttl == 0 {
+		return errors.New("archive: the main flows table needs a TTL")
+	}
+	if c.config.Archive.Lead >= ttl {
+		return fmt.Errorf("archive: lead (%s) should be shorter than the TTL of the main flows table (%s)",
+			c.config.Archive.Lead, ttl)
+	}
+	return nil
+}
+
+// archivePath returns the path (or the URL) of the file for the provided hour.
+func (c *Component) archivePath(start time.Time) string {
+	name := fmt.Sprintf("flows-%s.parquet", start.UTC().Format("2006010215"))
+	if c.config.Archive.S3.URL != "" {
+		return fmt.Sprintf("%s/%s", strings.TrimRight(c.config.Archive.S3.URL, "/"), name)
+	}
+	return path.Join(c.config.Archive.Directory, name)
+}
+
+// archiveTableFunction returns the table function to read or write the
+// provided archive file.
+func (c *Component) archiveTableFunction(path string) sb.Expr {
+	if c.config.Archive.S3.URL == "" {
+		return sb.Function("file", sb.String(path), sb.String(archiveFormat))
+	}
+	if c.config.Archive.S3.AccessKeyID == "" {
+		return sb.Function("s3", sb.String(path), sb.String(archiveFormat))
+	}
+	return sb.Function("s3", sb.String(path),
+		sb.String(c.config.Archive.S3.AccessKeyID),
+		sb.String(c.config.Archive.S3.SecretAccessKey),
+		sb.String(archiveFormat))
+}
+
+// archiveColumns returns the columns to archive. Aliased columns are not
+// archived as they are computed from the other ones.
+func (c *Component) archiveColumns() string {
+	return strings.Join(c.d.Schema.ClickHouseSelectColumns(schema.ClickHouseSkipAliasedColumns), ", ")
+}
+
+// createArchivesTable creates the table recording the archived hours.
+func (c *Component) createArchivesTable(ctx context.Context) error {
+	createQuery := sb.CreateTable(c.table(archivesTable)).
+		Columns(
+			sb.NewColumnDef("TimeStart", "DateTime"),
+			sb.NewColumnDef("Path", "String"),
+			sb.NewColumnDef("Rows", "UInt64"),
+			sb.NewColumnDef("TimeArchived", "DateTime"),
+		).
+		Engine(c.mergeTreeEngine(archivesTable, "Replacing", sb.Column("TimeArchived"))).
+		OrderBy(sb.Column("TimeStart"))
+
+	// Check if the table already exists
+	if ok, err := c.tableAlreadyExists(ctx, archivesTable, "create_table_query", createQuery); err != nil {
+		return err
+	} else if ok {
+		c.r.Info().Msgf("%s table already exists, skip migration", archivesTable)
+		return errSkipStep
+	}
+
+	c.r.Info().Msgf("create %s table", archivesTable)
+	if err := c.d.ClickHouse.ExecOnCluster(ctx, createQuery.OrReplace()); err != nil {
+		return fmt.Errorf("cannot create %s table: %w", archivesTable, err)
+	}
+	return nil
+}
+
+// archiveLoop periodically archives the hours of flows about to expire. It
+// starts once the migrations are done.
+func (c *Component) archiveLoop() error {
+	select {
+	case <-c.t.Dying():
+		return nil
+	case <-c.migrationsDone:
+	}
+	ticker := time.NewTicker(c.config.Archive.Interval)
+	defer ticker.Stop()
+	for {
+		if err := c.archiveExpiringHours(c.t.Context(nil)); err != nil {
+			c.r.Err(err).Msg("unable to archive flows")
+		}
+		select {
+		case <-c.t.Dying():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+// archiveExpiringHours archives each hour of flows expiring in less than the
+// configured lead time and not already archived.
+func (c *Component) archiveExpiringHours(ctx context.Context) error {
+	cutoff := time.Now().Add(c.config.Archive.Lead - c.config.Resolutions[0].TTL).Truncate(time.Hour)
+	var hours []struct {
+		TimeStart time.Time `ch:"TimeStart"`
+	}
+	if err := c.d.ClickHouse.Select(ctx, &hours, fmt.Sprintf(`
+SELECT DISTINCT toStartOfHour(TimeReceived) AS TimeStart
+FROM %s
+WHERE TimeReceived < $1
+AND TimeStart NOT IN (SELECT TimeStart FROM %s)
+ORDER BY TimeStart ASC
+`, c.table(c.distributedTable("flows")), c.table(archivesTable)), cutoff); err != nil {
+		return fmt.Errorf("cannot list hours to archive: %w", err)
+	}
+	for _, hour := range hours {
+		if err := c.archiveHour(ctx, hour.TimeStart); err != nil {
+			c.metrics.archiveErrors.Inc()
+			return err
+		}
+	}
+	return nil
+}
+
+// archiveHour exports one hour of flows to a Parquet file and records it in
+// the archives table.
+func (c *Component) archiveHour(ctx context.Context, start time.Time) error {
+	end := start.Add(time.Hour)
+	path := c.archivePath(start)
+	l := c.r.With().Time("start", start).Str("path", path).Logger()
+	l.Info().Msg("archive flows")
+
+	ctx = clickhouse.Context(ctx, clickhouse.WithSettings(clickhouse.Settings{
+		"engine_file_truncate_on_insert": 1,
+		"s3_truncate_on_insert":          1,
+	}))
+	columns := c.archiveColumns()
+	if err := c.d.ClickHouse.Exec(ctx, fmt.Sprintf(`
+INSERT INTO FUNCTION %s
+SELECT %s
+FROM %s
+WHERE TimeReceived >= $1 AND TimeReceived < $2
+`, c.archiveTableFunction(path), columns, c.table(c.distributedTable("flows"))), start, end); err != nil {
+		return fmt.Errorf("cannot archive flows to %s: %w", path, err)
+	}
+	var rows uint64
+	row := c.d.ClickHouse.QueryRow(ctx, fmt.Sprintf(`SELECT count() FROM %s`,
+		c.archiveTableFunction(path)))
+	if err := row.Scan(&rows); err != nil {
+		return fmt.Errorf("cannot count archived flows in %s: %w", path, err)
+	}
+	if err := c.d.ClickHouse.Exec(ctx, fmt.Sprintf(`
+INSERT INTO %s (TimeStart, Path, Rows, TimeArchived)
+VALUES ($1, $2, $3, now())
+`, c.table(archivesTable)), start, path, rows); err != nil {
+		return fmt.Errorf("cannot record archive %s: %w", path, err)
+	}
+	c.metrics.archivedHours.Inc()
+	c.metrics.archivedRows.Add(float64(rows))
+	l.Info().Uint64("rows", rows).Msg("flows archived")
+	return nil
+}
+
+// RestoreArchive imports the archived flows between start and end into a new
+// table. The table has the same columns as the main flows table and it is
+// not expired: it should be dropped once it is not needed anymore. It returns
+// the number of imported flows.
+func (c *Component) RestoreArchive(ctx context.Context, start, end time.Time, table string) (uint64, error) {
+	if !c.config.Archive.Enabled() {
+		return 0, errors.New("archiving is not configured")
+	}
+	var hours []archivedHour
+	if err := c.d.ClickHouse.Select(ctx, &hours, fmt.Sprintf(`
+SELECT TimeStart, Path
+FROM %s FINAL
+WHERE TimeStart >= toStartOfHour($1) AND TimeStart < $2
+ORDER BY TimeStart ASC
+`, c.table(archivesTable)), start, end); err != nil {
+		return 0, fmt.Errorf("cannot list archived flows: %w", err)
+	}
+	if len(hours) == 0 {
+		return 0, errors.New("no archived flows in the requested range")
+	}
+
+	columns, err := sb.ParseColumnDefs(c.d.Schema.ClickHouseCreateTable())
+	if err != nil {
+		return 0, fmt.Errorf("cannot build query to create %s: %w", table, err)
+	}
+	createQuery := sb.CreateTable(c.table(table)).
+		Columns(columns...).
+		Engine(sb.NewEngine("MergeTree")).
+		OrderBy(sb.Columns("TimeReceived", "ExporterAddress")...)
+	ctx = clickhouse.Context(ctx, clickhouse.WithSettings(clickhouse.Settings{
+		"allow_suspicious_low_cardinality_types": 1,
+	}))
+	if err := c.d.ClickHouse.Exec(ctx, createQuery.String()); err != nil {
+		return 0, fmt.Errorf("cannot create %s: %w", table, err)
+	}
+
+	names := c.archiveColumns()
+	var total uint64
+	for _, hour := range hours {
+		c.r.Info().Time("start", hour.TimeStart).Str("path", hour.Path).Msg("restore archived flows")
+		if err := c.d.ClickHouse.Exec(ctx, fmt.Sprintf(`
+INSERT INTO %s (%s)
+SELECT %s
+FROM %s
+WHERE TimeReceived >= $1 AND TimeReceived < $2
+`, c.table(table), names, names, c.archiveTableFunction(hour.Path)), start, end); err != nil {
+			return total, fmt.Errorf("cannot restore archived flows from %s: %w", hour.Path, err)
+		}
+	}
+	row := c.d.ClickHouse.QueryRow(ctx, fmt.Sprintf(`SELECT count() FROM %s`, c.table(table)))
+	if err := row.Scan(&total); err != nil {
+		return 0, fmt.Errorf("cannot count restored flows: %w", err)
+	}
+	return total, nil
+}
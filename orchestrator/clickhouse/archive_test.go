@@ -0,0 +1,155 @@
+// SPDX-FileCopyrightText: 2026 Free Mobile
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package clickhouse
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"akvorado/common/clickhousedb"
+	"akvorado/common/daemon"
+	"akvorado/common/helpers"
+	"akvorado/common/httpserver"
+	"akvorado/common/reporter"
+	"akvorado/common/schema"
+)
+
+func TestArchiveConfiguration(t *testing.T) {
+	cases := []struct {
+		Pos    helpers.Pos
+		Modify func(*Configuration)
+		Error  bool
+	}{
+		{
+			Pos:    helpers.Mark(),
+			Modify: func(*Configuration) {},
+		}, {
+			Pos: helpers.Mark(),
+			Modify: func(c *Configuration) {
+				c.Archive.Directory = "akvorado"
+			},
+		}, {
+			Pos: helpers.Mark(),
+			Modify: func(c *Configuration) {
+				c.Archive.Directory = "akvorado"
+				c.Archive.S3.URL = "http://minio:9000/akvorado"
+			},
+			Error: true,
+		}, {
+			Pos: helpers.Mark(),
+			Modify: func(c *Configuration) {
+				c.Archive.Directory = "akvorado"
+				c.Resolutions[0].TTL = 0
+			},
+			Error: true,
+		}, {
+			Pos: helpers.Mark(),
+			Modify: func(c *Configuration) {
+				c.Archive.S3.URL = "http://minio:9000/akvorado"
+				c.Archive.Lead = c.Resolutions[0].TTL
+			},
+			Error: true,
+		},
+	}
+	for _, tc := range cases {
+		r := reporter.NewMock(t)
+		config := DefaultConfiguration()
+		tc.Modify(&config)
+		_, err := New(r, config, Dependencies{
+			Daemon: daemon.NewMock(t),
+			HTTP:   httpserver.NewMock(t, r),
+			Schema: schema.NewMock(t),
+		})
+		if err != nil && !tc.Error {
+			t.Errorf("%sNew() error:\n%+v", tc.Pos, err)
+		} else if err == nil && tc.Error {
+			t.Errorf("%sNew() did not error", tc.Pos)
+		}
+	}
+}
+
+func TestArchiveTableFunction(t *testing.T) {
+	start := time.Date(2026, 10, 19, 5, 0, 0, 0, time.UTC)
+	cases := []struct {
+		Pos      helpers.Pos
+		Archive  ArchiveConfiguration
+		Expected string
+	}{
+		{
+			Pos:      helpers.Mark(),
+			Archive:  ArchiveConfiguration{Directory: "akvorado/archives"},
+			Expected: "file('akvorado/archives/flows-2026101905.parquet', 'Parquet')",
+		}, {
+			Pos:      helpers.Mark(),
+			Archive:  ArchiveConfiguration{S3: ArchiveS3Configuration{URL: "http://minio:9000/akvorado/"}},
+			Expected: "s3('http://minio:9000/akvorado/flows-2026101905.parquet', 'Parquet')",
+		}, {
+			Pos: helpers.Mark(),
+			Archive: ArchiveConfiguration{S3: ArchiveS3Configuration{
+				URL:             "http://minio:9000/akvorado",
+				AccessKeyID:     "akvorado",
+				SecretAccessKey: "secret",
+			}},
+			Expected: "s3('http://minio:9000/akvorado/flows-2026101905.parquet', 'akvorado', 'secret', 'Parquet')",
+		},
+	}
+	for _, tc := range cases {
+		c := Component{config: Configuration{Archive: tc.Archive}}
+		got := c.archiveTableFunction(c.archivePath(start)).String()
+		if diff := helpers.Diff(got, tc.Expected); diff != "" {
+			t.Errorf("%sarchiveTableFunction() (-got, +want):\n%s", tc.Pos, diff)
+		}
+	}
+}
+
+func TestArchiveAndRestore(t *testing.T) {
+	r := reporter.NewMock(t)
+	chComponent := clickhousedb.SetupClickHouse(t, r, false)
+	dropAllTables(t, chComponent)
+	ch := startTestComponentWithConfig(t, r, chComponent, nil, func(c *Configuration) {
+		c.Archive.Directory = fmt.Sprintf("akvorado-%s", chComponent.DatabaseName())
+		c.Archive.Interval = time.Hour
+	})
+
+	// Insert a flow about to expire and a recent one.
+	expiring := time.Now().Add(ch.config.Archive.Lead - ch.config.Resolutions[0].TTL - 2*time.Hour).
+		Truncate(time.Hour).Add(time.Minute)
+	for _, received := range []time.Time{expiring, time.Now()} {
+		if err := chComponent.Exec(t.Context(),
+			"INSERT INTO flows (TimeReceived, Bytes, Packets) VALUES ($1, 1000, 1)",
+			received); err != nil {
+			t.Fatalf("Exec() error:\n%+v", err)
+		}
+	}
+
+	if err := ch.archiveExpiringHours(t.Context()); err != nil {
+		t.Fatalf("archiveExpiringHours() error:\n%+v", err)
+	}
+	// Nothing new to archive
+	if err := ch.archiveExpiringHours(t.Context()); err != nil {
+		t.Fatalf("archiveExpiringHours() error:\n%+v", err)
+	}
+	gotMetrics := r.GetMetrics("akvorado_orchestrator_clickhouse_", "archived_")
+	expectedMetrics := map[string]string{
+		"archived_hours_total": "1",
+		"archived_flows_total": "1",
+	}
+	if diff := helpers.Diff(gotMetrics, expectedMetrics); diff != "" {
+		t.Fatalf("Metrics (-got, +want):\n%s", diff)
+	}
+
+	count, err := ch.RestoreArchive(t.Context(),
+		expiring.Add(-time.Hour), expiring.Add(time.Hour), "flows_restored")
+	if err != nil {
+		t.Fatalf("RestoreArchive() error:\n%+v", err)
+	}
+	if count != 1 {
+		t.Fatalf("RestoreArchive() restored %d flows, not 1", count)
+	}
+	if _, err := ch.RestoreArchive(t.Context(),
+		time.Now().Add(-time.Hour), time.Now(), "flows_restored_recent"); err == nil {
+		t.Fatal("RestoreArchive() did not error on a range without archive")
+	}
+}
@@ -9,6 +9,7 @@ import (
 	"time"
 
 	"akvorado/common/helpers"
+	"akvorado/outlet/networks"
 
 	"github.com/go-viper/mapstructure/v2"
 )
@@ -32,6 +33,16 @@ type Configuration struct {
 	// OrchestratorBasicAuth holds optional basic auth credentials to reach
 	// orchestrator from ClickHouse
 	OrchestratorBasicAuth *ConfigurationBasicAuth
+	// Archive describes how to archive the main flows table before its data
+	// expires.
+	Archive ArchiveConfiguration
+	// Anomalies describes how to detect unusual traffic from the
+	// consolidated flows.
+	Anomalies AnomaliesConfiguration
+	// Networks is the networks configuration of the outlet. The networks
+	// dictionary is built from the static networks and the network sources.
+	// This is set by the orchestrator.
+	Networks networks.Configuration
 }
 
 // ConfigurationBasicAuth holds Username and Password subfields
@@ -41,6 +52,74 @@ type ConfigurationBasicAuth struct {
 	Password string `validate:"min=1"`
 }
 
+// ArchiveConfiguration describes how to archive the main flows table to
+// Parquet files. Archiving is enabled when a directory or a S3 URL is set.
+type ArchiveConfiguration struct {
+	// Directory is where ClickHouse writes the Parquet files. It is relative
+	// to the user files directory of the ClickHouse server.
+	Directory string
+	// S3 describes an S3-compatible bucket to write the Parquet files to.
+	S3 ArchiveS3Configuration
+	// Lead is how long before its expiration an hour of flows is archived.
+	Lead time.Duration `validate:"min=10m"`
+	// Interval is how often to look for hours of flows to archive.
+	Interval time.Duration `validate:"min=1m"`
+}
+
+// ArchiveS3Configuration describes an S3-compatible bucket.
+type ArchiveS3Configuration struct {
+	// URL is the URL of the bucket, with an optional prefix.
+	URL string `validate:"isdefault|url"`
+	// AccessKeyID is the access key to use. When empty, no credentials are
+	// provided.
+	AccessKeyID string
+	// SecretAccessKey is the secret key associated to the access key.
+	SecretAccessKey string `validate:"required_with=AccessKeyID"`
+}
+
+// Enabled tells if archiving is enabled.
+func (ac ArchiveConfiguration) Enabled() bool {
+	return ac.Directory != "" || ac.S3.URL != ""
+}
+
+// AnomaliesConfiguration describes how to detect unusual traffic. Each
+// detector aggregates the traffic of the 5-minute table on a set of dimensions
+// and compares each interval to the same hour of the week during the previous
+// weeks. Detection is enabled when at least one detector is configured.
+type AnomaliesConfiguration struct {
+	// Detectors is the list of dimension sets to watch.
+	Detectors []AnomalyDetectorConfiguration `validate:"dive"`
+	// Sensitivity is how many deviations from the baseline a rate should be
+	// to be flagged. Lower values flag more anomalies.
+	Sensitivity float64 `validate:"gt=0"`
+	// Weeks is the number of previous weeks used to compute the baselines.
+	Weeks int `validate:"min=1"`
+	// MinimumRate is the minimum rate, in bits per second, the current rate
+	// or the baseline should reach to be flagged.
+	MinimumRate uint64
+	// Delay is how long to wait for an interval to be complete before
+	// checking it.
+	Delay time.Duration `validate:"min=0"`
+	// Interval is how often to look for anomalies.
+	Interval time.Duration `validate:"min=1m"`
+	// TTL is how long to keep the detected anomalies.
+	TTL time.Duration `validate:"min=1h"`
+}
+
+// AnomalyDetectorConfiguration describes a set of dimensions to watch for
+// anomalies.
+type AnomalyDetectorConfiguration struct {
+	// Name is the name of the detector, recorded with each anomaly.
+	Name string `validate:"required,alphanumunderscore"`
+	// Dimensions is the list of columns to aggregate the traffic on.
+	Dimensions []string `validate:"min=1"`
+}
+
+// Enabled tells if anomaly detection is enabled.
+func (ac AnomaliesConfiguration) Enabled() bool {
+	return len(ac.Detectors) > 0
+}
+
 // TableSettings is a map of ClickHouse table settings.
 // Values should be integers or strings.
 type TableSettings map[string]any
@@ -70,6 +149,19 @@ func DefaultConfiguration() Configuration {
 			{Interval: time.Hour, TTL: 12 * 30 * 24 * time.Hour},      // 1 year
 		},
 		MaxPartitions: 50,
+		Archive: ArchiveConfiguration{
+			Lead:     6 * time.Hour,
+			Interval: 10 * time.Minute,
+		},
+		Anomalies: AnomaliesConfiguration{
+			Sensitivity: 5,
+			Weeks:       4,
+			MinimumRate: 1_000_000,
+			Delay:       5 * time.Minute,
+			Interval:    5 * time.Minute,
+			TTL:         90 * 24 * time.Hour,
+		},
+		Networks: networks.DefaultConfiguration(),
 	}
 }
 
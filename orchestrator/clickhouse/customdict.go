@@ -0,0 +1,127 @@
+// SPDX-FileCopyrightText: 2026 Free Mobile
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package clickhouse
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"strconv"
+
+	"akvorado/common/remotedatasource"
+)
+
+// errCustomDictMissingKey is triggered when a result from a remote source does
+// not contain all the keys of the custom dictionary.
+var errCustomDictMissingKey = errors.New("missing key")
+
+// customDictRow is a row of a custom dictionary fetched from a remote source.
+// Values are indexed by key or attribute name.
+type customDictRow struct {
+	Values map[string]any `mapstructure:",remain"`
+}
+
+// remoteCustomDictSources returns the remote sources of the custom
+// dictionaries, indexed by dictionary name.
+func (c *Component) remoteCustomDictSources() map[string]remotedatasource.Source {
+	sources := map[string]remotedatasource.Source{}
+	for name, dict := range c.d.Schema.GetCustomDictConfig() {
+		if dict.RemoteSource != nil {
+			sources[name] = *dict.RemoteSource
+		}
+	}
+	return sources
+}
+
+// UpdateCustomDict updates a custom dictionary from its remote source. It
+// returns the number of rows retrieved.
+func (c *Component) UpdateCustomDict(ctx context.Context, name string, source remotedatasource.Source) (int, error) {
+	results, err := c.customDictFetcher.Fetch(ctx, name, source)
+	if err != nil {
+		return 0, err
+	}
+	content, err := c.customDictCSV(name, results)
+	if err != nil {
+		return 0, err
+	}
+	c.updateCustomDict(ctx, name, content)
+	return len(results), nil
+}
+
+// customDictCSV turns the rows fetched for a custom dictionary into a CSV file
+// with the keys first and the attributes after.
+func (c *Component) customDictCSV(name string, rows []customDictRow) ([]byte, error) {
+	dict := c.d.Schema.GetCustomDictConfig()[name]
+	header := []string{}
+	for _, k := range dict.Keys {
+		header = append(header, k.Name)
+	}
+	for _, a := range dict.Attributes {
+		header = append(header, a.Name)
+	}
+
+	var buf bytes.Buffer
+	wr := csv.NewWriter(&buf)
+	wr.Write(header)
+	record := make([]string, len(header))
+	for idx, row := range rows {
+		for i, column := range header {
+			value, ok := row.Values[column]
+			if !ok && i < len(dict.Keys) {
+				c.r.Error().Str("name", name).Int("index", idx).Msgf("key %q missing", column)
+				return nil, errCustomDictMissingKey
+			}
+			record[i] = customDictValue(value)
+		}
+		wr.Write(record)
+	}
+	wr.Flush()
+	return buf.Bytes(), wr.Error()
+}
+
+// customDictValue formats a value decoded from a remote source for a CSV file.
+func customDictValue(value any) string {
+	switch value := value.(type) {
+	case nil:
+		return ""
+	case string:
+		return value
+	case float64:
+		// JSON numbers are decoded as float64
+		return strconv.FormatFloat(value, 'f', -1, 64)
+	default:
+		return fmt.Sprint(value)
+	}
+}
+
+// updateCustomDict replaces the content of a custom dictionary served to
+// ClickHouse. When it changes, the dictionary is reloaded. Until the migrations
+// are done, this is left to the migrations.
+func (c *Component) updateCustomDict(ctx context.Context, name string, content []byte) {
+	c.customDictLock.Lock()
+	unchanged := bytes.Equal(c.customDictContents[name], content)
+	c.customDictContents[name] = content
+	c.customDictLock.Unlock()
+	if unchanged {
+		return
+	}
+	select {
+	case <-c.migrationsDone:
+	default:
+		return
+	}
+	if err := c.ReloadDictionary(ctx, fmt.Sprintf("custom_dict_%s", name)); err != nil {
+		c.r.Err(err).Str("name", name).Msg("unable to reload custom dictionary")
+	}
+}
+
+// customDictContent returns the last content fetched for a custom dictionary.
+func (c *Component) customDictContent(name string) ([]byte, bool) {
+	c.customDictLock.RLock()
+	defer c.customDictLock.RUnlock()
+	content, ok := c.customDictContents[name]
+	return content, ok
+}
@@ -0,0 +1,66 @@
+// SPDX-FileCopyrightText: 2026 Free Mobile
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package clickhouse
+
+import (
+	"testing"
+
+	"akvorado/common/daemon"
+	"akvorado/common/helpers"
+	"akvorado/common/httpserver"
+	"akvorado/common/remotedatasource"
+	"akvorado/common/reporter"
+	"akvorado/common/schema"
+)
+
+func TestCustomDictCSV(t *testing.T) {
+	r := reporter.NewMock(t)
+	config := DefaultConfiguration()
+	config.SkipMigrations = true
+	schemaConfig := schema.DefaultConfiguration()
+	schemaConfig.CustomDictionaries = map[string]schema.CustomDict{
+		"test": {
+			Keys: []schema.CustomDictKey{
+				{Name: "agent", Type: "String"},
+				{Name: "index", Type: "UInt32"},
+			},
+			Attributes: []schema.CustomDictAttribute{
+				{Name: "role", Type: "String"},
+				{Name: "speed", Type: "UInt64"},
+			},
+			RemoteSource: &remotedatasource.Source{},
+		},
+	}
+	sch, err := schema.New(schemaConfig)
+	if err != nil {
+		t.Fatalf("schema.New() error:\n%+v", err)
+	}
+	c, err := New(r, config, Dependencies{
+		Daemon: daemon.NewMock(t),
+		HTTP:   httpserver.NewMock(t, r),
+		Schema: sch,
+	})
+	if err != nil {
+		t.Fatalf("New() error:\n%+v", err)
+	}
+
+	got, err := c.customDictCSV("test", []customDictRow{
+		{Values: map[string]any{"agent": "192.0.2.1", "index": 10.0, "role": "peering", "speed": 100000000000.0}},
+		{Values: map[string]any{"agent": "192.0.2.1", "index": 11.0, "speed": nil, "extra": "ignored"}},
+	})
+	if err != nil {
+		t.Fatalf("customDictCSV() error:\n%+v", err)
+	}
+	expected := "agent,index,role,speed\n192.0.2.1,10,peering,100000000000\n192.0.2.1,11,,\n"
+	if diff := helpers.Diff(string(got), expected); diff != "" {
+		t.Fatalf("customDictCSV() (-got, +want):\n%s", diff)
+	}
+
+	_, err = c.customDictCSV("test", []customDictRow{
+		{Values: map[string]any{"agent": "192.0.2.1", "role": "peering"}},
+	})
+	if err != errCustomDictMissingKey {
+		t.Fatalf("customDictCSV() error:\n%+v", err)
+	}
+}
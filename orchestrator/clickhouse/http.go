@@ -31,10 +31,23 @@ func (c *Component) registerHTTPHandlers() error {
 	// Add handler for custom dicts
 	for name, dict := range c.d.Schema.GetCustomDictConfig() {
 		c.d.HTTP.AddHandler(fmt.Sprintf("/api/v0/orchestrator/clickhouse/custom_dict_%s.csv", name), http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
-			file, err := os.ReadFile(dict.Source)
-			if err != nil {
-				c.r.Err(err).Msg("unable to deliver custom dict csv file")
-				http.Error(w, fmt.Sprintf("unable to deliver custom dict csv file %s", dict.Source), http.StatusNotFound)
+			var file []byte
+			if dict.RemoteSource != nil {
+				// Serve the last content fetched from the remote source
+				var ok bool
+				file, ok = c.customDictContent(name)
+				if !ok {
+					http.Error(w, fmt.Sprintf("custom dict %s not ready", name), http.StatusServiceUnavailable)
+					return
+				}
+			} else {
+				var err error
+				file, err = os.ReadFile(dict.Source)
+				if err != nil {
+					c.r.Err(err).Msg("unable to deliver custom dict csv file")
+					http.Error(w, fmt.Sprintf("unable to deliver custom dict csv file %s", dict.Source), http.StatusNotFound)
+					return
+				}
 			}
 			w.Header().Set("Content-Type", "text/csv; charset=utf-8")
 			w.WriteHeader(http.StatusOK)
@@ -42,6 +55,21 @@ func (c *Component) registerHTTPHandlers() error {
 		}))
 	}
 
+	// networks.csv
+	c.d.HTTP.AddHandler("/api/v0/orchestrator/clickhouse/networks.csv",
+		http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+			file, err := c.networksCSV()
+			if err != nil {
+				c.r.Err(err).Msg("unable to build networks csv file")
+				http.Error(w, "Unable to build networks file.",
+					http.StatusInternalServerError)
+				return
+			}
+			w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+			w.WriteHeader(http.StatusOK)
+			w.Write(file)
+		}))
+
 	// asns.csv (when there are some custom-defined ASNs)
 	if len(c.config.ASNs) != 0 {
 		c.d.HTTP.AddHandler("/api/v0/orchestrator/clickhouse/asns.csv",
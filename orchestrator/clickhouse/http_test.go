@@ -4,13 +4,18 @@
 package clickhouse
 
 import (
+	"net/http"
+	"net/http/httptest"
 	"testing"
+	"time"
 
 	"akvorado/common/daemon"
 	"akvorado/common/helpers"
 	"akvorado/common/httpserver"
+	"akvorado/common/remotedatasource"
 	"akvorado/common/reporter"
 	"akvorado/common/schema"
+	"akvorado/outlet/networks"
 )
 
 func TestHTTPEndpoints(t *testing.T) {
@@ -26,6 +31,47 @@ func TestHTTPEndpoints(t *testing.T) {
 	schemaConfig.CustomDictionaries["none"] = schema.CustomDict{
 		Source: "none.csv",
 	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/customers" {
+			http.Error(w, "not found", http.StatusNotFound)
+			return
+		}
+		w.Header().Add("Content-Type", "application/json")
+		w.Write([]byte(`{"customers": [
+  {"prefix": "192.0.2.0/24", "name": "Customer 1", "asn": 64501},
+  {"prefix": "198.51.100.0/24", "name": "Customer, 2"},
+  {"prefix": "203.0.113.0/24", "name": "Customer 3", "asn": 4200000001}
+]}`))
+	}))
+	defer server.Close()
+	remoteSource := func(path string) *remotedatasource.Source {
+		return &remotedatasource.Source{
+			URL:       server.URL + path,
+			Method:    "GET",
+			Timeout:   time.Second,
+			Interval:  time.Minute,
+			Transform: remotedatasource.MustParseTransformQuery(".customers[] | {prefix, role: .name, asn}"),
+		}
+	}
+	schemaConfig.CustomDictionaries["remote"] = schema.CustomDict{
+		Keys:         []schema.CustomDictKey{{Name: "prefix", Type: "String"}},
+		Attributes:   []schema.CustomDictAttribute{{Name: "role", Type: "String"}, {Name: "asn", Type: "UInt32"}},
+		RemoteSource: remoteSource("/customers"),
+	}
+	schemaConfig.CustomDictionaries["unavailable"] = schema.CustomDict{
+		Keys:         []schema.CustomDictKey{{Name: "prefix", Type: "String"}},
+		Attributes:   []schema.CustomDictAttribute{{Name: "role", Type: "String"}},
+		RemoteSource: remoteSource("/nothing"),
+	}
+
+	config.Networks.Networks = helpers.MustNewSubnetMap(map[string]networks.NetworkAttributes{
+		"192.0.2.0/24":      {Name: "customers"},
+		"198.51.100.128/25": {Name: "servers"},
+		"2001:db8::/32":     {Name: "ipv6"},
+	})
+	config.Networks.NetworkSources = map[string]remotedatasource.Source{
+		"customers": *remoteSource("/customers"),
+	}
 
 	sch, err := schema.New(schemaConfig)
 	if err != nil {
@@ -42,6 +88,22 @@ func TestHTTPEndpoints(t *testing.T) {
 		t.Fatalf("New() error:\n%+v", err)
 	}
 	helpers.StartStop(t, c)
+	// Wait for the remote source to be fetched
+	for range 100 {
+		if _, ok := c.customDictContent("remote"); ok {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	for range 100 {
+		c.networkSourcesLock.RLock()
+		_, ok := c.networkSources["customers"]
+		c.networkSourcesLock.RUnlock()
+		if ok {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
 
 	cases := helpers.HTTPEndpointCases{
 		{
@@ -59,6 +121,17 @@ func TestHTTPEndpoints(t *testing.T) {
 				`"asn","name"`,
 				`1,"Level 3 Communications"`,
 			},
+		}, {
+			URL:         "/api/v0/orchestrator/clickhouse/networks.csv",
+			ContentType: "text/csv; charset=utf-8",
+			FirstLines: []string{
+				`network,prefix`,
+				`192.0.2.0/24,192.0.2.0/24`,
+				`198.51.100.0/24,198.51.100.0/24`,
+				`198.51.100.128/25,198.51.100.128/25`,
+				`203.0.113.0/24,203.0.113.0/24`,
+				`2001:db8::/32,2001:db8::/32`,
+			},
 		}, {
 			URL:         "/api/v0/orchestrator/clickhouse/custom_dict_none.csv",
 			ContentType: "text/plain; charset=utf-8",
@@ -73,6 +146,22 @@ func TestHTTPEndpoints(t *testing.T) {
 				`col_a,col_b`,
 				`1,2`,
 			},
+		}, {
+			URL:         "/api/v0/orchestrator/clickhouse/custom_dict_remote.csv",
+			ContentType: "text/csv; charset=utf-8",
+			FirstLines: []string{
+				`prefix,role,asn`,
+				`192.0.2.0/24,Customer 1,64501`,
+				`198.51.100.0/24,"Customer, 2",`,
+				`203.0.113.0/24,Customer 3,4200000001`,
+			},
+		}, {
+			URL:         "/api/v0/orchestrator/clickhouse/custom_dict_unavailable.csv",
+			ContentType: "text/plain; charset=utf-8",
+			StatusCode:  503,
+			FirstLines: []string{
+				"custom dict unavailable not ready",
+			},
 		},
 	}
 
@@ -9,6 +9,13 @@ type metrics struct {
 	migrationsRunning    reporter.Gauge
 	migrationsApplied    reporter.Counter
 	migrationsNotApplied reporter.Counter
+
+	archivedHours reporter.Counter
+	archivedRows  reporter.Counter
+	archiveErrors reporter.Counter
+
+	anomaliesChecks reporter.Counter
+	anomaliesErrors reporter.Counter
 }
 
 func (c *Component) initMetrics() {
@@ -30,4 +37,34 @@ func (c *Component) initMetrics() {
 			Help: "Number of migration steps not applied.",
 		},
 	)
+	c.metrics.archivedHours = c.r.Counter(
+		reporter.CounterOpts{
+			Name: "archived_hours_total",
+			Help: "Number of hours of flows archived.",
+		},
+	)
+	c.metrics.archivedRows = c.r.Counter(
+		reporter.CounterOpts{
+			Name: "archived_flows_total",
+			Help: "Number of flows archived.",
+		},
+	)
+	c.metrics.archiveErrors = c.r.Counter(
+		reporter.CounterOpts{
+			Name: "archive_errors_total",
+			Help: "Number of errors while archiving flows.",
+		},
+	)
+	c.metrics.anomaliesChecks = c.r.Counter(
+		reporter.CounterOpts{
+			Name: "anomalies_checked_intervals_total",
+			Help: "Number of intervals checked for anomalies.",
+		},
+	)
+	c.metrics.anomaliesErrors = c.r.Counter(
+		reporter.CounterOpts{
+			Name: "anomalies_errors_total",
+			Help: "Number of errors while detecting anomalies.",
+		},
+	)
 }
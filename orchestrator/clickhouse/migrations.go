@@ -94,6 +94,12 @@ func (c *Component) migrateDatabase() error {
 					sb.Attribute("port", "UInt16").Injective(),
 					sb.Attribute("name", "String"),
 				}, sb.Columns("port"))
+		}, func(ctx context.Context) error {
+			return c.createDictionary(ctx, schema.DictionaryNetworks, "ip_trie",
+				[]sb.DictionaryAttribute{
+					sb.Attribute("network", "String"),
+					sb.Attribute("prefix", "String"),
+				}, sb.Columns("network"))
 		})
 	if err != nil {
 		return err
@@ -162,6 +168,20 @@ func (c *Component) migrateDatabase() error {
 	if err != nil {
 		return err
 	}
+	if c.config.Archive.Enabled() {
+		if err := c.wrapMigrations(ctx, c.createArchivesTable); err != nil {
+			return err
+		}
+	}
+	if c.config.Anomalies.Enabled() {
+		if err := c.wrapMigrations(ctx,
+			c.createAnomaliesTable,
+			func(ctx context.Context) error {
+				return c.createDistributedTable(ctx, anomaliesTable)
+			}); err != nil {
+			return err
+		}
+	}
 
 	close(c.migrationsDone)
 	c.metrics.migrationsRunning.Set(0)
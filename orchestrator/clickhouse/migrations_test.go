@@ -224,11 +224,6 @@ func isOldTable(schema *schema.Component, table string) bool {
 	if strings.Contains(table, schema.ClickHouseHash()) {
 		return false
 	}
-	// The networks dictionary was used by previous versions. Migrations do not
-	// drop it, this is left to the user.
-	if table == "networks" {
-		return true
-	}
 	oldSuffixes := []string{
 		"_raw",
 		"_raw_consumer",
@@ -391,6 +386,7 @@ WHERE database=currentDatabase() AND table NOT LIKE '.%'`)
 				fmt.Sprintf("flows_%s_raw_consumer", hash),
 				"flows_local",
 				schema.DictionaryICMP,
+				schema.DictionaryNetworks,
 				schema.DictionaryProtocols,
 				schema.DictionaryTCP,
 				schema.DictionaryUDP,
@@ -0,0 +1,92 @@
+// SPDX-FileCopyrightText: 2026 Free Mobile
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package clickhouse
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"net/netip"
+	"slices"
+
+	"akvorado/common/helpers"
+	"akvorado/common/remotedatasource"
+	"akvorado/common/schema"
+)
+
+// networkPrefix is a prefix fetched from a remote network source. The networks
+// dictionary only tells which network an address belongs to, therefore the
+// attributes are ignored.
+type networkPrefix struct {
+	Prefix netip.Prefix
+}
+
+// UpdateNetworkSource updates the prefixes of a remote network source. It
+// returns the number of prefixes retrieved.
+func (c *Component) UpdateNetworkSource(ctx context.Context, name string, source remotedatasource.Source) (int, error) {
+	results, err := c.networkSourcesFetcher.Fetch(ctx, name, source)
+	if err != nil {
+		return 0, err
+	}
+	prefixes := make([]netip.Prefix, 0, len(results))
+	for _, result := range results {
+		prefixes = append(prefixes, result.Prefix)
+	}
+	c.updateNetworkSource(ctx, name, prefixes)
+	return len(results), nil
+}
+
+// updateNetworkSource replaces the prefixes of a remote network source. When
+// they change, the networks dictionary is reloaded. Until the migrations are
+// done, this is left to the migrations.
+func (c *Component) updateNetworkSource(ctx context.Context, name string, prefixes []netip.Prefix) {
+	c.networkSourcesLock.Lock()
+	unchanged := slices.Equal(c.networkSources[name], prefixes)
+	c.networkSources[name] = prefixes
+	c.networkSourcesLock.Unlock()
+	if unchanged {
+		return
+	}
+	select {
+	case <-c.migrationsDone:
+	default:
+		return
+	}
+	if err := c.ReloadDictionary(ctx, schema.DictionaryNetworks); err != nil {
+		c.r.Err(err).Str("name", name).Msg("unable to reload networks dictionary")
+	}
+}
+
+// networksCSV returns the content of the networks dictionary: the static
+// networks and the ones from the remote sources. Each network is keyed by
+// itself, so a lookup returns the most specific network containing an address.
+func (c *Component) networksCSV() ([]byte, error) {
+	prefixes := []netip.Prefix{}
+	if c.config.Networks.Networks != nil {
+		for prefix := range c.config.Networks.Networks.All() {
+			prefixes = append(prefixes, prefix)
+		}
+	}
+	c.networkSourcesLock.RLock()
+	for _, sourcePrefixes := range c.networkSources {
+		prefixes = append(prefixes, sourcePrefixes...)
+	}
+	c.networkSourcesLock.RUnlock()
+	for idx := range prefixes {
+		prefixes[idx] = helpers.UnmapPrefix(prefixes[idx].Masked())
+	}
+	slices.SortFunc(prefixes, func(a, b netip.Prefix) int {
+		return a.Compare(b)
+	})
+	prefixes = slices.Compact(prefixes)
+
+	var buf bytes.Buffer
+	wr := csv.NewWriter(&buf)
+	wr.Write([]string{"network", "prefix"})
+	for _, prefix := range prefixes {
+		wr.Write([]string{prefix.String(), prefix.String()})
+	}
+	wr.Flush()
+	return buf.Bytes(), wr.Error()
+}
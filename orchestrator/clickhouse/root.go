@@ -6,7 +6,10 @@ package clickhouse
 
 import (
 	"errors"
+	"fmt"
+	"net/netip"
 	"sort"
+	"sync"
 	"time"
 
 	"github.com/cenkalti/backoff/v7"
@@ -15,6 +18,7 @@ import (
 	"akvorado/common/clickhousedb"
 	"akvorado/common/daemon"
 	"akvorado/common/httpserver"
+	"akvorado/common/remotedatasource"
 	"akvorado/common/reporter"
 	"akvorado/common/schema"
 )
@@ -29,6 +33,14 @@ type Component struct {
 
 	shards int // number of shards if in a cluster
 
+	customDictFetcher  *remotedatasource.Component[customDictRow]
+	customDictContents map[string][]byte // last content of remote custom dicts
+	customDictLock     sync.RWMutex
+
+	networkSourcesFetcher *remotedatasource.Component[networkPrefix]
+	networkSources        map[string][]netip.Prefix // last prefixes of network sources
+	networkSourcesLock    sync.RWMutex
+
 	migrationsDone chan bool // closed when migrations are done
 	migrationsOnce chan bool // closed after first attempt to migrate
 }
@@ -49,9 +61,24 @@ func New(r *reporter.Reporter, configuration Configuration, dependencies Depende
 		config:         configuration,
 		migrationsDone: make(chan bool),
 		migrationsOnce: make(chan bool),
+
+		customDictContents: make(map[string][]byte),
+		networkSources:     make(map[string][]netip.Prefix),
 	}
 	c.initMetrics()
 
+	var err error
+	c.customDictFetcher, err = remotedatasource.New[customDictRow](
+		r, c.UpdateCustomDict, "custom_dict", c.remoteCustomDictSources())
+	if err != nil {
+		return nil, fmt.Errorf("unable to initialize remote data source fetcher component: %w", err)
+	}
+	c.networkSourcesFetcher, err = remotedatasource.New[networkPrefix](
+		r, c.UpdateNetworkSource, "network_source", c.config.Networks.NetworkSources)
+	if err != nil {
+		return nil, fmt.Errorf("unable to initialize remote data source fetcher component: %w", err)
+	}
+
 	if err := c.registerHTTPHandlers(); err != nil {
 		return nil, err
 	}
@@ -63,6 +90,12 @@ func New(r *reporter.Reporter, configuration Configuration, dependencies Depende
 	if len(c.config.Resolutions) == 0 || c.config.Resolutions[0].Interval != 0 {
 		return nil, errors.New("resolutions need to be configured, including interval: 0")
 	}
+	if err := c.checkArchiveConfiguration(); err != nil {
+		return nil, err
+	}
+	if err := c.checkAnomaliesConfiguration(); err != nil {
+		return nil, err
+	}
 
 	c.d.Daemon.Track(&c.t, "orchestrator/clickhouse")
 
@@ -108,6 +141,23 @@ func (c *Component) Start() error {
 				}
 			}
 		})
+
+		// Archiving
+		if c.config.Archive.Enabled() {
+			c.t.Go(c.archiveLoop)
+		}
+
+		// Anomaly detection
+		if c.config.Anomalies.Enabled() {
+			c.t.Go(c.anomaliesLoop)
+		}
+	}
+
+	if err := c.customDictFetcher.Start(); err != nil {
+		return fmt.Errorf("unable to start custom dictionaries fetcher component: %w", err)
+	}
+	if err := c.networkSourcesFetcher.Start(); err != nil {
+		return fmt.Errorf("unable to start network sources fetcher component: %w", err)
 	}
 
 	c.r.Info().Msg("ClickHouse component started")
@@ -119,5 +169,7 @@ func (c *Component) Stop() error {
 	c.r.Info().Msg("stopping ClickHouse component")
 	defer c.r.Info().Msg("ClickHouse component stopped")
 	c.t.Kill(nil)
+	c.customDictFetcher.Stop()
+	c.networkSourcesFetcher.Stop()
 	return c.t.Wait()
 }
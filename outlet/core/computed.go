@@ -0,0 +1,229 @@
+// SPDX-FileCopyrightText: 2026 Free Mobile
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package core
+
+import (
+	"fmt"
+	"net/netip"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"akvorado/common/schema"
+	"akvorado/outlet/networks"
+
+	"github.com/expr-lang/expr"
+	"github.com/expr-lang/expr/vm"
+)
+
+// ComputedColumnConfiguration tells how to populate a computed column declared
+// in the schema.
+type ComputedColumnConfiguration struct {
+	// Column is the name of the computed column
+	Column string `validate:"required"`
+	// Rule is an expression returning the value of the column
+	Rule ComputedColumnRule
+}
+
+// ComputedColumnRule defines a rule returning the value of a computed column.
+// When it returns nil, the column is left empty.
+type ComputedColumnRule struct {
+	program *vm.Program
+}
+
+// computedColumnEndpoint contains the information about one end of the flow
+// exposed to a computed column rule.
+type computedColumnEndpoint struct {
+	Addr      string
+	Port      uint16
+	AS        uint32
+	NetName   string
+	NetRole   string
+	NetSite   string
+	NetRegion string
+	NetTenant string
+	Country   string
+}
+
+// computedColumnEnvironment defines the environment used by a computed column
+// rule. The exporter and the interfaces are exposed like for observation point
+// rules.
+type computedColumnEnvironment struct {
+	Exporter     observationPointExporter
+	InIf         observationPointInterface
+	OutIf        observationPointInterface
+	Src          computedColumnEndpoint
+	Dst          computedColumnEndpoint
+	EType        uint32
+	Proto        uint8
+	SamplingRate uint64
+	Bytes        uint64
+	Packets      uint64
+}
+
+// reverse returns the environment seen from the other direction. It is used
+// to compute the Dst or OutIf column of a pair.
+func (env computedColumnEnvironment) reverse() computedColumnEnvironment {
+	env.Src, env.Dst = env.Dst, env.Src
+	env.InIf, env.OutIf = env.OutIf, env.InIf
+	return env
+}
+
+// exec executes the computed column rule with the provided environment.
+func (ccr *ComputedColumnRule) exec(env computedColumnEnvironment) (any, error) {
+	result, err := expr.Run(ccr.program, env)
+	if err != nil {
+		return nil, fmt.Errorf("unable to execute computed column rule %q: %w", ccr, err)
+	}
+	return result, nil
+}
+
+// UnmarshalText compiles a computed column rule.
+func (ccr *ComputedColumnRule) UnmarshalText(text []byte) error {
+	program, err := expr.Compile(string(text),
+		expr.Env(computedColumnEnvironment{}),
+		expr.Function(
+			"Format",
+			func(params ...any) (any, error) {
+				return fmt.Sprintf(params[0].(string), params[1:]...), nil
+			},
+			new(func(string, ...any) string),
+		))
+	if err != nil {
+		return fmt.Errorf("cannot compile computed column rule %q: %w", string(text), err)
+	}
+	ccr.program = program
+	return nil
+}
+
+// String turns a computed column rule into a string
+func (ccr ComputedColumnRule) String() string {
+	if ccr.program == nil {
+		return ""
+	}
+	return ccr.program.Source().String()
+}
+
+// MarshalText turns a computed column rule into a string
+func (ccr ComputedColumnRule) MarshalText() ([]byte, error) {
+	return []byte(ccr.String()), nil
+}
+
+// computedColumn is a computed column with its rule, ready to be used.
+type computedColumn struct {
+	rule ComputedColumnRule
+	// key is the column to populate, while reverseKey is the other column of
+	// the pair (Dst or OutIf), if any.
+	key        schema.ColumnKey
+	reverseKey schema.ColumnKey
+	isString   bool
+}
+
+// newComputedColumns checks the computed column rules against the schema.
+func newComputedColumns(configuration []ComputedColumnConfiguration, sch *schema.Component) ([]computedColumn, error) {
+	computed := map[string]schema.ComputedColumn{}
+	for _, cc := range sch.GetComputedColumns() {
+		computed[cc.Name] = cc
+	}
+	result := make([]computedColumn, 0, len(configuration))
+	for _, cc := range configuration {
+		definition, ok := computed[cc.Column]
+		if !ok {
+			return nil, fmt.Errorf("unknown computed column %q", cc.Column)
+		}
+		if cc.Rule.program == nil {
+			return nil, fmt.Errorf("computed column %q requires a rule", cc.Column)
+		}
+		column, ok := sch.LookupColumnByName(cc.Column)
+		if !ok || column.Disabled {
+			return nil, fmt.Errorf("computed column %q is disabled", cc.Column)
+		}
+		c := computedColumn{
+			rule:     cc.Rule,
+			key:      column.Key,
+			isString: definition.Type == "String",
+		}
+		var reverseName string
+		if strings.HasPrefix(cc.Column, "Src") {
+			reverseName = "Dst" + cc.Column[3:]
+		} else if strings.HasPrefix(cc.Column, "InIf") {
+			reverseName = "OutIf" + cc.Column[4:]
+		}
+		if reverseName != "" {
+			if column, ok := sch.LookupColumnByName(reverseName); ok && !column.Disabled {
+				c.reverseKey = column.Key
+			}
+		}
+		result = append(result, c)
+	}
+	return result, nil
+}
+
+// newComputedColumnEndpoint builds the information about one end of a flow.
+func newComputedColumnEndpoint(flow *schema.FlowMessage, addr netip.Addr, portKey schema.ColumnKey, asn uint32, net networks.NetworkAttributes) computedColumnEndpoint {
+	endpoint := computedColumnEndpoint{
+		Port:      uint16(flow.GetUint(portKey)),
+		AS:        asn,
+		NetName:   net.Name,
+		NetRole:   net.Role,
+		NetSite:   net.Site,
+		NetRegion: net.Region,
+		NetTenant: net.Tenant,
+		Country:   net.Country,
+	}
+	if addr.IsValid() {
+		endpoint.Addr = addr.Unmap().String()
+	}
+	return endpoint
+}
+
+// computeColumns executes the computed column rules and populates the
+// associated columns. On error, the column is left empty.
+func (c *Component) computeColumns(flow *schema.FlowMessage, env computedColumnEnvironment) {
+	for idx, cc := range c.computedColumns {
+		c.computeColumn(flow, idx, cc, cc.key, env)
+		if cc.reverseKey != 0 {
+			c.computeColumn(flow, idx, cc, cc.reverseKey, env.reverse())
+		}
+	}
+}
+
+func (c *Component) computeColumn(flow *schema.FlowMessage, idx int, cc computedColumn, key schema.ColumnKey, env computedColumnEnvironment) {
+	result, err := cc.rule.exec(env)
+	if err == nil {
+		err = cc.append(flow, key, result)
+	}
+	if err != nil {
+		c.classifierErrLogger.Err(err).
+			Str("column", key.String()).
+			Str("exporter", env.Exporter.Name).
+			Msg("error executing computed column rule")
+		c.metrics.classifierErrors.WithLabelValues("computed-column", strconv.Itoa(idx)).Inc()
+	}
+}
+
+// append adds the result of a rule to the provided column.
+func (cc computedColumn) append(flow *schema.FlowMessage, key schema.ColumnKey, result any) error {
+	if result == nil {
+		return nil
+	}
+	if cc.isString {
+		value, ok := result.(string)
+		if !ok {
+			return fmt.Errorf("computed column %s expects a string, got %v", key, result)
+		}
+		flow.AppendString(key, value)
+		return nil
+	}
+	value := reflect.ValueOf(result)
+	switch {
+	case value.CanUint():
+		flow.AppendUint(key, value.Uint())
+	case value.CanInt() && value.Int() >= 0:
+		flow.AppendUint(key, uint64(value.Int()))
+	default:
+		return fmt.Errorf("computed column %s expects an unsigned integer, got %v", key, result)
+	}
+	return nil
+}
@@ -0,0 +1,205 @@
+// SPDX-FileCopyrightText: 2026 Free Mobile
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package core
+
+import (
+	"testing"
+
+	"akvorado/common/helpers"
+	"akvorado/common/schema"
+)
+
+func TestComputedColumnRule(t *testing.T) {
+	cases := []struct {
+		Description string
+		Program     string
+		Environment computedColumnEnvironment
+		Expected    any
+		ExpectedErr bool
+	}{
+		{
+			Description: "constant",
+			Program:     `"web"`,
+			Expected:    "web",
+		}, {
+			Description: "ports and network roles",
+			Program:     `Dst.Port in [80, 443] && Dst.NetRole == "servers" ? "web" : nil`,
+			Environment: computedColumnEnvironment{
+				Dst: computedColumnEndpoint{Port: 443, NetRole: "servers"},
+			},
+			Expected: "web",
+		}, {
+			Description: "no match",
+			Program:     `Dst.Port in [80, 443] && Dst.NetRole == "servers" ? "web" : nil`,
+			Environment: computedColumnEnvironment{
+				Dst: computedColumnEndpoint{Port: 22, NetRole: "servers"},
+			},
+			Expected: nil,
+		}, {
+			Description: "format",
+			Program:     `Format("%s-%s", Exporter.Site, InIf.Provider)`,
+			Environment: computedColumnEnvironment{
+				Exporter: observationPointExporter{Site: "par1"},
+				InIf:     observationPointInterface{Provider: "cogent"},
+			},
+			Expected: "par1-cogent",
+		}, {
+			Description: "unknown field",
+			Program:     `Src.Nothing`,
+			ExpectedErr: true,
+		}, {
+			Description: "syntax error",
+			Program:     `Src.Port ==`,
+			ExpectedErr: true,
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.Description, func(t *testing.T) {
+			var rule ComputedColumnRule
+			err := rule.UnmarshalText([]byte(tc.Program))
+			if err != nil && !tc.ExpectedErr {
+				t.Fatalf("UnmarshalText(%q) error:\n%+v", tc.Program, err)
+			} else if err == nil && tc.ExpectedErr {
+				t.Fatalf("UnmarshalText(%q) did not error", tc.Program)
+			}
+			if err != nil {
+				return
+			}
+			got, err := rule.exec(tc.Environment)
+			if err != nil {
+				t.Fatalf("exec(%q) error:\n%+v", tc.Program, err)
+			}
+			if diff := helpers.Diff(got, tc.Expected); diff != "" {
+				t.Fatalf("exec(%q) (-got, +want):\n%s", tc.Program, diff)
+			}
+		})
+	}
+}
+
+func TestComputedColumnEnvironmentReverse(t *testing.T) {
+	env := computedColumnEnvironment{
+		InIf:  observationPointInterface{Name: "in"},
+		OutIf: observationPointInterface{Name: "out"},
+		Src:   computedColumnEndpoint{Port: 1},
+		Dst:   computedColumnEndpoint{Port: 2},
+		Proto: 6,
+	}
+	expected := computedColumnEnvironment{
+		InIf:  observationPointInterface{Name: "out"},
+		OutIf: observationPointInterface{Name: "in"},
+		Src:   computedColumnEndpoint{Port: 2},
+		Dst:   computedColumnEndpoint{Port: 1},
+		Proto: 6,
+	}
+	if diff := helpers.Diff(env.reverse(), expected); diff != "" {
+		t.Fatalf("reverse() (-got, +want):\n%s", diff)
+	}
+}
+
+func TestNewComputedColumns(t *testing.T) {
+	schemaConfiguration := schema.DefaultConfiguration()
+	schemaConfiguration.ComputedColumns = []schema.ComputedColumn{
+		{Name: "ServiceName", Type: "String"},
+		{Name: "SrcTier", Type: "UInt8"},
+	}
+	sch, err := schema.New(schemaConfiguration)
+	if err != nil {
+		t.Fatalf("schema.New() error:\n%+v", err)
+	}
+	rule := func(program string) ComputedColumnRule {
+		var rule ComputedColumnRule
+		if err := rule.UnmarshalText([]byte(program)); err != nil {
+			t.Fatalf("UnmarshalText(%q) error:\n%+v", program, err)
+		}
+		return rule
+	}
+	serviceName, _ := sch.LookupColumnByName("ServiceName")
+	srcTier, _ := sch.LookupColumnByName("SrcTier")
+	dstTier, _ := sch.LookupColumnByName("DstTier")
+
+	got, err := newComputedColumns([]ComputedColumnConfiguration{
+		{Column: "ServiceName", Rule: rule(`"web"`)},
+		{Column: "SrcTier", Rule: rule(`1`)},
+	}, sch)
+	if err != nil {
+		t.Fatalf("newComputedColumns() error:\n%+v", err)
+	}
+	gotKeys := [][2]schema.ColumnKey{}
+	for _, cc := range got {
+		gotKeys = append(gotKeys, [2]schema.ColumnKey{cc.key, cc.reverseKey})
+	}
+	expectedKeys := [][2]schema.ColumnKey{
+		{serviceName.Key, 0},
+		{srcTier.Key, dstTier.Key},
+	}
+	if diff := helpers.Diff(gotKeys, expectedKeys); diff != "" {
+		t.Fatalf("newComputedColumns() (-got, +want):\n%s", diff)
+	}
+
+	for _, tc := range []struct {
+		Configuration ComputedColumnConfiguration
+		ExpectedErr   string
+	}{
+		{
+			Configuration: ComputedColumnConfiguration{Column: "SrcNetName", Rule: rule(`"web"`)},
+			ExpectedErr:   `unknown computed column "SrcNetName"`,
+		}, {
+			Configuration: ComputedColumnConfiguration{Column: "DstTier", Rule: rule(`1`)},
+			ExpectedErr:   `unknown computed column "DstTier"`,
+		}, {
+			Configuration: ComputedColumnConfiguration{Column: "ServiceName"},
+			ExpectedErr:   `computed column "ServiceName" requires a rule`,
+		},
+	} {
+		_, err := newComputedColumns([]ComputedColumnConfiguration{tc.Configuration}, sch)
+		if err == nil {
+			t.Errorf("newComputedColumns(%q) did not error", tc.Configuration.Column)
+		} else if diff := helpers.Diff(err.Error(), tc.ExpectedErr); diff != "" {
+			t.Errorf("newComputedColumns(%q) error (-got, +want):\n%s", tc.Configuration.Column, diff)
+		}
+	}
+}
+
+func TestComputedColumnAppend(t *testing.T) {
+	schemaConfiguration := schema.DefaultConfiguration()
+	schemaConfiguration.ComputedColumns = []schema.ComputedColumn{
+		{Name: "ServiceName", Type: "String"},
+		{Name: "Tier", Type: "UInt16"},
+	}
+	sch, err := schema.New(schemaConfiguration)
+	if err != nil {
+		t.Fatalf("schema.New() error:\n%+v", err)
+	}
+	serviceName, _ := sch.LookupColumnByName("ServiceName")
+	tier, _ := sch.LookupColumnByName("Tier")
+	stringColumn := computedColumn{key: serviceName.Key, isString: true}
+	uintColumn := computedColumn{key: tier.Key}
+
+	cases := []struct {
+		Column      computedColumn
+		Result      any
+		Expected    map[schema.ColumnKey]any
+		ExpectedErr bool
+	}{
+		{stringColumn, "web", map[schema.ColumnKey]any{serviceName.Key: "web"}, false},
+		{stringColumn, nil, nil, false},
+		{stringColumn, 12, nil, true},
+		{uintColumn, 12, map[schema.ColumnKey]any{tier.Key: uint16(12)}, false},
+		{uintColumn, uint32(13), map[schema.ColumnKey]any{tier.Key: uint16(13)}, false},
+		{uintColumn, -1, nil, true},
+		{uintColumn, "web", nil, true},
+	}
+	for _, tc := range cases {
+		bf := sch.NewFlowMessage()
+		err := tc.Column.append(bf, tc.Column.key, tc.Result)
+		if err != nil && !tc.ExpectedErr {
+			t.Errorf("append(%v) error:\n%+v", tc.Result, err)
+		} else if err == nil && tc.ExpectedErr {
+			t.Errorf("append(%v) did not error", tc.Result)
+		}
+		if diff := helpers.Diff(bf.OtherColumns, tc.Expected); diff != "" {
+			t.Errorf("append(%v) (-got, +want):\n%s", tc.Result, diff)
+		}
+	}
+}
@@ -32,6 +32,20 @@ type Configuration struct {
 	NetProviders []NetProvider `validate:"dive"`
 	// StartupDelay defines how long to wait at start before processing flows.
 	StartupDelay time.Duration `validate:"eq=0|min=1s"`
+	// Deduplication defines how to handle flows seen by several exporters
+	Deduplication DeduplicationConfiguration
+	// ComputedColumns defines rules to populate the computed columns of the schema
+	ComputedColumns []ComputedColumnConfiguration `validate:"dive"`
+}
+
+// DeduplicationConfiguration describes how to handle traffic crossing several
+// exporters. Only flows matching the observation point are kept as is. The
+// other ones are either dropped or marked as duplicates.
+type DeduplicationConfiguration struct {
+	// Mode tells what to do with duplicate flows
+	Mode DeduplicationMode
+	// ObservationPoint is a rule telling if a flow is at the observation point
+	ObservationPoint ObservationPointRule
 }
 
 // DefaultConfiguration represents the default configuration for the core component.
@@ -42,6 +56,10 @@ func DefaultConfiguration() Configuration {
 		ClassifierCacheDuration: 5 * time.Minute,
 		ASNProviders:            []ASNProvider{ASNProviderFlow, ASNProviderRouting, ASNProviderNetworks},
 		NetProviders:            []NetProvider{NetProviderFlow, NetProviderRouting},
+		Deduplication: DeduplicationConfiguration{
+			Mode: DeduplicationDisabled,
+		},
+		ComputedColumns: []ComputedColumnConfiguration{},
 	}
 }
 
@@ -50,6 +68,8 @@ type (
 	ASNProvider int
 	// NetProvider describes one network mask provider.
 	NetProvider int
+	// DeduplicationMode describes what to do with duplicate flows.
+	DeduplicationMode int
 )
 
 const (
@@ -75,6 +95,16 @@ const (
 	NetProviderRouting
 )
 
+const (
+	// DeduplicationDisabled does not look for duplicate flows.
+	DeduplicationDisabled DeduplicationMode = iota
+	// DeduplicationDrop drops flows outside the observation point.
+	DeduplicationDrop
+	// DeduplicationMark marks flows outside the observation point with the
+	// Duplicate column.
+	DeduplicationMark
+)
+
 // ASNProviderUnmarshallerHook normalize a net provider configuration:
 //   - map bmp to routing
 //   - map geo-ip to networks, as the GeoIP databases are merged into the networks
@@ -0,0 +1,133 @@
+// SPDX-FileCopyrightText: 2026 Free Mobile
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package core
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/expr-lang/expr"
+	"github.com/expr-lang/expr/vm"
+)
+
+// ObservationPointRule defines a rule telling if a flow was seen at the
+// observation point. As flows are sampled, the same packet cannot be matched
+// across exporters. Instead, the traffic is only counted where it is observed
+// once, like on external interfaces.
+type ObservationPointRule struct {
+	program *vm.Program
+}
+
+// observationPointExporter contains the information about the exporter exposed
+// to an observation point rule.
+type observationPointExporter struct {
+	IP     string
+	Name   string
+	Group  string
+	Role   string
+	Site   string
+	Region string
+	Tenant string
+}
+
+// observationPointInterface contains the information about an interface
+// exposed to an observation point rule.
+type observationPointInterface struct {
+	Name         string
+	Description  string
+	Provider     string
+	Connectivity string
+	Boundary     string
+}
+
+// observationPointEnvironment defines the environment used by an observation
+// point rule. It is also used as a cache key.
+type observationPointEnvironment struct {
+	Exporter     observationPointExporter
+	InIf         observationPointInterface
+	OutIf        observationPointInterface
+	SamplingRate uint64
+}
+
+// newObservationPointExporter builds the information about an exporter.
+func newObservationPointExporter(exporter exporterInfo, ec exporterClassification) observationPointExporter {
+	return observationPointExporter{
+		IP:     exporter.IP,
+		Name:   exporter.Name,
+		Group:  ec.Group,
+		Role:   ec.Role,
+		Site:   ec.Site,
+		Region: ec.Region,
+		Tenant: ec.Tenant,
+	}
+}
+
+// newObservationPointInterface builds the information about an interface.
+func newObservationPointInterface(ic interfaceClassification) observationPointInterface {
+	return observationPointInterface{
+		Name:         ic.Name,
+		Description:  ic.Description,
+		Provider:     ic.Provider,
+		Connectivity: ic.Connectivity,
+		Boundary:     ic.Boundary.String(),
+	}
+}
+
+// exec executes the observation point rule with the provided environment.
+func (opr *ObservationPointRule) exec(env observationPointEnvironment) (bool, error) {
+	result, err := expr.Run(opr.program, env)
+	if err != nil {
+		return false, fmt.Errorf("unable to execute observation point rule %q: %w", opr, err)
+	}
+	return result.(bool), nil
+}
+
+// UnmarshalText compiles an observation point rule.
+func (opr *ObservationPointRule) UnmarshalText(text []byte) error {
+	program, err := expr.Compile(string(text),
+		expr.Env(observationPointEnvironment{}),
+		expr.AsBool())
+	if err != nil {
+		return fmt.Errorf("cannot compile observation point rule %q: %w", string(text), err)
+	}
+	opr.program = program
+	return nil
+}
+
+// String turns an observation point rule into a string
+func (opr ObservationPointRule) String() string {
+	if opr.program == nil {
+		return ""
+	}
+	return opr.program.Source().String()
+}
+
+// MarshalText turns an observation point rule into a string
+func (opr ObservationPointRule) MarshalText() ([]byte, error) {
+	return []byte(opr.String()), nil
+}
+
+// isDuplicate tells if a flow is outside the observation point. On error, the
+// flow is not considered as a duplicate.
+func (c *Component) isDuplicate(t time.Time, exporter exporterInfo, ec exporterClassification, inIf, outIf interfaceClassification, samplingRate uint64) bool {
+	env := observationPointEnvironment{
+		Exporter:     newObservationPointExporter(exporter, ec),
+		InIf:         newObservationPointInterface(inIf),
+		OutIf:        newObservationPointInterface(outIf),
+		SamplingRate: samplingRate,
+	}
+	if duplicate, ok := c.observationPointCache.Get(t, env); ok {
+		return duplicate
+	}
+	observed, err := c.config.Deduplication.ObservationPoint.exec(env)
+	if err != nil {
+		c.classifierErrLogger.Err(err).
+			Str("exporter", exporter.Name).
+			Msg("error executing observation point rule")
+		c.metrics.classifierErrors.WithLabelValues("observation-point", "0").Inc()
+		return false
+	}
+	c.observationPointCache.Put(t, env, !observed)
+	return !observed
+}
@@ -105,7 +105,7 @@ func (w *worker) enrichFlow(exporterIP netip.Addr, exporterStr string) bool {
 	}
 
 	// Classification
-	if !c.classifyExporter(t, exporterStr, flowExporterName, flow, expClassification) ||
+	if !c.classifyExporter(t, exporterStr, flowExporterName, flow, &expClassification) ||
 		!c.classifyInterface(t, flow,
 			exporterInfo{IP: exporterStr, Name: flowExporterName},
 			interfaceInfo{
@@ -114,7 +114,7 @@ func (w *worker) enrichFlow(exporterIP netip.Addr, exporterStr string) bool {
 				Description: flowOutIfDescription,
 				Speed:       flowOutIfSpeed,
 				VLAN:        flowOutIfVlan,
-			}, outIfClassification,
+			}, &outIfClassification,
 			false) ||
 		!c.classifyInterface(t, flow,
 			exporterInfo{IP: exporterStr, Name: flowExporterName},
@@ -124,12 +124,23 @@ func (w *worker) enrichFlow(exporterIP netip.Addr, exporterStr string) bool {
 				Description: flowInIfDescription,
 				Speed:       flowInIfSpeed,
 				VLAN:        flowInIfVlan,
-			}, inIfClassification,
+			}, &inIfClassification,
 			true) {
 		// Flow is rejected
 		return true
 	}
 
+	// Deduplication
+	if c.config.Deduplication.Mode != DeduplicationDisabled &&
+		c.isDuplicate(t, exporterInfo{IP: exporterStr, Name: flowExporterName},
+			expClassification, inIfClassification, outIfClassification, flow.SamplingRate) {
+		c.metrics.flowsDuplicates.WithLabelValues(exporterStr).Inc()
+		if c.config.Deduplication.Mode == DeduplicationDrop {
+			return true
+		}
+		flow.AppendUint(schema.ColumnDuplicate, 1)
+	}
+
 	ctx := c.t.Context(context.Background())
 	sourceRouting := c.d.Routing.Lookup(ctx, flow.SrcAddr, netip.Addr{}, flow.ExporterAddress)
 	destRouting := c.d.Routing.Lookup(ctx, flow.DstAddr, flow.NextHop, flow.ExporterAddress)
@@ -185,6 +196,22 @@ func (w *worker) enrichFlow(exporterIP netip.Addr, exporterStr string) bool {
 	flow.AppendUint(schema.ColumnInIfSpeed, uint64(flowInIfSpeed))
 	flow.AppendUint(schema.ColumnOutIfSpeed, uint64(flowOutIfSpeed))
 
+	// Computed columns
+	if len(c.computedColumns) > 0 {
+		c.computeColumns(flow, computedColumnEnvironment{
+			Exporter:     newObservationPointExporter(exporterInfo{IP: exporterStr, Name: flowExporterName}, expClassification),
+			InIf:         newObservationPointInterface(inIfClassification),
+			OutIf:        newObservationPointInterface(outIfClassification),
+			Src:          newComputedColumnEndpoint(flow, flow.SrcAddr, schema.ColumnSrcPort, flow.SrcAS, srcNet),
+			Dst:          newComputedColumnEndpoint(flow, flow.DstAddr, schema.ColumnDstPort, flow.DstAS, dstNet),
+			EType:        uint32(flow.GetUint(schema.ColumnEType)),
+			Proto:        uint8(flow.GetUint(schema.ColumnProto)),
+			SamplingRate: flow.SamplingRate,
+			Bytes:        flow.GetUint(schema.ColumnBytes),
+			Packets:      flow.GetUint(schema.ColumnPackets),
+		})
+	}
+
 	return skip
 }
 
@@ -276,21 +303,22 @@ func (c *Component) writeExporter(flow *schema.FlowMessage, classification expor
 	return true
 }
 
-func (c *Component) classifyExporter(t time.Time, ip, name string, flow *schema.FlowMessage, classification exporterClassification) bool {
+func (c *Component) classifyExporter(t time.Time, ip, name string, flow *schema.FlowMessage, classification *exporterClassification) bool {
 	// we already have the info provided by the metadata component
-	if (classification != exporterClassification{}) {
-		return c.writeExporter(flow, classification)
+	if (*classification != exporterClassification{}) {
+		return c.writeExporter(flow, *classification)
 	}
 	if len(c.config.ExporterClassifiers) == 0 {
 		return true
 	}
 	si := exporterInfo{IP: ip, Name: name}
-	if classification, ok := c.classifierExporterCache.Get(t, si); ok {
-		return c.writeExporter(flow, classification)
+	if cached, ok := c.classifierExporterCache.Get(t, si); ok {
+		*classification = cached
+		return c.writeExporter(flow, *classification)
 	}
 
 	for idx, rule := range c.config.ExporterClassifiers {
-		if err := rule.exec(si, &classification); err != nil {
+		if err := rule.exec(si, classification); err != nil {
 			c.classifierErrLogger.Err(err).
 				Str("type", "exporter").
 				Int("index", idx).
@@ -304,8 +332,8 @@ func (c *Component) classifyExporter(t time.Time, ip, name string, flow *schema.
 		}
 		break
 	}
-	c.classifierExporterCache.Put(t, si, classification)
-	return c.writeExporter(flow, classification)
+	c.classifierExporterCache.Put(t, si, *classification)
+	return c.writeExporter(flow, *classification)
 }
 
 func (c *Component) writeInterface(flow *schema.FlowMessage, classification interfaceClassification, directionIn bool) bool {
@@ -333,31 +361,32 @@ func (c *Component) classifyInterface(
 	fl *schema.FlowMessage,
 	ei exporterInfo,
 	ii interfaceInfo,
-	classification interfaceClassification,
+	classification *interfaceClassification,
 	directionIn bool,
 ) bool {
 	// we already have the info provided by the metadata component
-	if (classification != interfaceClassification{}) {
+	if (*classification != interfaceClassification{}) {
 		classification.Name = ii.Name
 		classification.Description = ii.Description
-		return c.writeInterface(fl, classification, directionIn)
+		return c.writeInterface(fl, *classification, directionIn)
 	}
 	if len(c.config.InterfaceClassifiers) == 0 {
 		classification.Name = ii.Name
 		classification.Description = ii.Description
-		c.writeInterface(fl, classification, directionIn)
+		c.writeInterface(fl, *classification, directionIn)
 		return true
 	}
 	key := exporterAndInterfaceInfo{
 		Exporter:  ei,
 		Interface: ii,
 	}
-	if classification, ok := c.classifierInterfaceCache.Get(t, key); ok {
-		return c.writeInterface(fl, classification, directionIn)
+	if cached, ok := c.classifierInterfaceCache.Get(t, key); ok {
+		*classification = cached
+		return c.writeInterface(fl, *classification, directionIn)
 	}
 
 	for idx, rule := range c.config.InterfaceClassifiers {
-		err := rule.exec(ei, ii, &classification)
+		err := rule.exec(ei, ii, classification)
 		if err != nil {
 			c.classifierErrLogger.Err(err).
 				Str("type", "interface").
@@ -382,8 +411,8 @@ func (c *Component) classifyInterface(
 	if classification.Description == "" {
 		classification.Description = ii.Description
 	}
-	c.classifierInterfaceCache.Put(t, key, classification)
-	return c.writeInterface(fl, classification, directionIn)
+	c.classifierInterfaceCache.Put(t, key, *classification)
+	return c.writeInterface(fl, *classification, directionIn)
 }
 
 func isPrivateAS(as uint32) bool {
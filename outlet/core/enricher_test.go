@@ -36,6 +36,7 @@ func TestEnrich(t *testing.T) {
 		Configuration   helpers.M
 		GeoIP           bool
 		Networks        *networks.Configuration
+		ComputedColumns []schema.ComputedColumn
 		InputFlow       func() *schema.FlowMessage
 		OutputFlow      *schema.FlowMessage
 		ExpectedMetrics map[string]string
@@ -396,6 +397,160 @@ ClassifyProviderRegex(Interface.Description, "^Transit: ([^ ]+)", "$1")`,
 				},
 			},
 		},
+		{
+			Name: "deduplication, drop duplicate",
+			Configuration: helpers.M{
+				"interfaceclassifiers": []string{`ClassifyInternal()`},
+				"deduplication": helpers.M{
+					"mode":             "drop",
+					"observationpoint": `InIf.Boundary == "external" || OutIf.Boundary == "external"`,
+				},
+			},
+			InputFlow: func() *schema.FlowMessage {
+				return &schema.FlowMessage{
+					SamplingRate:    1000,
+					ExporterAddress: netip.MustParseAddr("::ffff:192.0.2.142"),
+					InIf:            100,
+					OutIf:           200,
+				}
+			},
+			OutputFlow: nil,
+			ExpectedMetrics: map[string]string{
+				`flows_duplicates_total{exporter="192.0.2.142"}`: "1",
+			},
+		},
+		{
+			Name: "deduplication, mark duplicate",
+			Configuration: helpers.M{
+				"interfaceclassifiers": []string{`ClassifyInternal()`},
+				"deduplication": helpers.M{
+					"mode":             "mark",
+					"observationpoint": `InIf.Boundary == "external" || OutIf.Boundary == "external"`,
+				},
+			},
+			InputFlow: func() *schema.FlowMessage {
+				return &schema.FlowMessage{
+					SamplingRate:    1000,
+					ExporterAddress: netip.MustParseAddr("::ffff:192.0.2.142"),
+					InIf:            100,
+					OutIf:           200,
+				}
+			},
+			OutputFlow: &schema.FlowMessage{
+				SamplingRate:    1000,
+				InIf:            100,
+				OutIf:           200,
+				ExporterAddress: netip.MustParseAddr("::ffff:192.0.2.142"),
+				OtherColumns: map[schema.ColumnKey]any{
+					schema.ColumnExporterName:     "192_0_2_142",
+					schema.ColumnInIfName:         "Gi0/0/100",
+					schema.ColumnOutIfName:        "Gi0/0/200",
+					schema.ColumnInIfDescription:  "Interface 100",
+					schema.ColumnOutIfDescription: "Interface 200",
+					schema.ColumnInIfSpeed:        uint32(1000),
+					schema.ColumnOutIfSpeed:       uint32(1000),
+					schema.ColumnInIfBoundary:     uint8(schema.InterfaceBoundaryInternal),
+					schema.ColumnOutIfBoundary:    uint8(schema.InterfaceBoundaryInternal),
+					schema.ColumnDuplicate:        uint8(1),
+				},
+			},
+			ExpectedMetrics: map[string]string{
+				`flows_duplicates_total{exporter="192.0.2.142"}`: "1",
+			},
+		},
+		{
+			Name: "deduplication, observation point",
+			Configuration: helpers.M{
+				"interfaceclassifiers": []string{`Interface.Index == 100 && ClassifyExternal()`, `ClassifyInternal()`},
+				"deduplication": helpers.M{
+					"mode":             "drop",
+					"observationpoint": `InIf.Boundary == "external" || OutIf.Boundary == "external"`,
+				},
+			},
+			InputFlow: func() *schema.FlowMessage {
+				return &schema.FlowMessage{
+					SamplingRate:    1000,
+					ExporterAddress: netip.MustParseAddr("::ffff:192.0.2.142"),
+					InIf:            100,
+					OutIf:           200,
+				}
+			},
+			OutputFlow: &schema.FlowMessage{
+				SamplingRate:    1000,
+				InIf:            100,
+				OutIf:           200,
+				ExporterAddress: netip.MustParseAddr("::ffff:192.0.2.142"),
+				OtherColumns: map[schema.ColumnKey]any{
+					schema.ColumnExporterName:     "192_0_2_142",
+					schema.ColumnInIfName:         "Gi0/0/100",
+					schema.ColumnOutIfName:        "Gi0/0/200",
+					schema.ColumnInIfDescription:  "Interface 100",
+					schema.ColumnOutIfDescription: "Interface 200",
+					schema.ColumnInIfSpeed:        uint32(1000),
+					schema.ColumnOutIfSpeed:       uint32(1000),
+					schema.ColumnInIfBoundary:     uint8(schema.InterfaceBoundaryExternal),
+					schema.ColumnOutIfBoundary:    uint8(schema.InterfaceBoundaryInternal),
+				},
+			},
+		},
+		{
+			Name: "computed columns",
+			Configuration: helpers.M{
+				"computedcolumns": []helpers.M{
+					{
+						"column": "ServiceName",
+						"rule":   `Proto == 6 && Dst.Port == 443 ? "https" : nil`,
+					}, {
+						"column": "InIfTier",
+						"rule":   `InIf.Name == "Gi0/0/100" ? 1 : 2`,
+					}, {
+						"column": "SrcExporterSite",
+						"rule":   `Format("%s-%d", Exporter.Name, Src.Port)`,
+					},
+				},
+			},
+			ComputedColumns: []schema.ComputedColumn{
+				{Name: "ServiceName", Type: "String"},
+				{Name: "InIfTier", Type: "UInt8"},
+				{Name: "SrcExporterSite", Type: "String"},
+			},
+			InputFlow: func() *schema.FlowMessage {
+				return &schema.FlowMessage{
+					SamplingRate:    1000,
+					ExporterAddress: netip.MustParseAddr("::ffff:192.0.2.142"),
+					InIf:            100,
+					OutIf:           200,
+					OtherColumns: map[schema.ColumnKey]any{
+						schema.ColumnProto:   6,
+						schema.ColumnSrcPort: 34567,
+						schema.ColumnDstPort: 443,
+					},
+				}
+			},
+			OutputFlow: &schema.FlowMessage{
+				SamplingRate:    1000,
+				InIf:            100,
+				OutIf:           200,
+				ExporterAddress: netip.MustParseAddr("::ffff:192.0.2.142"),
+				OtherColumns: map[schema.ColumnKey]any{
+					schema.ColumnExporterName:     "192_0_2_142",
+					schema.ColumnInIfName:         "Gi0/0/100",
+					schema.ColumnOutIfName:        "Gi0/0/200",
+					schema.ColumnInIfDescription:  "Interface 100",
+					schema.ColumnOutIfDescription: "Interface 200",
+					schema.ColumnInIfSpeed:        uint32(1000),
+					schema.ColumnOutIfSpeed:       uint32(1000),
+					schema.ColumnProto:            uint32(6),
+					schema.ColumnSrcPort:          uint16(34567),
+					schema.ColumnDstPort:          uint16(443),
+					schema.ColumnLast:             "https",             // ServiceName
+					schema.ColumnLast + 1:         uint8(1),            // InIfTier
+					schema.ColumnLast + 2:         uint8(2),            // OutIfTier
+					schema.ColumnLast + 3:         "192_0_2_142-34567", // SrcExporterSite
+					schema.ColumnLast + 4:         "192_0_2_142-443",   // DstExporterSite
+				},
+			},
+		},
 		{
 			Name: "configure twice boundary",
 			Configuration: helpers.M{
@@ -749,6 +904,15 @@ ClassifyProviderRegex(Interface.Description, "^Transit: ([^ ]+)", "$1")`,
 				Routing:    routingComponent,
 				Schema:     schema.NewMock(t).EnableAllColumns(),
 			}
+			if tc.ComputedColumns != nil {
+				schemaConfiguration := schema.DefaultConfiguration()
+				schemaConfiguration.ComputedColumns = tc.ComputedColumns
+				schemaComponent, err := schema.New(schemaConfiguration)
+				if err != nil {
+					t.Fatalf("schema.New() error:\n%+v", err)
+				}
+				dependencies.Schema = schemaComponent.EnableAllColumns()
+			}
 			var geoipComponent *geoip.Component
 			if tc.GeoIP {
 				geoipComponent = geoip.NewMock(t, r, true)
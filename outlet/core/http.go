@@ -4,7 +4,9 @@
 package core
 
 import (
+	"cmp"
 	"net/http"
+	"slices"
 	"strconv"
 	"sync/atomic"
 	"time"
@@ -65,3 +67,31 @@ func (c *Component) FlowsHTTPHandler(w http.ResponseWriter, req *http.Request) {
 		}
 	}
 }
+
+// exporterInformation is the information returned about an exporter.
+type exporterInformation struct {
+	Exporter    string `json:"exporter"`
+	RateLimited uint64 `json:"rate-limited"`
+}
+
+// ExportersHTTPHandler lists the exporters rate-limited by this outlet with
+// the number of dropped flows. The optional "exporter" query parameter
+// restricts the list to one exporter.
+func (c *Component) ExportersHTTPHandler(w http.ResponseWriter, req *http.Request) {
+	filter := req.URL.Query().Get("exporter")
+	exporters := []exporterInformation{}
+	for exporter, rateLimited := range c.rateLimiter.rateLimitedFlows() {
+		name := exporter.Unmap().String()
+		if filter != "" && name != filter {
+			continue
+		}
+		exporters = append(exporters, exporterInformation{
+			Exporter:    name,
+			RateLimited: rateLimited,
+		})
+	}
+	slices.SortFunc(exporters, func(a, b exporterInformation) int {
+		return cmp.Compare(a.Exporter, b.Exporter)
+	})
+	httpserver.WriteJSON(w, http.StatusOK, exporters)
+}
@@ -16,6 +16,7 @@ type metrics struct {
 	flowsForwarded   *reporter.CounterVec
 	flowsErrors      *reporter.CounterVec
 	flowsRateLimited *reporter.CounterVec
+	flowsDuplicates  *reporter.CounterVec
 	flowsHTTPClients reporter.GaugeFunc
 
 	classifierExporterCacheSize  reporter.CounterFunc
@@ -65,6 +66,13 @@ func (c *Component) initMetrics() {
 		},
 		[]string{"exporter"},
 	)
+	c.metrics.flowsDuplicates = c.r.CounterVec(
+		reporter.CounterOpts{
+			Name: "flows_duplicates_total",
+			Help: "Number of flows outside the observation point.",
+		},
+		[]string{"exporter"},
+	)
 	c.metrics.flowsHTTPClients = c.r.GaugeFunc(
 		reporter.GaugeOpts{
 			Name: "flows_http_clients",
@@ -19,6 +19,7 @@ type perExporterRateLimiter struct {
 	total         uint64  // received during the current second
 	factor        float64 // sampling rate correction computed from the last second
 	currentSecond uint64  // second the two counters above apply to
+	rateLimited   uint64  // dropped since the exporter was first seen
 }
 
 // newRateLimiter returns a new per-exporter rate limiter.
@@ -56,6 +57,7 @@ func (rl rateLimiter) allowOneMessage(exporter netip.Addr, rateLimit, timeReceiv
 		value.total++
 		if value.total > rateLimit {
 			value.dropped++
+			value.rateLimited++
 			verdict = false
 		}
 		return value, xsync.UpdateOp
@@ -63,3 +65,13 @@ func (rl rateLimiter) allowOneMessage(exporter netip.Addr, rateLimit, timeReceiv
 	value, _ := rl.Compute(exporter, update)
 	return verdict, value.factor
 }
+
+// rateLimitedFlows returns the number of flows dropped for each exporter.
+func (rl rateLimiter) rateLimitedFlows() map[netip.Addr]uint64 {
+	result := map[netip.Addr]uint64{}
+	rl.Range(func(exporter netip.Addr, value perExporterRateLimiter) bool {
+		result[exporter] = value.rateLimited
+		return true
+	})
+	return result
+}
@@ -36,6 +36,12 @@ func TestRateLimiterEnforced(t *testing.T) {
 	if diff := helpers.Diff(allowed, 100); diff != "" {
 		t.Fatalf("allow() during second second (-got, +want):\n%s", diff)
 	}
+
+	// Dropped flows are accumulated over the seconds
+	expected := map[netip.Addr]uint64{exporter: 100}
+	if diff := helpers.Diff(rl.rateLimitedFlows(), expected); diff != "" {
+		t.Fatalf("rateLimitedFlows() (-got, +want):\n%s", diff)
+	}
 }
 
 func TestRateLimiterSamplingRateFactor(t *testing.T) {
@@ -5,6 +5,7 @@
 package core
 
 import (
+	"errors"
 	"time"
 
 	"gopkg.in/tomb.v2"
@@ -39,6 +40,8 @@ type Component struct {
 	classifierExporterCache  *cache.Cache[exporterInfo, exporterClassification]
 	classifierInterfaceCache *cache.Cache[exporterAndInterfaceInfo, interfaceClassification]
 	classifierErrLogger      reporter.Logger
+	observationPointCache    *cache.Cache[observationPointEnvironment, bool]
+	computedColumns          []computedColumn
 
 	rateLimiter rateLimiter
 }
@@ -59,6 +62,20 @@ type Dependencies struct {
 
 // New creates a new core component.
 func New(r *reporter.Reporter, configuration Configuration, dependencies Dependencies) (*Component, error) {
+	if configuration.Deduplication.Mode != DeduplicationDisabled {
+		if configuration.Deduplication.ObservationPoint.program == nil {
+			return nil, errors.New("deduplication requires an observation point rule")
+		}
+		if configuration.Deduplication.Mode == DeduplicationMark {
+			if column, ok := dependencies.Schema.LookupColumnByKey(schema.ColumnDuplicate); !ok || column.Disabled {
+				return nil, errors.New("deduplication in mark mode requires the Duplicate column")
+			}
+		}
+	}
+	computedColumns, err := newComputedColumns(configuration.ComputedColumns, dependencies.Schema)
+	if err != nil {
+		return nil, err
+	}
 	c := Component{
 		r:      r,
 		d:      &dependencies,
@@ -71,6 +88,8 @@ func New(r *reporter.Reporter, configuration Configuration, dependencies Depende
 		classifierExporterCache:  cache.New[exporterInfo, exporterClassification](),
 		classifierInterfaceCache: cache.New[exporterAndInterfaceInfo, interfaceClassification](),
 		classifierErrLogger:      r.Sample(reporter.BurstSampler(10*time.Second, 3)),
+		observationPointCache:    cache.New[observationPointEnvironment, bool](),
+		computedColumns:          computedColumns,
 
 		rateLimiter: newRateLimiter(),
 	}
@@ -93,11 +112,13 @@ func (c *Component) Start() error {
 				before := time.Now().Add(-c.config.ClassifierCacheDuration)
 				c.classifierExporterCache.DeleteLastAccessedBefore(before)
 				c.classifierInterfaceCache.DeleteLastAccessedBefore(before)
+				c.observationPointCache.DeleteLastAccessedBefore(before)
 			}
 		}
 	})
 
 	c.d.HTTP.APIRouter.GET("/api/v0/outlet/flows", c.FlowsHTTPHandler)
+	c.d.HTTP.APIRouter.GET("/api/v0/outlet/exporters", c.ExportersHTTPHandler)
 
 	// Processing flows can be delayed to let the other components collect their
 	// data first.
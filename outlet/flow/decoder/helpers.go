@@ -38,6 +38,7 @@ func ParseIPv4(sch *schema.Component, bf *schema.FlowMessage, decap pb.RawFlow_D
 		}
 		bf.AppendUint(schema.ColumnProto, uint64(proto))
 	}
+	outer := data
 	ihl := int((data[0] & 0xf) * 4)
 	if len(data) >= ihl {
 		data = data[ihl:]
@@ -47,6 +48,9 @@ func ParseIPv4(sch *schema.Component, bf *schema.FlowMessage, decap pb.RawFlow_D
 	if fragoffset == 0 {
 		innerL3Length := ParseL4(sch, bf, decap, data, proto)
 		if decap != pb.RawFlow_DECAP_NONE {
+			if innerL3Length > 0 {
+				appendTunnel(sch, bf, outer[12:16], outer[16:20], proto)
+			}
 			return innerL3Length
 		}
 		return l3Length
@@ -81,9 +85,13 @@ func ParseIPv6(sch *schema.Component, bf *schema.FlowMessage, decap pb.RawFlow_D
 			// TODO fragmentID/fragmentOffset are in a separate header
 		}
 	}
+	outer := data
 	data = data[40:]
 	innerL3Length := ParseL4(sch, bf, decap, data, proto)
 	if decap != pb.RawFlow_DECAP_NONE {
+		if innerL3Length > 0 {
+			appendTunnel(sch, bf, outer[8:24], outer[24:40], proto)
+		}
 		return innerL3Length
 	}
 	return l3Length
@@ -97,8 +105,14 @@ func ParseL4(sch *schema.Component, bf *schema.FlowMessage, decap pb.RawFlow_Dec
 	case pb.RawFlow_DECAP_VXLAN:
 		if proto == constants.ProtoUDP && len(data) > 16 && binary.BigEndian.Uint16(data[2:4]) == constants.PortVXLAN {
 			// It looks like a VXLAN packet!
+			vni := binary.BigEndian.Uint32(data[12:16]) >> 8
+			hasVNI := data[8]&0x08 != 0
 			data = data[16:]
-			return ParseEthernet(sch, bf, pb.RawFlow_DECAP_NONE, data)
+			l3Length := ParseEthernet(sch, bf, pb.RawFlow_DECAP_NONE, data)
+			if l3Length > 0 && hasVNI && !sch.IsDisabled(schema.ColumnGroupTunnel) {
+				bf.AppendUint(schema.ColumnTunnelID, uint64(vni))
+			}
+			return l3Length
 		}
 		return 0
 	case pb.RawFlow_DECAP_GRE:
@@ -111,13 +125,20 @@ func ParseL4(sch *schema.Component, bf *schema.FlowMessage, decap pb.RawFlow_Dec
 			}
 			skip := 4 + bits.OnesCount16(flagAndVersion)*4
 			if len(data) >= skip {
-				data = data[skip:]
+				var l3Length uint64
 				switch greProtocol {
 				case constants.ETypeIPv4:
-					return ParseIPv4(sch, bf, pb.RawFlow_DECAP_NONE, data)
+					l3Length = ParseIPv4(sch, bf, pb.RawFlow_DECAP_NONE, data[skip:])
 				case constants.ETypeIPv6:
-					return ParseIPv6(sch, bf, pb.RawFlow_DECAP_NONE, data)
+					l3Length = ParseIPv6(sch, bf, pb.RawFlow_DECAP_NONE, data[skip:])
 				}
+				if l3Length > 0 && flagAndVersion&0x2000 != 0 && !sch.IsDisabled(schema.ColumnGroupTunnel) {
+					// The key comes after the optional checksum
+					offset := 4 + bits.OnesCount16(flagAndVersion&0x8000)*4
+					bf.AppendUint(schema.ColumnTunnelID,
+						uint64(binary.BigEndian.Uint32(data[offset:offset+4])))
+				}
+				return l3Length
 			}
 			return 0
 		}
@@ -190,6 +211,18 @@ func ParseL4(sch *schema.Component, bf *schema.FlowMessage, decap pb.RawFlow_Dec
 	return 0
 }
 
+// appendTunnel records the outer header of a decapsulated packet. It is only
+// called once the inner packet has been parsed, as the flow is dropped
+// otherwise.
+func appendTunnel(sch *schema.Component, bf *schema.FlowMessage, src, dst []byte, proto uint8) {
+	if sch.IsDisabled(schema.ColumnGroupTunnel) {
+		return
+	}
+	bf.AppendIPv6(schema.ColumnSrcAddrTunnel, DecodeIP(src))
+	bf.AppendIPv6(schema.ColumnDstAddrTunnel, DecodeIP(dst))
+	bf.AppendUint(schema.ColumnTunnelProto, uint64(proto))
+}
+
 // ParseEthernet parses an Ethernet packet and returns L3 length.
 func ParseEthernet(sch *schema.Component, bf *schema.FlowMessage, decap pb.RawFlow_DecapsulationProtocol, data []byte) uint64 {
 	if len(data) < 14 {
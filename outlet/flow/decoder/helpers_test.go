@@ -216,11 +216,14 @@ func TestDecodeIPv4IPv4(t *testing.T) {
 		SrcAddr: netip.MustParseAddr("::ffff:192.168.0.1"),
 		DstAddr: netip.MustParseAddr("::ffff:192.168.0.2"),
 		OtherColumns: map[schema.ColumnKey]any{
-			schema.ColumnEType:        uint32(constants.ETypeIPv4),
-			schema.ColumnProto:        uint32(constants.ProtoICMPv4),
-			schema.ColumnIPTTL:        uint8(255),
-			schema.ColumnIPFragmentID: uint32(18),
-			schema.ColumnICMPv4Type:   uint8(8),
+			schema.ColumnEType:         uint32(constants.ETypeIPv4),
+			schema.ColumnProto:         uint32(constants.ProtoICMPv4),
+			schema.ColumnIPTTL:         uint8(255),
+			schema.ColumnIPFragmentID:  uint32(18),
+			schema.ColumnICMPv4Type:    uint8(8),
+			schema.ColumnSrcAddrTunnel: netip.MustParseAddr("::ffff:10.1.2.1"),
+			schema.ColumnDstAddrTunnel: netip.MustParseAddr("::ffff:10.1.2.2"),
+			schema.ColumnTunnelProto:   uint8(constants.ProtoIPv4),
 		},
 	}
 	if diff := helpers.Diff(bf, expected); diff != "" {
@@ -240,12 +243,15 @@ func TestDecodeIPv4IPv6(t *testing.T) {
 		SrcAddr: netip.MustParseAddr("2001:610:1908:a000::149:20"),
 		DstAddr: netip.MustParseAddr("2002:2470:9ffa:fa9:0:dd:ed00:2"),
 		OtherColumns: map[schema.ColumnKey]any{
-			schema.ColumnEType:    uint32(constants.ETypeIPv6),
-			schema.ColumnProto:    uint32(constants.ProtoTCP),
-			schema.ColumnIPTTL:    uint8(58),
-			schema.ColumnSrcPort:  uint16(80),
-			schema.ColumnDstPort:  uint16(35673),
-			schema.ColumnTCPFlags: uint16(16),
+			schema.ColumnEType:         uint32(constants.ETypeIPv6),
+			schema.ColumnProto:         uint32(constants.ProtoTCP),
+			schema.ColumnIPTTL:         uint8(58),
+			schema.ColumnSrcPort:       uint16(80),
+			schema.ColumnDstPort:       uint16(35673),
+			schema.ColumnTCPFlags:      uint16(16),
+			schema.ColumnSrcAddrTunnel: netip.MustParseAddr("::ffff:216.66.80.30"),
+			schema.ColumnDstAddrTunnel: netip.MustParseAddr("::ffff:192.168.2.2"),
+			schema.ColumnTunnelProto:   uint8(constants.ProtoIPv6),
 		},
 	}
 	if diff := helpers.Diff(bf, expected); diff != "" {
@@ -265,13 +271,16 @@ func TestDecodeGREv6Plain(t *testing.T) {
 		SrcAddr: netip.MustParseAddr("2001:db8::2"),
 		DstAddr: netip.MustParseAddr("2001:db8::1"),
 		OtherColumns: map[schema.ColumnKey]any{
-			schema.ColumnEType:    uint32(constants.ETypeIPv6),
-			schema.ColumnProto:    uint32(constants.ProtoTCP),
-			schema.ColumnIPTTL:    uint8(255),
-			schema.ColumnSrcPort:  uint16(18716),
-			schema.ColumnDstPort:  uint16(23),
-			schema.ColumnIPTos:    uint8(192),
-			schema.ColumnTCPFlags: uint16(24),
+			schema.ColumnEType:         uint32(constants.ETypeIPv6),
+			schema.ColumnProto:         uint32(constants.ProtoTCP),
+			schema.ColumnIPTTL:         uint8(255),
+			schema.ColumnSrcPort:       uint16(18716),
+			schema.ColumnDstPort:       uint16(23),
+			schema.ColumnIPTos:         uint8(192),
+			schema.ColumnTCPFlags:      uint16(24),
+			schema.ColumnSrcAddrTunnel: netip.MustParseAddr("::ffff:172.16.23.2"),
+			schema.ColumnDstAddrTunnel: netip.MustParseAddr("::ffff:192.168.47.1"),
+			schema.ColumnTunnelProto:   uint8(constants.ProtoGRE),
 		},
 	}
 	if diff := helpers.Diff(bf, expected); diff != "" {
@@ -291,12 +300,15 @@ func TestDecodeGREv4Plain(t *testing.T) {
 		SrcAddr: netip.MustParseAddr("::ffff:66.59.111.190"),
 		DstAddr: netip.MustParseAddr("::ffff:66.59.111.182"),
 		OtherColumns: map[schema.ColumnKey]any{
-			schema.ColumnEType:   uint32(constants.ETypeIPv4),
-			schema.ColumnProto:   uint32(constants.ProtoUDP),
-			schema.ColumnIPTTL:   uint8(64),
-			schema.ColumnSrcPort: uint16(123),
-			schema.ColumnDstPort: uint16(123),
-			schema.ColumnIPTos:   uint8(16),
+			schema.ColumnEType:         uint32(constants.ETypeIPv4),
+			schema.ColumnProto:         uint32(constants.ProtoUDP),
+			schema.ColumnIPTTL:         uint8(64),
+			schema.ColumnSrcPort:       uint16(123),
+			schema.ColumnDstPort:       uint16(123),
+			schema.ColumnIPTos:         uint8(16),
+			schema.ColumnSrcAddrTunnel: netip.MustParseAddr("::ffff:172.27.1.66"),
+			schema.ColumnDstAddrTunnel: netip.MustParseAddr("::ffff:66.59.109.137"),
+			schema.ColumnTunnelProto:   uint8(constants.ProtoGRE),
 		},
 	}
 	if diff := helpers.Diff(bf, expected); diff != "" {
@@ -316,11 +328,15 @@ func TestDecodeGREv4Key(t *testing.T) {
 		SrcAddr: netip.MustParseAddr("::ffff:192.168.1.101"),
 		DstAddr: netip.MustParseAddr("::ffff:192.168.2.102"),
 		OtherColumns: map[schema.ColumnKey]any{
-			schema.ColumnEType:        uint32(constants.ETypeIPv4),
-			schema.ColumnProto:        uint32(constants.ProtoICMPv4),
-			schema.ColumnIPTTL:        uint8(254),
-			schema.ColumnIPFragmentID: uint32(1119),
-			schema.ColumnICMPv4Type:   uint8(8),
+			schema.ColumnEType:         uint32(constants.ETypeIPv4),
+			schema.ColumnProto:         uint32(constants.ProtoICMPv4),
+			schema.ColumnIPTTL:         uint8(254),
+			schema.ColumnIPFragmentID:  uint32(1119),
+			schema.ColumnICMPv4Type:    uint8(8),
+			schema.ColumnSrcAddrTunnel: netip.MustParseAddr("::ffff:192.168.12.1"),
+			schema.ColumnDstAddrTunnel: netip.MustParseAddr("::ffff:192.168.12.2"),
+			schema.ColumnTunnelProto:   uint8(constants.ProtoGRE),
+			schema.ColumnTunnelID:      uint32(1),
 		},
 	}
 	if diff := helpers.Diff(bf, expected); diff != "" {
@@ -346,6 +362,9 @@ func TestDecodeSRv6(t *testing.T) {
 			schema.ColumnIPv6FlowLabel: uint32(0x0ae0a9),
 			schema.ColumnSrcPort:       uint16(50701),
 			schema.ColumnDstPort:       uint16(5001),
+			schema.ColumnSrcAddrTunnel: netip.MustParseAddr("1:2:1::1"),
+			schema.ColumnDstAddrTunnel: netip.MustParseAddr("f2::"),
+			schema.ColumnTunnelProto:   uint8(constants.ProtoSRH),
 		},
 	}
 	if diff := helpers.Diff(bf, expected); diff != "" {
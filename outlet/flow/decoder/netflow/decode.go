@@ -35,7 +35,7 @@ const (
 
 const juniperPEN = 2636
 
-func (nd *Decoder) decodeNFv5(packet *netflowlegacy.PacketNetFlowV5, ts, sysUptime uint64, options decoder.Options, bf *schema.FlowMessage, finalize decoder.FinalizeFlowFunc) {
+func (nd *Decoder) decodeNFv5(packet *netflowlegacy.PacketNetFlowV5, ts, sysUptime uint64, pt packetTimes, options decoder.Options, bf *schema.FlowMessage, finalize decoder.FinalizeFlowFunc) {
 	for _, record := range packet.Records {
 		bf.SamplingRate = uint64(packet.SamplingInterval)
 		bf.InIf = uint32(record.Input)
@@ -60,6 +60,7 @@ func (nd *Decoder) decodeNFv5(packet *netflowlegacy.PacketNetFlowV5, ts, sysUpti
 		if options.TimestampSource == pb.RawFlow_TS_NETFLOW_FIRST_SWITCHED {
 			bf.TimeReceived = uint32(ts - sysUptime + uint64(record.First))
 		}
+		flowTimes{startUptime: uint64(record.First), endUptime: uint64(record.Last)}.append(pt, bf)
 		if bf.SamplingRate == 0 {
 			bf.SamplingRate = 1
 		}
@@ -67,7 +68,7 @@ func (nd *Decoder) decodeNFv5(packet *netflowlegacy.PacketNetFlowV5, ts, sysUpti
 	}
 }
 
-func (nd *Decoder) decodeNFv9IPFIX(version uint16, obsDomainID uint32, flowSets []any, tao *templatesAndOptions, ts, sysUptime uint64, options decoder.Options, exporter string, bf *schema.FlowMessage, finalize decoder.FinalizeFlowFunc) {
+func (nd *Decoder) decodeNFv9IPFIX(version uint16, obsDomainID uint32, flowSets []any, tao *templatesAndOptions, ts, sysUptime uint64, pt packetTimes, options decoder.Options, exporter string, bf *schema.FlowMessage, finalize decoder.FinalizeFlowFunc) {
 	// Look for sampling rate in option data flowsets
 	for _, flowSet := range flowSets {
 		switch tFlowSet := flowSet.(type) {
@@ -103,13 +104,13 @@ func (nd *Decoder) decodeNFv9IPFIX(version uint16, obsDomainID uint32, flowSets
 			}
 		case netflow.DataFlowSet:
 			for _, record := range tFlowSet.Records {
-				nd.decodeRecord(version, obsDomainID, tao, record.Values, ts, sysUptime, options, exporter, bf, finalize)
+				nd.decodeRecord(version, obsDomainID, tao, record.Values, ts, sysUptime, pt, options, exporter, bf, finalize)
 			}
 		}
 	}
 }
 
-func (nd *Decoder) decodeRecord(version uint16, obsDomainID uint32, tao *templatesAndOptions, fields []netflow.DataField, ts, sysUptime uint64, options decoder.Options, exporter string, bf *schema.FlowMessage, finalize decoder.FinalizeFlowFunc) {
+func (nd *Decoder) decodeRecord(version uint16, obsDomainID uint32, tao *templatesAndOptions, fields []netflow.DataField, ts, sysUptime uint64, pt packetTimes, options decoder.Options, exporter string, bf *schema.FlowMessage, finalize decoder.FinalizeFlowFunc) {
 	// reversePresent is a bitset for fields in the reversed direction. The
 	// bitset is nil initially and fields will be added to it when we see them
 	// during the first pass.
@@ -123,6 +124,7 @@ func (nd *Decoder) decodeRecord(version uint16, obsDomainID uint32, tao *templat
 		var proto, icmpType, icmpCode uint8
 		var foundIcmpTypeCode bool
 		var decapOK bool
+		var times flowTimes
 		mplsLabels := make([]uint32, 0, 5)
 		for _, field := range fields {
 			v, ok := field.Value.([]byte)
@@ -292,6 +294,36 @@ func (nd *Decoder) decodeRecord(version uint16, obsDomainID uint32, tao *templat
 					}
 				}
 
+				// Flow timestamps
+				switch field.Type {
+				case netflow.NFV9_FIELD_FIRST_SWITCHED:
+					times.startUptime = decodeUNumber(v)
+				case netflow.NFV9_FIELD_LAST_SWITCHED:
+					times.endUptime = decodeUNumber(v)
+				case netflow.IPFIX_FIELD_systemInitTimeMilliseconds:
+					times.systemInit = decodeUNumber(v)
+				case netflow.IPFIX_FIELD_flowStartSeconds:
+					times.start = decodeUNumber(v) * 1000
+				case netflow.IPFIX_FIELD_flowEndSeconds:
+					times.end = decodeUNumber(v) * 1000
+				case netflow.IPFIX_FIELD_flowStartMilliseconds:
+					times.start = decodeUNumber(v)
+				case netflow.IPFIX_FIELD_flowEndMilliseconds:
+					times.end = decodeUNumber(v)
+				case netflow.IPFIX_FIELD_flowStartMicroseconds, netflow.IPFIX_FIELD_flowStartNanoseconds:
+					times.start = ntpToMilliseconds(decodeUNumber(v))
+				case netflow.IPFIX_FIELD_flowEndMicroseconds, netflow.IPFIX_FIELD_flowEndNanoseconds:
+					times.end = ntpToMilliseconds(decodeUNumber(v))
+				case netflow.IPFIX_FIELD_flowStartDeltaMicroseconds:
+					times.start = pt.export*1000 - decodeUNumber(v)/1000
+				case netflow.IPFIX_FIELD_flowEndDeltaMicroseconds:
+					times.end = pt.export*1000 - decodeUNumber(v)/1000
+				case netflow.IPFIX_FIELD_flowDurationMilliseconds:
+					times.duration = decodeUNumber(v)
+				case netflow.IPFIX_FIELD_flowDurationMicroseconds:
+					times.duration = decodeUNumber(v) / 1000
+				}
+
 				if !nd.d.Schema.IsDisabled(schema.ColumnGroupNAT) {
 					// NAT
 					switch field.Type {
@@ -379,6 +411,7 @@ func (nd *Decoder) decodeRecord(version uint16, obsDomainID uint32, tao *templat
 				bf.AppendUint(schema.ColumnICMPv6Code, uint64(icmpCode))
 			}
 		}
+		times.append(pt, bf)
 		bf.AppendUint(schema.ColumnEType, uint64(etype))
 		if len(mplsLabels) > 0 {
 			bf.AppendArrayUInt32(schema.ColumnMPLSLabels, mplsLabels)
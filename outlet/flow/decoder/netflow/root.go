@@ -99,6 +99,7 @@ func (nd *Decoder) Decode(in decoder.RawFlow, options decoder.Options, bf *schem
 
 	var (
 		sysUptime   uint64
+		pt          packetTimes
 		versionStr  string
 		flowSets    []any
 		obsDomainID uint32
@@ -134,7 +135,8 @@ func (nd *Decoder) Decode(in decoder.RawFlow, options decoder.Options, bf *schem
 			ts = uint64(packetNFv5.UnixSecs)
 			sysUptime = uint64(packetNFv5.SysUptime)
 		}
-		nd.decodeNFv5(&packetNFv5, ts, sysUptime, options, bf, finalize2)
+		pt = packetTimes{export: uint64(packetNFv5.UnixSecs), sysUptime: uint64(packetNFv5.SysUptime)}
+		nd.decodeNFv5(&packetNFv5, ts, sysUptime, pt, options, bf, finalize2)
 	case 9:
 		var packetNFv9 netflow.NFv9Packet
 		if err := netflow.DecodeMessageNetFlow(buf, tao, netflow.FlowContext{}, &packetNFv9); err != nil {
@@ -153,7 +155,8 @@ func (nd *Decoder) Decode(in decoder.RawFlow, options decoder.Options, bf *schem
 			ts = uint64(packetNFv9.UnixSeconds)
 			sysUptime = uint64(packetNFv9.SystemUptime)
 		}
-		nd.decodeNFv9IPFIX(version, obsDomainID, flowSets, tao, ts, sysUptime, options, key, bf, finalize2)
+		pt = packetTimes{export: uint64(packetNFv9.UnixSeconds), sysUptime: uint64(packetNFv9.SystemUptime)}
+		nd.decodeNFv9IPFIX(version, obsDomainID, flowSets, tao, ts, sysUptime, pt, options, key, bf, finalize2)
 	case 10:
 		var packetIPFIX netflow.IPFIXPacket
 		if err := netflow.DecodeMessageIPFIX(buf, tao, netflow.FlowContext{}, &packetIPFIX); err != nil {
@@ -171,7 +174,8 @@ func (nd *Decoder) Decode(in decoder.RawFlow, options decoder.Options, bf *schem
 		if options.TimestampSource == pb.RawFlow_TS_NETFLOW_PACKET {
 			ts = uint64(packetIPFIX.ExportTime)
 		}
-		nd.decodeNFv9IPFIX(version, obsDomainID, flowSets, tao, ts, sysUptime, options, key, bf, finalize2)
+		pt = packetTimes{export: uint64(packetIPFIX.ExportTime)}
+		nd.decodeNFv9IPFIX(version, obsDomainID, flowSets, tao, ts, sysUptime, pt, options, key, bf, finalize2)
 	default:
 		nd.errLogger.Warn().Str("exporter", key).Msgf("unknown NetFlow version %d", version)
 		nd.metrics.packets.WithLabelValues(key, "unknown").
@@ -154,6 +154,8 @@ func TestDecode(t *testing.T) {
 				schema.ColumnTCPFlags:         uint16(16),
 				schema.ColumnIngressVRFID:     uint32(1610612738),
 				schema.ColumnEgressVRFID:      uint32(1610612738),
+				schema.ColumnTimeFlowStart:    uint32(1647285925),
+				schema.ColumnTimeFlowEnd:      uint32(1647285925),
 			},
 		}, {
 			SamplingRate:    30000,
@@ -177,6 +179,8 @@ func TestDecode(t *testing.T) {
 				schema.ColumnTCPFlags:         uint16(16),
 				schema.ColumnIngressVRFID:     uint32(1610612738),
 				schema.ColumnEgressVRFID:      uint32(1610612738),
+				schema.ColumnTimeFlowStart:    uint32(1647285925),
+				schema.ColumnTimeFlowEnd:      uint32(1647285925),
 			},
 		}, {
 			SamplingRate:    30000,
@@ -200,6 +204,8 @@ func TestDecode(t *testing.T) {
 				schema.ColumnTCPFlags:         uint16(16),
 				schema.ColumnIngressVRFID:     uint32(1610612738),
 				schema.ColumnEgressVRFID:      uint32(1610612736),
+				schema.ColumnTimeFlowStart:    uint32(1647285925),
+				schema.ColumnTimeFlowEnd:      uint32(1647285925),
 			},
 		}, {
 			SamplingRate:    30000,
@@ -223,6 +229,8 @@ func TestDecode(t *testing.T) {
 				schema.ColumnTCPFlags:         uint16(16),
 				schema.ColumnIngressVRFID:     uint32(1610612738),
 				schema.ColumnEgressVRFID:      uint32(1610612738),
+				schema.ColumnTimeFlowStart:    uint32(1647285925),
+				schema.ColumnTimeFlowEnd:      uint32(1647285925),
 			},
 		},
 	}
@@ -317,6 +325,8 @@ func TestDecodeSamplingRate(t *testing.T) {
 				schema.ColumnDstPort:       uint16(10907),
 				schema.ColumnEType:         uint32(constants.ETypeIPv4),
 				schema.ColumnFlowDirection: uint8(schema.DirectionIngress),
+				schema.ColumnTimeFlowStart: uint32(1691746198),
+				schema.ColumnTimeFlowEnd:   uint32(1691746198),
 			},
 		},
 	}
@@ -385,6 +395,9 @@ func TestDecodeMultipleSamplingRates(t *testing.T) {
 				schema.ColumnFlowDirection:    uint8(schema.DirectionIngress),
 				schema.ColumnIngressVRFID:     uint32(1610612736),
 				schema.ColumnEgressVRFID:      uint32(1610612736),
+				schema.ColumnTimeFlowStart:    uint32(1701360969),
+				schema.ColumnTimeFlowEnd:      uint32(1701360974),
+				schema.ColumnFlowDuration:     uint32(4911),
 			},
 		},
 		{
@@ -411,6 +424,9 @@ func TestDecodeMultipleSamplingRates(t *testing.T) {
 				schema.ColumnFlowDirection:    uint8(schema.DirectionIngress),
 				schema.ColumnIngressVRFID:     uint32(1610612736),
 				schema.ColumnEgressVRFID:      uint32(1610612736),
+				schema.ColumnTimeFlowStart:    uint32(1701360971),
+				schema.ColumnTimeFlowEnd:      uint32(1701360973),
+				schema.ColumnFlowDuration:     uint32(1870),
 			},
 		},
 	}
@@ -452,6 +468,8 @@ func TestDecodeICMP(t *testing.T) {
 				schema.ColumnPackets:       uint64(1),
 				schema.ColumnProto:         uint32(constants.ProtoICMPv6),
 				schema.ColumnFlowDirection: uint8(schema.DirectionIngress),
+				schema.ColumnTimeFlowStart: uint32(1685867993),
+				schema.ColumnTimeFlowEnd:   uint32(1685867993),
 			},
 		},
 		{
@@ -466,6 +484,8 @@ func TestDecodeICMP(t *testing.T) {
 				schema.ColumnPackets:       uint64(1),
 				schema.ColumnProto:         uint32(constants.ProtoICMPv6),
 				schema.ColumnFlowDirection: uint8(schema.DirectionIngress),
+				schema.ColumnTimeFlowStart: uint32(1685867993),
+				schema.ColumnTimeFlowEnd:   uint32(1685867993),
 			},
 		},
 		{
@@ -480,6 +500,8 @@ func TestDecodeICMP(t *testing.T) {
 				schema.ColumnPackets:       uint64(1),
 				schema.ColumnProto:         uint32(constants.ProtoICMPv4),
 				schema.ColumnFlowDirection: uint8(schema.DirectionIngress),
+				schema.ColumnTimeFlowStart: uint32(1685867995),
+				schema.ColumnTimeFlowEnd:   uint32(1685867995),
 			},
 		},
 		{
@@ -493,6 +515,8 @@ func TestDecodeICMP(t *testing.T) {
 				schema.ColumnProto:         uint32(constants.ProtoICMPv4),
 				schema.ColumnFlowDirection: uint8(schema.DirectionIngress),
 				// Type/Code  = 0
+				schema.ColumnTimeFlowStart: uint32(1685867995),
+				schema.ColumnTimeFlowEnd:   uint32(1685867995),
 			},
 		},
 	}
@@ -601,6 +625,8 @@ func TestDecodeMPLS(t *testing.T) {
 				schema.ColumnMPLSLabels:       []uint32{20005, 524250},
 				schema.ColumnFlowDirection:    uint8(schema.DirectionEgress),
 				schema.ColumnEgressVRFID:      uint32(1),
+				schema.ColumnTimeFlowStart:    uint32(1699893330),
+				schema.ColumnTimeFlowEnd:      uint32(1699893330),
 			},
 		}, {
 			ExporterAddress: netip.MustParseAddr("::ffff:127.0.0.1"),
@@ -621,6 +647,9 @@ func TestDecodeMPLS(t *testing.T) {
 				schema.ColumnMPLSLabels:       []uint32{20006, 524275},
 				schema.ColumnFlowDirection:    uint8(schema.DirectionEgress),
 				schema.ColumnEgressVRFID:      uint32(1),
+				schema.ColumnTimeFlowStart:    uint32(1699893297),
+				schema.ColumnTimeFlowEnd:      uint32(1699893381),
+				schema.ColumnFlowDuration:     uint32(84000),
 			},
 		},
 	}
@@ -667,13 +696,15 @@ func TestDecodeNFv5(t *testing.T) {
 					SrcNetMask:      19,
 					DstNetMask:      24,
 					OtherColumns: map[schema.ColumnKey]any{
-						schema.ColumnBytes:    uint64(133),
-						schema.ColumnPackets:  uint64(1),
-						schema.ColumnEType:    uint32(constants.ETypeIPv4),
-						schema.ColumnProto:    uint32(constants.ProtoTCP),
-						schema.ColumnSrcPort:  uint16(30104),
-						schema.ColumnDstPort:  uint16(11963),
-						schema.ColumnTCPFlags: uint16(0x18),
+						schema.ColumnBytes:         uint64(133),
+						schema.ColumnPackets:       uint64(1),
+						schema.ColumnEType:         uint32(constants.ETypeIPv4),
+						schema.ColumnProto:         uint32(constants.ProtoTCP),
+						schema.ColumnSrcPort:       uint16(30104),
+						schema.ColumnDstPort:       uint16(11963),
+						schema.ColumnTCPFlags:      uint16(0x18),
+						schema.ColumnTimeFlowStart: uint32(1680626664),
+						schema.ColumnTimeFlowEnd:   uint32(1680626664),
 					},
 				},
 			}
@@ -817,16 +848,18 @@ func TestDecodePhysicalInterfaces(t *testing.T) {
 			DstAddr:         netip.MustParseAddr("::ffff:212.82.101.24"),
 			NextHop:         netip.MustParseAddr("::"),
 			OtherColumns: map[schema.ColumnKey]any{
-				schema.ColumnSrcMAC:       uint64(0xc014fef6c365),
-				schema.ColumnDstMAC:       uint64(0xe8b6c24ae34c),
-				schema.ColumnPackets:      uint64(3),
-				schema.ColumnBytes:        uint64(4506),
-				schema.ColumnSrcPort:      uint16(55629),
-				schema.ColumnDstPort:      uint16(993),
-				schema.ColumnTCPFlags:     uint16(0x10),
-				schema.ColumnEType:        uint32(constants.ETypeIPv4),
-				schema.ColumnProto:        uint32(constants.ProtoTCP),
-				schema.ColumnIngressVRFID: uint32(311),
+				schema.ColumnSrcMAC:        uint64(0xc014fef6c365),
+				schema.ColumnDstMAC:        uint64(0xe8b6c24ae34c),
+				schema.ColumnPackets:       uint64(3),
+				schema.ColumnBytes:         uint64(4506),
+				schema.ColumnSrcPort:       uint16(55629),
+				schema.ColumnDstPort:       uint16(993),
+				schema.ColumnTCPFlags:      uint16(0x10),
+				schema.ColumnEType:         uint32(constants.ETypeIPv4),
+				schema.ColumnProto:         uint32(constants.ProtoTCP),
+				schema.ColumnIngressVRFID:  uint32(311),
+				schema.ColumnTimeFlowStart: uint32(1737739081),
+				schema.ColumnTimeFlowEnd:   uint32(1737739081),
 			},
 		},
 	}
@@ -865,14 +898,17 @@ func TestDecodeRFC5103(t *testing.T) {
 			SrcAddr:         netip.MustParseAddr("::ffff:10.10.1.4"),
 			DstAddr:         netip.MustParseAddr("::ffff:10.10.1.1"),
 			OtherColumns: map[schema.ColumnKey]any{
-				schema.ColumnSrcMAC:  uint64(0x00e01c3c17c2),
-				schema.ColumnDstMAC:  uint64(0x001f33d98160),
-				schema.ColumnPackets: uint64(1),
-				schema.ColumnBytes:   uint64(62),
-				schema.ColumnSrcPort: uint16(56166),
-				schema.ColumnDstPort: uint16(53),
-				schema.ColumnEType:   uint32(constants.ETypeIPv4),
-				schema.ColumnProto:   uint32(constants.ProtoUDP),
+				schema.ColumnSrcMAC:        uint64(0x00e01c3c17c2),
+				schema.ColumnDstMAC:        uint64(0x001f33d98160),
+				schema.ColumnPackets:       uint64(1),
+				schema.ColumnBytes:         uint64(62),
+				schema.ColumnSrcPort:       uint16(56166),
+				schema.ColumnDstPort:       uint16(53),
+				schema.ColumnEType:         uint32(constants.ETypeIPv4),
+				schema.ColumnProto:         uint32(constants.ProtoUDP),
+				schema.ColumnTimeFlowStart: uint32(1254722767),
+				schema.ColumnTimeFlowEnd:   uint32(1254722767),
+				schema.ColumnFlowDuration:  uint32(34),
 			},
 		}, {
 			// First biflow, reverse
@@ -883,14 +919,17 @@ func TestDecodeRFC5103(t *testing.T) {
 			SrcAddr:         netip.MustParseAddr("::ffff:10.10.1.1"),
 			DstAddr:         netip.MustParseAddr("::ffff:10.10.1.4"),
 			OtherColumns: map[schema.ColumnKey]any{
-				schema.ColumnDstMAC:  uint64(0x00e01c3c17c2),
-				schema.ColumnSrcMAC:  uint64(0x001f33d98160),
-				schema.ColumnPackets: uint64(1),
-				schema.ColumnBytes:   uint64(128),
-				schema.ColumnDstPort: uint16(56166),
-				schema.ColumnSrcPort: uint16(53),
-				schema.ColumnEType:   uint32(constants.ETypeIPv4),
-				schema.ColumnProto:   uint32(constants.ProtoUDP),
+				schema.ColumnDstMAC:        uint64(0x00e01c3c17c2),
+				schema.ColumnSrcMAC:        uint64(0x001f33d98160),
+				schema.ColumnPackets:       uint64(1),
+				schema.ColumnBytes:         uint64(128),
+				schema.ColumnDstPort:       uint16(56166),
+				schema.ColumnSrcPort:       uint16(53),
+				schema.ColumnEType:         uint32(constants.ETypeIPv4),
+				schema.ColumnProto:         uint32(constants.ProtoUDP),
+				schema.ColumnTimeFlowStart: uint32(1254722767),
+				schema.ColumnTimeFlowEnd:   uint32(1254722767),
+				schema.ColumnFlowDuration:  uint32(34),
 			},
 		}, {
 			// Second biflow, direct, no reverse
@@ -901,14 +940,16 @@ func TestDecodeRFC5103(t *testing.T) {
 			SrcAddr:         netip.MustParseAddr("::ffff:10.10.1.20"),
 			DstAddr:         netip.MustParseAddr("::ffff:10.10.1.255"),
 			OtherColumns: map[schema.ColumnKey]any{
-				schema.ColumnSrcMAC:  uint64(0x00023fec6111),
-				schema.ColumnDstMAC:  uint64(0xffffffffffff),
-				schema.ColumnPackets: uint64(1),
-				schema.ColumnBytes:   uint64(229),
-				schema.ColumnSrcPort: uint16(138),
-				schema.ColumnDstPort: uint16(138),
-				schema.ColumnEType:   uint32(constants.ETypeIPv4),
-				schema.ColumnProto:   uint32(constants.ProtoUDP),
+				schema.ColumnSrcMAC:        uint64(0x00023fec6111),
+				schema.ColumnDstMAC:        uint64(0xffffffffffff),
+				schema.ColumnPackets:       uint64(1),
+				schema.ColumnBytes:         uint64(229),
+				schema.ColumnSrcPort:       uint16(138),
+				schema.ColumnDstPort:       uint16(138),
+				schema.ColumnEType:         uint32(constants.ETypeIPv4),
+				schema.ColumnProto:         uint32(constants.ProtoUDP),
+				schema.ColumnTimeFlowStart: uint32(1254722776),
+				schema.ColumnTimeFlowEnd:   uint32(1254722776),
 			},
 		}, {
 			// Third biflow, direct
@@ -919,15 +960,18 @@ func TestDecodeRFC5103(t *testing.T) {
 			SrcAddr:         netip.MustParseAddr("::ffff:10.10.1.4"),
 			DstAddr:         netip.MustParseAddr("::ffff:74.53.140.153"),
 			OtherColumns: map[schema.ColumnKey]any{
-				schema.ColumnSrcMAC:   uint64(0x00e01c3c17c2),
-				schema.ColumnDstMAC:   uint64(0x001f33d98160),
-				schema.ColumnPackets:  uint64(28),
-				schema.ColumnBytes:    uint64(21673),
-				schema.ColumnSrcPort:  uint16(1470),
-				schema.ColumnDstPort:  uint16(25),
-				schema.ColumnEType:    uint32(constants.ETypeIPv4),
-				schema.ColumnProto:    uint32(constants.ProtoTCP),
-				schema.ColumnTCPFlags: uint16(0x1b),
+				schema.ColumnSrcMAC:        uint64(0x00e01c3c17c2),
+				schema.ColumnDstMAC:        uint64(0x001f33d98160),
+				schema.ColumnPackets:       uint64(28),
+				schema.ColumnBytes:         uint64(21673),
+				schema.ColumnSrcPort:       uint16(1470),
+				schema.ColumnDstPort:       uint16(25),
+				schema.ColumnEType:         uint32(constants.ETypeIPv4),
+				schema.ColumnProto:         uint32(constants.ProtoTCP),
+				schema.ColumnTCPFlags:      uint16(0x1b),
+				schema.ColumnTimeFlowStart: uint32(1254722767),
+				schema.ColumnTimeFlowEnd:   uint32(1254722775),
+				schema.ColumnFlowDuration:  uint32(7577),
 			},
 		}, {
 			// Third biflow, reverse
@@ -938,15 +982,18 @@ func TestDecodeRFC5103(t *testing.T) {
 			SrcAddr:         netip.MustParseAddr("::ffff:74.53.140.153"),
 			DstAddr:         netip.MustParseAddr("::ffff:10.10.1.4"),
 			OtherColumns: map[schema.ColumnKey]any{
-				schema.ColumnSrcMAC:   uint64(0x001f33d98160),
-				schema.ColumnDstMAC:   uint64(0x00e01c3c17c2),
-				schema.ColumnPackets:  uint64(25),
-				schema.ColumnBytes:    uint64(1546),
-				schema.ColumnSrcPort:  uint16(25),
-				schema.ColumnDstPort:  uint16(1470),
-				schema.ColumnEType:    uint32(constants.ETypeIPv4),
-				schema.ColumnProto:    uint32(constants.ProtoTCP),
-				schema.ColumnTCPFlags: uint16(0x1b),
+				schema.ColumnSrcMAC:        uint64(0x001f33d98160),
+				schema.ColumnDstMAC:        uint64(0x00e01c3c17c2),
+				schema.ColumnPackets:       uint64(25),
+				schema.ColumnBytes:         uint64(1546),
+				schema.ColumnSrcPort:       uint16(25),
+				schema.ColumnDstPort:       uint16(1470),
+				schema.ColumnEType:         uint32(constants.ETypeIPv4),
+				schema.ColumnProto:         uint32(constants.ProtoTCP),
+				schema.ColumnTCPFlags:      uint16(0x1b),
+				schema.ColumnTimeFlowStart: uint32(1254722767),
+				schema.ColumnTimeFlowEnd:   uint32(1254722775),
+				schema.ColumnFlowDuration:  uint32(7577),
 			},
 		}, {
 			// Last biflow, direct, no reverse
@@ -957,12 +1004,15 @@ func TestDecodeRFC5103(t *testing.T) {
 			SrcAddr:         netip.MustParseAddr("::ffff:192.168.1.1"),
 			DstAddr:         netip.MustParseAddr("::ffff:10.10.1.4"),
 			OtherColumns: map[schema.ColumnKey]any{
-				schema.ColumnSrcMAC:  uint64(0x001f33d98160),
-				schema.ColumnDstMAC:  uint64(0x00e01c3c17c2),
-				schema.ColumnPackets: uint64(4),
-				schema.ColumnBytes:   uint64(2304),
-				schema.ColumnEType:   uint32(constants.ETypeIPv4),
-				schema.ColumnProto:   uint32(constants.ProtoICMPv4),
+				schema.ColumnSrcMAC:        uint64(0x001f33d98160),
+				schema.ColumnDstMAC:        uint64(0x00e01c3c17c2),
+				schema.ColumnPackets:       uint64(4),
+				schema.ColumnBytes:         uint64(2304),
+				schema.ColumnEType:         uint32(constants.ETypeIPv4),
+				schema.ColumnProto:         uint32(constants.ProtoICMPv4),
+				schema.ColumnTimeFlowStart: uint32(1254722770),
+				schema.ColumnTimeFlowEnd:   uint32(1254722770),
+				schema.ColumnFlowDuration:  uint32(1),
 			},
 		},
 	}
@@ -1097,6 +1147,9 @@ func TestDecodeSRv6(t *testing.T) {
 				schema.ColumnProto:         uint32(constants.ProtoICMPv4),
 				schema.ColumnIPTTL:         uint8(63),
 				schema.ColumnIPFragmentID:  uint32(0xc96b),
+				schema.ColumnSrcAddrTunnel: netip.MustParseAddr("fc30:2200:1b::"),
+				schema.ColumnDstAddrTunnel: netip.MustParseAddr("fc30:2200:23:e009::"),
+				schema.ColumnTunnelProto:   uint8(constants.ProtoIPv4),
 				// schema.ColumnICMPv4Type: uint8(0),
 			},
 		},
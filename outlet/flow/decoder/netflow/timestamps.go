@@ -0,0 +1,67 @@
+// SPDX-FileCopyrightText: 2026 Free Mobile
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package netflow
+
+import "akvorado/common/schema"
+
+// ntpEpochOffset is the number of seconds between the NTP epoch (1900) and the
+// Unix epoch (1970).
+const ntpEpochOffset = 2_208_988_800
+
+// packetTimes are the timestamps from the header of a packet. They turn the
+// relative timestamps of the records into absolute ones.
+type packetTimes struct {
+	export    uint64 // export time, in seconds since epoch
+	sysUptime uint64 // uptime of the exporter at export time, in milliseconds (NetFlow v5 and v9)
+}
+
+// flowTimes are the timestamps of a flow, in milliseconds since epoch. A zero
+// value means the timestamp is unknown.
+type flowTimes struct {
+	start    uint64
+	end      uint64
+	duration uint64
+
+	// Timestamps relative to the boot of the exporter, in milliseconds.
+	startUptime uint64
+	endUptime   uint64
+	// Boot time of the exporter, in milliseconds since epoch, when the record
+	// tells it (IPFIX systemInitTimeMilliseconds).
+	systemInit uint64
+}
+
+// ntpToMilliseconds converts an NTP timestamp, used by IPFIX for microsecond
+// and nanosecond timestamps (RFC 7011, section 6.1.9 and 6.1.10), to
+// milliseconds since epoch.
+func ntpToMilliseconds(v uint64) uint64 {
+	seconds := v >> 32
+	if seconds < ntpEpochOffset {
+		return 0
+	}
+	return (seconds-ntpEpochOffset)*1000 + (v&0xffffffff)*1000>>32
+}
+
+// append adds the timestamps of the flow to the flow message. Relative
+// timestamps need the boot time of the exporter: either from the record or from
+// the header of the packet.
+func (ft flowTimes) append(pt packetTimes, bf *schema.FlowMessage) {
+	boot := ft.systemInit
+	if boot == 0 && pt.export > 0 && pt.sysUptime > 0 {
+		boot = pt.export*1000 - pt.sysUptime
+	}
+	if boot > 0 {
+		if ft.start == 0 && ft.startUptime > 0 {
+			ft.start = boot + ft.startUptime
+		}
+		if ft.end == 0 && ft.endUptime > 0 {
+			ft.end = boot + ft.endUptime
+		}
+	}
+	if ft.duration == 0 && ft.start > 0 && ft.end > ft.start {
+		ft.duration = ft.end - ft.start
+	}
+	bf.AppendDateTime(schema.ColumnTimeFlowStart, uint32(ft.start/1000))
+	bf.AppendDateTime(schema.ColumnTimeFlowEnd, uint32(ft.end/1000))
+	bf.AppendUint(schema.ColumnFlowDuration, ft.duration)
+}
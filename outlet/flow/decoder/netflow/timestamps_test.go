@@ -0,0 +1,99 @@
+// SPDX-FileCopyrightText: 2026 Free Mobile
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package netflow
+
+import (
+	"testing"
+
+	"akvorado/common/helpers"
+	"akvorado/common/schema"
+)
+
+func TestNTPToMilliseconds(t *testing.T) {
+	cases := []struct {
+		Input    uint64
+		Expected uint64
+	}{
+		{0, 0},
+		// Before the Unix epoch
+		{1 << 32, 0},
+		{ntpEpochOffset << 32, 0},
+		{(ntpEpochOffset + 1) << 32, 1000},
+		{(ntpEpochOffset+1)<<32 | 1<<31, 1500},
+		// From ipfixprobe-data.pcap
+		{14876527905155294439, 1254722767492},
+	}
+	for _, tc := range cases {
+		if got := ntpToMilliseconds(tc.Input); got != tc.Expected {
+			t.Errorf("ntpToMilliseconds(%d) = %d, expected %d", tc.Input, got, tc.Expected)
+		}
+	}
+}
+
+func TestFlowTimesAppend(t *testing.T) {
+	cases := []struct {
+		Description string
+		Flow        flowTimes
+		Packet      packetTimes
+		Expected    map[schema.ColumnKey]any
+	}{
+		{
+			Description: "no timestamps",
+			Expected:    map[schema.ColumnKey]any{},
+		}, {
+			Description: "absolute timestamps",
+			Flow:        flowTimes{start: 1_700_000_000_500, end: 1_700_000_010_000},
+			Expected: map[schema.ColumnKey]any{
+				schema.ColumnTimeFlowStart: uint32(1_700_000_000),
+				schema.ColumnTimeFlowEnd:   uint32(1_700_000_010),
+				schema.ColumnFlowDuration:  uint32(9500),
+			},
+		}, {
+			Description: "explicit duration",
+			Flow:        flowTimes{start: 1_700_000_000_000, end: 1_700_000_010_000, duration: 12},
+			Expected: map[schema.ColumnKey]any{
+				schema.ColumnTimeFlowStart: uint32(1_700_000_000),
+				schema.ColumnTimeFlowEnd:   uint32(1_700_000_010),
+				schema.ColumnFlowDuration:  uint32(12),
+			},
+		}, {
+			Description: "relative timestamps with packet header",
+			Flow:        flowTimes{startUptime: 10_000, endUptime: 25_000},
+			Packet:      packetTimes{export: 1_700_000_100, sysUptime: 30_000},
+			Expected: map[schema.ColumnKey]any{
+				schema.ColumnTimeFlowStart: uint32(1_700_000_080),
+				schema.ColumnTimeFlowEnd:   uint32(1_700_000_095),
+				schema.ColumnFlowDuration:  uint32(15_000),
+			},
+		}, {
+			Description: "relative timestamps with system init time",
+			Flow:        flowTimes{startUptime: 10_000, endUptime: 25_000, systemInit: 1_700_000_000_000},
+			Packet:      packetTimes{export: 1_700_000_100},
+			Expected: map[schema.ColumnKey]any{
+				schema.ColumnTimeFlowStart: uint32(1_700_000_010),
+				schema.ColumnTimeFlowEnd:   uint32(1_700_000_025),
+				schema.ColumnFlowDuration:  uint32(15_000),
+			},
+		}, {
+			Description: "relative timestamps without boot time",
+			Flow:        flowTimes{startUptime: 10_000, endUptime: 25_000},
+			Packet:      packetTimes{export: 1_700_000_100},
+			Expected:    map[schema.ColumnKey]any{},
+		},
+	}
+	sch := schema.NewMock(t).EnableAllColumns()
+	for _, tc := range cases {
+		t.Run(tc.Description, func(t *testing.T) {
+			bf := sch.NewFlowMessage()
+			tc.Flow.append(tc.Packet, bf)
+			got := bf.OtherColumns
+			if got == nil {
+				got = map[schema.ColumnKey]any{}
+			}
+			if diff := helpers.Diff(got, tc.Expected); diff != "" {
+				t.Fatalf("append() (-got, +want):\n%s", diff)
+			}
+		})
+	}
+}
@@ -624,6 +624,10 @@ func TestDecode(t *testing.T) {
 					schema.ColumnIPTTL:         uint8(64),
 					schema.ColumnICMPv6Type:    uint8(128),
 					schema.ColumnIPv6FlowLabel: uint32(0x0a461c),
+					schema.ColumnSrcAddrTunnel: netip.MustParseAddr("::ffff:192.168.108.40"),
+					schema.ColumnDstAddrTunnel: netip.MustParseAddr("::ffff:192.168.15.14"),
+					schema.ColumnTunnelProto:   uint8(constants.ProtoUDP),
+					schema.ColumnTunnelID:      uint32(100),
 					// schema.ColumnICMPv6Code:   0,
 				},
 			},
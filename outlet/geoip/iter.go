@@ -7,11 +7,14 @@ import (
 	"net/netip"
 )
 
-// GeoInfo describes geographical data of a geo database.
+// GeoInfo describes geographical data of a geo database. Coordinates are
+// zero when the database does not provide them.
 type GeoInfo struct {
-	Country string
-	City    string
-	State   string
+	Country   string
+	City      string
+	State     string
+	Latitude  float64
+	Longitude float64
 }
 
 // ASNInfo describes ASN data of an ASN database.
@@ -34,6 +34,10 @@ func (g *ipinfoGeoInfo) UnmarshalMaxMindDBCursor(cursor mmdbdata.Cursor) (mmdbda
 			g.State, next, err = value.ReadString()
 		case "city":
 			g.City, next, err = value.ReadString()
+		case "latitude":
+			g.Latitude, next, err = readIPInfoCoordinate(value)
+		case "longitude":
+			g.Longitude, next, err = readIPInfoCoordinate(value)
 		default:
 			next, err = value.Skip()
 		}
@@ -44,6 +48,20 @@ func (g *ipinfoGeoInfo) UnmarshalMaxMindDBCursor(cursor mmdbdata.Cursor) (mmdbda
 	return entries.End(next)
 }
 
+// readIPInfoCoordinate reads a coordinate. They are stored as strings. An
+// invalid coordinate is ignored.
+func readIPInfoCoordinate(cursor mmdbdata.Cursor) (float64, mmdbdata.Cursor, error) {
+	coordinate, next, err := cursor.ReadString()
+	if err != nil {
+		return 0, mmdbdata.Cursor{}, err
+	}
+	value, err := strconv.ParseFloat(coordinate, 64)
+	if err != nil {
+		return 0, next, nil
+	}
+	return value, next, nil
+}
+
 // ipinfoASNInfo is an alias for ASNInfo with ipinfo-specific unmarshaling
 type ipinfoASNInfo ASNInfo
 
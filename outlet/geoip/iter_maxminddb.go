@@ -32,6 +32,8 @@ func (g *maxmindGeoInfo) UnmarshalMaxMindDBCursor(cursor mmdbdata.Cursor) (mmdbd
 			next, err = g.unmarshalCity(value)
 		case "subdivisions":
 			next, err = g.unmarshalSubdivisions(value)
+		case "location":
+			next, err = g.unmarshalLocation(value)
 		default:
 			next, err = value.Skip()
 		}
@@ -66,6 +68,33 @@ func (g *maxmindGeoInfo) unmarshalCountry(cursor mmdbdata.Cursor) (mmdbdata.Curs
 	return entries.End(next)
 }
 
+// unmarshalLocation keeps the coordinates of the location.
+func (g *maxmindGeoInfo) unmarshalLocation(cursor mmdbdata.Cursor) (mmdbdata.Cursor, error) {
+	entries, err := cursor.MapReader()
+	if err != nil {
+		return mmdbdata.Cursor{}, err
+	}
+	next := entries.First()
+	for range entries.Len() {
+		key, value, keyErr := next.ReadMapKey()
+		if keyErr != nil {
+			return mmdbdata.Cursor{}, keyErr
+		}
+		switch string(key) {
+		case "latitude":
+			g.Latitude, next, err = value.ReadFloat()
+		case "longitude":
+			g.Longitude, next, err = value.ReadFloat()
+		default:
+			next, err = value.Skip()
+		}
+		if err != nil {
+			return mmdbdata.Cursor{}, err
+		}
+	}
+	return entries.End(next)
+}
+
 // unmarshalCity looks for the localized names of the city.
 func (g *maxmindGeoInfo) unmarshalCity(cursor mmdbdata.Cursor) (mmdbdata.Cursor, error) {
 	entries, err := cursor.MapReader()
@@ -95,13 +95,13 @@ func TestIterGeoDatabases(t *testing.T) {
 		"203.0.113.5",
 	}
 	expected := []GeoInfo{
-		{Country: "JP", State: "Shimane", City: "Matsue"},
+		{Country: "JP", State: "Shimane", City: "Matsue", Latitude: 35.48333, Longitude: 133.05},
 		{Country: "SG"},
 		{Country: "CA"},
 		{Country: "HK"},
-		{Country: "GB", State: "ENG", City: "Boxford"},
-		{Country: "IT"},
-		{Country: "BT"},
+		{Country: "GB", State: "ENG", City: "Boxford", Latitude: 51.75, Longitude: -1.25},
+		{Country: "IT", Latitude: 42.83333, Longitude: 12.83333},
+		{Country: "BT", Latitude: 27.5, Longitude: 90.5},
 		{},
 	}
 	if diff := helpers.Diff(iterGeo(t, c, ips), expected); diff != "" {